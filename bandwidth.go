@@ -0,0 +1,77 @@
+package s3resource
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// maxBandwidthBurst caps how many bytes are read or written at once, so that
+// concurrent parts take turns rather than waiting for whole buffers
+const maxBandwidthBurst = 256 * 1024
+
+// newBandwidthLimiter returns a token bucket refilled with bytesPerSecond,
+// or nil if bytesPerSecond is not positive
+func newBandwidthLimiter(bytesPerSecond int64) *rate.Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(min(bytesPerSecond, maxBandwidthBurst)))
+}
+
+// waitForBandwidth blocks until n bytes may be transferred or ctx is done
+func waitForBandwidth(ctx context.Context, limiter *rate.Limiter, n int) error {
+	for n > 0 {
+		chunk := min(n, limiter.Burst())
+		if err := limiter.WaitN(ctx, chunk); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+type throttledReader struct {
+	io.Reader
+	limiter *rate.Limiter
+	ctx     context.Context
+}
+
+func (tr throttledReader) Read(p []byte) (int, error) {
+	if len(p) > tr.limiter.Burst() {
+		p = p[:tr.limiter.Burst()]
+	}
+
+	n, err := tr.Reader.Read(p)
+	if waitErr := waitForBandwidth(tr.ctx, tr.limiter, n); waitErr != nil {
+		return n, waitErr
+	}
+	return n, err
+}
+
+// throttledSectionReader throttles a part body while keeping it seekable,
+// so that the SDK can rewind it to retry
+type throttledSectionReader struct {
+	*io.SectionReader
+	limiter *rate.Limiter
+	ctx     context.Context
+}
+
+func (tr throttledSectionReader) Read(p []byte) (int, error) {
+	return throttledReader{tr.SectionReader, tr.limiter, tr.ctx}.Read(p)
+}
+
+type throttledWriterAt struct {
+	io.WriterAt
+	limiter *rate.Limiter
+	ctx     context.Context
+}
+
+func (tw throttledWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if err := waitForBandwidth(tw.ctx, tw.limiter, len(p)); err != nil {
+		return 0, err
+	}
+	return tw.WriterAt.WriteAt(p, off)
+}
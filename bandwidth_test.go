@@ -0,0 +1,65 @@
+package s3resource
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bandwidth limiting", func() {
+	const bytesPerSecond = 1024 * 1024
+
+	var data []byte
+
+	BeforeEach(func() {
+		// The bucket starts full, so this takes about half a second
+		data = bytes.Repeat([]byte("x"), maxBandwidthBurst+bytesPerSecond/2)
+	})
+
+	It("does not limit when no bandwidth is given", func() {
+		Expect(newBandwidthLimiter(0)).To(BeNil())
+	})
+
+	It("throttles reads", func() {
+		reader := throttledReader{bytes.NewReader(data), newBandwidthLimiter(bytesPerSecond), context.Background()}
+
+		start := time.Now()
+		read, err := io.ReadAll(reader)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(read).To(Equal(data))
+		Expect(time.Since(start)).To(BeNumerically(">=", 400*time.Millisecond))
+	})
+
+	It("throttles writes shared across writers", func() {
+		file, err := os.Create(filepath.Join(GinkgoT().TempDir(), "file"))
+		Expect(err).ToNot(HaveOccurred())
+		defer file.Close()
+
+		writer := throttledWriterAt{file, newBandwidthLimiter(bytesPerSecond), context.Background()}
+		half := len(data) / 2
+
+		start := time.Now()
+		done := make(chan error, 2)
+		go func() {
+			_, err := writer.WriteAt(data[:half], 0)
+			done <- err
+		}()
+		go func() {
+			_, err := writer.WriteAt(data[half:], int64(half))
+			done <- err
+		}()
+		Expect(<-done).To(Succeed())
+		Expect(<-done).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 400*time.Millisecond))
+
+		written, err := os.ReadFile(file.Name())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(written).To(Equal(data))
+	})
+})
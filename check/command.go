@@ -1,36 +1,91 @@
 package check
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"os"
 
 	s3resource "github.com/concourse/s3-resource"
 	"github.com/concourse/s3-resource/versions"
 )
 
 type Command struct {
+	stderr   io.Writer
 	s3client s3resource.S3Client
+
+	// queue receives the event notifications of the bucket, or is nil if
+	// the bucket is listed on every check
+	queue s3resource.EventQueue
+	// stateDir keeps when the bucket was last listed, between checks made
+	// in the same container
+	stateDir string
 }
 
-func NewCommand(s3client s3resource.S3Client) *Command {
+func NewCommand(stderr io.Writer, s3client s3resource.S3Client, queue s3resource.EventQueue) *Command {
 	return &Command{
+		stderr:   stderr,
 		s3client: s3client,
+		queue:    queue,
+		stateDir: os.TempDir(),
 	}
 }
 
-func (command *Command) Run(request Request) (Response, error) {
+func (command *Command) Run(ctx context.Context, request Request) (Response, error) {
+	for _, note := range request.Source.ApplyProvider() {
+		fmt.Fprintln(command.stderr, note)
+	}
+
 	if ok, message := request.Source.IsValid(); !ok {
-		return Response{}, errors.New(message)
+		return Response{}, s3resource.NewConfigError(message)
+	}
+
+	if request.Source.Preflight {
+		err := command.preflight(ctx, request)
+		if err != nil {
+			return Response{}, err
+		}
 	}
 
+	if command.queue != nil {
+		return command.checkByEvents(ctx, request)
+	}
+
+	return command.check(ctx, request)
+}
+
+func (command *Command) check(ctx context.Context, request Request) (Response, error) {
 	if request.Source.Regexp != "" {
-		return command.checkByRegex(request), nil
+		return command.checkByRegex(ctx, request)
 	} else {
-		return command.checkByVersionedFile(request), nil
+		return command.checkByVersionedFile(ctx, request)
+	}
+}
+
+// preflight checks the bucket and that the versions can be listed
+func (command *Command) preflight(ctx context.Context, request Request) error {
+	options := s3resource.PreflightOptions{
+		Identity: true,
+		Actions:  []string{"s3:ListBucket"},
+	}
+	if request.Source.VersionedFile != "" {
+		options.Versioned = true
+		options.Actions = []string{"s3:ListBucketVersions"}
+		options.Prefix = request.Source.VersionedFile
 	}
+
+	report := command.s3client.Preflight(ctx, request.Source.Bucket, options)
+	report.Print(command.stderr)
+
+	return report.Err()
 }
 
-func (command *Command) checkByRegex(request Request) Response {
-	extractions := versions.GetBucketFileVersions(command.s3client, request.Source)
+func (command *Command) checkByRegex(ctx context.Context, request Request) (Response, error) {
+	extractions, err := command.bucketFileVersions(ctx, request.Source, request.Version.Path)
+	if err != nil {
+		return nil, err
+	}
 
 	if request.Source.InitialPath != "" {
 		extraction, ok := versions.Extract(request.Source.InitialPath, request.Source.Regexp)
@@ -40,24 +95,39 @@ func (command *Command) checkByRegex(request Request) Response {
 	}
 
 	if len(extractions) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	lastVersion, matched := versions.Extract(request.Version.Path, request.Source.Regexp)
 	if !matched {
-		return latestVersion(extractions)
+		return latestVersion(extractions), nil
 	} else {
-		return newVersions(lastVersion, extractions)
+		return newVersions(lastVersion, extractions), nil
 	}
 }
 
-func (command *Command) checkByVersionedFile(request Request) Response {
+// bucketFileVersions reads the versions from the inventory of the bucket if
+// one is configured, and lists the bucket until its first report arrives
+func (command *Command) bucketFileVersions(ctx context.Context, source s3resource.Source, lastPath string) (versions.Extractions, error) {
+	if !source.Inventory.IsEnabled() {
+		return versions.GetBucketFileVersions(ctx, command.s3client, source)
+	}
+
+	extractions, err := versions.GetInventoryFileVersions(ctx, command.s3client, source, lastPath)
+	if errors.Is(err, versions.ErrNoInventory) {
+		fmt.Fprintf(command.stderr, "%s, listing the bucket instead\n", err)
+		return versions.GetBucketFileVersions(ctx, command.s3client, source)
+	}
+	return extractions, err
+}
+
+func (command *Command) checkByVersionedFile(ctx context.Context, request Request) (Response, error) {
 	response := Response{}
 
-	bucketVersions, err := command.s3client.BucketFileVersions(request.Source.Bucket, request.Source.VersionedFile)
+	bucketVersions, err := command.s3client.BucketFileVersions(ctx, request.Source.Bucket, request.Source.VersionedFile)
 
 	if err != nil {
-		s3resource.Fatal("finding versions", err)
+		return nil, fmt.Errorf("error finding versions: %w", err)
 	}
 
 	if request.Source.InitialVersion != "" {
@@ -65,7 +135,7 @@ func (command *Command) checkByVersionedFile(request Request) Response {
 	}
 
 	if len(bucketVersions) == 0 {
-		return response
+		return response, nil
 	}
 
 	requestVersionIndex := -1
@@ -93,7 +163,7 @@ func (command *Command) checkByVersionedFile(request Request) Response {
 		}
 	}
 
-	return response
+	return response, nil
 }
 
 func latestVersion(extractions versions.Extractions) Response {
@@ -1,10 +1,13 @@
 package check_test
 
 import (
+	"context"
+	"errors"
 	"os"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 
 	s3resource "github.com/concourse/s3-resource"
 	"github.com/concourse/s3-resource/fakes"
@@ -18,6 +21,7 @@ var _ = Describe("Check Command", func() {
 			tmpPath string
 			request Request
 
+			stderr   *gbytes.Buffer
 			s3client *fakes.FakeS3Client
 			command  *Command
 		)
@@ -34,7 +38,8 @@ var _ = Describe("Check Command", func() {
 			}
 
 			s3client = &fakes.FakeS3Client{}
-			command = NewCommand(s3client)
+			stderr = gbytes.NewBuffer()
+			command = NewCommand(stderr, s3client, nil)
 
 			s3client.ChunkedBucketListReturnsOnCall(0, s3resource.BucketListChunk{
 				Truncated:         false,
@@ -58,6 +63,8 @@ var _ = Describe("Check Command", func() {
 		})
 
 		AfterEach(func() {
+			stderr.Close()
+
 			err := os.RemoveAll(tmpPath)
 			Ω(err).ShouldNot(HaveOccurred())
 		})
@@ -67,7 +74,7 @@ var _ = Describe("Check Command", func() {
 				request.Version.Path = ""
 				request.Source.Regexp = "files/abc-(.*).tgz"
 
-				response, err := command.Run(request)
+				response, err := command.Run(context.Background(), request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(response).Should(HaveLen(1))
@@ -84,7 +91,7 @@ var _ = Describe("Check Command", func() {
 					request.Source.InitialPath = "files/abc-0.0.tgz"
 					request.Source.Regexp = "files/abc-(.*).tgz"
 
-					response, err := command.Run(request)
+					response, err := command.Run(context.Background(), request)
 					Ω(err).ShouldNot(HaveOccurred())
 
 					Ω(response).Should(HaveLen(1))
@@ -99,7 +106,7 @@ var _ = Describe("Check Command", func() {
 			Context("when the regexp does not match anything", func() {
 				It("does not explode", func() {
 					request.Source.Regexp = "no-files/missing-(.*).tgz"
-					response, err := command.Run(request)
+					response, err := command.Run(context.Background(), request)
 					Ω(err).ShouldNot(HaveOccurred())
 
 					Ω(response).Should(HaveLen(0))
@@ -111,7 +118,7 @@ var _ = Describe("Check Command", func() {
 						request.Source.InitialPath = "no-files/missing-0.0.tgz"
 						request.Source.Regexp = "no-files/missing-(.*).tgz"
 
-						response, err := command.Run(request)
+						response, err := command.Run(context.Background(), request)
 						Ω(err).ShouldNot(HaveOccurred())
 
 						Ω(response).Should(HaveLen(1))
@@ -128,7 +135,7 @@ var _ = Describe("Check Command", func() {
 				It("returns the latest version that matches the regex", func() {
 					request.Version.Path = "files/abc-0.0.1.tgz"
 					request.Source.Regexp = `files/abc-(2\.33.*).tgz`
-					response, err := command.Run(request)
+					response, err := command.Run(context.Background(), request)
 					Ω(err).ShouldNot(HaveOccurred())
 
 					Ω(response).Should(HaveLen(1))
@@ -139,7 +146,7 @@ var _ = Describe("Check Command", func() {
 			Context("when the regexp does not contain any magic regexp char", func() {
 				It("does not explode", func() {
 					request.Source.Regexp = "files/abc-3/no-magic"
-					response, err := command.Run(request)
+					response, err := command.Run(context.Background(), request)
 					Ω(err).ShouldNot(HaveOccurred())
 
 					Ω(response).Should(HaveLen(0))
@@ -153,7 +160,7 @@ var _ = Describe("Check Command", func() {
 					request.Version.Path = "files/abc-2.4.3.tgz"
 					request.Source.Regexp = "files/abc-(.*).tgz"
 
-					response, err := command.Run(request)
+					response, err := command.Run(context.Background(), request)
 					Ω(err).ShouldNot(HaveOccurred())
 
 					Ω(response).Should(HaveLen(3))
@@ -185,7 +192,7 @@ var _ = Describe("Check Command", func() {
 						request.Version.VersionID = "file-version-2"
 						request.Source.VersionedFile = "files/versioned-file"
 
-						response, err := command.Run(request)
+						response, err := command.Run(context.Background(), request)
 						Ω(err).ShouldNot(HaveOccurred())
 
 						Ω(response).Should(HaveLen(2))
@@ -209,7 +216,7 @@ var _ = Describe("Check Command", func() {
 						request.Version.VersionID = ""
 						request.Source.VersionedFile = "files/versioned-file"
 
-						response, err := command.Run(request)
+						response, err := command.Run(context.Background(), request)
 						Ω(err).ShouldNot(HaveOccurred())
 
 						Ω(response).Should(HaveLen(0))
@@ -221,7 +228,7 @@ var _ = Describe("Check Command", func() {
 							request.Source.VersionedFile = "files/versioned-file"
 							request.Source.InitialVersion = "file-version-0"
 
-							response, err := command.Run(request)
+							response, err := command.Run(context.Background(), request)
 							Ω(err).ShouldNot(HaveOccurred())
 
 							Ω(response).Should(HaveLen(1))
@@ -233,6 +240,258 @@ var _ = Describe("Check Command", func() {
 						})
 					})
 				})
+
+				Context("when the bucket is not versioned", func() {
+					BeforeEach(func() {
+						s3client.BucketFileVersionsReturns([]string{}, &s3resource.Error{
+							Kind: s3resource.ErrNotVersioned,
+							Err:  errors.New("bucket is not versioned"),
+						})
+					})
+
+					It("returns an error", func() {
+						request.Source.VersionedFile = "files/versioned-file"
+
+						_, err := command.Run(context.Background(), request)
+						Ω(err).Should(MatchError("error finding versions: bucket is not versioned"))
+						Ω(errors.Is(err, s3resource.ErrNotVersioned)).Should(BeTrue())
+					})
+				})
+			})
+		})
+
+		Context("when an inventory is configured", func() {
+			BeforeEach(func() {
+				request.Source.Inventory = s3resource.Inventory{Prefix: "inventory/bucket-name/all"}
+			})
+
+			It("lists the bucket until the first report is delivered", func() {
+				request.Source.Regexp = "files/abc-(.*).tgz"
+				s3client.ChunkedBucketListReturnsOnCall(0, s3resource.BucketListChunk{}, nil)
+				s3client.ChunkedBucketListReturnsOnCall(1, s3resource.BucketListChunk{
+					Paths: []string{"files/abc-0.0.1.tgz", "files/abc-3.53.tgz"},
+				}, nil)
+
+				response, err := command.Run(context.Background(), request)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(response).Should(ConsistOf(s3resource.Version{Path: "files/abc-3.53.tgz"}))
+				Ω(stderr).Should(gbytes.Say("listing the bucket instead"))
+
+				_, _, prefix, _ := s3client.ChunkedBucketListArgsForCall(0)
+				Ω(prefix).Should(Equal("inventory/bucket-name/all/"))
+				_, _, prefix, _ = s3client.ChunkedBucketListArgsForCall(1)
+				Ω(prefix).Should(Equal("files/"))
+			})
+
+			It("requires regexp", func() {
+				request.Source.VersionedFile = "files/versioned-file"
+
+				_, err := command.Run(context.Background(), request)
+				Ω(err).Should(MatchError("inventory can only be used with regexp"))
+				Ω(errors.Is(err, s3resource.ErrInvalidConfig)).Should(BeTrue())
+			})
+		})
+
+		Context("when an SQS queue is configured", func() {
+			var queue *fakes.FakeEventQueue
+
+			BeforeEach(func() {
+				GinkgoT().Setenv("TMPDIR", tmpPath)
+
+				queue = &fakes.FakeEventQueue{}
+				command = NewCommand(stderr, s3client, queue)
+
+				request.Source.SQSQueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/bucket-events"
+				request.Source.Regexp = "files/abc-(.*).tgz"
+
+				queue.ReceiveEventsReturns(s3resource.Events{
+					Messages: []s3resource.EventMessage{
+						{ReceiptHandle: "message-1", Objects: []s3resource.ObjectEvent{
+							{Bucket: "bucket-name", Key: "files/abc-3.60.tgz", Sequencer: "02"},
+							{Bucket: "bucket-name", Key: "files/abc-2.4.3.tgz", Sequencer: "01"},
+						}},
+						{ReceiptHandle: "message-2", Objects: []s3resource.ObjectEvent{
+							{Bucket: "bucket-name", Key: "files/abc-1.0.0.tgz", Sequencer: "03"},
+						}},
+						{ReceiptHandle: "message-3", Objects: []s3resource.ObjectEvent{
+							{Bucket: "bucket-name", Key: "files/nested/abc-4.0.0.tgz", Sequencer: "04"},
+						}},
+						{ReceiptHandle: "message-4", Objects: []s3resource.ObjectEvent{
+							{Bucket: "other-bucket", Key: "files/abc-5.0.0.tgz", Sequencer: "05"},
+						}},
+						{ReceiptHandle: "message-5"},
+					},
+				}, nil)
+			})
+
+			It("lists the bucket when there is no previous version", func() {
+				response, err := command.Run(context.Background(), request)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(response).Should(Equal(Response{{Path: "files/abc-3.53.tgz"}}))
+				Ω(s3client.ChunkedBucketListCallCount()).Should(Equal(1))
+				Ω(queue.DeleteEventsCallCount()).Should(Equal(1))
+			})
+
+			It("deletes only the messages about its objects, or without ObjectCreated events", func() {
+				_, err := command.Run(context.Background(), request)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(queue.DeleteEventsCallCount()).Should(Equal(1))
+				_, events := queue.DeleteEventsArgsForCall(0)
+				Ω(events.Messages).Should(HaveLen(3))
+				Ω(events.Messages[0].ReceiptHandle).Should(Equal("message-1"))
+				Ω(events.Messages[1].ReceiptHandle).Should(Equal("message-2"))
+				Ω(events.Messages[2].ReceiptHandle).Should(Equal("message-5"))
+			})
+
+			It("merges the created objects with the previous version once the bucket was listed", func() {
+				request.Version.Path = "files/abc-3.53.tgz"
+				_, err := command.Run(context.Background(), request)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				request.Version.Path = "files/abc-2.4.3.tgz"
+				response, err := command.Run(context.Background(), request)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(response).Should(Equal(Response{
+					{Path: "files/abc-2.4.3.tgz"},
+					{Path: "files/abc-3.60.tgz"},
+				}))
+
+				Ω(s3client.ChunkedBucketListCallCount()).Should(Equal(1))
+				Ω(queue.DeleteEventsCallCount()).Should(Equal(2))
+			})
+
+			It("lists the bucket again once the listing interval has passed", func() {
+				request.Source.SQSListingInterval = "1ns"
+				request.Version.Path = "files/abc-3.53.tgz"
+				s3client.ChunkedBucketListReturnsOnCall(1, s3resource.BucketListChunk{
+					Paths: []string{"files/abc-3.53.tgz"},
+				}, nil)
+
+				for range 2 {
+					response, err := command.Run(context.Background(), request)
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(response).Should(Equal(Response{{Path: "files/abc-3.53.tgz"}}))
+				}
+
+				Ω(s3client.ChunkedBucketListCallCount()).Should(Equal(2))
+			})
+
+			It("orders the versions of a versioned file by their sequencers", func() {
+				request.Source.Regexp = ""
+				request.Source.VersionedFile = "files/versioned-file"
+				request.Version.VersionID = "file-version-1"
+				s3client.BucketFileVersionsReturns([]string{"file-version-1"}, nil)
+
+				_, err := command.Run(context.Background(), request)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				queue.ReceiveEventsReturns(s3resource.Events{
+					Messages: []s3resource.EventMessage{
+						{ReceiptHandle: "message-1", Objects: []s3resource.ObjectEvent{
+							{Bucket: "bucket-name", Key: "files/versioned-file", VersionID: "file-version-3", Sequencer: "0100"},
+							{Bucket: "bucket-name", Key: "files/versioned-file", VersionID: "file-version-2", Sequencer: "F0"},
+						}},
+						{ReceiptHandle: "message-2", Objects: []s3resource.ObjectEvent{
+							{Bucket: "bucket-name", Key: "files/versioned-file", VersionID: "file-version-1", Sequencer: "E0"},
+						}},
+						{ReceiptHandle: "message-3", Objects: []s3resource.ObjectEvent{
+							{Bucket: "bucket-name", Key: "files/other-file", VersionID: "other-version", Sequencer: "FF"},
+						}},
+					},
+				}, nil)
+
+				response, err := command.Run(context.Background(), request)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(response).Should(Equal(Response{
+					{VersionID: "file-version-1"},
+					{VersionID: "file-version-2"},
+					{VersionID: "file-version-3"},
+				}))
+				Ω(s3client.BucketFileVersionsCallCount()).Should(Equal(1))
+
+				_, events := queue.DeleteEventsArgsForCall(1)
+				Ω(events.Messages).Should(HaveLen(2))
+				Ω(events.Messages[0].ReceiptHandle).Should(Equal("message-1"))
+				Ω(events.Messages[1].ReceiptHandle).Should(Equal("message-2"))
+			})
+
+			It("keeps the messages when the check fails", func() {
+				s3client.ChunkedBucketListReturnsOnCall(0, s3resource.BucketListChunk{}, errors.New("S3 failure"))
+
+				_, err := command.Run(context.Background(), request)
+				Ω(err).Should(HaveOccurred())
+				Ω(queue.DeleteEventsCallCount()).Should(Equal(0))
+			})
+
+			It("returns an error when the queue cannot be read", func() {
+				queue.ReceiveEventsReturns(s3resource.Events{}, errors.New("queue failure"))
+
+				_, err := command.Run(context.Background(), request)
+				Ω(err).Should(MatchError("error receiving events: queue failure"))
+			})
+		})
+
+		Context("when listing the bucket fails", func() {
+			BeforeEach(func() {
+				s3client.ChunkedBucketListReturnsOnCall(0, s3resource.BucketListChunk{}, &s3resource.Error{
+					Kind:      s3resource.ErrAccessDenied,
+					Operation: "ListObjectsV2",
+					Err:       errors.New("access denied"),
+				})
+			})
+
+			It("returns an error", func() {
+				request.Source.Regexp = "files/abc-(.*).tgz"
+
+				_, err := command.Run(context.Background(), request)
+				Ω(err).Should(MatchError("error listing files: access denied"))
+				Ω(errors.Is(err, s3resource.ErrAccessDenied)).Should(BeTrue())
+			})
+		})
+
+		Context("when configured to run preflight checks", func() {
+			It("checks that the versions can be listed", func() {
+				request.Source.Preflight = true
+				request.Source.VersionedFile = "files/versioned-file"
+				s3client.BucketFileVersionsReturns([]string{}, nil)
+
+				_, err := command.Run(context.Background(), request)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(s3client.PreflightCallCount()).Should(Equal(1))
+				_, bucketName, options := s3client.PreflightArgsForCall(0)
+				Ω(bucketName).Should(Equal("bucket-name"))
+				Ω(options).Should(Equal(s3resource.PreflightOptions{
+					Identity:  true,
+					Versioned: true,
+					Actions:   []string{"s3:ListBucketVersions"},
+					Prefix:    "files/versioned-file",
+				}))
+			})
+
+			It("does not check for versions if a check fails", func() {
+				request.Source.Preflight = true
+				request.Source.Regexp = "files/abc-(.*).tgz"
+				s3client.PreflightReturns(s3resource.PreflightReport{
+					{Check: "credentials", Err: errors.New("expired")},
+				})
+
+				_, err := command.Run(context.Background(), request)
+				Ω(err).Should(MatchError("preflight check credentials failed: expired"))
+				Ω(stderr).Should(gbytes.Say("credentials"))
+				Ω(s3client.ChunkedBucketListCallCount()).Should(Equal(0))
+			})
+		})
+
+		Context("when the source is invalid", func() {
+			It("returns a config error", func() {
+				request.Source.Regexp = "files/abc-(.*).tgz"
+				request.Source.VersionedFile = "files/versioned-file"
+
+				_, err := command.Run(context.Background(), request)
+				Ω(err).Should(MatchError("please specify either regexp or versioned_file"))
+				Ω(errors.Is(err, s3resource.ErrInvalidConfig)).Should(BeTrue())
 			})
 		})
 	})
@@ -0,0 +1,166 @@
+package check
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	s3resource "github.com/concourse/s3-resource"
+	"github.com/concourse/s3-resource/versions"
+)
+
+// DefaultSQSListingInterval is how often the bucket is still listed when
+// sqs_queue_url is set, to catch up with lost or expired events
+const DefaultSQSListingInterval = time.Hour
+
+// checkByEvents finds the versions created since the last version in the
+// event notifications waiting in the queue. The bucket is listed instead
+// when there is no last version yet, and once every listing interval.
+func (command *Command) checkByEvents(ctx context.Context, request Request) (Response, error) {
+	events, err := command.queue.ReceiveEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error receiving events: %w", err)
+	}
+
+	var response Response
+	if lastVersion, ok := lastEventVersion(request); ok && !command.listingDue(request.Source) {
+		response = eventVersions(request, lastVersion, events.Objects())
+	} else {
+		response, err = command.check(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		command.recordListing(request.Source)
+	}
+
+	// The versions of the deleted events are in the response, or were in
+	// the listing. The events of other objects are left to the resources
+	// sharing the queue.
+	if err := command.queue.DeleteEvents(ctx, handledEvents(request.Source, events)); err != nil {
+		return nil, fmt.Errorf("error deleting events: %w", err)
+	}
+
+	return response, nil
+}
+
+// handledEvents returns the messages whose events are all about the objects
+// of source, including those which held no ObjectCreated events
+func handledEvents(source s3resource.Source, events s3resource.Events) s3resource.Events {
+	handled := s3resource.Events{Messages: []s3resource.EventMessage{}}
+	for _, message := range events.Messages {
+		if !slices.ContainsFunc(message.Objects, func(event s3resource.ObjectEvent) bool { return !handlesEvent(source, event) }) {
+			handled.Messages = append(handled.Messages, message)
+		}
+	}
+	return handled
+}
+
+// handlesEvent is whether the object of event is one of the objects of
+// source, whether it becomes a version or not
+func handlesEvent(source s3resource.Source, event s3resource.ObjectEvent) bool {
+	if event.Bucket != source.Bucket {
+		return false
+	}
+	if source.Regexp == "" {
+		return event.Key == source.VersionedFile
+	}
+
+	matchingPaths, err := versions.MatchPaths([]string{event.Key}, source.Regexp)
+	return err == nil && len(matchingPaths) > 0
+}
+
+// lastEventVersion returns the last version, if it can be compared with the
+// versions of the events
+func lastEventVersion(request Request) (s3resource.Version, bool) {
+	if request.Source.Regexp == "" {
+		return request.Version, request.Version.VersionID != ""
+	}
+
+	matchingPaths, err := versions.MatchPaths([]string{request.Version.Path}, request.Source.Regexp)
+	if err != nil || len(matchingPaths) == 0 {
+		return s3resource.Version{}, false
+	}
+	return request.Version, true
+}
+
+// eventVersions merges the objects created according to events with
+// lastVersion, as a check listing the bucket would
+func eventVersions(request Request, lastVersion s3resource.Version, events []s3resource.ObjectEvent) Response {
+	source := request.Source
+
+	var created []s3resource.ObjectEvent
+	for _, event := range events {
+		if event.Bucket == source.Bucket {
+			created = append(created, event)
+		}
+	}
+
+	if source.Regexp != "" {
+		paths := []string{lastVersion.Path}
+		for _, event := range created {
+			paths = append(paths, event.Key)
+		}
+		slices.Sort(paths)
+		paths = slices.Compact(paths)
+
+		// The regexp compiled when lastVersion was matched
+		matchingPaths, _ := versions.MatchPaths(paths, source.Regexp)
+		last, _ := versions.Extract(lastVersion.Path, source.Regexp)
+		return newVersions(last, versions.ExtractVersions(matchingPaths, source.Regexp))
+	}
+
+	slices.SortStableFunc(created, func(a s3resource.ObjectEvent, b s3resource.ObjectEvent) int {
+		return s3resource.CompareSequencers(a.Sequencer, b.Sequencer)
+	})
+
+	response := Response{lastVersion}
+	for _, event := range created {
+		if event.Key != source.VersionedFile || event.VersionID == "" {
+			continue
+		}
+		if !slices.ContainsFunc(response, func(version s3resource.Version) bool { return version.VersionID == event.VersionID }) {
+			response = append(response, s3resource.Version{VersionID: event.VersionID})
+		}
+	}
+	return response
+}
+
+// listingStateFile is where the time of the last listing of the bucket of
+// source is kept. It depends on the queue and the objects looked for, so
+// that resources sharing a container do not share it.
+func (command *Command) listingStateFile(source s3resource.Source) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{source.SQSQueueURL, source.Bucket, source.Regexp, source.VersionedFile}, "\x00")))
+	return filepath.Join(command.stateDir, "s3-resource-listing-"+hex.EncodeToString(sum[:8]))
+}
+
+// listingDue is whether the listing interval has passed since the bucket
+// was last listed, or the bucket has not been listed in this container
+func (command *Command) listingDue(source s3resource.Source) bool {
+	interval := DefaultSQSListingInterval
+	if source.SQSListingInterval != "" {
+		interval, _ = time.ParseDuration(source.SQSListingInterval)
+	}
+
+	contents, err := os.ReadFile(command.listingStateFile(source))
+	if err != nil {
+		return true
+	}
+	listedAt, err := time.Parse(time.RFC3339Nano, string(contents))
+	if err != nil {
+		return true
+	}
+
+	return time.Since(listedAt) >= interval
+}
+
+// recordListing records that the bucket was listed. A failure only means
+// that the bucket is listed again by the next check.
+func (command *Command) recordListing(source s3resource.Source) {
+	os.WriteFile(command.listingStateFile(source), []byte(time.Now().Format(time.RFC3339Nano)), 0644)
+}
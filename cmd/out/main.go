@@ -16,10 +16,20 @@ func main() {
 
 	var request out.Request
 	inputRequest(&request)
+	for _, note := range request.Source.ApplyProvider() {
+		s3resource.Sayf("%s\n", note)
+	}
+
+	ctx, cancel, err := s3resource.NewCommandContext(request.Source.Timeouts.Operation)
+	if err != nil {
+		s3resource.Fatal("parsing operation timeout", err)
+	}
+	defer cancel()
 
 	sourceDir := os.Args[1]
 
 	awsConfig, err := s3resource.NewAwsConfig(
+		ctx,
 		request.Source.AccessKeyID,
 		request.Source.SecretAccessKey,
 		request.Source.SessionToken,
@@ -28,9 +38,32 @@ func main() {
 		request.Source.SkipSSLVerification,
 		request.Source.CABundle,
 		request.Source.UseAwsCredsProvider,
+		s3resource.AwsConfigOptions{
+			Profile:           request.Source.AwsProfile,
+			SharedConfig:      request.Source.AwsConfig,
+			SharedCredentials: request.Source.AwsCredentials,
+			HTTPProxy:         request.Source.HTTPProxy,
+			HTTPSProxy:        request.Source.HTTPSProxy,
+			NoProxy:           request.Source.NoProxy,
+			ProxyUsername:     request.Source.ProxyUsername,
+			ProxyPassword:     request.Source.ProxyPassword,
+			ProxyCABundle:     request.Source.ProxyCABundle,
+			ClientCert:        request.Source.ClientCert,
+			ClientKey:         request.Source.ClientKey,
+			TLSMinVersion:     request.Source.TLSMinVersion,
+			TLSCipherSuites:   request.Source.TLSCipherSuites,
+
+			RetryMaxAttempts: request.Source.Retry.MaxAttempts,
+			RetryMaxBackoff:  request.Source.Retry.MaxBackoff,
+			RetryMode:        request.Source.Retry.Mode,
+			ConnectTimeout:   request.Source.Timeouts.Connect,
+			RequestTimeout:   request.Source.Timeouts.Request,
+
+			Debug: request.Source.Debug,
+		},
 	)
 	if err != nil {
-		s3resource.Fatal("error creating aws config", err)
+		s3resource.Fatal("error creating aws config", s3resource.CommandError(ctx, err))
 	}
 
 	client, err := s3resource.NewS3Client(
@@ -41,15 +74,29 @@ func main() {
 		request.Source.UsePathStyle,
 		request.Source.SkipS3Checksums,
 		request.Source.ChecksumAlgorithm,
+		s3resource.S3ClientOptions{
+			SSECustomerKey:               request.Source.SSECustomerKey,
+			SSECustomerAlgorithm:         request.Source.SSECustomerAlgorithm,
+			ClientSideEncryptionKey:      request.Source.ClientSideEncryption.Key,
+			ClientSideEncryptionKMSKeyID: request.Source.ClientSideEncryption.KMSKeyID,
+			RequesterPays:                request.Source.RequesterPays,
+			ExpectedBucketOwner:          request.Source.ExpectedBucketOwner,
+			UseAccelerateEndpoint:        request.Source.UseAccelerate,
+			UseDualStack:                 request.Source.UseDualStack,
+			UseFIPS:                      request.Source.UseFIPS,
+			MaxBandwidth:                 request.Source.MaxBandwidth,
+			Progress:                     request.Source.Progress,
+			ProgressInterval:             request.Source.ProgressInterval,
+		},
 	)
 	if err != nil {
 		s3resource.Fatal("error creating s3 client", err)
 	}
 
 	command := out.NewCommand(os.Stderr, client)
-	response, err := command.Run(sourceDir, request)
+	response, err := command.Run(ctx, sourceDir, request)
 	if err != nil {
-		s3resource.Fatal("running command", err)
+		s3resource.Fatal("running command", s3resource.CommandError(ctx, err))
 	}
 
 	outputResponse(response)
@@ -0,0 +1,106 @@
+package s3resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/smithy-go/logging"
+)
+
+// DebugLevel is what is logged about each request made to S3, STS or KMS
+type DebugLevel string
+
+const (
+	// DebugRequests logs the method, URL and headers of each request and
+	// response, including the request IDs, and each retry attempt
+	DebugRequests DebugLevel = "requests"
+
+	// DebugSigning also logs the canonical request and string to sign of
+	// each request, to track down signature mismatches
+	DebugSigning DebugLevel = "signing"
+)
+
+var debugLevels = []DebugLevel{DebugRequests, DebugSigning}
+
+// UnmarshalJSON accepts one of the levels, or a boolean where true means
+// DebugRequests
+func (level *DebugLevel) UnmarshalJSON(data []byte) error {
+	var enabled bool
+	if err := json.Unmarshal(data, &enabled); err == nil {
+		*level = ""
+		if enabled {
+			*level = DebugRequests
+		}
+		return nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("debug must be true, false or a level: %w", err)
+	}
+	*level = DebugLevel(name)
+
+	return nil
+}
+
+func (level DebugLevel) clientLogMode() aws.ClientLogMode {
+	switch level {
+	case DebugRequests:
+		return aws.LogRequest | aws.LogResponse | aws.LogRetries
+	case DebugSigning:
+		return aws.LogRequest | aws.LogResponse | aws.LogRetries | aws.LogSigning
+	}
+	return 0
+}
+
+const redacted = "REDACTED"
+
+// redactions replace the secrets in requests, canonical requests and
+// presigned URLs. The credential scope is kept, as it shows the region and
+// service a request was signed for.
+var redactions = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// The access key ID in the Authorization header or X-Amz-Credential
+	{regexp.MustCompile(`(?i)(credential=)[^/,\s&%]+`), "${1}" + redacted},
+	// The signature in the Authorization header or X-Amz-Signature
+	{regexp.MustCompile(`(?i)(signature=)[0-9a-f]+`), "${1}" + redacted},
+	// Session tokens and SSE-C keys, as headers or in canonical requests.
+	// The MD5 of an SSE-C key is kept to tell keys apart.
+	{regexp.MustCompile(`(?im)^(x-amz-security-token|x-amz-(?:copy-source-)?server-side-encryption-customer-key|proxy-authorization)(\s*:\s*)[^\r\n]*`), "${1}${2}" + redacted},
+	// Session tokens in presigned URLs
+	{regexp.MustCompile(`(?i)(x-amz-security-token=)[^&\s]+`), "${1}" + redacted},
+}
+
+// redact replaces the credentials, signatures and SSE-C keys in message
+func redact(message string) string {
+	for _, redaction := range redactions {
+		message = redaction.pattern.ReplaceAllString(message, redaction.replacement)
+	}
+	return message
+}
+
+// redactingLogger writes the SDK's log messages to output with their secrets
+// redacted
+type redactingLogger struct {
+	mu     *sync.Mutex
+	output io.Writer
+}
+
+func newRedactingLogger(output io.Writer) redactingLogger {
+	return redactingLogger{mu: &sync.Mutex{}, output: output}
+}
+
+func (logger redactingLogger) Logf(classification logging.Classification, format string, v ...any) {
+	message := redact(fmt.Sprintf(format, v...))
+
+	// Parts are transferred concurrently, so messages must not interleave
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	fmt.Fprintf(logger.output, "%s %s\n", classification, message)
+}
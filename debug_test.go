@@ -0,0 +1,88 @@
+package s3resource
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Debug logging", func() {
+	Describe("DebugLevel", func() {
+		DescribeTable("unmarshals booleans and levels",
+			func(value string, expected DebugLevel) {
+				var level DebugLevel
+				Expect(json.Unmarshal([]byte(value), &level)).To(Succeed())
+				Expect(level).To(Equal(expected))
+			},
+			Entry("true", `true`, DebugRequests),
+			Entry("false", `false`, DebugLevel("")),
+			Entry("a level", `"signing"`, DebugSigning),
+		)
+
+		It("errors on other values", func() {
+			var level DebugLevel
+			Expect(json.Unmarshal([]byte(`1`), &level)).ToNot(Succeed())
+		})
+	})
+
+	Describe("redact", func() {
+		It("redacts the access key and signature but keeps the scope", func() {
+			message := redact("Authorization: AWS4-HMAC-SHA256 Credential=AKIAEXAMPLE/20240101/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-date, Signature=0123456789abcdef\r\n")
+			Expect(message).To(Equal("Authorization: AWS4-HMAC-SHA256 Credential=REDACTED/20240101/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-date, Signature=REDACTED\r\n"))
+		})
+
+		It("redacts session tokens and SSE-C keys but not the key's MD5", func() {
+			message := redact("X-Amz-Security-Token: some-token\r\nX-Amz-Server-Side-Encryption-Customer-Key: some-key\r\nX-Amz-Server-Side-Encryption-Customer-Key-Md5: some-md5\r\n")
+			Expect(message).To(Equal("X-Amz-Security-Token: REDACTED\r\nX-Amz-Server-Side-Encryption-Customer-Key: REDACTED\r\nX-Amz-Server-Side-Encryption-Customer-Key-Md5: some-md5\r\n"))
+
+			message = redact("x-amz-security-token:some-token\nx-amz-server-side-encryption-customer-key:some-key\n")
+			Expect(message).To(Equal("x-amz-security-token:REDACTED\nx-amz-server-side-encryption-customer-key:REDACTED\n"))
+		})
+
+		It("redacts presigned URLs", func() {
+			message := redact("https://bucket.s3.amazonaws.com/key?X-Amz-Credential=AKIAEXAMPLE%2F20240101%2Fus-east-1%2Fs3%2Faws4_request&X-Amz-Security-Token=some%2Ftoken&X-Amz-Signature=0123456789abcdef")
+			Expect(message).To(Equal("https://bucket.s3.amazonaws.com/key?X-Amz-Credential=REDACTED%2F20240101%2Fus-east-1%2Fs3%2Faws4_request&X-Amz-Security-Token=REDACTED&X-Amz-Signature=REDACTED"))
+		})
+	})
+
+	It("logs requests and responses without their secrets", func() {
+		server := NewTestServer(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Amz-Request-Id", "some-request-id")
+			w.Header().Set("Content-Type", "application/xml")
+			io.WriteString(w, `<Tagging><TagSet></TagSet></Tagging>`)
+		})
+
+		cfg, err := NewAwsConfig(context.Background(), "AKIAEXAMPLE", "some-secret-key", "some-session-token", "", "", false, "", false, AwsConfigOptions{
+			Debug: DebugSigning,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		output := &bytes.Buffer{}
+		cfg.Logger = newRedactingLogger(output)
+
+		sseCustomerKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("k"), 32))
+		client, err := NewS3Client(io.Discard, cfg, server.URL, false, true, true, "", S3ClientOptions{
+			SSECustomerKey: sseCustomerKey,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = client.(*s3client).headObject(context.Background(), "bucket", "key", "")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(output.String()).To(ContainSubstring("HEAD /bucket/key"))
+		Expect(output.String()).To(ContainSubstring("X-Amz-Request-Id: some-request-id"))
+		Expect(output.String()).To(ContainSubstring("CANONICAL STRING"))
+		Expect(output.String()).To(ContainSubstring("Credential=REDACTED/"))
+		Expect(output.String()).ToNot(ContainSubstring("AKIAEXAMPLE"))
+		Expect(output.String()).ToNot(ContainSubstring("some-secret-key"))
+		Expect(output.String()).ToNot(ContainSubstring("some-session-token"))
+		Expect(output.String()).ToNot(ContainSubstring(sseCustomerKey))
+		Expect(output.String()).ToNot(MatchRegexp(`Signature=[0-9a-f]`))
+	})
+})
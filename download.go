@@ -0,0 +1,143 @@
+package s3resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// MaxDownloadResumes is how many times a failed download is resumed by
+// requesting only the ranges which have not been written yet
+const MaxDownloadResumes = 5
+
+// ErrObjectChanged is returned when an object is overwritten while it is
+// being downloaded, so that its parts can't be combined
+var ErrObjectChanged = errors.New("object changed while it was being downloaded")
+
+// byteRange is the range of bytes [start, end)
+type byteRange struct {
+	start int64
+	end   int64
+}
+
+// writtenRanges records the byte ranges written to a file, so that a failed
+// download can be resumed from the ranges which are missing
+type writtenRanges struct {
+	io.WriterAt
+
+	mu     sync.Mutex
+	ranges []byteRange
+}
+
+func (w *writtenRanges) WriteAt(p []byte, off int64) (int, error) {
+	n, err := w.WriterAt.WriteAt(p, off)
+	if n > 0 {
+		w.add(byteRange{off, off + int64(n)})
+	}
+	return n, err
+}
+
+// add records r, merging it with the ranges it overlaps or touches
+func (w *writtenRanges) add(r byteRange) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ranges := append(w.ranges, r)
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+
+	merged := ranges[:1]
+	for _, next := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if next.start <= last.end {
+			last.end = max(last.end, next.end)
+		} else {
+			merged = append(merged, next)
+		}
+	}
+	w.ranges = merged
+}
+
+// missing returns the ranges of [0, size) which have not been written
+func (w *writtenRanges) missing(size int64) []byteRange {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var missing []byteRange
+	var pos int64
+	for _, r := range w.ranges {
+		if r.start > pos {
+			missing = append(missing, byteRange{pos, min(r.start, size)})
+		}
+		pos = max(pos, r.end)
+		if pos >= size {
+			break
+		}
+	}
+	if pos < size {
+		missing = append(missing, byteRange{pos, size})
+	}
+
+	return missing
+}
+
+// downloadMissingRanges requests each of the ranges of getObject and writes
+// them to w at their offsets
+func (client *s3client) downloadMissingRanges(ctx context.Context, getObject *s3.GetObjectInput, w io.WriterAt, ranges []byteRange) error {
+	for _, r := range ranges {
+		rangeInput := *getObject
+		rangeInput.Range = aws.String(fmt.Sprintf("bytes=%d-%d", r.start, r.end-1))
+
+		output, err := client.client.GetObject(ctx, &rangeInput)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(io.NewOffsetWriter(w, r.start), output.Body)
+		output.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isObjectChanged reports whether err is a failed If-Match precondition
+func isObjectChanged(err error) bool {
+	var responseErr *awshttp.ResponseError
+	return errors.As(err, &responseErr) && responseErr.HTTPStatusCode() == http.StatusPreconditionFailed
+}
+
+// resumeDownload keeps requesting the ranges of the object which have not
+// been written yet until it is complete, giving up after MaxDownloadResumes
+// attempts or when the object has changed
+func (client *s3client) resumeDownload(ctx context.Context, getObject *s3.GetObjectInput, w io.WriterAt, written *writtenRanges, size int64, err error) error {
+	for attempt := 1; err != nil && attempt <= MaxDownloadResumes; attempt++ {
+		if isObjectChanged(err) || ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+		err = client.downloadMissingRanges(ctx, getObject, w, written.missing(size))
+	}
+
+	if isObjectChanged(err) {
+		return fmt.Errorf("%w: %w", ErrObjectChanged, err)
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+package s3resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resuming downloads", func() {
+	Describe("writtenRanges", func() {
+		It("merges the ranges written and returns the missing ones", func() {
+			written := &writtenRanges{WriterAt: discardWriterAt{}}
+			written.add(byteRange{10, 20})
+			written.add(byteRange{40, 50})
+			written.add(byteRange{18, 30})
+			written.add(byteRange{30, 35})
+
+			Expect(written.ranges).To(Equal([]byteRange{{10, 35}, {40, 50}}))
+			Expect(written.missing(60)).To(Equal([]byteRange{{0, 10}, {35, 40}, {50, 60}}))
+		})
+
+		It("returns nothing when everything has been written", func() {
+			written := &writtenRanges{WriterAt: discardWriterAt{}}
+			_, err := written.WriteAt(make([]byte, 60), 0)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(written.missing(60)).To(BeEmpty())
+		})
+
+		It("returns the whole file when nothing has been written", func() {
+			written := &writtenRanges{WriterAt: discardWriterAt{}}
+			Expect(written.missing(60)).To(Equal([]byteRange{{0, 60}}))
+		})
+	})
+
+	Describe("downloadMissingRanges", func() {
+		var (
+			data   []byte
+			etag   string
+			client *s3client
+		)
+
+		BeforeEach(func() {
+			data = []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+			etag = `"some-etag"`
+
+			server := NewTestServer(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("If-Match") != etag {
+					w.WriteHeader(http.StatusPreconditionFailed)
+					return
+				}
+
+				var start, end int
+				_, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+				Expect(err).ToNot(HaveOccurred())
+
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write(data[start : end+1])
+			})
+
+			client = NewTestS3Client(io.Discard, NewTestAwsConfig(""), server.URL, S3ClientOptions{}).(*s3client)
+		})
+
+		It("writes only the missing ranges", func() {
+			file, err := os.Create(filepath.Join(GinkgoT().TempDir(), "file"))
+			Expect(err).ToNot(HaveOccurred())
+			defer file.Close()
+
+			_, err = file.WriteAt(data[5:20], 5)
+			Expect(err).ToNot(HaveOccurred())
+
+			getObject := &s3.GetObjectInput{
+				Bucket:  aws.String("bucket"),
+				Key:     aws.String("key"),
+				IfMatch: aws.String(`"some-etag"`),
+			}
+			err = client.downloadMissingRanges(context.Background(), getObject, file, []byteRange{{0, 5}, {20, int64(len(data))}})
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := os.ReadFile(file.Name())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(Equal(data))
+		})
+
+		It("does not resume when the object has changed", func() {
+			etag = `"new-etag"`
+
+			written := &writtenRanges{WriterAt: discardWriterAt{}}
+			getObject := &s3.GetObjectInput{
+				Bucket:  aws.String("bucket"),
+				Key:     aws.String("key"),
+				IfMatch: aws.String(`"some-etag"`),
+			}
+
+			err := client.downloadMissingRanges(context.Background(), getObject, written, written.missing(int64(len(data))))
+			Expect(isObjectChanged(err)).To(BeTrue())
+
+			err = client.resumeDownload(context.Background(), getObject, written, written, int64(len(data)), err)
+			Expect(errors.Is(err, ErrObjectChanged)).To(BeTrue())
+		})
+	})
+})
+
+// discardWriterAt is an io.WriterAt which discards everything written
+type discardWriterAt struct{}
+
+func (discardWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	return len(p), nil
+}
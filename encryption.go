@@ -0,0 +1,346 @@
+package s3resource
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// Objects encrypted on the client are stored as a sequence of independently
+// sealed AES-256-GCM chunks. Each chunk's nonce is built from a random prefix,
+// the chunk's index and a flag marking the final chunk, so chunks cannot be
+// reordered, dropped or truncated without decryption failing.
+//
+// Everything needed to decrypt an object other than the key encryption key is
+// stored in the object's metadata.
+const (
+	ClientSideEncryptionAlgorithm = "AES256-GCM-STREAM"
+
+	clientSideEncryptionChunkSize       = 64 * 1024
+	clientSideEncryptionNoncePrefixSize = 7
+
+	cseMetaAlgorithm     = "cse-algorithm"
+	cseMetaKeyWrap       = "cse-key-wrap"
+	cseMetaWrappedKey    = "cse-wrapped-key"
+	cseMetaNoncePrefix   = "cse-nonce-prefix"
+	cseMetaChunkSize     = "cse-chunk-size"
+	cseMetaPlaintextSize = "cse-plaintext-size"
+
+	keyWrapKMS    = "kms"
+	keyWrapStatic = "AES256-GCM"
+)
+
+var ErrClientSideEncryptionNotConfigured = errors.New("object is encrypted on the client but no client_side_encryption key is configured")
+
+// keyWrapper generates and protects the per-object data keys
+type keyWrapper interface {
+	algorithm() string
+	newDataKey(ctx context.Context) (plaintext []byte, wrapped []byte, err error)
+	unwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+type kmsKeyWrapper struct {
+	client *kms.Client
+	keyID  string
+}
+
+func (wrapper kmsKeyWrapper) algorithm() string {
+	return keyWrapKMS
+}
+
+func (wrapper kmsKeyWrapper) newDataKey(ctx context.Context) ([]byte, []byte, error) {
+	output, err := wrapper.client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:   aws.String(wrapper.keyID),
+		KeySpec: kmstypes.DataKeySpecAes256,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error generating data key: %w", err)
+	}
+
+	return output.Plaintext, output.CiphertextBlob, nil
+}
+
+func (wrapper kmsKeyWrapper) unwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	output, err := wrapper.client.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:          aws.String(wrapper.keyID),
+		CiphertextBlob: wrapped,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting data key: %w", err)
+	}
+
+	return output.Plaintext, nil
+}
+
+type staticKeyWrapper struct {
+	key []byte
+}
+
+func (wrapper staticKeyWrapper) algorithm() string {
+	return keyWrapStatic
+}
+
+func (wrapper staticKeyWrapper) newDataKey(ctx context.Context) ([]byte, []byte, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, err
+	}
+
+	aead, err := newGCM(wrapper.key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	return dataKey, aead.Seal(nonce, nonce, dataKey, nil), nil
+}
+
+func (wrapper staticKeyWrapper) unwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	aead, err := newGCM(wrapper.key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("error decrypting data key: wrapped key is too short")
+	}
+
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting data key: %w", err)
+	}
+
+	return dataKey, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// encryptionEnvelope holds the parameters of a single encrypted object
+type encryptionEnvelope struct {
+	aead        cipher.AEAD
+	noncePrefix []byte
+	chunkSize   int
+}
+
+func newEncryptionEnvelope(ctx context.Context, wrapper keyWrapper, plaintextSize int64) (encryptionEnvelope, map[string]string, error) {
+	dataKey, wrappedKey, err := wrapper.newDataKey(ctx)
+	if err != nil {
+		return encryptionEnvelope{}, nil, err
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return encryptionEnvelope{}, nil, err
+	}
+
+	noncePrefix := make([]byte, clientSideEncryptionNoncePrefixSize)
+	if _, err := rand.Read(noncePrefix); err != nil {
+		return encryptionEnvelope{}, nil, err
+	}
+
+	metadata := map[string]string{
+		cseMetaAlgorithm:     ClientSideEncryptionAlgorithm,
+		cseMetaKeyWrap:       wrapper.algorithm(),
+		cseMetaWrappedKey:    base64.StdEncoding.EncodeToString(wrappedKey),
+		cseMetaNoncePrefix:   base64.StdEncoding.EncodeToString(noncePrefix),
+		cseMetaChunkSize:     strconv.Itoa(clientSideEncryptionChunkSize),
+		cseMetaPlaintextSize: strconv.FormatInt(plaintextSize, 10),
+	}
+
+	return encryptionEnvelope{
+		aead:        aead,
+		noncePrefix: noncePrefix,
+		chunkSize:   clientSideEncryptionChunkSize,
+	}, metadata, nil
+}
+
+// isClientSideEncrypted reports whether the object metadata describes an
+// object encrypted by this resource
+func isClientSideEncrypted(metadata map[string]string) bool {
+	_, ok := metadata[cseMetaAlgorithm]
+	return ok
+}
+
+func openEncryptionEnvelope(ctx context.Context, wrapper keyWrapper, metadata map[string]string) (encryptionEnvelope, error) {
+	if algorithm := metadata[cseMetaAlgorithm]; algorithm != ClientSideEncryptionAlgorithm {
+		return encryptionEnvelope{}, fmt.Errorf("unsupported client-side encryption algorithm: %s", algorithm)
+	}
+
+	if wrapper == nil {
+		return encryptionEnvelope{}, ErrClientSideEncryptionNotConfigured
+	}
+
+	if keyWrap := metadata[cseMetaKeyWrap]; keyWrap != wrapper.algorithm() {
+		return encryptionEnvelope{}, fmt.Errorf("object data key is wrapped with %s but %s is configured", keyWrap, wrapper.algorithm())
+	}
+
+	wrappedKey, err := base64.StdEncoding.DecodeString(metadata[cseMetaWrappedKey])
+	if err != nil {
+		return encryptionEnvelope{}, fmt.Errorf("error decoding wrapped data key: %w", err)
+	}
+
+	noncePrefix, err := base64.StdEncoding.DecodeString(metadata[cseMetaNoncePrefix])
+	if err != nil || len(noncePrefix) != clientSideEncryptionNoncePrefixSize {
+		return encryptionEnvelope{}, errors.New("invalid client-side encryption nonce prefix")
+	}
+
+	chunkSize, err := strconv.Atoi(metadata[cseMetaChunkSize])
+	if err != nil || chunkSize <= 0 {
+		return encryptionEnvelope{}, errors.New("invalid client-side encryption chunk size")
+	}
+
+	dataKey, err := wrapper.unwrapKey(ctx, wrappedKey)
+	if err != nil {
+		return encryptionEnvelope{}, err
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return encryptionEnvelope{}, err
+	}
+
+	return encryptionEnvelope{
+		aead:        aead,
+		noncePrefix: noncePrefix,
+		chunkSize:   chunkSize,
+	}, nil
+}
+
+func (envelope encryptionEnvelope) nonce(index uint32, final bool) []byte {
+	nonce := make([]byte, envelope.aead.NonceSize())
+	copy(nonce, envelope.noncePrefix)
+	binary.BigEndian.PutUint32(nonce[len(envelope.noncePrefix):], index)
+	if final {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+// ciphertextSize returns the size of the encrypted form of plaintextSize bytes
+func (envelope encryptionEnvelope) ciphertextSize(plaintextSize int64) int64 {
+	chunks := plaintextSize / int64(envelope.chunkSize)
+	if plaintextSize%int64(envelope.chunkSize) != 0 || chunks == 0 {
+		chunks++
+	}
+	return plaintextSize + chunks*int64(envelope.aead.Overhead())
+}
+
+// encryptingReader reads plaintext from the underlying reader and returns
+// the sealed chunks
+type encryptingReader struct {
+	envelope  encryptionEnvelope
+	plaintext *bufio.Reader
+	chunk     []byte
+	sealed    []byte
+	index     uint32
+	done      bool
+}
+
+func (envelope encryptionEnvelope) encrypt(plaintext io.Reader) io.Reader {
+	return &encryptingReader{
+		envelope:  envelope,
+		plaintext: bufio.NewReaderSize(plaintext, envelope.chunkSize),
+		chunk:     make([]byte, envelope.chunkSize),
+	}
+}
+
+func (reader *encryptingReader) Read(p []byte) (int, error) {
+	for len(reader.sealed) == 0 {
+		if reader.done {
+			return 0, io.EOF
+		}
+
+		if err := reader.sealNextChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, reader.sealed)
+	reader.sealed = reader.sealed[n:]
+	return n, nil
+}
+
+func (reader *encryptingReader) sealNextChunk() error {
+	n, err := io.ReadFull(reader.plaintext, reader.chunk)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	final := err != nil
+	if !final {
+		// A full chunk is only the last one if nothing follows it
+		if _, err := reader.plaintext.Peek(1); err == io.EOF {
+			final = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	nonce := reader.envelope.nonce(reader.index, final)
+	reader.sealed = reader.envelope.aead.Seal(reader.sealed[:0], nonce, reader.chunk[:n], nil)
+	reader.index++
+	reader.done = final
+
+	return nil
+}
+
+// decrypt reads sealed chunks from ciphertext and writes the plaintext to w
+func (envelope encryptionEnvelope) decrypt(w io.Writer, ciphertext io.Reader) error {
+	sealedSize := envelope.chunkSize + envelope.aead.Overhead()
+	reader := bufio.NewReaderSize(ciphertext, sealedSize)
+	sealed := make([]byte, sealedSize)
+	var plaintext []byte
+
+	for index := uint32(0); ; index++ {
+		n, err := io.ReadFull(reader, sealed)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		final := err != nil
+		if !final {
+			if _, err := reader.Peek(1); err == io.EOF {
+				final = true
+			} else if err != nil {
+				return err
+			}
+		}
+
+		plaintext, err = envelope.aead.Open(plaintext[:0], envelope.nonce(index, final), sealed[:n], nil)
+		if err != nil {
+			return fmt.Errorf("error decrypting object: chunk %d failed authentication", index)
+		}
+
+		if _, err := w.Write(plaintext); err != nil {
+			return err
+		}
+
+		if final {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package s3resource
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client-side encryption", func() {
+	var (
+		wrapper  staticKeyWrapper
+		envelope encryptionEnvelope
+		metadata map[string]string
+	)
+
+	BeforeEach(func() {
+		key := make([]byte, 32)
+		_, err := rand.Read(key)
+		Expect(err).ToNot(HaveOccurred())
+
+		wrapper = staticKeyWrapper{key: key}
+		envelope, metadata, err = newEncryptionEnvelope(context.TODO(), wrapper, 0)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	encrypt := func(plaintext []byte) []byte {
+		ciphertext, err := io.ReadAll(envelope.encrypt(bytes.NewReader(plaintext)))
+		Expect(err).ToNot(HaveOccurred())
+		return ciphertext
+	}
+
+	DescribeTable("round trips",
+		func(size int) {
+			plaintext := make([]byte, size)
+			_, err := rand.Read(plaintext)
+			Expect(err).ToNot(HaveOccurred())
+
+			ciphertext := encrypt(plaintext)
+			Expect(int64(len(ciphertext))).To(Equal(envelope.ciphertextSize(int64(size))))
+
+			opened, err := openEncryptionEnvelope(context.TODO(), wrapper, metadata)
+			Expect(err).ToNot(HaveOccurred())
+
+			decrypted := &bytes.Buffer{}
+			err = opened.decrypt(decrypted, bytes.NewReader(ciphertext))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decrypted.Bytes()).To(Equal(plaintext))
+		},
+		Entry("an empty object", 0),
+		Entry("an object smaller than a chunk", 100),
+		Entry("an object of exactly one chunk", clientSideEncryptionChunkSize),
+		Entry("an object one byte larger than a chunk", clientSideEncryptionChunkSize+1),
+		Entry("an object of several chunks", 3*clientSideEncryptionChunkSize+17),
+	)
+
+	It("detects modified ciphertext", func() {
+		ciphertext := encrypt(bytes.Repeat([]byte("a"), 100))
+		ciphertext[10] ^= 0xff
+
+		err := envelope.decrypt(io.Discard, bytes.NewReader(ciphertext))
+		Expect(err).To(MatchError(ContainSubstring("failed authentication")))
+	})
+
+	It("detects truncated ciphertext", func() {
+		ciphertext := encrypt(bytes.Repeat([]byte("a"), 2*clientSideEncryptionChunkSize+1))
+		truncated := ciphertext[:clientSideEncryptionChunkSize+envelope.aead.Overhead()]
+
+		err := envelope.decrypt(io.Discard, bytes.NewReader(truncated))
+		Expect(err).To(MatchError(ContainSubstring("failed authentication")))
+	})
+
+	It("refuses to open an envelope with the wrong key", func() {
+		otherKey := make([]byte, 32)
+		_, err := rand.Read(otherKey)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = openEncryptionEnvelope(context.TODO(), staticKeyWrapper{key: otherKey}, metadata)
+		Expect(err).To(MatchError(ContainSubstring("error decrypting data key")))
+	})
+
+	It("requires a key to open an envelope", func() {
+		_, err := openEncryptionEnvelope(context.TODO(), nil, metadata)
+		Expect(err).To(Equal(ErrClientSideEncryptionNotConfigured))
+	})
+
+	It("refuses to open an envelope wrapped by a different mechanism", func() {
+		metadata[cseMetaKeyWrap] = keyWrapKMS
+
+		_, err := openEncryptionEnvelope(context.TODO(), wrapper, metadata)
+		Expect(err).To(MatchError(ContainSubstring("object data key is wrapped with kms")))
+	})
+})
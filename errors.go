@@ -0,0 +1,227 @@
+package s3resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// The kinds of Error, to be matched with errors.Is
+var (
+	ErrAuth          = errors.New("authentication failed")
+	ErrAccessDenied  = errors.New("access denied")
+	ErrNotFound      = errors.New("not found")
+	ErrNotVersioned  = errors.New("bucket is not versioned")
+	ErrInvalidConfig = errors.New("invalid configuration")
+)
+
+// Error is an error of one of the kinds ErrAuth, ErrAccessDenied,
+// ErrNotFound, ErrNotVersioned or ErrInvalidConfig. Its message is the
+// message of Err, so that classifying an error does not change it.
+type Error struct {
+	Kind error
+
+	// Service and Operation name the API operation which failed, if any,
+	// e.g. "S3" and "ListObjectsV2"
+	Service   string
+	Operation string
+
+	Err error
+}
+
+func (err *Error) Error() string {
+	return err.Err.Error()
+}
+
+func (err *Error) Unwrap() []error {
+	return []error{err.Kind, err.Err}
+}
+
+// NewConfigError returns an ErrInvalidConfig error with message
+func NewConfigError(message string) error {
+	return &Error{Kind: ErrInvalidConfig, Err: errors.New(message)}
+}
+
+// Hint explains how to fix err, or is empty if there is nothing to add
+func (err *Error) Hint() string {
+	switch err.Kind {
+	case ErrAuth:
+		return "check that access_key_id, secret_access_key and session_token, or the credentials of aws_role_arn or aws_profile, are correct and have not expired"
+	case ErrAccessDenied:
+		if action, ok := iamActions[err.Operation]; ok {
+			return fmt.Sprintf("the credentials need the %s permission on the resource, see Required IAM Permissions in the README", action)
+		}
+		return "check the IAM permissions of the credentials and the policy of the bucket"
+	case ErrNotFound:
+		if err.Service == "SQS" {
+			return "check that sqs_queue_url is correct and that the queue exists"
+		}
+		return "check that bucket, region_name and endpoint are correct and that the object exists"
+	case ErrNotVersioned:
+		return "versioned_file requires versioning to be enabled on the bucket"
+	case ErrInvalidConfig:
+		if err.Operation != "" {
+			return "check that region_name matches the region of the bucket, and that endpoint is correct"
+		}
+	}
+	return ""
+}
+
+// Hint explains how to fix err if it is an Error, or is empty otherwise
+func Hint(err error) string {
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified.Hint()
+	}
+	return ""
+}
+
+// iamActions are the permissions each operation requires, as listed in the
+// README
+var iamActions = map[string]string{
+	"HeadBucket":              "s3:ListBucket",
+	"ListObjectsV2":           "s3:ListBucket",
+	"ListObjectVersions":      "s3:ListBucketVersions",
+	"GetBucketVersioning":     "s3:GetBucketVersioning",
+	"HeadObject":              "s3:GetObject (or s3:GetObjectVersion with versioned_file)",
+	"GetObject":               "s3:GetObject (or s3:GetObjectVersion with versioned_file)",
+	"PutObject":               "s3:PutObject and s3:PutObjectAcl",
+	"CreateMultipartUpload":   "s3:PutObject and s3:PutObjectAcl",
+	"UploadPart":              "s3:PutObject",
+	"CompleteMultipartUpload": "s3:PutObject",
+	"GetObjectTagging":        "s3:GetObjectTagging (or s3:GetObjectVersionTagging with versioned_file)",
+	"PutObjectTagging":        "s3:PutObjectTagging (or s3:PutObjectVersionTagging with versioned_file)",
+	"RestoreObject":           "s3:RestoreObject",
+	"ListMultipartUploads":    "s3:ListBucketMultipartUploads",
+	"ListParts":               "s3:ListMultipartUploadParts",
+	"AbortMultipartUpload":    "s3:AbortMultipartUpload",
+	"DeleteObject":            "s3:DeleteObject (or s3:DeleteObjectVersion with versioned_file)",
+	"PutObjectRetention":      "s3:PutObjectRetention",
+	"PutObjectLegalHold":      "s3:PutObjectLegalHold",
+	"GetObjectRetention":      "s3:GetObjectRetention",
+	"GetObjectLegalHold":      "s3:GetObjectLegalHold",
+	"AssumeRole":              "sts:AssumeRole",
+	"GenerateDataKey":         "kms:GenerateDataKey",
+	"Decrypt":                 "kms:Decrypt",
+	"ReceiveMessage":          "sqs:ReceiveMessage",
+	"DeleteMessageBatch":      "sqs:DeleteMessage",
+}
+
+// errorKinds classifies the error codes of S3, STS, KMS and SQS. HEAD requests
+// have no body, so their errors are named after the HTTP status instead.
+var errorKinds = map[string]error{
+	"InvalidAccessKeyId":           ErrAuth,
+	"InvalidClientTokenId":         ErrAuth,
+	"SignatureDoesNotMatch":        ErrAuth,
+	"ExpiredToken":                 ErrAuth,
+	"InvalidToken":                 ErrAuth,
+	"TokenRefreshRequired":         ErrAuth,
+	"AccessDenied":                 ErrAccessDenied,
+	"AccessDeniedException":        ErrAccessDenied,
+	"AllAccessDisabled":            ErrAccessDenied,
+	"Forbidden":                    ErrAccessDenied,
+	"NoSuchBucket":                 ErrNotFound,
+	"NoSuchKey":                    ErrNotFound,
+	"NoSuchVersion":                ErrNotFound,
+	"NoSuchUpload":                 ErrNotFound,
+	"NotFound":                     ErrNotFound,
+	"PermanentRedirect":            ErrInvalidConfig,
+	"MovedPermanently":             ErrInvalidConfig,
+	"AuthorizationHeaderMalformed": ErrInvalidConfig,
+	"InvalidBucketName":            ErrInvalidConfig,
+
+	// SQS names some errors as its query protocol did
+	"QueueDoesNotExist":                       ErrNotFound,
+	"AWS.SimpleQueueService.NonExistentQueue": ErrNotFound,
+}
+
+// classifyError wraps err in an Error if its kind is known. operation is
+// used unless err comes from another operation, e.g. assuming a role.
+func classifyError(service string, operation string, err error) error {
+	var classified *Error
+	if err == nil || errors.As(err, &classified) {
+		return err
+	}
+
+	var operationErr *smithy.OperationError
+	if errors.As(err, &operationErr) {
+		service = operationErr.Service()
+		operation = operationErr.Operation()
+	}
+
+	kind := errorKind(err)
+	if kind == nil {
+		return err
+	}
+
+	return &Error{Kind: kind, Service: service, Operation: operation, Err: err}
+}
+
+func errorKind(err error) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		if kind, ok := errorKinds[apiErr.ErrorCode()]; ok {
+			return kind
+		}
+	}
+
+	var responseErr *awshttp.ResponseError
+	if errors.As(err, &responseErr) {
+		switch responseErr.HTTPStatusCode() {
+		case http.StatusForbidden:
+			return ErrAccessDenied
+		case http.StatusNotFound:
+			return ErrNotFound
+		}
+	}
+
+	return nil
+}
+
+// classifyErrorsMiddleware classifies the error of every operation made by
+// the clients of a config
+var classifyErrorsMiddleware = middleware.InitializeMiddlewareFunc("ClassifyErrors", func(
+	ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+) (middleware.InitializeOutput, middleware.Metadata, error) {
+	out, metadata, err := next.HandleInitialize(ctx, in)
+	if err != nil {
+		err = classifyError(awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx), err)
+	}
+	return out, metadata, err
+})
+
+// addClassifyErrors adds classifyErrorsMiddleware after the middleware which
+// registers the service and operation names
+func addClassifyErrors(stack *middleware.Stack) error {
+	return stack.Initialize.Add(classifyErrorsMiddleware, middleware.After)
+}
+
+// authErrorProvider classifies the errors retrieving credentials as ErrAuth,
+// e.g. when there are no credentials to be found
+type authErrorProvider struct {
+	aws.CredentialsProvider
+}
+
+func (provider authErrorProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	creds, err := provider.CredentialsProvider.Retrieve(ctx)
+	if err != nil {
+		var classified *Error
+		if !errors.As(err, &classified) {
+			err = &Error{Kind: ErrAuth, Err: err}
+		}
+	}
+	return creds, err
+}
+
+// IsCredentialsProvider lets the SDK tell which provider is wrapped, e.g. so
+// that anonymous requests are not signed
+func (provider authErrorProvider) IsCredentialsProvider(target aws.CredentialsProvider) bool {
+	return aws.IsCredentialsProvider(provider.CredentialsProvider, target)
+}
@@ -0,0 +1,100 @@
+package s3resource
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Errors", func() {
+	var client *s3client
+
+	respondWith := func(status int, body string) {
+		server := NewTestServer(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(status)
+			io.WriteString(w, body)
+		})
+
+		client = NewTestS3Client(io.Discard, NewTestAwsConfig(""), server.URL, S3ClientOptions{}).(*s3client)
+	}
+
+	It("classifies error codes", func() {
+		respondWith(http.StatusForbidden, `<Error><Code>InvalidAccessKeyId</Code><Message>The AWS Access Key Id you provided does not exist in our records.</Message></Error>`)
+
+		_, err := client.ChunkedBucketList(context.Background(), "bucket", "", nil)
+		Expect(errors.Is(err, ErrAuth)).To(BeTrue())
+		Expect(err).To(MatchError(ContainSubstring("InvalidAccessKeyId")))
+		Expect(Hint(err)).To(ContainSubstring("access_key_id"))
+	})
+
+	It("names the IAM action which was denied", func() {
+		respondWith(http.StatusForbidden, `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+
+		_, err := client.ChunkedBucketList(context.Background(), "bucket", "", nil)
+		Expect(errors.Is(err, ErrAccessDenied)).To(BeTrue())
+
+		var classified *Error
+		Expect(errors.As(err, &classified)).To(BeTrue())
+		Expect(classified.Service).To(Equal("S3"))
+		Expect(classified.Operation).To(Equal("ListObjectsV2"))
+		Expect(Hint(err)).To(ContainSubstring("s3:ListBucket permission"))
+	})
+
+	It("classifies HEAD requests by their status", func() {
+		respondWith(http.StatusNotFound, "")
+
+		_, err := client.headObject(context.Background(), "bucket", "key", "")
+		Expect(errors.Is(err, ErrNotFound)).To(BeTrue())
+	})
+
+	It("classifies buckets without versioning", func() {
+		respondWith(http.StatusOK, `<VersioningConfiguration></VersioningConfiguration>`)
+
+		_, err := client.BucketFileVersions(context.Background(), "bucket", "key")
+		Expect(err).To(MatchError("bucket is not versioned"))
+		Expect(errors.Is(err, ErrNotVersioned)).To(BeTrue())
+		Expect(Hint(err)).To(ContainSubstring("versioned_file"))
+	})
+
+	It("leaves other errors alone", func() {
+		respondWith(http.StatusInternalServerError, `<Error><Code>InternalError</Code><Message>We encountered an internal error.</Message></Error>`)
+
+		_, err := client.ChunkedBucketList(context.Background(), "bucket", "", nil)
+		Expect(err).To(HaveOccurred())
+
+		var classified *Error
+		Expect(errors.As(err, &classified)).To(BeFalse())
+		Expect(Hint(err)).To(BeEmpty())
+	})
+
+	It("classifies credentials which can't be retrieved", func() {
+		server := NewTestServer(http.NotFound)
+
+		failing := aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{}, errors.New("no credentials")
+		})
+		s3Client := s3.New(s3.Options{
+			Credentials:  authErrorProvider{failing},
+			Region:       "us-east-1",
+			BaseEndpoint: aws.String(server.URL),
+			UsePathStyle: true,
+		})
+
+		_, err := s3Client.HeadBucket(context.Background(), &s3.HeadBucketInput{Bucket: aws.String("bucket")})
+		Expect(errors.Is(err, ErrAuth)).To(BeTrue())
+	})
+
+	It("classifies config errors", func() {
+		err := NewConfigError("please specify either regexp or versioned_file")
+		Expect(err).To(MatchError("please specify either regexp or versioned_file"))
+		Expect(errors.Is(err, ErrInvalidConfig)).To(BeTrue())
+	})
+})
@@ -0,0 +1,215 @@
+package s3resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+//counterfeiter:generate -o fakes . EventQueue
+
+// EventQueue receives the S3 event notifications of a bucket from a queue
+type EventQueue interface {
+	// ReceiveEvents receives the messages waiting in the queue. They are
+	// received again after the visibility timeout of the queue unless they
+	// are deleted.
+	ReceiveEvents(ctx context.Context) (Events, error)
+	DeleteEvents(ctx context.Context, events Events) error
+}
+
+// Events are the messages received from a queue
+type Events struct {
+	Messages []EventMessage
+}
+
+// EventMessage is a message of a queue with the ObjectCreated events it
+// holds, if any
+type EventMessage struct {
+	// ReceiptHandle identifies the message to delete once its events have
+	// been handled
+	ReceiptHandle string
+
+	Objects []ObjectEvent
+}
+
+// Objects returns the objects created according to all the messages
+func (events Events) Objects() []ObjectEvent {
+	objects := []ObjectEvent{}
+	for _, message := range events.Messages {
+		objects = append(objects, message.Objects...)
+	}
+	return objects
+}
+
+// ObjectEvent is an ObjectCreated event notification
+type ObjectEvent struct {
+	Bucket    string
+	Key       string
+	VersionID string
+
+	// Sequencer orders the events of the same key, see CompareSequencers
+	Sequencer string
+}
+
+// CompareSequencers compares the sequencers of two events of the same key,
+// returning a positive number if a happened after b. Sequencers of different
+// lengths compare as if the shorter one was left padded with zeros.
+func CompareSequencers(a string, b string) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+// maxEventReceives limits how many batches of up to 10 messages are received
+// at once, so that a busy bucket cannot keep a check running. The rest of
+// the messages are received by the next check.
+const maxEventReceives = 100
+
+// sqsRegionPattern finds the region in the hostnames of SQS endpoints, e.g.
+// sqs.eu-west-1.amazonaws.com or eu-west-1.queue.amazonaws.com
+var sqsRegionPattern = regexp.MustCompile(`^(?:sqs\.([a-z0-9-]+)|([a-z0-9-]+)\.queue)\.amazonaws\.com(?:\.cn)?$`)
+
+type sqsEventQueue struct {
+	client   *sqs.Client
+	queueURL string
+}
+
+// NewEventQueue receives the messages of the SQS queue at queueURL. Requests
+// are sent to the host of queueURL, so that queues of other regions and
+// local stand-ins can be used.
+func NewEventQueue(awsConfig *aws.Config, queueURL string) (EventQueue, error) {
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing sqs queue url: %w", err)
+	}
+
+	endpoint := u.Scheme + "://" + u.Host
+	client := sqs.NewFromConfig(*awsConfig, func(o *sqs.Options) {
+		o.BaseEndpoint = aws.String(endpoint)
+		if match := sqsRegionPattern.FindStringSubmatch(u.Hostname()); match != nil {
+			o.Region = match[1] + match[2]
+		}
+	})
+
+	return &sqsEventQueue{client: client, queueURL: queueURL}, nil
+}
+
+func (queue *sqsEventQueue) ReceiveEvents(ctx context.Context) (Events, error) {
+	events := Events{Messages: []EventMessage{}}
+
+	for range maxEventReceives {
+		output, err := queue.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(queue.queueURL),
+			MaxNumberOfMessages: 10,
+			WaitTimeSeconds:     1,
+		})
+		if err != nil {
+			return Events{}, err
+		}
+		if len(output.Messages) == 0 {
+			break
+		}
+
+		for _, message := range output.Messages {
+			events.Messages = append(events.Messages, EventMessage{
+				ReceiptHandle: aws.ToString(message.ReceiptHandle),
+				Objects:       parseObjectEvents(aws.ToString(message.Body)),
+			})
+		}
+	}
+
+	return events, nil
+}
+
+func (queue *sqsEventQueue) DeleteEvents(ctx context.Context, events Events) error {
+	for start := 0; start < len(events.Messages); start += 10 {
+		end := min(start+10, len(events.Messages))
+
+		entries := make([]types.DeleteMessageBatchRequestEntry, 0, end-start)
+		for i, message := range events.Messages[start:end] {
+			entries = append(entries, types.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(i)),
+				ReceiptHandle: aws.String(message.ReceiptHandle),
+			})
+		}
+
+		output, err := queue.client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(queue.queueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			return err
+		}
+		if len(output.Failed) > 0 {
+			failed := output.Failed[0]
+			return fmt.Errorf("error deleting %d messages: %s: %s", len(output.Failed), aws.ToString(failed.Code), aws.ToString(failed.Message))
+		}
+	}
+
+	return nil
+}
+
+// s3EventNotification is the body of the messages S3 sends to a queue, or
+// the message of an SNS notification when S3 sends them to a topic instead
+type s3EventNotification struct {
+	Records []struct {
+		EventName string `json:"eventName"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key       string `json:"key"`
+				VersionID string `json:"versionId"`
+				Sequencer string `json:"sequencer"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+// parseObjectEvents returns the ObjectCreated events of a message. Other
+// events, test events and messages which are not S3 event notifications have
+// none.
+func parseObjectEvents(body string) []ObjectEvent {
+	var notification s3EventNotification
+	if err := json.Unmarshal([]byte(body), &notification); err != nil {
+		return nil
+	}
+
+	if notification.Type == "Notification" {
+		return parseObjectEvents(notification.Message)
+	}
+
+	var events []ObjectEvent
+	for _, record := range notification.Records {
+		if !strings.HasPrefix(record.EventName, "ObjectCreated:") {
+			continue
+		}
+
+		// Keys are URL encoded, with spaces as "+"
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			continue
+		}
+
+		events = append(events, ObjectEvent{
+			Bucket:    record.S3.Bucket.Name,
+			Key:       key,
+			VersionID: record.S3.Object.VersionID,
+			Sequencer: record.S3.Object.Sequencer,
+		})
+	}
+	return events
+}
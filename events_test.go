@@ -0,0 +1,161 @@
+package s3resource
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/concourse/s3-resource/integration/s3server"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Events", func() {
+	var (
+		ctx       context.Context
+		server    *s3server.Server
+		awsConfig *aws.Config
+		client    S3Client
+		queueURL  string
+		queue     EventQueue
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = NewTestS3Server("us-east-1")
+		server.CreateBucket("bucket", false)
+		server.CreateBucket("versioned-bucket", true)
+		queueURL = server.CreateQueue("bucket-events")
+		server.NotifyQueue("bucket", queueURL)
+		server.NotifyQueue("versioned-bucket", queueURL)
+
+		awsConfig = NewTestAwsConfig("us-east-1")
+		client = NewTestS3Client(io.Discard, awsConfig, server.URL, S3ClientOptions{})
+
+		var err error
+		queue, err = NewEventQueue(awsConfig, queueURL)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	upload := func(bucketName string, key string) string {
+		localPath := filepath.Join(GinkgoT().TempDir(), "file")
+		Expect(os.WriteFile(localPath, []byte(key), 0644)).To(Succeed())
+
+		versionID, err := client.UploadFile(ctx, bucketName, key, localPath, UploadFileOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return versionID
+	}
+
+	It("receives the objects created in the buckets", func() {
+		upload("bucket", "files/abc 1.tgz")
+		versionID := upload("versioned-bucket", "files/versioned-file")
+
+		events, err := queue.ReceiveEvents(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events.Messages).To(HaveLen(2))
+		Expect(events.Messages[0].ReceiptHandle).ToNot(BeEmpty())
+		Expect(events.Messages[0].Objects).To(HaveLen(1))
+
+		objects := events.Objects()
+		Expect(objects).To(HaveLen(2))
+
+		Expect(objects[0].Bucket).To(Equal("bucket"))
+		Expect(objects[0].Key).To(Equal("files/abc 1.tgz"))
+		Expect(objects[0].VersionID).To(BeEmpty())
+
+		Expect(objects[1].Bucket).To(Equal("versioned-bucket"))
+		Expect(objects[1].Key).To(Equal("files/versioned-file"))
+		Expect(objects[1].VersionID).To(Equal(versionID))
+		Expect(CompareSequencers(objects[1].Sequencer, objects[0].Sequencer)).To(BeNumerically(">", 0))
+	})
+
+	It("receives more messages than fit in a batch", func() {
+		for range 25 {
+			upload("bucket", "files/abc-1.tgz")
+		}
+
+		events, err := queue.ReceiveEvents(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events.Objects()).To(HaveLen(25))
+		Expect(events.Messages).To(HaveLen(25))
+	})
+
+	It("deletes the given messages, which are then not received again", func() {
+		for range 15 {
+			upload("bucket", "files/abc-1.tgz")
+		}
+		upload("bucket", "files/other.tgz")
+
+		events, err := queue.ReceiveEvents(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events.Messages).To(HaveLen(16))
+		Expect(queue.DeleteEvents(ctx, Events{Messages: events.Messages[:15]})).To(Succeed())
+
+		upload("bucket", "files/abc-2.tgz")
+
+		// The message which was not deleted is received again once its
+		// visibility timeout has passed
+		server.ExpireVisibility(queueURL)
+
+		events, err = queue.ReceiveEvents(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events.Objects()).To(ConsistOf(
+			HaveField("Key", "files/other.tgz"),
+			HaveField("Key", "files/abc-2.tgz"),
+		))
+	})
+
+	It("keeps the messages without ObjectCreated events, so that they are deleted", func() {
+		sqsClient := sqs.NewFromConfig(*awsConfig, func(o *sqs.Options) {
+			o.BaseEndpoint = aws.String(server.URL)
+		})
+		for _, body := range []string{
+			`{"Service":"Amazon S3","Event":"s3:TestEvent","Bucket":"bucket"}`,
+			`{"Records":[{"eventName":"ObjectRemoved:Delete","s3":{"bucket":{"name":"bucket"},"object":{"key":"files/abc-1.tgz"}}}]}`,
+			`not json`,
+			`{"Type":"Notification","Message":"{\"Records\":[{\"eventName\":\"ObjectCreated:Copy\",\"s3\":{\"bucket\":{\"name\":\"bucket\"},\"object\":{\"key\":\"files/abc-3.tgz\",\"sequencer\":\"0A\"}}}]}"}`,
+		} {
+			_, err := sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+				QueueUrl:    aws.String(queueURL),
+				MessageBody: aws.String(body),
+			})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		events, err := queue.ReceiveEvents(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events.Messages).To(HaveLen(4))
+		Expect(events.Objects()).To(Equal([]ObjectEvent{
+			{Bucket: "bucket", Key: "files/abc-3.tgz", Sequencer: "0A"},
+		}))
+	})
+
+	It("returns an error when the queue does not exist", func() {
+		queue, err := NewEventQueue(awsConfig, server.URL+"/123456789012/missing-queue")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = queue.ReceiveEvents(ctx)
+		Expect(errors.Is(err, ErrNotFound)).To(BeTrue())
+		Expect(Hint(err)).To(Equal("check that sqs_queue_url is correct and that the queue exists"))
+	})
+
+	It("compares sequencers of different lengths", func() {
+		Expect(CompareSequencers("0055AED6DCD90281E5", "0055AED6DCD90281E6")).To(BeNumerically("<", 0))
+		Expect(CompareSequencers("0055AED6DCD90281E5FF", "0055AED6DCD90281E6")).To(BeNumerically(">", 0))
+		Expect(CompareSequencers("0A", "0A")).To(BeZero())
+	})
+
+	It("finds the region of AWS queues in their URL", func() {
+		for _, host := range []string{"sqs.eu-west-1.amazonaws.com", "eu-west-1.queue.amazonaws.com"} {
+			match := sqsRegionPattern.FindStringSubmatch(host)
+			Expect(match).ToNot(BeNil())
+			Expect(match[1] + match[2]).To(Equal("eu-west-1"))
+		}
+		Expect(sqsRegionPattern.MatchString("localhost")).To(BeFalse())
+	})
+})
@@ -0,0 +1,189 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package fakes
+
+import (
+	"context"
+	"sync"
+
+	s3resource "github.com/concourse/s3-resource"
+)
+
+type FakeEventQueue struct {
+	DeleteEventsStub        func(context.Context, s3resource.Events) error
+	deleteEventsMutex       sync.RWMutex
+	deleteEventsArgsForCall []struct {
+		arg1 context.Context
+		arg2 s3resource.Events
+	}
+	deleteEventsReturns struct {
+		result1 error
+	}
+	deleteEventsReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ReceiveEventsStub        func(context.Context) (s3resource.Events, error)
+	receiveEventsMutex       sync.RWMutex
+	receiveEventsArgsForCall []struct {
+		arg1 context.Context
+	}
+	receiveEventsReturns struct {
+		result1 s3resource.Events
+		result2 error
+	}
+	receiveEventsReturnsOnCall map[int]struct {
+		result1 s3resource.Events
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeEventQueue) DeleteEvents(arg1 context.Context, arg2 s3resource.Events) error {
+	fake.deleteEventsMutex.Lock()
+	ret, specificReturn := fake.deleteEventsReturnsOnCall[len(fake.deleteEventsArgsForCall)]
+	fake.deleteEventsArgsForCall = append(fake.deleteEventsArgsForCall, struct {
+		arg1 context.Context
+		arg2 s3resource.Events
+	}{arg1, arg2})
+	stub := fake.DeleteEventsStub
+	fakeReturns := fake.deleteEventsReturns
+	fake.recordInvocation("DeleteEvents", []interface{}{arg1, arg2})
+	fake.deleteEventsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeEventQueue) DeleteEventsCallCount() int {
+	fake.deleteEventsMutex.RLock()
+	defer fake.deleteEventsMutex.RUnlock()
+	return len(fake.deleteEventsArgsForCall)
+}
+
+func (fake *FakeEventQueue) DeleteEventsCalls(stub func(context.Context, s3resource.Events) error) {
+	fake.deleteEventsMutex.Lock()
+	defer fake.deleteEventsMutex.Unlock()
+	fake.DeleteEventsStub = stub
+}
+
+func (fake *FakeEventQueue) DeleteEventsArgsForCall(i int) (context.Context, s3resource.Events) {
+	fake.deleteEventsMutex.RLock()
+	defer fake.deleteEventsMutex.RUnlock()
+	argsForCall := fake.deleteEventsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventQueue) DeleteEventsReturns(result1 error) {
+	fake.deleteEventsMutex.Lock()
+	defer fake.deleteEventsMutex.Unlock()
+	fake.DeleteEventsStub = nil
+	fake.deleteEventsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventQueue) DeleteEventsReturnsOnCall(i int, result1 error) {
+	fake.deleteEventsMutex.Lock()
+	defer fake.deleteEventsMutex.Unlock()
+	fake.DeleteEventsStub = nil
+	if fake.deleteEventsReturnsOnCall == nil {
+		fake.deleteEventsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteEventsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventQueue) ReceiveEvents(arg1 context.Context) (s3resource.Events, error) {
+	fake.receiveEventsMutex.Lock()
+	ret, specificReturn := fake.receiveEventsReturnsOnCall[len(fake.receiveEventsArgsForCall)]
+	fake.receiveEventsArgsForCall = append(fake.receiveEventsArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.ReceiveEventsStub
+	fakeReturns := fake.receiveEventsReturns
+	fake.recordInvocation("ReceiveEvents", []interface{}{arg1})
+	fake.receiveEventsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventQueue) ReceiveEventsCallCount() int {
+	fake.receiveEventsMutex.RLock()
+	defer fake.receiveEventsMutex.RUnlock()
+	return len(fake.receiveEventsArgsForCall)
+}
+
+func (fake *FakeEventQueue) ReceiveEventsCalls(stub func(context.Context) (s3resource.Events, error)) {
+	fake.receiveEventsMutex.Lock()
+	defer fake.receiveEventsMutex.Unlock()
+	fake.ReceiveEventsStub = stub
+}
+
+func (fake *FakeEventQueue) ReceiveEventsArgsForCall(i int) context.Context {
+	fake.receiveEventsMutex.RLock()
+	defer fake.receiveEventsMutex.RUnlock()
+	argsForCall := fake.receiveEventsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventQueue) ReceiveEventsReturns(result1 s3resource.Events, result2 error) {
+	fake.receiveEventsMutex.Lock()
+	defer fake.receiveEventsMutex.Unlock()
+	fake.ReceiveEventsStub = nil
+	fake.receiveEventsReturns = struct {
+		result1 s3resource.Events
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventQueue) ReceiveEventsReturnsOnCall(i int, result1 s3resource.Events, result2 error) {
+	fake.receiveEventsMutex.Lock()
+	defer fake.receiveEventsMutex.Unlock()
+	fake.ReceiveEventsStub = nil
+	if fake.receiveEventsReturnsOnCall == nil {
+		fake.receiveEventsReturnsOnCall = make(map[int]struct {
+			result1 s3resource.Events
+			result2 error
+		})
+	}
+	fake.receiveEventsReturnsOnCall[i] = struct {
+		result1 s3resource.Events
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventQueue) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeEventQueue) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ s3resource.EventQueue = new(FakeEventQueue)
@@ -2,17 +2,21 @@
 package fakes
 
 import (
+	"context"
+	"io"
 	"sync"
+	"time"
 
 	s3resource "github.com/concourse/s3-resource"
 )
 
 type FakeS3Client struct {
-	BucketFileVersionsStub        func(string, string) ([]string, error)
+	BucketFileVersionsStub        func(context.Context, string, string) ([]string, error)
 	bucketFileVersionsMutex       sync.RWMutex
 	bucketFileVersionsArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
+		arg3 string
 	}
 	bucketFileVersionsReturns struct {
 		result1 []string
@@ -22,11 +26,12 @@ type FakeS3Client struct {
 		result1 []string
 		result2 error
 	}
-	BucketFilesStub        func(string, string) ([]string, error)
+	BucketFilesStub        func(context.Context, string, string) ([]string, error)
 	bucketFilesMutex       sync.RWMutex
 	bucketFilesArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
+		arg3 string
 	}
 	bucketFilesReturns struct {
 		result1 []string
@@ -36,12 +41,29 @@ type FakeS3Client struct {
 		result1 []string
 		result2 error
 	}
-	ChunkedBucketListStub        func(string, string, *string) (s3resource.BucketListChunk, error)
+	BucketFilesModifiedSinceStub        func(context.Context, string, string, time.Time) ([]string, error)
+	bucketFilesModifiedSinceMutex       sync.RWMutex
+	bucketFilesModifiedSinceArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 time.Time
+	}
+	bucketFilesModifiedSinceReturns struct {
+		result1 []string
+		result2 error
+	}
+	bucketFilesModifiedSinceReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	ChunkedBucketListStub        func(context.Context, string, string, *string) (s3resource.BucketListChunk, error)
 	chunkedBucketListMutex       sync.RWMutex
 	chunkedBucketListArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 *string
+		arg3 string
+		arg4 *string
 	}
 	chunkedBucketListReturns struct {
 		result1 s3resource.BucketListChunk
@@ -51,11 +73,12 @@ type FakeS3Client struct {
 		result1 s3resource.BucketListChunk
 		result2 error
 	}
-	DeleteFileStub        func(string, string) error
+	DeleteFileStub        func(context.Context, string, string) error
 	deleteFileMutex       sync.RWMutex
 	deleteFileArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
+		arg3 string
 	}
 	deleteFileReturns struct {
 		result1 error
@@ -63,12 +86,13 @@ type FakeS3Client struct {
 	deleteFileReturnsOnCall map[int]struct {
 		result1 error
 	}
-	DeleteVersionedFileStub        func(string, string, string) error
+	DeleteVersionedFileStub        func(context.Context, string, string, string) error
 	deleteVersionedFileMutex       sync.RWMutex
 	deleteVersionedFileArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
+		arg4 string
 	}
 	deleteVersionedFileReturns struct {
 		result1 error
@@ -76,13 +100,15 @@ type FakeS3Client struct {
 	deleteVersionedFileReturnsOnCall map[int]struct {
 		result1 error
 	}
-	DownloadFileStub        func(string, string, string, string) error
+	DownloadFileStub        func(context.Context, string, string, string, string, s3resource.DownloadFileOptions) error
 	downloadFileMutex       sync.RWMutex
 	downloadFileArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
 		arg4 string
+		arg5 string
+		arg6 s3resource.DownloadFileOptions
 	}
 	downloadFileReturns struct {
 		result1 error
@@ -90,13 +116,29 @@ type FakeS3Client struct {
 	downloadFileReturnsOnCall map[int]struct {
 		result1 error
 	}
-	DownloadTagsStub        func(string, string, string, string) error
+	DownloadObjectLockStub        func(context.Context, string, string, string, string) error
+	downloadObjectLockMutex       sync.RWMutex
+	downloadObjectLockArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 string
+	}
+	downloadObjectLockReturns struct {
+		result1 error
+	}
+	downloadObjectLockReturnsOnCall map[int]struct {
+		result1 error
+	}
+	DownloadTagsStub        func(context.Context, string, string, string, string) error
 	downloadTagsMutex       sync.RWMutex
 	downloadTagsArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
 		arg4 string
+		arg5 string
 	}
 	downloadTagsReturns struct {
 		result1 error
@@ -104,13 +146,57 @@ type FakeS3Client struct {
 	downloadTagsReturnsOnCall map[int]struct {
 		result1 error
 	}
-	SetTagsStub        func(string, string, string, map[string]string) error
+	OpenFileStub        func(context.Context, string, string) (io.ReadCloser, error)
+	openFileMutex       sync.RWMutex
+	openFileArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	openFileReturns struct {
+		result1 io.ReadCloser
+		result2 error
+	}
+	openFileReturnsOnCall map[int]struct {
+		result1 io.ReadCloser
+		result2 error
+	}
+	PreflightStub        func(context.Context, string, s3resource.PreflightOptions) s3resource.PreflightReport
+	preflightMutex       sync.RWMutex
+	preflightArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 s3resource.PreflightOptions
+	}
+	preflightReturns struct {
+		result1 s3resource.PreflightReport
+	}
+	preflightReturnsOnCall map[int]struct {
+		result1 s3resource.PreflightReport
+	}
+	RestoreObjectStub        func(context.Context, string, string, string, s3resource.RestoreObjectOptions) error
+	restoreObjectMutex       sync.RWMutex
+	restoreObjectArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 s3resource.RestoreObjectOptions
+	}
+	restoreObjectReturns struct {
+		result1 error
+	}
+	restoreObjectReturnsOnCall map[int]struct {
+		result1 error
+	}
+	SetTagsStub        func(context.Context, string, string, string, map[string]string) error
 	setTagsMutex       sync.RWMutex
 	setTagsArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
-		arg4 map[string]string
+		arg4 string
+		arg5 map[string]string
 	}
 	setTagsReturns struct {
 		result1 error
@@ -118,13 +204,14 @@ type FakeS3Client struct {
 	setTagsReturnsOnCall map[int]struct {
 		result1 error
 	}
-	URLStub        func(string, string, bool, string) (string, error)
+	URLStub        func(context.Context, string, string, bool, string) (string, error)
 	uRLMutex       sync.RWMutex
 	uRLArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 bool
-		arg4 string
+		arg3 string
+		arg4 bool
+		arg5 string
 	}
 	uRLReturns struct {
 		result1 string
@@ -134,13 +221,14 @@ type FakeS3Client struct {
 		result1 string
 		result2 error
 	}
-	UploadFileStub        func(string, string, string, s3resource.UploadFileOptions) (string, error)
+	UploadFileStub        func(context.Context, string, string, string, s3resource.UploadFileOptions) (string, error)
 	uploadFileMutex       sync.RWMutex
 	uploadFileArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
-		arg4 s3resource.UploadFileOptions
+		arg4 string
+		arg5 s3resource.UploadFileOptions
 	}
 	uploadFileReturns struct {
 		result1 string
@@ -154,19 +242,20 @@ type FakeS3Client struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeS3Client) BucketFileVersions(arg1 string, arg2 string) ([]string, error) {
+func (fake *FakeS3Client) BucketFileVersions(arg1 context.Context, arg2 string, arg3 string) ([]string, error) {
 	fake.bucketFileVersionsMutex.Lock()
 	ret, specificReturn := fake.bucketFileVersionsReturnsOnCall[len(fake.bucketFileVersionsArgsForCall)]
 	fake.bucketFileVersionsArgsForCall = append(fake.bucketFileVersionsArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-	}{arg1, arg2})
+		arg3 string
+	}{arg1, arg2, arg3})
 	stub := fake.BucketFileVersionsStub
 	fakeReturns := fake.bucketFileVersionsReturns
-	fake.recordInvocation("BucketFileVersions", []interface{}{arg1, arg2})
+	fake.recordInvocation("BucketFileVersions", []interface{}{arg1, arg2, arg3})
 	fake.bucketFileVersionsMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -180,17 +269,17 @@ func (fake *FakeS3Client) BucketFileVersionsCallCount() int {
 	return len(fake.bucketFileVersionsArgsForCall)
 }
 
-func (fake *FakeS3Client) BucketFileVersionsCalls(stub func(string, string) ([]string, error)) {
+func (fake *FakeS3Client) BucketFileVersionsCalls(stub func(context.Context, string, string) ([]string, error)) {
 	fake.bucketFileVersionsMutex.Lock()
 	defer fake.bucketFileVersionsMutex.Unlock()
 	fake.BucketFileVersionsStub = stub
 }
 
-func (fake *FakeS3Client) BucketFileVersionsArgsForCall(i int) (string, string) {
+func (fake *FakeS3Client) BucketFileVersionsArgsForCall(i int) (context.Context, string, string) {
 	fake.bucketFileVersionsMutex.RLock()
 	defer fake.bucketFileVersionsMutex.RUnlock()
 	argsForCall := fake.bucketFileVersionsArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeS3Client) BucketFileVersionsReturns(result1 []string, result2 error) {
@@ -219,19 +308,20 @@ func (fake *FakeS3Client) BucketFileVersionsReturnsOnCall(i int, result1 []strin
 	}{result1, result2}
 }
 
-func (fake *FakeS3Client) BucketFiles(arg1 string, arg2 string) ([]string, error) {
+func (fake *FakeS3Client) BucketFiles(arg1 context.Context, arg2 string, arg3 string) ([]string, error) {
 	fake.bucketFilesMutex.Lock()
 	ret, specificReturn := fake.bucketFilesReturnsOnCall[len(fake.bucketFilesArgsForCall)]
 	fake.bucketFilesArgsForCall = append(fake.bucketFilesArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-	}{arg1, arg2})
+		arg3 string
+	}{arg1, arg2, arg3})
 	stub := fake.BucketFilesStub
 	fakeReturns := fake.bucketFilesReturns
-	fake.recordInvocation("BucketFiles", []interface{}{arg1, arg2})
+	fake.recordInvocation("BucketFiles", []interface{}{arg1, arg2, arg3})
 	fake.bucketFilesMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -245,17 +335,17 @@ func (fake *FakeS3Client) BucketFilesCallCount() int {
 	return len(fake.bucketFilesArgsForCall)
 }
 
-func (fake *FakeS3Client) BucketFilesCalls(stub func(string, string) ([]string, error)) {
+func (fake *FakeS3Client) BucketFilesCalls(stub func(context.Context, string, string) ([]string, error)) {
 	fake.bucketFilesMutex.Lock()
 	defer fake.bucketFilesMutex.Unlock()
 	fake.BucketFilesStub = stub
 }
 
-func (fake *FakeS3Client) BucketFilesArgsForCall(i int) (string, string) {
+func (fake *FakeS3Client) BucketFilesArgsForCall(i int) (context.Context, string, string) {
 	fake.bucketFilesMutex.RLock()
 	defer fake.bucketFilesMutex.RUnlock()
 	argsForCall := fake.bucketFilesArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeS3Client) BucketFilesReturns(result1 []string, result2 error) {
@@ -284,20 +374,88 @@ func (fake *FakeS3Client) BucketFilesReturnsOnCall(i int, result1 []string, resu
 	}{result1, result2}
 }
 
-func (fake *FakeS3Client) ChunkedBucketList(arg1 string, arg2 string, arg3 *string) (s3resource.BucketListChunk, error) {
+func (fake *FakeS3Client) BucketFilesModifiedSince(arg1 context.Context, arg2 string, arg3 string, arg4 time.Time) ([]string, error) {
+	fake.bucketFilesModifiedSinceMutex.Lock()
+	ret, specificReturn := fake.bucketFilesModifiedSinceReturnsOnCall[len(fake.bucketFilesModifiedSinceArgsForCall)]
+	fake.bucketFilesModifiedSinceArgsForCall = append(fake.bucketFilesModifiedSinceArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 time.Time
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.BucketFilesModifiedSinceStub
+	fakeReturns := fake.bucketFilesModifiedSinceReturns
+	fake.recordInvocation("BucketFilesModifiedSince", []interface{}{arg1, arg2, arg3, arg4})
+	fake.bucketFilesModifiedSinceMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeS3Client) BucketFilesModifiedSinceCallCount() int {
+	fake.bucketFilesModifiedSinceMutex.RLock()
+	defer fake.bucketFilesModifiedSinceMutex.RUnlock()
+	return len(fake.bucketFilesModifiedSinceArgsForCall)
+}
+
+func (fake *FakeS3Client) BucketFilesModifiedSinceCalls(stub func(context.Context, string, string, time.Time) ([]string, error)) {
+	fake.bucketFilesModifiedSinceMutex.Lock()
+	defer fake.bucketFilesModifiedSinceMutex.Unlock()
+	fake.BucketFilesModifiedSinceStub = stub
+}
+
+func (fake *FakeS3Client) BucketFilesModifiedSinceArgsForCall(i int) (context.Context, string, string, time.Time) {
+	fake.bucketFilesModifiedSinceMutex.RLock()
+	defer fake.bucketFilesModifiedSinceMutex.RUnlock()
+	argsForCall := fake.bucketFilesModifiedSinceArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeS3Client) BucketFilesModifiedSinceReturns(result1 []string, result2 error) {
+	fake.bucketFilesModifiedSinceMutex.Lock()
+	defer fake.bucketFilesModifiedSinceMutex.Unlock()
+	fake.BucketFilesModifiedSinceStub = nil
+	fake.bucketFilesModifiedSinceReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeS3Client) BucketFilesModifiedSinceReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.bucketFilesModifiedSinceMutex.Lock()
+	defer fake.bucketFilesModifiedSinceMutex.Unlock()
+	fake.BucketFilesModifiedSinceStub = nil
+	if fake.bucketFilesModifiedSinceReturnsOnCall == nil {
+		fake.bucketFilesModifiedSinceReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.bucketFilesModifiedSinceReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeS3Client) ChunkedBucketList(arg1 context.Context, arg2 string, arg3 string, arg4 *string) (s3resource.BucketListChunk, error) {
 	fake.chunkedBucketListMutex.Lock()
 	ret, specificReturn := fake.chunkedBucketListReturnsOnCall[len(fake.chunkedBucketListArgsForCall)]
 	fake.chunkedBucketListArgsForCall = append(fake.chunkedBucketListArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 *string
-	}{arg1, arg2, arg3})
+		arg3 string
+		arg4 *string
+	}{arg1, arg2, arg3, arg4})
 	stub := fake.ChunkedBucketListStub
 	fakeReturns := fake.chunkedBucketListReturns
-	fake.recordInvocation("ChunkedBucketList", []interface{}{arg1, arg2, arg3})
+	fake.recordInvocation("ChunkedBucketList", []interface{}{arg1, arg2, arg3, arg4})
 	fake.chunkedBucketListMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3)
+		return stub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -311,17 +469,17 @@ func (fake *FakeS3Client) ChunkedBucketListCallCount() int {
 	return len(fake.chunkedBucketListArgsForCall)
 }
 
-func (fake *FakeS3Client) ChunkedBucketListCalls(stub func(string, string, *string) (s3resource.BucketListChunk, error)) {
+func (fake *FakeS3Client) ChunkedBucketListCalls(stub func(context.Context, string, string, *string) (s3resource.BucketListChunk, error)) {
 	fake.chunkedBucketListMutex.Lock()
 	defer fake.chunkedBucketListMutex.Unlock()
 	fake.ChunkedBucketListStub = stub
 }
 
-func (fake *FakeS3Client) ChunkedBucketListArgsForCall(i int) (string, string, *string) {
+func (fake *FakeS3Client) ChunkedBucketListArgsForCall(i int) (context.Context, string, string, *string) {
 	fake.chunkedBucketListMutex.RLock()
 	defer fake.chunkedBucketListMutex.RUnlock()
 	argsForCall := fake.chunkedBucketListArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeS3Client) ChunkedBucketListReturns(result1 s3resource.BucketListChunk, result2 error) {
@@ -350,19 +508,20 @@ func (fake *FakeS3Client) ChunkedBucketListReturnsOnCall(i int, result1 s3resour
 	}{result1, result2}
 }
 
-func (fake *FakeS3Client) DeleteFile(arg1 string, arg2 string) error {
+func (fake *FakeS3Client) DeleteFile(arg1 context.Context, arg2 string, arg3 string) error {
 	fake.deleteFileMutex.Lock()
 	ret, specificReturn := fake.deleteFileReturnsOnCall[len(fake.deleteFileArgsForCall)]
 	fake.deleteFileArgsForCall = append(fake.deleteFileArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-	}{arg1, arg2})
+		arg3 string
+	}{arg1, arg2, arg3})
 	stub := fake.DeleteFileStub
 	fakeReturns := fake.deleteFileReturns
-	fake.recordInvocation("DeleteFile", []interface{}{arg1, arg2})
+	fake.recordInvocation("DeleteFile", []interface{}{arg1, arg2, arg3})
 	fake.deleteFileMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
@@ -376,17 +535,17 @@ func (fake *FakeS3Client) DeleteFileCallCount() int {
 	return len(fake.deleteFileArgsForCall)
 }
 
-func (fake *FakeS3Client) DeleteFileCalls(stub func(string, string) error) {
+func (fake *FakeS3Client) DeleteFileCalls(stub func(context.Context, string, string) error) {
 	fake.deleteFileMutex.Lock()
 	defer fake.deleteFileMutex.Unlock()
 	fake.DeleteFileStub = stub
 }
 
-func (fake *FakeS3Client) DeleteFileArgsForCall(i int) (string, string) {
+func (fake *FakeS3Client) DeleteFileArgsForCall(i int) (context.Context, string, string) {
 	fake.deleteFileMutex.RLock()
 	defer fake.deleteFileMutex.RUnlock()
 	argsForCall := fake.deleteFileArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeS3Client) DeleteFileReturns(result1 error) {
@@ -412,20 +571,21 @@ func (fake *FakeS3Client) DeleteFileReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeS3Client) DeleteVersionedFile(arg1 string, arg2 string, arg3 string) error {
+func (fake *FakeS3Client) DeleteVersionedFile(arg1 context.Context, arg2 string, arg3 string, arg4 string) error {
 	fake.deleteVersionedFileMutex.Lock()
 	ret, specificReturn := fake.deleteVersionedFileReturnsOnCall[len(fake.deleteVersionedFileArgsForCall)]
 	fake.deleteVersionedFileArgsForCall = append(fake.deleteVersionedFileArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
-	}{arg1, arg2, arg3})
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
 	stub := fake.DeleteVersionedFileStub
 	fakeReturns := fake.deleteVersionedFileReturns
-	fake.recordInvocation("DeleteVersionedFile", []interface{}{arg1, arg2, arg3})
+	fake.recordInvocation("DeleteVersionedFile", []interface{}{arg1, arg2, arg3, arg4})
 	fake.deleteVersionedFileMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3)
+		return stub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1
@@ -439,17 +599,17 @@ func (fake *FakeS3Client) DeleteVersionedFileCallCount() int {
 	return len(fake.deleteVersionedFileArgsForCall)
 }
 
-func (fake *FakeS3Client) DeleteVersionedFileCalls(stub func(string, string, string) error) {
+func (fake *FakeS3Client) DeleteVersionedFileCalls(stub func(context.Context, string, string, string) error) {
 	fake.deleteVersionedFileMutex.Lock()
 	defer fake.deleteVersionedFileMutex.Unlock()
 	fake.DeleteVersionedFileStub = stub
 }
 
-func (fake *FakeS3Client) DeleteVersionedFileArgsForCall(i int) (string, string, string) {
+func (fake *FakeS3Client) DeleteVersionedFileArgsForCall(i int) (context.Context, string, string, string) {
 	fake.deleteVersionedFileMutex.RLock()
 	defer fake.deleteVersionedFileMutex.RUnlock()
 	argsForCall := fake.deleteVersionedFileArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeS3Client) DeleteVersionedFileReturns(result1 error) {
@@ -475,21 +635,23 @@ func (fake *FakeS3Client) DeleteVersionedFileReturnsOnCall(i int, result1 error)
 	}{result1}
 }
 
-func (fake *FakeS3Client) DownloadFile(arg1 string, arg2 string, arg3 string, arg4 string) error {
+func (fake *FakeS3Client) DownloadFile(arg1 context.Context, arg2 string, arg3 string, arg4 string, arg5 string, arg6 s3resource.DownloadFileOptions) error {
 	fake.downloadFileMutex.Lock()
 	ret, specificReturn := fake.downloadFileReturnsOnCall[len(fake.downloadFileArgsForCall)]
 	fake.downloadFileArgsForCall = append(fake.downloadFileArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
 		arg4 string
-	}{arg1, arg2, arg3, arg4})
+		arg5 string
+		arg6 s3resource.DownloadFileOptions
+	}{arg1, arg2, arg3, arg4, arg5, arg6})
 	stub := fake.DownloadFileStub
 	fakeReturns := fake.downloadFileReturns
-	fake.recordInvocation("DownloadFile", []interface{}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("DownloadFile", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6})
 	fake.downloadFileMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3, arg4)
+		return stub(arg1, arg2, arg3, arg4, arg5, arg6)
 	}
 	if specificReturn {
 		return ret.result1
@@ -503,17 +665,17 @@ func (fake *FakeS3Client) DownloadFileCallCount() int {
 	return len(fake.downloadFileArgsForCall)
 }
 
-func (fake *FakeS3Client) DownloadFileCalls(stub func(string, string, string, string) error) {
+func (fake *FakeS3Client) DownloadFileCalls(stub func(context.Context, string, string, string, string, s3resource.DownloadFileOptions) error) {
 	fake.downloadFileMutex.Lock()
 	defer fake.downloadFileMutex.Unlock()
 	fake.DownloadFileStub = stub
 }
 
-func (fake *FakeS3Client) DownloadFileArgsForCall(i int) (string, string, string, string) {
+func (fake *FakeS3Client) DownloadFileArgsForCall(i int) (context.Context, string, string, string, string, s3resource.DownloadFileOptions) {
 	fake.downloadFileMutex.RLock()
 	defer fake.downloadFileMutex.RUnlock()
 	argsForCall := fake.downloadFileArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6
 }
 
 func (fake *FakeS3Client) DownloadFileReturns(result1 error) {
@@ -539,21 +701,87 @@ func (fake *FakeS3Client) DownloadFileReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeS3Client) DownloadTags(arg1 string, arg2 string, arg3 string, arg4 string) error {
+func (fake *FakeS3Client) DownloadObjectLock(arg1 context.Context, arg2 string, arg3 string, arg4 string, arg5 string) error {
+	fake.downloadObjectLockMutex.Lock()
+	ret, specificReturn := fake.downloadObjectLockReturnsOnCall[len(fake.downloadObjectLockArgsForCall)]
+	fake.downloadObjectLockArgsForCall = append(fake.downloadObjectLockArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 string
+	}{arg1, arg2, arg3, arg4, arg5})
+	stub := fake.DownloadObjectLockStub
+	fakeReturns := fake.downloadObjectLockReturns
+	fake.recordInvocation("DownloadObjectLock", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.downloadObjectLockMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeS3Client) DownloadObjectLockCallCount() int {
+	fake.downloadObjectLockMutex.RLock()
+	defer fake.downloadObjectLockMutex.RUnlock()
+	return len(fake.downloadObjectLockArgsForCall)
+}
+
+func (fake *FakeS3Client) DownloadObjectLockCalls(stub func(context.Context, string, string, string, string) error) {
+	fake.downloadObjectLockMutex.Lock()
+	defer fake.downloadObjectLockMutex.Unlock()
+	fake.DownloadObjectLockStub = stub
+}
+
+func (fake *FakeS3Client) DownloadObjectLockArgsForCall(i int) (context.Context, string, string, string, string) {
+	fake.downloadObjectLockMutex.RLock()
+	defer fake.downloadObjectLockMutex.RUnlock()
+	argsForCall := fake.downloadObjectLockArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeS3Client) DownloadObjectLockReturns(result1 error) {
+	fake.downloadObjectLockMutex.Lock()
+	defer fake.downloadObjectLockMutex.Unlock()
+	fake.DownloadObjectLockStub = nil
+	fake.downloadObjectLockReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeS3Client) DownloadObjectLockReturnsOnCall(i int, result1 error) {
+	fake.downloadObjectLockMutex.Lock()
+	defer fake.downloadObjectLockMutex.Unlock()
+	fake.DownloadObjectLockStub = nil
+	if fake.downloadObjectLockReturnsOnCall == nil {
+		fake.downloadObjectLockReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.downloadObjectLockReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeS3Client) DownloadTags(arg1 context.Context, arg2 string, arg3 string, arg4 string, arg5 string) error {
 	fake.downloadTagsMutex.Lock()
 	ret, specificReturn := fake.downloadTagsReturnsOnCall[len(fake.downloadTagsArgsForCall)]
 	fake.downloadTagsArgsForCall = append(fake.downloadTagsArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
 		arg4 string
-	}{arg1, arg2, arg3, arg4})
+		arg5 string
+	}{arg1, arg2, arg3, arg4, arg5})
 	stub := fake.DownloadTagsStub
 	fakeReturns := fake.downloadTagsReturns
-	fake.recordInvocation("DownloadTags", []interface{}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("DownloadTags", []interface{}{arg1, arg2, arg3, arg4, arg5})
 	fake.downloadTagsMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3, arg4)
+		return stub(arg1, arg2, arg3, arg4, arg5)
 	}
 	if specificReturn {
 		return ret.result1
@@ -567,17 +795,17 @@ func (fake *FakeS3Client) DownloadTagsCallCount() int {
 	return len(fake.downloadTagsArgsForCall)
 }
 
-func (fake *FakeS3Client) DownloadTagsCalls(stub func(string, string, string, string) error) {
+func (fake *FakeS3Client) DownloadTagsCalls(stub func(context.Context, string, string, string, string) error) {
 	fake.downloadTagsMutex.Lock()
 	defer fake.downloadTagsMutex.Unlock()
 	fake.DownloadTagsStub = stub
 }
 
-func (fake *FakeS3Client) DownloadTagsArgsForCall(i int) (string, string, string, string) {
+func (fake *FakeS3Client) DownloadTagsArgsForCall(i int) (context.Context, string, string, string, string) {
 	fake.downloadTagsMutex.RLock()
 	defer fake.downloadTagsMutex.RUnlock()
 	argsForCall := fake.downloadTagsArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
 func (fake *FakeS3Client) DownloadTagsReturns(result1 error) {
@@ -603,21 +831,216 @@ func (fake *FakeS3Client) DownloadTagsReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeS3Client) SetTags(arg1 string, arg2 string, arg3 string, arg4 map[string]string) error {
+func (fake *FakeS3Client) OpenFile(arg1 context.Context, arg2 string, arg3 string) (io.ReadCloser, error) {
+	fake.openFileMutex.Lock()
+	ret, specificReturn := fake.openFileReturnsOnCall[len(fake.openFileArgsForCall)]
+	fake.openFileArgsForCall = append(fake.openFileArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.OpenFileStub
+	fakeReturns := fake.openFileReturns
+	fake.recordInvocation("OpenFile", []interface{}{arg1, arg2, arg3})
+	fake.openFileMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeS3Client) OpenFileCallCount() int {
+	fake.openFileMutex.RLock()
+	defer fake.openFileMutex.RUnlock()
+	return len(fake.openFileArgsForCall)
+}
+
+func (fake *FakeS3Client) OpenFileCalls(stub func(context.Context, string, string) (io.ReadCloser, error)) {
+	fake.openFileMutex.Lock()
+	defer fake.openFileMutex.Unlock()
+	fake.OpenFileStub = stub
+}
+
+func (fake *FakeS3Client) OpenFileArgsForCall(i int) (context.Context, string, string) {
+	fake.openFileMutex.RLock()
+	defer fake.openFileMutex.RUnlock()
+	argsForCall := fake.openFileArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeS3Client) OpenFileReturns(result1 io.ReadCloser, result2 error) {
+	fake.openFileMutex.Lock()
+	defer fake.openFileMutex.Unlock()
+	fake.OpenFileStub = nil
+	fake.openFileReturns = struct {
+		result1 io.ReadCloser
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeS3Client) OpenFileReturnsOnCall(i int, result1 io.ReadCloser, result2 error) {
+	fake.openFileMutex.Lock()
+	defer fake.openFileMutex.Unlock()
+	fake.OpenFileStub = nil
+	if fake.openFileReturnsOnCall == nil {
+		fake.openFileReturnsOnCall = make(map[int]struct {
+			result1 io.ReadCloser
+			result2 error
+		})
+	}
+	fake.openFileReturnsOnCall[i] = struct {
+		result1 io.ReadCloser
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeS3Client) Preflight(arg1 context.Context, arg2 string, arg3 s3resource.PreflightOptions) s3resource.PreflightReport {
+	fake.preflightMutex.Lock()
+	ret, specificReturn := fake.preflightReturnsOnCall[len(fake.preflightArgsForCall)]
+	fake.preflightArgsForCall = append(fake.preflightArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 s3resource.PreflightOptions
+	}{arg1, arg2, arg3})
+	stub := fake.PreflightStub
+	fakeReturns := fake.preflightReturns
+	fake.recordInvocation("Preflight", []interface{}{arg1, arg2, arg3})
+	fake.preflightMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeS3Client) PreflightCallCount() int {
+	fake.preflightMutex.RLock()
+	defer fake.preflightMutex.RUnlock()
+	return len(fake.preflightArgsForCall)
+}
+
+func (fake *FakeS3Client) PreflightCalls(stub func(context.Context, string, s3resource.PreflightOptions) s3resource.PreflightReport) {
+	fake.preflightMutex.Lock()
+	defer fake.preflightMutex.Unlock()
+	fake.PreflightStub = stub
+}
+
+func (fake *FakeS3Client) PreflightArgsForCall(i int) (context.Context, string, s3resource.PreflightOptions) {
+	fake.preflightMutex.RLock()
+	defer fake.preflightMutex.RUnlock()
+	argsForCall := fake.preflightArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeS3Client) PreflightReturns(result1 s3resource.PreflightReport) {
+	fake.preflightMutex.Lock()
+	defer fake.preflightMutex.Unlock()
+	fake.PreflightStub = nil
+	fake.preflightReturns = struct {
+		result1 s3resource.PreflightReport
+	}{result1}
+}
+
+func (fake *FakeS3Client) PreflightReturnsOnCall(i int, result1 s3resource.PreflightReport) {
+	fake.preflightMutex.Lock()
+	defer fake.preflightMutex.Unlock()
+	fake.PreflightStub = nil
+	if fake.preflightReturnsOnCall == nil {
+		fake.preflightReturnsOnCall = make(map[int]struct {
+			result1 s3resource.PreflightReport
+		})
+	}
+	fake.preflightReturnsOnCall[i] = struct {
+		result1 s3resource.PreflightReport
+	}{result1}
+}
+
+func (fake *FakeS3Client) RestoreObject(arg1 context.Context, arg2 string, arg3 string, arg4 string, arg5 s3resource.RestoreObjectOptions) error {
+	fake.restoreObjectMutex.Lock()
+	ret, specificReturn := fake.restoreObjectReturnsOnCall[len(fake.restoreObjectArgsForCall)]
+	fake.restoreObjectArgsForCall = append(fake.restoreObjectArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 s3resource.RestoreObjectOptions
+	}{arg1, arg2, arg3, arg4, arg5})
+	stub := fake.RestoreObjectStub
+	fakeReturns := fake.restoreObjectReturns
+	fake.recordInvocation("RestoreObject", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.restoreObjectMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeS3Client) RestoreObjectCallCount() int {
+	fake.restoreObjectMutex.RLock()
+	defer fake.restoreObjectMutex.RUnlock()
+	return len(fake.restoreObjectArgsForCall)
+}
+
+func (fake *FakeS3Client) RestoreObjectCalls(stub func(context.Context, string, string, string, s3resource.RestoreObjectOptions) error) {
+	fake.restoreObjectMutex.Lock()
+	defer fake.restoreObjectMutex.Unlock()
+	fake.RestoreObjectStub = stub
+}
+
+func (fake *FakeS3Client) RestoreObjectArgsForCall(i int) (context.Context, string, string, string, s3resource.RestoreObjectOptions) {
+	fake.restoreObjectMutex.RLock()
+	defer fake.restoreObjectMutex.RUnlock()
+	argsForCall := fake.restoreObjectArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeS3Client) RestoreObjectReturns(result1 error) {
+	fake.restoreObjectMutex.Lock()
+	defer fake.restoreObjectMutex.Unlock()
+	fake.RestoreObjectStub = nil
+	fake.restoreObjectReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeS3Client) RestoreObjectReturnsOnCall(i int, result1 error) {
+	fake.restoreObjectMutex.Lock()
+	defer fake.restoreObjectMutex.Unlock()
+	fake.RestoreObjectStub = nil
+	if fake.restoreObjectReturnsOnCall == nil {
+		fake.restoreObjectReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.restoreObjectReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeS3Client) SetTags(arg1 context.Context, arg2 string, arg3 string, arg4 string, arg5 map[string]string) error {
 	fake.setTagsMutex.Lock()
 	ret, specificReturn := fake.setTagsReturnsOnCall[len(fake.setTagsArgsForCall)]
 	fake.setTagsArgsForCall = append(fake.setTagsArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
-		arg4 map[string]string
-	}{arg1, arg2, arg3, arg4})
+		arg4 string
+		arg5 map[string]string
+	}{arg1, arg2, arg3, arg4, arg5})
 	stub := fake.SetTagsStub
 	fakeReturns := fake.setTagsReturns
-	fake.recordInvocation("SetTags", []interface{}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("SetTags", []interface{}{arg1, arg2, arg3, arg4, arg5})
 	fake.setTagsMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3, arg4)
+		return stub(arg1, arg2, arg3, arg4, arg5)
 	}
 	if specificReturn {
 		return ret.result1
@@ -631,17 +1054,17 @@ func (fake *FakeS3Client) SetTagsCallCount() int {
 	return len(fake.setTagsArgsForCall)
 }
 
-func (fake *FakeS3Client) SetTagsCalls(stub func(string, string, string, map[string]string) error) {
+func (fake *FakeS3Client) SetTagsCalls(stub func(context.Context, string, string, string, map[string]string) error) {
 	fake.setTagsMutex.Lock()
 	defer fake.setTagsMutex.Unlock()
 	fake.SetTagsStub = stub
 }
 
-func (fake *FakeS3Client) SetTagsArgsForCall(i int) (string, string, string, map[string]string) {
+func (fake *FakeS3Client) SetTagsArgsForCall(i int) (context.Context, string, string, string, map[string]string) {
 	fake.setTagsMutex.RLock()
 	defer fake.setTagsMutex.RUnlock()
 	argsForCall := fake.setTagsArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
 func (fake *FakeS3Client) SetTagsReturns(result1 error) {
@@ -667,21 +1090,22 @@ func (fake *FakeS3Client) SetTagsReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeS3Client) URL(arg1 string, arg2 string, arg3 bool, arg4 string) (string, error) {
+func (fake *FakeS3Client) URL(arg1 context.Context, arg2 string, arg3 string, arg4 bool, arg5 string) (string, error) {
 	fake.uRLMutex.Lock()
 	ret, specificReturn := fake.uRLReturnsOnCall[len(fake.uRLArgsForCall)]
 	fake.uRLArgsForCall = append(fake.uRLArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 bool
-		arg4 string
-	}{arg1, arg2, arg3, arg4})
+		arg3 string
+		arg4 bool
+		arg5 string
+	}{arg1, arg2, arg3, arg4, arg5})
 	stub := fake.URLStub
 	fakeReturns := fake.uRLReturns
-	fake.recordInvocation("URL", []interface{}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("URL", []interface{}{arg1, arg2, arg3, arg4, arg5})
 	fake.uRLMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3, arg4)
+		return stub(arg1, arg2, arg3, arg4, arg5)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -695,17 +1119,17 @@ func (fake *FakeS3Client) URLCallCount() int {
 	return len(fake.uRLArgsForCall)
 }
 
-func (fake *FakeS3Client) URLCalls(stub func(string, string, bool, string) (string, error)) {
+func (fake *FakeS3Client) URLCalls(stub func(context.Context, string, string, bool, string) (string, error)) {
 	fake.uRLMutex.Lock()
 	defer fake.uRLMutex.Unlock()
 	fake.URLStub = stub
 }
 
-func (fake *FakeS3Client) URLArgsForCall(i int) (string, string, bool, string) {
+func (fake *FakeS3Client) URLArgsForCall(i int) (context.Context, string, string, bool, string) {
 	fake.uRLMutex.RLock()
 	defer fake.uRLMutex.RUnlock()
 	argsForCall := fake.uRLArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
 func (fake *FakeS3Client) URLReturns(result1 string, result2 error) {
@@ -734,21 +1158,22 @@ func (fake *FakeS3Client) URLReturnsOnCall(i int, result1 string, result2 error)
 	}{result1, result2}
 }
 
-func (fake *FakeS3Client) UploadFile(arg1 string, arg2 string, arg3 string, arg4 s3resource.UploadFileOptions) (string, error) {
+func (fake *FakeS3Client) UploadFile(arg1 context.Context, arg2 string, arg3 string, arg4 string, arg5 s3resource.UploadFileOptions) (string, error) {
 	fake.uploadFileMutex.Lock()
 	ret, specificReturn := fake.uploadFileReturnsOnCall[len(fake.uploadFileArgsForCall)]
 	fake.uploadFileArgsForCall = append(fake.uploadFileArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
-		arg4 s3resource.UploadFileOptions
-	}{arg1, arg2, arg3, arg4})
+		arg4 string
+		arg5 s3resource.UploadFileOptions
+	}{arg1, arg2, arg3, arg4, arg5})
 	stub := fake.UploadFileStub
 	fakeReturns := fake.uploadFileReturns
-	fake.recordInvocation("UploadFile", []interface{}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("UploadFile", []interface{}{arg1, arg2, arg3, arg4, arg5})
 	fake.uploadFileMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3, arg4)
+		return stub(arg1, arg2, arg3, arg4, arg5)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -762,17 +1187,17 @@ func (fake *FakeS3Client) UploadFileCallCount() int {
 	return len(fake.uploadFileArgsForCall)
 }
 
-func (fake *FakeS3Client) UploadFileCalls(stub func(string, string, string, s3resource.UploadFileOptions) (string, error)) {
+func (fake *FakeS3Client) UploadFileCalls(stub func(context.Context, string, string, string, s3resource.UploadFileOptions) (string, error)) {
 	fake.uploadFileMutex.Lock()
 	defer fake.uploadFileMutex.Unlock()
 	fake.UploadFileStub = stub
 }
 
-func (fake *FakeS3Client) UploadFileArgsForCall(i int) (string, string, string, s3resource.UploadFileOptions) {
+func (fake *FakeS3Client) UploadFileArgsForCall(i int) (context.Context, string, string, string, s3resource.UploadFileOptions) {
 	fake.uploadFileMutex.RLock()
 	defer fake.uploadFileMutex.RUnlock()
 	argsForCall := fake.uploadFileArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
 func (fake *FakeS3Client) UploadFileReturns(result1 string, result2 error) {
@@ -804,26 +1229,6 @@ func (fake *FakeS3Client) UploadFileReturnsOnCall(i int, result1 string, result2
 func (fake *FakeS3Client) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.bucketFileVersionsMutex.RLock()
-	defer fake.bucketFileVersionsMutex.RUnlock()
-	fake.bucketFilesMutex.RLock()
-	defer fake.bucketFilesMutex.RUnlock()
-	fake.chunkedBucketListMutex.RLock()
-	defer fake.chunkedBucketListMutex.RUnlock()
-	fake.deleteFileMutex.RLock()
-	defer fake.deleteFileMutex.RUnlock()
-	fake.deleteVersionedFileMutex.RLock()
-	defer fake.deleteVersionedFileMutex.RUnlock()
-	fake.downloadFileMutex.RLock()
-	defer fake.downloadFileMutex.RUnlock()
-	fake.downloadTagsMutex.RLock()
-	defer fake.downloadTagsMutex.RUnlock()
-	fake.setTagsMutex.RLock()
-	defer fake.setTagsMutex.RUnlock()
-	fake.uRLMutex.RLock()
-	defer fake.uRLMutex.RUnlock()
-	fake.uploadFileMutex.RLock()
-	defer fake.uploadFileMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
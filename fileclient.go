@@ -0,0 +1,737 @@
+package s3resource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FileEndpointScheme selects the local filesystem backend when endpoint is
+// a URL such as file:///var/lib/s3
+const FileEndpointScheme = "file"
+
+// fileMetadataDir holds the versions of the objects in a bucket and their
+// sidecar files
+const fileMetadataDir = ".s3-resource"
+
+// nullVersionID is the version of an object which was put in the bucket
+// directory by hand, as S3 names the version of objects in unversioned
+// buckets
+const nullVersionID = "null"
+
+// fileListChunkSize is how many files and prefixes ChunkedBucketList returns
+// at once, as S3 does
+const fileListChunkSize = 1000
+
+// fileClient stores each bucket as a directory under root, with the latest
+// version of each object at its key. Every version is kept in the
+// fileMetadataDir of the bucket, next to a sidecar file with its tags and
+// Object Lock state:
+//
+//	<root>/<bucket>/<key>
+//	<root>/<bucket>/.s3-resource/<key>/<version ID>
+//	<root>/<bucket>/.s3-resource/<key>/<version ID>.json
+type fileClient struct {
+	root string
+	transfers
+}
+
+// fileObjectMetadata is the sidecar file of an object version
+type fileObjectMetadata struct {
+	ContentType string            `json:"content_type,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	ObjectLock  ObjectLock        `json:"object_lock"`
+}
+
+func newFileClient(progressOutput io.Writer, endpoint string, options S3ClientOptions) (*fileClient, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing given endpoint: %w", err)
+	}
+	if (u.Host != "" && u.Host != "localhost") || !filepath.IsAbs(filepath.FromSlash(u.Path)) {
+		return nil, fmt.Errorf("file endpoint must be an absolute path, e.g. file:///var/lib/s3: %s", endpoint)
+	}
+
+	if options.SSECustomerKey != "" || options.ClientSideEncryptionKey != "" || options.ClientSideEncryptionKMSKeyID != "" {
+		return nil, errors.New("encryption is not supported with a file endpoint")
+	}
+
+	transfers, err := newTransfers(progressOutput, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileClient{root: filepath.FromSlash(u.Path), transfers: transfers}, nil
+}
+
+func (client *fileClient) bucketDir(bucketName string) (string, error) {
+	if bucketName == "" || bucketName == "." || bucketName == ".." || strings.ContainsAny(bucketName, `/\`) {
+		return "", NewConfigError(fmt.Sprintf("invalid bucket name: %s", bucketName))
+	}
+
+	dir := filepath.Join(client.root, bucketName)
+	info, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.IsDir()) {
+		return "", &Error{Kind: ErrNotFound, Err: fmt.Errorf("bucket %s does not exist: create the directory %s", bucketName, dir)}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// objectPaths returns the file holding the latest version of remotePath and
+// the directory holding all its versions
+func (client *fileClient) objectPaths(bucketName string, remotePath string) (string, string, error) {
+	dir, err := client.bucketDir(bucketName)
+	if err != nil {
+		return "", "", err
+	}
+
+	segments := strings.Split(remotePath, "/")
+	for _, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", "", NewConfigError(fmt.Sprintf("key cannot be stored on the local filesystem: %s", remotePath))
+		}
+	}
+	if segments[0] == fileMetadataDir {
+		return "", "", NewConfigError(fmt.Sprintf("keys cannot start with %s: %s", fileMetadataDir, remotePath))
+	}
+
+	key := filepath.FromSlash(remotePath)
+	return filepath.Join(dir, key), filepath.Join(dir, fileMetadataDir, key), nil
+}
+
+// versions returns the version IDs of remotePath, newest first
+func (client *fileClient) versions(bucketName string, remotePath string) ([]string, error) {
+	latest, versionsDir, err := client.objectPaths(bucketName, remotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(versionsDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && isFileVersionID(entry.Name()) {
+			versions = append(versions, entry.Name())
+		}
+	}
+	slices.Sort(versions)
+	slices.Reverse(versions)
+
+	if len(versions) == 0 {
+		if _, err := os.Stat(latest); err == nil {
+			return []string{nullVersionID}, nil
+		}
+	}
+
+	return versions, nil
+}
+
+// versionFiles returns the file holding a version of remotePath, or its
+// latest version if versionID is empty, and the version's sidecar file
+func (client *fileClient) versionFiles(bucketName string, remotePath string, versionID string) (string, string, error) {
+	latest, versionsDir, err := client.objectPaths(bucketName, remotePath)
+	if err != nil {
+		return "", "", err
+	}
+
+	if versionID == "" {
+		// Deleting the file keeps its versions, as a delete marker would
+		if _, err := os.Stat(latest); errors.Is(err, fs.ErrNotExist) {
+			return "", "", &Error{Kind: ErrNotFound, Err: fmt.Errorf("object %s does not exist in bucket %s", remotePath, bucketName)}
+		} else if err != nil {
+			return "", "", err
+		}
+
+		versions, err := client.versions(bucketName, remotePath)
+		if err != nil {
+			return "", "", err
+		}
+		versionID = versions[0]
+	}
+
+	object := filepath.Join(versionsDir, versionID)
+	if versionID == nullVersionID {
+		object = latest
+	} else if !isFileVersionID(versionID) {
+		return "", "", &Error{Kind: ErrNotFound, Err: fmt.Errorf("version %s of %s does not exist", versionID, remotePath)}
+	}
+
+	if _, err := os.Stat(object); errors.Is(err, fs.ErrNotExist) {
+		return "", "", &Error{Kind: ErrNotFound, Err: fmt.Errorf("version %s of %s does not exist in bucket %s", versionID, remotePath, bucketName)}
+	} else if err != nil {
+		return "", "", err
+	}
+
+	return object, filepath.Join(versionsDir, versionID+".json"), nil
+}
+
+func (client *fileClient) BucketFiles(ctx context.Context, bucketName string, directoryPrefix string) ([]string, error) {
+	if !strings.HasSuffix(directoryPrefix, "/") {
+		directoryPrefix = directoryPrefix + "/"
+	}
+	var (
+		continuationToken *string
+		truncated         bool
+		paths             []string
+	)
+	for continuationToken, truncated = nil, true; truncated; {
+		chunk, err := client.ChunkedBucketList(ctx, bucketName, directoryPrefix, continuationToken)
+		if err != nil {
+			return []string{}, err
+		}
+		truncated = chunk.Truncated
+		continuationToken = chunk.ContinuationToken
+		paths = append(paths, chunk.Paths...)
+	}
+	return paths, nil
+}
+
+func (client *fileClient) BucketFileVersions(ctx context.Context, bucketName string, remotePath string) ([]string, error) {
+	return client.versions(bucketName, remotePath)
+}
+
+// ChunkedBucketList lists the files directly under prefix, and the prefixes
+// up to the next "/" of those further down, as S3 does with a delimiter
+func (client *fileClient) ChunkedBucketList(ctx context.Context, bucketName string, prefix string, continuationToken *string) (BucketListChunk, error) {
+	keys, err := client.keys(bucketName, prefix)
+	if err != nil {
+		return BucketListChunk{}, err
+	}
+
+	// Keys sharing a common prefix are next to each other once sorted
+	type entry struct {
+		name     string
+		isPrefix bool
+	}
+	var entries []entry
+	for _, key := range keys {
+		rest := key[len(prefix):]
+		if i := strings.Index(rest, "/"); i >= 0 {
+			commonPrefix := prefix + rest[:i+1]
+			if len(entries) == 0 || entries[len(entries)-1].name != commonPrefix {
+				entries = append(entries, entry{name: commonPrefix, isPrefix: true})
+			}
+			continue
+		}
+		entries = append(entries, entry{name: key})
+	}
+
+	if continuationToken != nil {
+		start, _ := slices.BinarySearchFunc(entries, *continuationToken, func(e entry, token string) int {
+			if e.name <= token {
+				return -1
+			}
+			return 1
+		})
+		entries = entries[start:]
+	}
+
+	chunk := BucketListChunk{
+		CommonPrefixes: []string{},
+		Paths:          []string{},
+	}
+	if len(entries) > fileListChunkSize {
+		entries = entries[:fileListChunkSize]
+		chunk.Truncated = true
+		chunk.ContinuationToken = &entries[len(entries)-1].name
+	}
+	for _, e := range entries {
+		if e.isPrefix {
+			chunk.CommonPrefixes = append(chunk.CommonPrefixes, e.name)
+		} else {
+			chunk.Paths = append(chunk.Paths, e.name)
+		}
+	}
+
+	return chunk, nil
+}
+
+// keys returns the keys of all the files under prefix, however deep, sorted
+func (client *fileClient) keys(bucketName string, prefix string) ([]string, error) {
+	dir, err := client.bucketDir(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the directory the prefix ends in can hold matching files
+	walkDir := dir
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		walkDir = filepath.Join(dir, filepath.FromSlash(prefix[:i]))
+	}
+	if rel, err := filepath.Rel(dir, walkDir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, NewConfigError(fmt.Sprintf("prefix is outside of the bucket: %s", prefix))
+	}
+
+	var keys []string
+	err = filepath.WalkDir(walkDir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() && file == filepath.Join(dir, fileMetadataDir) {
+			return filepath.SkipDir
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(keys)
+
+	return keys, nil
+}
+
+// BucketFilesModifiedSince returns all the files under prefix, however deep,
+// whose latest version was stored at or after since
+func (client *fileClient) BucketFilesModifiedSince(ctx context.Context, bucketName string, prefix string, since time.Time) ([]string, error) {
+	keys, err := client.keys(bucketName, prefix)
+	if err != nil {
+		return []string{}, err
+	}
+
+	paths := []string{}
+	for _, key := range keys {
+		latest, _, err := client.objectPaths(bucketName, key)
+		if err != nil {
+			return []string{}, err
+		}
+		info, err := os.Stat(latest)
+		if err != nil {
+			return []string{}, err
+		}
+		if !info.ModTime().Before(since) {
+			paths = append(paths, key)
+		}
+	}
+	return paths, nil
+}
+
+// UploadFile stores localPath as a new version of remotePath, and as its
+// latest version
+func (client *fileClient) UploadFile(ctx context.Context, bucketName string, remotePath string, localPath string, options UploadFileOptions) (string, error) {
+	latest, versionsDir, err := client.objectPaths(bucketName, remotePath)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(versionsDir, 0755); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(latest), 0755); err != nil {
+		return "", fmt.Errorf("key cannot be stored on the local filesystem: %s: %w", remotePath, err)
+	}
+
+	versionID, version, err := createVersionFile(versionsDir)
+	if err != nil {
+		return "", err
+	}
+
+	if err := client.transferFile(ctx, version, localPath); err != nil {
+		os.Remove(version.Name())
+		return "", err
+	}
+
+	metadata := fileObjectMetadata{
+		ContentType: options.ContentType,
+		ObjectLock: ObjectLock{
+			Mode:            options.ObjectLockMode,
+			RetainUntilDate: options.ObjectLockRetainUntilDate,
+		},
+	}
+	if options.ObjectLockLegalHold {
+		metadata.ObjectLock.LegalHold = "ON"
+	}
+	if err := writeFileMetadata(filepath.Join(versionsDir, versionID+".json"), metadata); err != nil {
+		return "", err
+	}
+
+	if err := replaceFile(ctx, latest, version.Name(), versionsDir); err != nil {
+		return "", err
+	}
+
+	return versionID, nil
+}
+
+// isFileVersionID reports whether name is the ID of a version, rather than
+// its sidecar or a file being uploaded
+func isFileVersionID(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// createVersionFile creates the file of a new version in versionsDir. Its
+// ID is the time it was created, so that IDs sort from oldest to newest.
+func createVersionFile(versionsDir string) (string, *os.File, error) {
+	id := time.Now().UnixNano()
+	for {
+		versionID := fmt.Sprintf("%020d", id)
+		file, err := os.OpenFile(filepath.Join(versionsDir, versionID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			id++
+			continue
+		}
+		return versionID, file, err
+	}
+}
+
+// copyFile copies source to destination, and closes destination
+func copyFile(ctx context.Context, destination *os.File, source string) error {
+	defer destination.Close()
+
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(destination, contextReader{ctx: ctx, Reader: file}); err != nil {
+		return err
+	}
+	return destination.Close()
+}
+
+// transferFile copies source to destination as copyFile does, reporting
+// its progress and throttling it as the uploads and downloads of S3 are
+func (client *fileClient) transferFile(ctx context.Context, destination *os.File, source string) error {
+	defer destination.Close()
+
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	progress := client.newProgress(info.Size())
+	defer progress.Wait()
+
+	var reader io.Reader = progressReader{contextReader{ctx: ctx, Reader: file}, progress}
+	if client.bandwidth != nil {
+		reader = throttledReader{reader, client.bandwidth, ctx}
+	}
+
+	if _, err := io.Copy(destination, reader); err != nil {
+		return err
+	}
+	progress.Complete()
+
+	return destination.Close()
+}
+
+// replaceFile replaces destination with a copy of source, so that the
+// destination is never seen partially written. The copy is written to
+// tempDir, out of sight of listings, which must be on the same filesystem.
+func replaceFile(ctx context.Context, destination string, source string, tempDir string) error {
+	temp, err := os.CreateTemp(tempDir, "upload-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if err := copyFile(ctx, temp, source); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+
+	if err := os.Rename(temp.Name(), destination); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	return nil
+}
+
+// contextReader stops reading once ctx is done
+type contextReader struct {
+	ctx context.Context
+	io.Reader
+}
+
+func (reader contextReader) Read(p []byte) (int, error) {
+	if err := reader.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return reader.Reader.Read(p)
+}
+
+func readFileMetadata(file string) (fileObjectMetadata, error) {
+	var metadata fileObjectMetadata
+
+	contents, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return metadata, nil
+	}
+	if err != nil {
+		return metadata, err
+	}
+
+	return metadata, json.Unmarshal(contents, &metadata)
+}
+
+func writeFileMetadata(file string, metadata fileObjectMetadata) error {
+	contents, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, contents, 0644)
+}
+
+func (client *fileClient) DownloadFile(ctx context.Context, bucketName string, remotePath string, versionID string, localPath string, options DownloadFileOptions) error {
+	object, _, err := client.versionFiles(bucketName, remotePath, versionID)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+
+	if err := client.transferFile(ctx, file, object); err != nil {
+		os.Remove(localPath)
+		return err
+	}
+	return nil
+}
+
+// OpenFile reads the latest version of remotePath
+func (client *fileClient) OpenFile(ctx context.Context, bucketName string, remotePath string) (io.ReadCloser, error) {
+	object, _, err := client.versionFiles(bucketName, remotePath, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(object)
+}
+
+// RestoreObject does nothing, as no object is ever archived
+func (client *fileClient) RestoreObject(ctx context.Context, bucketName string, remotePath string, versionID string, options RestoreObjectOptions) error {
+	_, _, err := client.versionFiles(bucketName, remotePath, versionID)
+	return err
+}
+
+func (client *fileClient) SetTags(ctx context.Context, bucketName string, remotePath string, versionID string, tags map[string]string) error {
+	_, sidecar, err := client.versionFiles(bucketName, remotePath, versionID)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := readFileMetadata(sidecar)
+	if err != nil {
+		return err
+	}
+	metadata.Tags = tags
+
+	if err := os.MkdirAll(filepath.Dir(sidecar), 0755); err != nil {
+		return err
+	}
+	return writeFileMetadata(sidecar, metadata)
+}
+
+func (client *fileClient) DownloadTags(ctx context.Context, bucketName string, remotePath string, versionID string, localPath string) error {
+	_, sidecar, err := client.versionFiles(bucketName, remotePath, versionID)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := readFileMetadata(sidecar)
+	if err != nil {
+		return err
+	}
+
+	tags := metadata.Tags
+	if tags == nil {
+		tags = map[string]string{}
+	}
+
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(localPath, tagsJSON, 0644)
+}
+
+func (client *fileClient) DownloadObjectLock(ctx context.Context, bucketName string, remotePath string, versionID string, localPath string) error {
+	_, sidecar, err := client.versionFiles(bucketName, remotePath, versionID)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := readFileMetadata(sidecar)
+	if err != nil {
+		return err
+	}
+
+	objectLockJSON, err := json.Marshal(metadata.ObjectLock)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(localPath, objectLockJSON, 0644)
+}
+
+// DeleteFile removes the latest version of remotePath, and keeps the others
+// as S3 keeps them behind a delete marker
+func (client *fileClient) DeleteFile(ctx context.Context, bucketName string, remotePath string) error {
+	latest, _, err := client.objectPaths(bucketName, remotePath)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(latest)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// DeleteVersionedFile removes a version of remotePath which is not locked. If
+// it was the latest version, the version before it becomes the latest.
+func (client *fileClient) DeleteVersionedFile(ctx context.Context, bucketName string, remotePath string, versionID string) error {
+	if versionID == "" {
+		return client.DeleteFile(ctx, bucketName, remotePath)
+	}
+
+	latest, versionsDir, err := client.objectPaths(bucketName, remotePath)
+	if err != nil {
+		return err
+	}
+
+	versions, err := client.versions(bucketName, remotePath)
+	if err != nil {
+		return err
+	}
+
+	object, sidecar, err := client.versionFiles(bucketName, remotePath, versionID)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := readFileMetadata(sidecar)
+	if err != nil {
+		return err
+	}
+	lock := metadata.ObjectLock
+	if lock.LegalHold == "ON" || (lock.RetainUntilDate != nil && lock.RetainUntilDate.After(time.Now())) {
+		return &Error{
+			Kind:      ErrAccessDenied,
+			Operation: "DeleteObject",
+			Err:       fmt.Errorf("version %s of %s is locked", versionID, remotePath),
+		}
+	}
+
+	if err := os.Remove(object); err != nil {
+		return err
+	}
+	if err := os.Remove(sidecar); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if versionID != versions[0] || versionID == nullVersionID {
+		return nil
+	}
+	if len(versions) == 1 {
+		err := os.Remove(latest)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	previous, _, err := client.versionFiles(bucketName, remotePath, versions[1])
+	if err != nil {
+		return err
+	}
+	return replaceFile(ctx, latest, previous, versionsDir)
+}
+
+// URL is the file URL of the version of remotePath. There is nothing to
+// sign, so private URLs are the same.
+func (client *fileClient) URL(ctx context.Context, bucketName string, remotePath string, private bool, versionID string) (string, error) {
+	latest, versionsDir, err := client.objectPaths(bucketName, remotePath)
+	if err != nil {
+		return "", err
+	}
+
+	file := latest
+	if versionID != "" && versionID != nullVersionID {
+		file = filepath.Join(versionsDir, versionID)
+	}
+
+	return (&url.URL{Scheme: FileEndpointScheme, Path: filepath.ToSlash(file)}).String(), nil
+}
+
+// Preflight checks that the bucket directory exists. Every bucket keeps
+// versions, and there are no credentials or permissions to check.
+func (client *fileClient) Preflight(ctx context.Context, bucketName string, options PreflightOptions) PreflightReport {
+	var report PreflightReport
+
+	if options.Identity {
+		report = append(report, PreflightResult{Check: "credentials", Skipped: true, Detail: "not used with a file endpoint"})
+	}
+
+	bucket := PreflightResult{Check: "bucket"}
+	dir, err := client.bucketDir(bucketName)
+	if err != nil {
+		bucket.Err = err
+		return append(report, bucket)
+	}
+	bucket.Detail = dir
+	report = append(report, bucket)
+
+	if options.Versioned {
+		report = append(report, PreflightResult{Check: "versioning", Detail: "enabled"})
+	}
+
+	for _, action := range options.Actions {
+		report = append(report, PreflightResult{Check: action, Skipped: true, Detail: "not used with a file endpoint"})
+	}
+
+	return report
+}
+
+// isFileEndpoint reports whether endpoint selects the local filesystem
+// backend
+func isFileEndpoint(endpoint string) bool {
+	scheme, _, ok := strings.Cut(endpoint, "://")
+	return ok && strings.EqualFold(scheme, FileEndpointScheme)
+}
@@ -0,0 +1,298 @@
+package s3resource_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	s3resource "github.com/concourse/s3-resource"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("File endpoints", func() {
+	var (
+		ctx      context.Context
+		root     string
+		localDir string
+		client   s3resource.S3Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		root = GinkgoT().TempDir()
+		localDir = GinkgoT().TempDir()
+		Expect(os.Mkdir(filepath.Join(root, "bucket"), 0755)).To(Succeed())
+
+		cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		client, err = s3resource.NewS3Client(io.Discard, cfg, "file://"+filepath.ToSlash(root), false, false, false, "", s3resource.S3ClientOptions{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	upload := func(remotePath string, contents string) string {
+		localPath := filepath.Join(localDir, "upload")
+		Expect(os.WriteFile(localPath, []byte(contents), 0644)).To(Succeed())
+
+		versionID, err := client.UploadFile(ctx, "bucket", remotePath, localPath, s3resource.NewUploadFileOptions())
+		Expect(err).ToNot(HaveOccurred())
+		return versionID
+	}
+
+	download := func(remotePath string, versionID string) string {
+		localPath := filepath.Join(localDir, "download")
+		Expect(client.DownloadFile(ctx, "bucket", remotePath, versionID, localPath, s3resource.NewDownloadFileOptions())).To(Succeed())
+
+		contents, err := os.ReadFile(localPath)
+		Expect(err).ToNot(HaveOccurred())
+		return string(contents)
+	}
+
+	It("requires an absolute path", func() {
+		cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = s3resource.NewS3Client(io.Discard, cfg, "file://relative/path", false, false, false, "", s3resource.S3ClientOptions{})
+		Expect(err).To(MatchError(ContainSubstring("file endpoint must be an absolute path")))
+	})
+
+	It("stores the latest version at the key", func() {
+		upload("files/abc-1.tgz", "some-contents")
+
+		contents, err := os.ReadFile(filepath.Join(root, "bucket", "files", "abc-1.tgz"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(contents)).To(Equal("some-contents"))
+		Expect(download("files/abc-1.tgz", "")).To(Equal("some-contents"))
+	})
+
+	It("keeps every version, newest first", func() {
+		first := upload("versioned-file", "first")
+		second := upload("versioned-file", "second")
+		Expect(second > first).To(BeTrue())
+
+		versions, err := client.BucketFileVersions(ctx, "bucket", "versioned-file")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions).To(Equal([]string{second, first}))
+
+		Expect(download("versioned-file", first)).To(Equal("first"))
+		Expect(download("versioned-file", second)).To(Equal("second"))
+		Expect(download("versioned-file", "")).To(Equal("second"))
+	})
+
+	It("gives objects put in the bucket by hand the null version", func() {
+		Expect(os.WriteFile(filepath.Join(root, "bucket", "by-hand"), []byte("by hand"), 0644)).To(Succeed())
+
+		versions, err := client.BucketFileVersions(ctx, "bucket", "by-hand")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions).To(Equal([]string{"null"}))
+		Expect(download("by-hand", "null")).To(Equal("by hand"))
+	})
+
+	It("lists with delimiter semantics", func() {
+		upload("files/abc-1.tgz", "1")
+		upload("files/abc-2.tgz", "2")
+		upload("files/abc-3/53.tgz", "3")
+		upload("files/other", "other")
+		upload("top-level", "top")
+
+		chunk, err := client.ChunkedBucketList(ctx, "bucket", "files/abc-", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(chunk.Truncated).To(BeFalse())
+		Expect(chunk.Paths).To(Equal([]string{"files/abc-1.tgz", "files/abc-2.tgz"}))
+		Expect(chunk.CommonPrefixes).To(Equal([]string{"files/abc-3/"}))
+
+		chunk, err = client.ChunkedBucketList(ctx, "bucket", "", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(chunk.Paths).To(Equal([]string{"top-level"}))
+		Expect(chunk.CommonPrefixes).To(Equal([]string{"files/"}))
+
+		files, err := client.BucketFiles(ctx, "bucket", "files")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(Equal([]string{"files/abc-1.tgz", "files/abc-2.tgz", "files/other"}))
+	})
+
+	It("lists the files modified since a time, however deep", func() {
+		upload("files/abc-1.tgz", "1")
+		upload("files/abc-2.tgz", "2")
+		upload("files/abc-3/53.tgz", "3")
+		upload("top-level", "top")
+
+		since := time.Now().Add(-time.Minute)
+		Expect(os.Chtimes(filepath.Join(root, "bucket", "files", "abc-2.tgz"), time.Time{}, since.Add(-time.Hour))).To(Succeed())
+
+		files, err := client.BucketFilesModifiedSince(ctx, "bucket", "files/", since)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(Equal([]string{"files/abc-1.tgz", "files/abc-3/53.tgz"}))
+
+		files, err = client.BucketFilesModifiedSince(ctx, "bucket", "", since)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(Equal([]string{"files/abc-1.tgz", "files/abc-3/53.tgz", "top-level"}))
+
+		file, err := client.OpenFile(ctx, "bucket", "top-level")
+		Expect(err).ToNot(HaveOccurred())
+		defer file.Close()
+		Expect(io.ReadAll(file)).To(Equal([]byte("top")))
+	})
+
+	It("lists in chunks", func() {
+		dir := filepath.Join(root, "bucket", "many")
+		Expect(os.Mkdir(dir, 0755)).To(Succeed())
+		for i := range 1500 {
+			Expect(os.WriteFile(filepath.Join(dir, fmt.Sprintf("file-%04d", i)), nil, 0644)).To(Succeed())
+		}
+
+		chunk, err := client.ChunkedBucketList(ctx, "bucket", "many/", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(chunk.Truncated).To(BeTrue())
+		Expect(chunk.Paths).To(HaveLen(1000))
+
+		chunk, err = client.ChunkedBucketList(ctx, "bucket", "many/", chunk.ContinuationToken)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(chunk.Truncated).To(BeFalse())
+		Expect(chunk.Paths).To(HaveLen(500))
+		Expect(chunk.Paths[0]).To(Equal("many/file-1000"))
+	})
+
+	It("keeps tags in sidecar files", func() {
+		first := upload("tagged", "first")
+		upload("tagged", "second")
+
+		Expect(client.SetTags(ctx, "bucket", "tagged", first, map[string]string{"some": "tag"})).To(Succeed())
+
+		tagsPath := filepath.Join(localDir, "tags.json")
+		Expect(client.DownloadTags(ctx, "bucket", "tagged", first, tagsPath)).To(Succeed())
+		Expect(tagsPath).To(BeAnExistingFile())
+		contents, err := os.ReadFile(tagsPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(contents).To(MatchJSON(`{"some": "tag"}`))
+
+		Expect(client.DownloadTags(ctx, "bucket", "tagged", "", tagsPath)).To(Succeed())
+		contents, err = os.ReadFile(tagsPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(contents).To(MatchJSON(`{}`))
+
+		Expect(filepath.Join(root, "bucket", ".s3-resource", "tagged", first+".json")).To(BeAnExistingFile())
+	})
+
+	It("keeps the Object Lock state and protects locked versions", func() {
+		localPath := filepath.Join(localDir, "upload")
+		Expect(os.WriteFile(localPath, []byte("locked"), 0644)).To(Succeed())
+
+		options := s3resource.NewUploadFileOptions()
+		retainUntilDate := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		options.ObjectLockMode = "GOVERNANCE"
+		options.ObjectLockRetainUntilDate = &retainUntilDate
+		versionID, err := client.UploadFile(ctx, "bucket", "locked", localPath, options)
+		Expect(err).ToNot(HaveOccurred())
+
+		objectLockPath := filepath.Join(localDir, "object_lock.json")
+		Expect(client.DownloadObjectLock(ctx, "bucket", "locked", versionID, objectLockPath)).To(Succeed())
+		contents, err := os.ReadFile(objectLockPath)
+		Expect(err).ToNot(HaveOccurred())
+		var objectLock s3resource.ObjectLock
+		Expect(json.Unmarshal(contents, &objectLock)).To(Succeed())
+		Expect(objectLock.Mode).To(Equal("GOVERNANCE"))
+		Expect(objectLock.RetainUntilDate.Equal(retainUntilDate)).To(BeTrue())
+
+		err = client.DeleteVersionedFile(ctx, "bucket", "locked", versionID)
+		Expect(errors.Is(err, s3resource.ErrAccessDenied)).To(BeTrue())
+	})
+
+	It("deletes versions, falling back to the one before", func() {
+		first := upload("versioned-file", "first")
+		second := upload("versioned-file", "second")
+
+		Expect(client.DeleteVersionedFile(ctx, "bucket", "versioned-file", second)).To(Succeed())
+		Expect(download("versioned-file", "")).To(Equal("first"))
+
+		versions, err := client.BucketFileVersions(ctx, "bucket", "versioned-file")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions).To(Equal([]string{first}))
+
+		Expect(client.DeleteVersionedFile(ctx, "bucket", "versioned-file", first)).To(Succeed())
+		Expect(filepath.Join(root, "bucket", "versioned-file")).ToNot(BeAnExistingFile())
+	})
+
+	It("keeps the other versions when deleting the file", func() {
+		first := upload("some-file", "first")
+
+		Expect(client.DeleteFile(ctx, "bucket", "some-file")).To(Succeed())
+		Expect(filepath.Join(root, "bucket", "some-file")).ToNot(BeAnExistingFile())
+		Expect(download("some-file", first)).To(Equal("first"))
+
+		err := client.DownloadFile(ctx, "bucket", "some-file", "", filepath.Join(localDir, "download"), s3resource.NewDownloadFileOptions())
+		Expect(errors.Is(err, s3resource.ErrNotFound)).To(BeTrue())
+		_, err = client.OpenFile(ctx, "bucket", "some-file")
+		Expect(errors.Is(err, s3resource.ErrNotFound)).To(BeTrue())
+	})
+
+	It("reports the progress of uploads and downloads and throttles them", func() {
+		cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		output := gbytes.NewBuffer()
+		client, err = s3resource.NewS3Client(output, cfg, "file://"+filepath.ToSlash(root), false, false, false, "", s3resource.S3ClientOptions{
+			MaxBandwidth:     "1MiB",
+			Progress:         s3resource.ProgressLines,
+			ProgressInterval: "50%",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		// The bandwidth starts with 256KiB to spare, so each transfer takes
+		// at least a quarter of a second
+		contents := strings.Repeat("x", 512*1024)
+
+		start := time.Now()
+		versionID := upload("some-file", contents)
+		Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+		Expect(output).To(gbytes.Say(`256\.00 KiB / 512\.00 KiB \(50%\)`))
+		Expect(output).To(gbytes.Say(`512\.00 KiB in \d+s`))
+
+		start = time.Now()
+		Expect(download("some-file", versionID)).To(Equal(contents))
+		Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+		Expect(output).To(gbytes.Say(`256\.00 KiB / 512\.00 KiB \(50%\)`))
+		Expect(output).To(gbytes.Say(`512\.00 KiB in \d+s`))
+	})
+
+	It("returns URLs to the files", func() {
+		versionID := upload("files/abc-1.tgz", "1")
+
+		url, err := client.URL(ctx, "bucket", "files/abc-1.tgz", false, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(url).To(Equal("file://" + filepath.ToSlash(filepath.Join(root, "bucket", "files", "abc-1.tgz"))))
+
+		url, err = client.URL(ctx, "bucket", "files/abc-1.tgz", true, versionID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(url).To(Equal("file://" + filepath.ToSlash(filepath.Join(root, "bucket", ".s3-resource", "files", "abc-1.tgz", versionID))))
+	})
+
+	It("does not find missing buckets and objects", func() {
+		_, err := client.ChunkedBucketList(ctx, "missing-bucket", "", nil)
+		Expect(errors.Is(err, s3resource.ErrNotFound)).To(BeTrue())
+
+		err = client.DownloadFile(ctx, "bucket", "missing", "", filepath.Join(localDir, "missing"), s3resource.NewDownloadFileOptions())
+		Expect(errors.Is(err, s3resource.ErrNotFound)).To(BeTrue())
+		Expect(filepath.Join(localDir, "missing")).ToNot(BeAnExistingFile())
+
+		report := client.Preflight(ctx, "missing-bucket", s3resource.PreflightOptions{})
+		Expect(errors.Is(report.Err(), s3resource.ErrNotFound)).To(BeTrue())
+	})
+
+	It("rejects keys and prefixes outside of the bucket", func() {
+		_, err := client.UploadFile(ctx, "bucket", "../escaped", filepath.Join(localDir, "upload"), s3resource.NewUploadFileOptions())
+		Expect(errors.Is(err, s3resource.ErrInvalidConfig)).To(BeTrue())
+
+		_, err = client.ChunkedBucketList(ctx, "bucket", "../", nil)
+		Expect(errors.Is(err, s3resource.ErrInvalidConfig)).To(BeTrue())
+	})
+})
@@ -1,13 +1,16 @@
 package in
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	s3resource "github.com/concourse/s3-resource"
 	"github.com/concourse/s3-resource/versions"
@@ -16,18 +19,24 @@ import (
 var ErrMissingPath = errors.New("missing path in request")
 
 type Command struct {
+	stderr   io.Writer
 	s3client s3resource.S3Client
 }
 
-func NewCommand(s3client s3resource.S3Client) *Command {
+func NewCommand(stderr io.Writer, s3client s3resource.S3Client) *Command {
 	return &Command{
+		stderr:   stderr,
 		s3client: s3client,
 	}
 }
 
-func (command *Command) Run(destinationDir string, request Request) (Response, error) {
+func (command *Command) Run(ctx context.Context, destinationDir string, request Request) (Response, error) {
+	for _, note := range request.Source.ApplyProvider() {
+		fmt.Fprintln(command.stderr, note)
+	}
+
 	if ok, message := request.Source.IsValid(); !ok {
-		return Response{}, errors.New(message)
+		return Response{}, s3resource.NewConfigError(message)
 	}
 
 	err := os.MkdirAll(destinationDir, 0755)
@@ -66,6 +75,13 @@ func (command *Command) Run(destinationDir string, request Request) (Response, e
 		isInitialVersion = request.Source.InitialVersion != "" && request.Version.VersionID == request.Source.InitialVersion
 	}
 
+	if request.Source.Preflight {
+		err = command.preflight(ctx, request, remotePath, versionID, isInitialVersion)
+		if err != nil {
+			return Response{}, err
+		}
+	}
+
 	if isInitialVersion {
 		if request.Source.InitialContentText != "" || request.Source.InitialContentBinary == "" {
 			err = command.createInitialFile(destinationDir, path.Base(remotePath), []byte(request.Source.InitialContentText))
@@ -76,7 +92,7 @@ func (command *Command) Run(destinationDir string, request Request) (Response, e
 		if request.Source.InitialContentBinary != "" {
 			b, err := base64.StdEncoding.DecodeString(request.Source.InitialContentBinary)
 			if err != nil {
-				return Response{}, errors.New("failed to decode initial_content_binary, make sure it's base64 encoded")
+				return Response{}, s3resource.NewConfigError("failed to decode initial_content_binary, make sure it's base64 encoded")
 			}
 			err = command.createInitialFile(destinationDir, path.Base(remotePath), b)
 			if err != nil {
@@ -88,20 +104,53 @@ func (command *Command) Run(destinationDir string, request Request) (Response, e
 		if request.Params.SkipDownload != "" {
 			skipDownload, err = strconv.ParseBool(request.Params.SkipDownload)
 			if err != nil {
-				return Response{}, fmt.Errorf("skip_download defined but invalid value: %s", request.Params.SkipDownload)
+				return Response{}, s3resource.NewConfigError(fmt.Sprintf("skip_download defined but invalid value: %s", request.Params.SkipDownload))
 			}
 		} else {
 			skipDownload = request.Source.SkipDownload
 		}
 
+		var restoreOpts s3resource.RestoreObjectOptions
+		if request.Params.Restore {
+			restoreOpts, err = restoreOptions(request.Params)
+			if err != nil {
+				return Response{}, err
+			}
+		}
+
 		if !skipDownload {
+			downloadOpts, err := downloadOptions(request)
+			if err != nil {
+				return Response{}, err
+			}
+
 			err = command.downloadFile(
+				ctx,
 				request.Source.Bucket,
 				remotePath,
 				versionID,
 				destinationDir,
 				path.Base(remotePath),
+				downloadOpts,
 			)
+
+			var archivedErr s3resource.ObjectArchivedError
+			if errors.As(err, &archivedErr) {
+				if !request.Params.Restore {
+					return Response{}, fmt.Errorf("%w: set the 'restore' param to restore it", err)
+				}
+
+				err = command.restoreAndDownloadFile(
+					ctx,
+					request.Source.Bucket,
+					remotePath,
+					versionID,
+					destinationDir,
+					path.Base(remotePath),
+					downloadOpts,
+					restoreOpts,
+				)
+			}
 			if err != nil {
 				return Response{}, err
 			}
@@ -122,6 +171,7 @@ func (command *Command) Run(destinationDir string, request Request) (Response, e
 
 		if request.Params.DownloadTags {
 			err = command.downloadTags(
+				ctx,
 				request.Source.Bucket,
 				remotePath,
 				versionID,
@@ -132,7 +182,20 @@ func (command *Command) Run(destinationDir string, request Request) (Response, e
 			}
 		}
 
-		url, err = command.getURL(request, remotePath)
+		if request.Params.DownloadObjectLock {
+			err = command.downloadObjectLock(
+				ctx,
+				request.Source.Bucket,
+				remotePath,
+				versionID,
+				destinationDir,
+			)
+			if err != nil {
+				return Response{}, err
+			}
+		}
+
+		url, err = command.getURL(ctx, request, remotePath)
 		if err != nil {
 			return Response{}, err
 		}
@@ -182,21 +245,114 @@ func (command *Command) writeVersionFile(destDir string, versionNumber string) e
 	return os.WriteFile(filepath.Join(destDir, "version"), []byte(versionNumber), 0644)
 }
 
-func (command *Command) downloadFile(bucketName string, remotePath string, versionID string, destinationDir string, destinationFile string) error {
+func (command *Command) downloadFile(ctx context.Context, bucketName string, remotePath string, versionID string, destinationDir string, destinationFile string, options s3resource.DownloadFileOptions) error {
 	localPath := filepath.Join(destinationDir, destinationFile)
 
 	return command.s3client.DownloadFile(
+		ctx,
 		bucketName,
 		remotePath,
 		versionID,
 		localPath,
+		options,
+	)
+}
+
+func (command *Command) restoreAndDownloadFile(ctx context.Context, bucketName string, remotePath string, versionID string, destinationDir string, destinationFile string, downloadOptions s3resource.DownloadFileOptions, options s3resource.RestoreObjectOptions) error {
+	err := command.s3client.RestoreObject(
+		ctx,
+		bucketName,
+		remotePath,
+		versionID,
+		options,
+	)
+	if err != nil {
+		return err
+	}
+
+	return command.downloadFile(
+		ctx,
+		bucketName,
+		remotePath,
+		versionID,
+		destinationDir,
+		destinationFile,
+		downloadOptions,
 	)
 }
 
-func (command *Command) downloadTags(bucketName string, remotePath string, versionID string, destinationDir string) error {
+// downloadOptions sets the part size and concurrency of the download from
+// the params, falling back to the defaults in the source
+func downloadOptions(request Request) (s3resource.DownloadFileOptions, error) {
+	options := s3resource.NewDownloadFileOptions()
+
+	partSize := request.Source.DownloadPartSize
+	if request.Params.DownloadPartSize != "" {
+		partSize = request.Params.DownloadPartSize
+	}
+	concurrency := request.Source.DownloadConcurrency
+	if request.Params.DownloadConcurrency != 0 {
+		concurrency = request.Params.DownloadConcurrency
+	}
+
+	if ok, message := s3resource.ValidateTransfer("", 0, partSize, concurrency); !ok {
+		return options, s3resource.NewConfigError(message)
+	}
+
+	if partSize != "" {
+		options.PartSize, _ = s3resource.ParseByteSize(partSize)
+	}
+	if concurrency != 0 {
+		options.Concurrency = concurrency
+	}
+
+	return options, nil
+}
+
+func restoreOptions(params Params) (s3resource.RestoreObjectOptions, error) {
+	options := s3resource.NewRestoreObjectOptions()
+
+	if params.RestoreTier != "" {
+		options.Tier = params.RestoreTier
+	}
+	if params.RestoreDays != 0 {
+		options.Days = params.RestoreDays
+	}
+	if params.RestoreTimeout != "" {
+		timeout, err := time.ParseDuration(params.RestoreTimeout)
+		if err != nil {
+			return options, s3resource.NewConfigError(fmt.Sprintf("restore_timeout defined but invalid value: %s", params.RestoreTimeout))
+		}
+		options.Timeout = timeout
+	}
+
+	if ok, message := s3resource.ValidateRestoreTier(options.Tier); !ok {
+		return options, s3resource.NewConfigError(message)
+	}
+	if options.Days < 1 {
+		return options, s3resource.NewConfigError("restore_days must be at least 1")
+	}
+
+	return options, nil
+}
+
+func (command *Command) downloadTags(ctx context.Context, bucketName string, remotePath string, versionID string, destinationDir string) error {
 	localPath := filepath.Join(destinationDir, "tags.json")
 
 	return command.s3client.DownloadTags(
+		ctx,
+		bucketName,
+		remotePath,
+		versionID,
+		localPath,
+	)
+}
+
+func (command *Command) downloadObjectLock(ctx context.Context, bucketName string, remotePath string, versionID string, destinationDir string) error {
+	localPath := filepath.Join(destinationDir, "object_lock.json")
+
+	return command.s3client.DownloadObjectLock(
+		ctx,
 		bucketName,
 		remotePath,
 		versionID,
@@ -228,8 +384,8 @@ func (command *Command) metadata(remotePath string, private bool, url string) []
 	return metadata
 }
 
-func (command *Command) getURL(request Request, remotePath string) (string, error) {
-	return command.s3client.URL(request.Source.Bucket, remotePath, request.Source.Private, request.Version.VersionID)
+func (command *Command) getURL(ctx context.Context, request Request, remotePath string) (string, error) {
+	return command.s3client.URL(ctx, request.Source.Bucket, remotePath, request.Source.Private, request.Version.VersionID)
 }
 
 func (command *Command) gets3URI(request Request, remotePath string) string {
@@ -274,3 +430,47 @@ func extractArchive(mime, filename string) error {
 
 	return nil
 }
+
+// preflight checks the bucket and everything fetching remotePath needs. The
+// initial version is not in the bucket, so its object is not checked.
+func (command *Command) preflight(ctx context.Context, request Request, remotePath string, versionID string, isInitialVersion bool) error {
+	options := s3resource.PreflightOptions{
+		Identity:  true,
+		Versioned: request.Source.VersionedFile != "",
+		Actions:   fetchActions(request),
+	}
+	if !isInitialVersion {
+		options.Key = remotePath
+		options.VersionID = versionID
+	}
+
+	report := command.s3client.Preflight(ctx, request.Source.Bucket, options)
+	report.Print(command.stderr)
+
+	return report.Err()
+}
+
+// fetchActions are the IAM actions fetching an object with the params needs,
+// as listed in the README
+func fetchActions(request Request) []string {
+	versioned := request.Source.VersionedFile != ""
+
+	actions := []string{"s3:GetObject"}
+	if versioned {
+		actions = []string{"s3:GetObjectVersion"}
+	}
+	if request.Params.DownloadTags {
+		if versioned {
+			actions = append(actions, "s3:GetObjectVersionTagging")
+		} else {
+			actions = append(actions, "s3:GetObjectTagging")
+		}
+	}
+	if request.Params.DownloadObjectLock {
+		actions = append(actions, "s3:GetObjectRetention", "s3:GetObjectLegalHold")
+	}
+	if request.Params.Restore {
+		actions = append(actions, "s3:RestoreObject")
+	}
+	return actions
+}
@@ -4,6 +4,8 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
+	"context"
+	"errors"
 	"io"
 	"log"
 	"os"
@@ -11,9 +13,11 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 
 	s3resource "github.com/concourse/s3-resource"
 	. "github.com/concourse/s3-resource/in"
@@ -29,6 +33,7 @@ var _ = Describe("In Command", func() {
 			request Request
 
 			s3client *fakes.FakeS3Client
+			stderr   *gbytes.Buffer
 			command  *Command
 		)
 
@@ -49,12 +54,14 @@ var _ = Describe("In Command", func() {
 			}
 
 			s3client = &fakes.FakeS3Client{}
-			command = NewCommand(s3client)
+			stderr = gbytes.NewBuffer()
+			command = NewCommand(stderr, s3client)
 
 			s3client.URLReturns("http://google.com", nil)
 		})
 
 		AfterEach(func() {
+			stderr.Close()
 			err := os.RemoveAll(tmpPath)
 			Ω(err).ShouldNot(HaveOccurred())
 		})
@@ -62,7 +69,7 @@ var _ = Describe("In Command", func() {
 		It("creates the destination directory", func() {
 			Ω(destDir).ShouldNot(ExistOnFilesystem())
 
-			_, err := command.Run(destDir, request)
+			_, err := command.Run(context.Background(), destDir, request)
 			Ω(err).ShouldNot(HaveOccurred())
 
 			Ω(destDir).Should(ExistOnFilesystem())
@@ -74,7 +81,7 @@ var _ = Describe("In Command", func() {
 			})
 
 			It("returns an error", func() {
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Expect(err).To(MatchError(ErrMissingPath))
 			})
 		})
@@ -85,7 +92,7 @@ var _ = Describe("In Command", func() {
 			})
 
 			It("doesn't download the file", func() {
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 				Ω(s3client.DownloadFileCallCount()).Should(Equal(0))
 			})
@@ -97,7 +104,7 @@ var _ = Describe("In Command", func() {
 			})
 
 			It("doesn't download the file", func() {
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 				Ω(s3client.DownloadFileCallCount()).Should(Equal(0))
 			})
@@ -110,7 +117,7 @@ var _ = Describe("In Command", func() {
 			})
 
 			It("doesn't download the file", func() {
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 				Ω(s3client.DownloadFileCallCount()).Should(Equal(1))
 			})
@@ -122,7 +129,7 @@ var _ = Describe("In Command", func() {
 			})
 
 			It("doesn't download the file", func() {
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("skip_download defined but invalid value"))
 			})
@@ -134,11 +141,11 @@ var _ = Describe("In Command", func() {
 			})
 
 			It("downloads the existing version of the file", func() {
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(s3client.DownloadFileCallCount()).Should(Equal(1))
-				bucketName, remotePath, versionID, localPath := s3client.DownloadFileArgsForCall(0)
+				_, bucketName, remotePath, versionID, localPath, _ := s3client.DownloadFileArgsForCall(0)
 
 				Ω(bucketName).Should(Equal("bucket-name"))
 				Ω(remotePath).Should(Equal("files/a-file-1.3"))
@@ -146,11 +153,40 @@ var _ = Describe("In Command", func() {
 				Ω(localPath).Should(Equal(filepath.Join(destDir, "a-file-1.3")))
 			})
 
+			It("downloads the file with the default part size and concurrency", func() {
+				_, err := command.Run(context.Background(), destDir, request)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				_, _, _, _, _, options := s3client.DownloadFileArgsForCall(0)
+				Ω(options).Should(Equal(s3resource.NewDownloadFileOptions()))
+			})
+
+			It("downloads the file with the part size and concurrency from the params", func() {
+				request.Source.DownloadPartSize = "8MiB"
+				request.Source.DownloadConcurrency = 4
+				request.Params.DownloadPartSize = "32MiB"
+
+				_, err := command.Run(context.Background(), destDir, request)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				_, _, _, _, _, options := s3client.DownloadFileArgsForCall(0)
+				Ω(options.PartSize).Should(Equal(int64(32 * 1024 * 1024)))
+				Ω(options.Concurrency).Should(Equal(4))
+			})
+
+			It("errors if the download part size is not a size", func() {
+				request.Params.DownloadPartSize = "big"
+
+				_, err := command.Run(context.Background(), destDir, request)
+				Ω(err).Should(MatchError("download_part_size must be a positive size (e.g. 64MiB)"))
+				Ω(s3client.DownloadFileCallCount()).Should(Equal(0))
+			})
+
 			It("creates a 'url' file that contains the URL", func() {
 				urlPath := filepath.Join(destDir, "url")
 				Ω(urlPath).ShouldNot(ExistOnFilesystem())
 
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(urlPath).Should(ExistOnFilesystem())
@@ -158,7 +194,7 @@ var _ = Describe("In Command", func() {
 				Ω(err).ShouldNot(HaveOccurred())
 				Ω(string(contents)).Should(Equal("http://google.com"))
 
-				bucketName, remotePath, private, versionID := s3client.URLArgsForCall(0)
+				_, bucketName, remotePath, private, versionID := s3client.URLArgsForCall(0)
 				Ω(bucketName).Should(Equal("bucket-name"))
 				Ω(remotePath).Should(Equal("files/a-file-1.3"))
 				Ω(private).Should(Equal(false))
@@ -169,7 +205,7 @@ var _ = Describe("In Command", func() {
 				uriPath := filepath.Join(destDir, "s3_uri")
 				Ω(uriPath).ShouldNot(ExistOnFilesystem())
 
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(uriPath).Should(ExistOnFilesystem())
@@ -178,6 +214,96 @@ var _ = Describe("In Command", func() {
 				Ω(string(contents)).Should(Equal("s3://" + request.Source.Bucket + "/files/a-file-1.3"))
 			})
 
+			It("does not download the object lock state by default", func() {
+				_, err := command.Run(context.Background(), destDir, request)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(s3client.DownloadObjectLockCallCount()).Should(Equal(0))
+			})
+
+			Context("when the object is archived", func() {
+				BeforeEach(func() {
+					s3client.DownloadFileReturnsOnCall(0, s3resource.ObjectArchivedError{StorageClass: "GLACIER"})
+				})
+
+				It("fails with a message explaining how to restore it", func() {
+					_, err := command.Run(context.Background(), destDir, request)
+					Ω(err).Should(MatchError("object is archived in GLACIER and must be restored before it can be downloaded: set the 'restore' param to restore it"))
+					Ω(s3client.RestoreObjectCallCount()).Should(Equal(0))
+				})
+
+				Context("when configured to restore archived objects", func() {
+					BeforeEach(func() {
+						request.Params.Restore = true
+					})
+
+					It("restores the object and then downloads it", func() {
+						_, err := command.Run(context.Background(), destDir, request)
+						Ω(err).ShouldNot(HaveOccurred())
+
+						Ω(s3client.RestoreObjectCallCount()).Should(Equal(1))
+						_, bucketName, remotePath, versionID, options := s3client.RestoreObjectArgsForCall(0)
+						Ω(bucketName).Should(Equal("bucket-name"))
+						Ω(remotePath).Should(Equal("files/a-file-1.3"))
+						Ω(versionID).Should(BeEmpty())
+						Ω(options).Should(Equal(s3resource.NewRestoreObjectOptions()))
+
+						Ω(s3client.DownloadFileCallCount()).Should(Equal(2))
+					})
+
+					It("uses the given tier, days and timeout", func() {
+						request.Params.RestoreTier = "Bulk"
+						request.Params.RestoreDays = 3
+						request.Params.RestoreTimeout = "48h"
+
+						_, err := command.Run(context.Background(), destDir, request)
+						Ω(err).ShouldNot(HaveOccurred())
+
+						_, _, _, _, options := s3client.RestoreObjectArgsForCall(0)
+						Ω(options).Should(Equal(s3resource.RestoreObjectOptions{
+							Tier:    "Bulk",
+							Days:    3,
+							Timeout: 48 * time.Hour,
+						}))
+					})
+
+					It("fails if the restore fails", func() {
+						s3client.RestoreObjectReturns(errors.New("timed out"))
+
+						_, err := command.Run(context.Background(), destDir, request)
+						Ω(err).Should(MatchError("timed out"))
+						Ω(s3client.DownloadFileCallCount()).Should(Equal(1))
+					})
+
+					It("fails before downloading if the tier is not valid", func() {
+						request.Params.RestoreTier = "Fast"
+
+						_, err := command.Run(context.Background(), destDir, request)
+						Ω(err).Should(MatchError("restore_tier must be one of: Bulk, Expedited, Standard"))
+						Ω(s3client.DownloadFileCallCount()).Should(Equal(0))
+					})
+				})
+			})
+
+			Context("when configured to download the object lock state", func() {
+				BeforeEach(func() {
+					request.Params.DownloadObjectLock = true
+				})
+
+				It("downloads the object lock state to 'object_lock.json'", func() {
+					_, err := command.Run(context.Background(), destDir, request)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(s3client.DownloadObjectLockCallCount()).Should(Equal(1))
+					_, bucketName, remotePath, versionID, localPath := s3client.DownloadObjectLockArgsForCall(0)
+
+					Ω(bucketName).Should(Equal("bucket-name"))
+					Ω(remotePath).Should(Equal("files/a-file-1.3"))
+					Ω(versionID).Should(BeEmpty())
+					Ω(localPath).Should(Equal(filepath.Join(destDir, "object_lock.json")))
+				})
+			})
+
 			Context("when configured with private URLs", func() {
 				BeforeEach(func() {
 					request.Source.Private = true
@@ -187,7 +313,7 @@ var _ = Describe("In Command", func() {
 					urlPath := filepath.Join(destDir, "url")
 					Ω(urlPath).ShouldNot(ExistOnFilesystem())
 
-					_, err := command.Run(destDir, request)
+					_, err := command.Run(context.Background(), destDir, request)
 					Ω(err).ShouldNot(HaveOccurred())
 
 					Ω(urlPath).Should(ExistOnFilesystem())
@@ -196,7 +322,7 @@ var _ = Describe("In Command", func() {
 					Ω(string(contents)).Should(Equal("http://google.com"))
 
 					Ω(s3client.URLCallCount()).Should(Equal(1))
-					bucketName, remotePath, private, versionID := s3client.URLArgsForCall(0)
+					_, bucketName, remotePath, private, versionID := s3client.URLArgsForCall(0)
 					Ω(bucketName).Should(Equal("bucket-name"))
 					Ω(remotePath).Should(Equal("files/a-file-1.3"))
 					Ω(private).Should(Equal(true))
@@ -208,7 +334,7 @@ var _ = Describe("In Command", func() {
 				versionFile := filepath.Join(destDir, "version")
 				Ω(versionFile).ShouldNot(ExistOnFilesystem())
 
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(versionFile).Should(ExistOnFilesystem())
@@ -219,14 +345,14 @@ var _ = Describe("In Command", func() {
 
 			Describe("the response", func() {
 				It("has a version that is the remote file path", func() {
-					response, err := command.Run(destDir, request)
+					response, err := command.Run(context.Background(), destDir, request)
 					Ω(err).ShouldNot(HaveOccurred())
 
 					Ω(response.Version.Path).Should(Equal("files/a-file-1.3"))
 				})
 
 				It("has metadata about the file", func() {
-					response, err := command.Run(destDir, request)
+					response, err := command.Run(context.Background(), destDir, request)
 					Ω(err).ShouldNot(HaveOccurred())
 
 					Ω(response.Metadata[0].Name).Should(Equal("filename"))
@@ -242,7 +368,7 @@ var _ = Describe("In Command", func() {
 					})
 
 					It("doesn't include the URL in the metadata", func() {
-						response, err := command.Run(destDir, request)
+						response, err := command.Run(context.Background(), destDir, request)
 						Ω(err).ShouldNot(HaveOccurred())
 
 						Ω(response.Metadata).Should(HaveLen(1))
@@ -252,13 +378,46 @@ var _ = Describe("In Command", func() {
 			})
 		})
 
+		Context("when configured to run preflight checks", func() {
+			BeforeEach(func() {
+				request.Source.Preflight = true
+			})
+
+			It("checks the object and the actions fetching it needs", func() {
+				request.Params.DownloadTags = true
+
+				_, err := command.Run(context.Background(), destDir, request)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(s3client.PreflightCallCount()).Should(Equal(1))
+				_, bucketName, options := s3client.PreflightArgsForCall(0)
+				Ω(bucketName).Should(Equal("bucket-name"))
+				Ω(options).Should(Equal(s3resource.PreflightOptions{
+					Identity: true,
+					Actions:  []string{"s3:GetObject", "s3:GetObjectTagging"},
+					Key:      "files/a-file-1.3",
+				}))
+			})
+
+			It("does not download if a check fails", func() {
+				s3client.PreflightReturns(s3resource.PreflightReport{
+					{Check: "s3:GetObject", Err: errors.New("forbidden")},
+				})
+
+				_, err := command.Run(context.Background(), destDir, request)
+				Ω(err).Should(MatchError("preflight check s3:GetObject failed: forbidden"))
+				Ω(stderr).Should(gbytes.Say(`failed\s+s3:GetObject\s+forbidden`))
+				Ω(s3client.DownloadFileCallCount()).Should(Equal(0))
+			})
+		})
+
 		Context("when the Regexp does not match the provided version", func() {
 			BeforeEach(func() {
 				request.Source.Regexp = "not-matching-anything"
 			})
 
 			It("returns an error", func() {
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("regex does not match provided version"))
 				Expect(err.Error()).To(ContainSubstring("files/a-file-1.3"))
@@ -272,7 +431,7 @@ var _ = Describe("In Command", func() {
 
 			Context("when the file is a tarball", func() {
 				BeforeEach(func() {
-					s3client.DownloadFileStub = func(bucketName string, remotePath string, versionID string, localPath string) error {
+					s3client.DownloadFileStub = func(_ context.Context, bucketName string, remotePath string, versionID string, localPath string, options s3resource.DownloadFileOptions) error {
 						src := filepath.Join(tmpPath, "some-file")
 
 						err := os.WriteFile(src, []byte("some-contents"), os.ModePerm)
@@ -289,7 +448,7 @@ var _ = Describe("In Command", func() {
 				})
 
 				It("extracts the tarball", func() {
-					_, err := command.Run(destDir, request)
+					_, err := command.Run(context.Background(), destDir, request)
 					Expect(err).NotTo(HaveOccurred())
 
 					bs, err := os.ReadFile(filepath.Join(destDir, "some-file"))
@@ -301,7 +460,7 @@ var _ = Describe("In Command", func() {
 
 			Context("when the file is a zip", func() {
 				BeforeEach(func() {
-					s3client.DownloadFileStub = func(bucketName string, remotePath string, versionID string, localPath string) error {
+					s3client.DownloadFileStub = func(_ context.Context, bucketName string, remotePath string, versionID string, localPath string, options s3resource.DownloadFileOptions) error {
 						inDir, err := os.MkdirTemp(tmpPath, "zip-dir")
 						Expect(err).NotTo(HaveOccurred())
 
@@ -316,7 +475,7 @@ var _ = Describe("In Command", func() {
 				})
 
 				It("unzips the zip", func() {
-					_, err := command.Run(destDir, request)
+					_, err := command.Run(context.Background(), destDir, request)
 					Expect(err).NotTo(HaveOccurred())
 
 					bs, err := os.ReadFile(filepath.Join(destDir, "some-file"))
@@ -331,7 +490,7 @@ var _ = Describe("In Command", func() {
 					request.Version.Path = "files/a-file-1.3.gz"
 					request.Source.Regexp = "files/a-file-(.*).gz"
 
-					s3client.DownloadFileStub = func(bucketName string, remotePath string, versionID string, localPath string) error {
+					s3client.DownloadFileStub = func(_ context.Context, bucketName string, remotePath string, versionID string, localPath string, options s3resource.DownloadFileOptions) error {
 						f, err := os.Create(localPath)
 						Expect(err).NotTo(HaveOccurred())
 
@@ -348,7 +507,7 @@ var _ = Describe("In Command", func() {
 				})
 
 				It("gunzips the gzip", func() {
-					_, err := command.Run(destDir, request)
+					_, err := command.Run(context.Background(), destDir, request)
 					Expect(err).NotTo(HaveOccurred())
 
 					bs, err := os.ReadFile(filepath.Join(destDir, "a-file-1.3"))
@@ -363,7 +522,7 @@ var _ = Describe("In Command", func() {
 					request.Version.Path = "files/a-file-1.3.tgz"
 					request.Source.Regexp = "files/a-file-(.*).tgz"
 
-					s3client.DownloadFileStub = func(bucketName string, remotePath string, versionID string, localPath string) error {
+					s3client.DownloadFileStub = func(_ context.Context, bucketName string, remotePath string, versionID string, localPath string, options s3resource.DownloadFileOptions) error {
 						err := os.MkdirAll(filepath.Join(tmpPath, "some-dir"), os.ModePerm)
 						Expect(err).NotTo(HaveOccurred())
 
@@ -403,7 +562,7 @@ var _ = Describe("In Command", func() {
 				})
 
 				It("extracts the gzipped tarball", func() {
-					_, err := command.Run(destDir, request)
+					_, err := command.Run(context.Background(), destDir, request)
 					Expect(err).NotTo(HaveOccurred())
 
 					Expect(filepath.Join(destDir, "some-dir", "some-file")).To(BeARegularFile())
@@ -423,7 +582,7 @@ var _ = Describe("In Command", func() {
 					request.Version.Path = "files/a-file-1.3.bz2"
 					request.Source.Regexp = "files/a-file-(.*).bz2"
 
-					s3client.DownloadFileStub = func(bucketName string, remotePath string, versionID string, localPath string) error {
+					s3client.DownloadFileStub = func(_ context.Context, bucketName string, remotePath string, versionID string, localPath string, options s3resource.DownloadFileOptions) error {
 						// Create uncompressed file
 						uncompressedPath := filepath.Join(tmpPath, "uncompressed-file")
 						err := os.WriteFile(uncompressedPath, []byte("some-contents"), os.ModePerm)
@@ -443,7 +602,7 @@ var _ = Describe("In Command", func() {
 				})
 
 				It("decompresses the bzip2 file", func() {
-					_, err := command.Run(destDir, request)
+					_, err := command.Run(context.Background(), destDir, request)
 					Expect(err).NotTo(HaveOccurred())
 
 					bs, err := os.ReadFile(filepath.Join(destDir, "a-file-1.3"))
@@ -458,7 +617,7 @@ var _ = Describe("In Command", func() {
 					request.Version.Path = "files/a-file-1.3.tar.bz2"
 					request.Source.Regexp = "files/a-file-(.*).tar.bz2"
 
-					s3client.DownloadFileStub = func(bucketName string, remotePath string, versionID string, localPath string) error {
+					s3client.DownloadFileStub = func(_ context.Context, bucketName string, remotePath string, versionID string, localPath string, options s3resource.DownloadFileOptions) error {
 						// Create directory structure
 						err := os.MkdirAll(filepath.Join(tmpPath, "some-dir"), os.ModePerm)
 						Expect(err).NotTo(HaveOccurred())
@@ -498,7 +657,7 @@ var _ = Describe("In Command", func() {
 				})
 
 				It("extracts the bzip2 compressed tarball", func() {
-					_, err := command.Run(destDir, request)
+					_, err := command.Run(context.Background(), destDir, request)
 					Expect(err).NotTo(HaveOccurred())
 
 					Expect(filepath.Join(destDir, "some-dir", "some-file")).To(BeARegularFile())
@@ -515,7 +674,7 @@ var _ = Describe("In Command", func() {
 
 			Context("when the file is not an archive", func() {
 				BeforeEach(func() {
-					s3client.DownloadFileStub = func(bucketName string, remotePath string, versionID string, localPath string) error {
+					s3client.DownloadFileStub = func(_ context.Context, bucketName string, remotePath string, versionID string, localPath string, options s3resource.DownloadFileOptions) error {
 						err := os.WriteFile(localPath, []byte("some-contents"), os.ModePerm)
 						Expect(err).NotTo(HaveOccurred())
 
@@ -524,7 +683,7 @@ var _ = Describe("In Command", func() {
 				})
 
 				It("returns an error", func() {
-					_, err := command.Run(destDir, request)
+					_, err := command.Run(context.Background(), destDir, request)
 					Expect(err).To(HaveOccurred())
 				})
 			})
@@ -541,7 +700,7 @@ var _ = Describe("In Command", func() {
 			})
 
 			It("it creates a file containing the initial text content", func() {
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				contentFile := filepath.Join(destDir, initialFilename)
@@ -557,7 +716,7 @@ var _ = Describe("In Command", func() {
 					request.Source.InitialContentBinary = "dGhlIGhhcmQgcXVlc3Rpb25zIGFyZSBoYXJkIPCfmYg="
 				})
 				It("it creates a file containing the initial binary content", func() {
-					_, err := command.Run(destDir, request)
+					_, err := command.Run(context.Background(), destDir, request)
 					Ω(err).ShouldNot(HaveOccurred())
 
 					contentFile := filepath.Join(destDir, initialFilename)
@@ -572,7 +731,7 @@ var _ = Describe("In Command", func() {
 						request.Source.InitialContentBinary = "not base64 data 🙈"
 					})
 					It("should return with an error", func() {
-						_, err := command.Run(destDir, request)
+						_, err := command.Run(context.Background(), destDir, request)
 						Ω(err).Should(HaveOccurred())
 					})
 				})
@@ -582,7 +741,7 @@ var _ = Describe("In Command", func() {
 				urlPath := filepath.Join(destDir, "url")
 				Ω(urlPath).ShouldNot(ExistOnFilesystem())
 
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(urlPath).ShouldNot(ExistOnFilesystem())
@@ -592,14 +751,14 @@ var _ = Describe("In Command", func() {
 				uriPath := filepath.Join(destDir, "s3_uri")
 				Ω(uriPath).ShouldNot(ExistOnFilesystem())
 
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(uriPath).ShouldNot(ExistOnFilesystem())
 			})
 
 			It("should not include a URL in the metadata", func() {
-				response, err := command.Run(destDir, request)
+				response, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				for _, metadatum := range response.Metadata {
@@ -609,7 +768,7 @@ var _ = Describe("In Command", func() {
 
 			It("should not attempt to unpack the initial content", func() {
 				request.Params.Unpack = true
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				contentFile := filepath.Join(destDir, initialFilename)
@@ -632,7 +791,7 @@ var _ = Describe("In Command", func() {
 			})
 
 			It("it creates a file containing the initial text content", func() {
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				contentFile := filepath.Join(destDir, filename)
@@ -648,7 +807,7 @@ var _ = Describe("In Command", func() {
 					request.Source.InitialContentBinary = "dGhlIGhhcmQgcXVlc3Rpb25zIGFyZSBoYXJkIPCfmYg="
 				})
 				It("it creates a file containing the initial binary content", func() {
-					_, err := command.Run(destDir, request)
+					_, err := command.Run(context.Background(), destDir, request)
 					Ω(err).ShouldNot(HaveOccurred())
 
 					contentFile := filepath.Join(destDir, filename)
@@ -663,7 +822,7 @@ var _ = Describe("In Command", func() {
 						request.Source.InitialContentBinary = "not base64 data 🙈"
 					})
 					It("should return with an error", func() {
-						_, err := command.Run(destDir, request)
+						_, err := command.Run(context.Background(), destDir, request)
 						Ω(err).Should(HaveOccurred())
 					})
 				})
@@ -673,7 +832,7 @@ var _ = Describe("In Command", func() {
 				urlPath := filepath.Join(destDir, "url")
 				Ω(urlPath).ShouldNot(ExistOnFilesystem())
 
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(urlPath).ShouldNot(ExistOnFilesystem())
@@ -683,14 +842,14 @@ var _ = Describe("In Command", func() {
 				uriPath := filepath.Join(destDir, "s3_uri")
 				Ω(uriPath).ShouldNot(ExistOnFilesystem())
 
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(uriPath).ShouldNot(ExistOnFilesystem())
 			})
 
 			It("should not include a URL in the metadata", func() {
-				response, err := command.Run(destDir, request)
+				response, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				for _, metadatum := range response.Metadata {
@@ -700,7 +859,7 @@ var _ = Describe("In Command", func() {
 
 			It("should not attempt to unpack the initial content", func() {
 				request.Params.Unpack = true
-				_, err := command.Run(destDir, request)
+				_, err := command.Run(context.Background(), destDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				contentFile := filepath.Join(destDir, filename)
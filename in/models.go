@@ -9,9 +9,17 @@ type Request struct {
 }
 
 type Params struct {
-	Unpack       bool   `json:"unpack"`
-	DownloadTags bool   `json:"download_tags"`
-	SkipDownload string `json:"skip_download"`
+	Unpack             bool   `json:"unpack"`
+	DownloadTags       bool   `json:"download_tags"`
+	DownloadObjectLock bool   `json:"download_object_lock"`
+	SkipDownload       string `json:"skip_download"`
+	Restore            bool   `json:"restore"`
+	RestoreTier        string `json:"restore_tier"`
+	RestoreDays        int32  `json:"restore_days"`
+	RestoreTimeout     string `json:"restore_timeout"`
+
+	DownloadPartSize    string `json:"download_part_size"`
+	DownloadConcurrency int    `json:"download_concurrency"`
 }
 
 type Response struct {
@@ -2,6 +2,7 @@ package integration_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"os"
 	"os/exec"
@@ -106,7 +107,7 @@ var _ = Describe("check", func() {
 					Ω(err).ShouldNot(HaveOccurred())
 					tempFile.Close()
 
-					_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "file-does-not-match-1"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-does-not-match-1"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
 					err = os.Remove(tempFile.Name())
@@ -114,7 +115,7 @@ var _ = Describe("check", func() {
 				})
 
 				AfterEach(func() {
-					err := s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "file-does-not-match-1"))
+					err := s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-does-not-match-1"))
 					Ω(err).ShouldNot(HaveOccurred())
 				})
 
@@ -140,10 +141,10 @@ var _ = Describe("check", func() {
 					Ω(err).ShouldNot(HaveOccurred())
 					tempFile.Close()
 
-					_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "file-does-match-2"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-does-match-2"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
-					_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "file-does-match-1"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-does-match-1"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
 					err = os.Remove(tempFile.Name())
@@ -151,10 +152,10 @@ var _ = Describe("check", func() {
 				})
 
 				AfterEach(func() {
-					err := s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "file-does-match-1"))
+					err := s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-does-match-1"))
 					Ω(err).ShouldNot(HaveOccurred())
 
-					err = s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "file-does-match-2"))
+					err = s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-does-match-2"))
 					Ω(err).ShouldNot(HaveOccurred())
 				})
 
@@ -205,7 +206,7 @@ var _ = Describe("check", func() {
 					Ω(err).ShouldNot(HaveOccurred())
 					tempFile.Close()
 
-					_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
 					err = os.Remove(tempFile.Name())
@@ -213,7 +214,7 @@ var _ = Describe("check", func() {
 				})
 
 				AfterEach(func() {
-					err := s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "versioned-file"))
+					err := s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "versioned-file"))
 					Ω(err).ShouldNot(HaveOccurred())
 				})
 
@@ -234,7 +235,7 @@ var _ = Describe("check", func() {
 					Ω(err).ShouldNot(HaveOccurred())
 					tempFile.Close()
 
-					_, err = s3client.UploadFile(versionedBucketName, filepath.Join(directoryPrefix, "file-does-not-match"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-does-not-match"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
 					err = os.Remove(tempFile.Name())
@@ -242,11 +243,11 @@ var _ = Describe("check", func() {
 				})
 
 				AfterEach(func() {
-					fileVersions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "file-does-not-match"))
+					fileVersions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-does-not-match"))
 					Ω(err).ShouldNot(HaveOccurred())
 
 					for _, fileVersion := range fileVersions {
-						err := s3client.DeleteVersionedFile(versionedBucketName, filepath.Join(directoryPrefix, "file-does-not-match"), fileVersion)
+						err := s3client.DeleteVersionedFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-does-not-match"), fileVersion)
 						Ω(err).ShouldNot(HaveOccurred())
 					}
 				})
@@ -274,10 +275,10 @@ var _ = Describe("check", func() {
 					Ω(err).ShouldNot(HaveOccurred())
 					tempFile.Close()
 
-					_, err = s3client.UploadFile(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
-					_, err = s3client.UploadFile(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
 					err = os.Remove(tempFile.Name())
@@ -285,11 +286,11 @@ var _ = Describe("check", func() {
 				})
 
 				AfterEach(func() {
-					fileVersions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"))
+					fileVersions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"))
 					Ω(err).ShouldNot(HaveOccurred())
 
 					for _, fileVersion := range fileVersions {
-						err := s3client.DeleteVersionedFile(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), fileVersion)
+						err := s3client.DeleteVersionedFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), fileVersion)
 						Ω(err).ShouldNot(HaveOccurred())
 					}
 				})
@@ -302,7 +303,7 @@ var _ = Describe("check", func() {
 					err := json.NewDecoder(reader).Decode(&response)
 					Ω(err).ShouldNot(HaveOccurred())
 
-					fileVersions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"))
+					fileVersions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"))
 					Ω(err).ShouldNot(HaveOccurred())
 
 					Ω(response).Should(Equal(check.Response{
@@ -348,7 +349,7 @@ var _ = Describe("check", func() {
 					Ω(err).ShouldNot(HaveOccurred())
 					tempFile.Close()
 
-					_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "file-does-not-match-1"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-does-not-match-1"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
 					err = os.Remove(tempFile.Name())
@@ -356,7 +357,7 @@ var _ = Describe("check", func() {
 				})
 
 				AfterEach(func() {
-					err := s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "file-does-not-match-1"))
+					err := s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-does-not-match-1"))
 					Ω(err).ShouldNot(HaveOccurred())
 				})
 
@@ -383,13 +384,13 @@ var _ = Describe("check", func() {
 					Ω(err).ShouldNot(HaveOccurred())
 					tempFile.Close()
 
-					_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "file-does-match-2"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-does-match-2"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
-					_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "file-does-match-1"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-does-match-1"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
-					_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "file-does-match-3"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-does-match-3"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
 					err = os.Remove(tempFile.Name())
@@ -397,13 +398,13 @@ var _ = Describe("check", func() {
 				})
 
 				AfterEach(func() {
-					err := s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "file-does-match-1"))
+					err := s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-does-match-1"))
 					Ω(err).ShouldNot(HaveOccurred())
 
-					err = s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "file-does-match-2"))
+					err = s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-does-match-2"))
 					Ω(err).ShouldNot(HaveOccurred())
 
-					err = s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "file-does-match-3"))
+					err = s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-does-match-3"))
 					Ω(err).ShouldNot(HaveOccurred())
 				})
 
@@ -437,16 +438,16 @@ var _ = Describe("check", func() {
 					Ω(err).ShouldNot(HaveOccurred())
 					tempFile.Close()
 
-					_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "file-1.2.0-rc.2"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-1.2.0-rc.2"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
-					_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "file-1.2.0-rc.1"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-1.2.0-rc.1"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
-					_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "file-1.1.0-rc.1"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-1.1.0-rc.1"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
-					_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "file-1.1.0-rc.2"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-1.1.0-rc.2"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
 					err = os.Remove(tempFile.Name())
@@ -454,16 +455,16 @@ var _ = Describe("check", func() {
 				})
 
 				AfterEach(func() {
-					err := s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "file-1.2.0-rc.2"))
+					err := s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-1.2.0-rc.2"))
 					Ω(err).ShouldNot(HaveOccurred())
 
-					err = s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "file-1.2.0-rc.1"))
+					err = s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-1.2.0-rc.1"))
 					Ω(err).ShouldNot(HaveOccurred())
 
-					err = s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "file-1.1.0-rc.1"))
+					err = s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-1.1.0-rc.1"))
 					Ω(err).ShouldNot(HaveOccurred())
 
-					err = s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "file-1.1.0-rc.2"))
+					err = s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-1.1.0-rc.2"))
 					Ω(err).ShouldNot(HaveOccurred())
 				})
 
@@ -508,7 +509,7 @@ var _ = Describe("check", func() {
 					Ω(err).ShouldNot(HaveOccurred())
 					tempFile.Close()
 
-					_, err = s3client.UploadFile(versionedBucketName, filepath.Join(directoryPrefix, "file-does-not-match"), tempFile.Name(), s3resource.NewUploadFileOptions())
+					_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-does-not-match"), tempFile.Name(), s3resource.NewUploadFileOptions())
 					Ω(err).ShouldNot(HaveOccurred())
 
 					err = os.Remove(tempFile.Name())
@@ -516,7 +517,7 @@ var _ = Describe("check", func() {
 
 					checkRequest.Source.VersionedFile = filepath.Join(directoryPrefix, "versioned-file")
 
-					fileVersions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "file-does-not-match"))
+					fileVersions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-does-not-match"))
 					Ω(err).ShouldNot(HaveOccurred())
 					checkRequest.Version.VersionID = fileVersions[0]
 
@@ -525,11 +526,11 @@ var _ = Describe("check", func() {
 				})
 
 				AfterEach(func() {
-					fileVersions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "file-does-not-match"))
+					fileVersions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-does-not-match"))
 					Ω(err).ShouldNot(HaveOccurred())
 
 					for _, fileVersion := range fileVersions {
-						err := s3client.DeleteVersionedFile(versionedBucketName, filepath.Join(directoryPrefix, "file-does-not-match"), fileVersion)
+						err := s3client.DeleteVersionedFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-does-not-match"), fileVersion)
 						Ω(err).ShouldNot(HaveOccurred())
 					}
 				})
@@ -554,18 +555,18 @@ var _ = Describe("check", func() {
 						Ω(err).ShouldNot(HaveOccurred())
 						tempFile.Close()
 
-						_, err = s3client.UploadFile(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
+						_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
 						Ω(err).ShouldNot(HaveOccurred())
 
-						_, err = s3client.UploadFile(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
+						_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
 						Ω(err).ShouldNot(HaveOccurred())
 
-						_, err = s3client.UploadFile(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
+						_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
 						Ω(err).ShouldNot(HaveOccurred())
 
 						checkRequest.Source.VersionedFile = filepath.Join(directoryPrefix, "versioned-file")
 
-						fileVersions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"))
+						fileVersions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"))
 						Ω(err).ShouldNot(HaveOccurred())
 						checkRequest.Version.VersionID = fileVersions[1]
 
@@ -577,11 +578,11 @@ var _ = Describe("check", func() {
 					})
 
 					AfterEach(func() {
-						fileVersions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"))
+						fileVersions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"))
 						Ω(err).ShouldNot(HaveOccurred())
 
 						for _, fileVersion := range fileVersions {
-							err := s3client.DeleteVersionedFile(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), fileVersion)
+							err := s3client.DeleteVersionedFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), fileVersion)
 							Ω(err).ShouldNot(HaveOccurred())
 						}
 					})
@@ -593,7 +594,7 @@ var _ = Describe("check", func() {
 						err := json.NewDecoder(reader).Decode(&response)
 						Ω(err).ShouldNot(HaveOccurred())
 
-						fileVersions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"))
+						fileVersions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"))
 						Ω(err).ShouldNot(HaveOccurred())
 
 						Ω(response).Should(Equal(check.Response{
@@ -617,18 +618,18 @@ var _ = Describe("check", func() {
 						Ω(err).ShouldNot(HaveOccurred())
 						tempFile.Close()
 
-						_, err = s3client.UploadFile(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
+						_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
 						Ω(err).ShouldNot(HaveOccurred())
 
-						_, err = s3client.UploadFile(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
+						_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
 						Ω(err).ShouldNot(HaveOccurred())
 
-						_, err = s3client.UploadFile(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
+						_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
 						Ω(err).ShouldNot(HaveOccurred())
 
 						checkRequest.Source.VersionedFile = filepath.Join(directoryPrefix, "versioned-file")
 
-						fileVersions, err = s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"))
+						fileVersions, err = s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"))
 						Ω(err).ShouldNot(HaveOccurred())
 						checkRequest.Version.VersionID = fileVersions[0]
 
@@ -638,16 +639,16 @@ var _ = Describe("check", func() {
 						err = os.Remove(tempFile.Name())
 						Ω(err).ShouldNot(HaveOccurred())
 
-						err = s3client.DeleteVersionedFile(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), fileVersions[0])
+						err = s3client.DeleteVersionedFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), fileVersions[0])
 						Ω(err).ShouldNot(HaveOccurred())
 					})
 
 					AfterEach(func() {
-						fileVersions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"))
+						fileVersions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"))
 						Ω(err).ShouldNot(HaveOccurred())
 
 						for _, fileVersion := range fileVersions {
-							err := s3client.DeleteVersionedFile(versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), fileVersion)
+							err := s3client.DeleteVersionedFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "versioned-file"), fileVersion)
 							Ω(err).ShouldNot(HaveOccurred())
 						}
 					})
@@ -669,4 +670,91 @@ var _ = Describe("check", func() {
 			})
 		})
 	})
+
+	Context("with an SQS queue", func() {
+		var (
+			directoryPrefix string
+			checkRequest    check.Request
+			tmpDir          string
+		)
+
+		upload := func(name string) {
+			tempFile, err := os.CreateTemp("", "file-to-upload")
+			Ω(err).ShouldNot(HaveOccurred())
+			tempFile.Close()
+			defer os.Remove(tempFile.Name())
+
+			_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, name), tempFile.Name(), s3resource.NewUploadFileOptions())
+			Ω(err).ShouldNot(HaveOccurred())
+		}
+
+		BeforeEach(func() {
+			if s3Server == nil {
+				Skip("event notifications are only set up on the S3 stand-in, skipping.")
+			}
+
+			directoryPrefix = "files-in-bucket-with-events"
+			queueURL := s3Server.CreateQueue("check-events")
+			s3Server.NotifyQueue(bucketName, queueURL)
+
+			checkRequest = check.Request{
+				Source: s3resource.Source{
+					AccessKeyID:     accessKeyID,
+					SecretAccessKey: secretAccessKey,
+					Bucket:          bucketName,
+					RegionName:      regionName,
+					Endpoint:        endpoint,
+					UsePathStyle:    pathStyle,
+					Regexp:          filepath.Join(directoryPrefix, "file-(.*)"),
+					SQSQueueURL:     queueURL,
+				},
+			}
+			err := json.NewEncoder(stdin).Encode(checkRequest)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			// The time of the last listing is kept in the temporary directory
+			tmpDir = GinkgoT().TempDir()
+			command.Env = append(os.Environ(), "TMPDIR="+tmpDir)
+
+			upload("file-1")
+		})
+
+		AfterEach(func() {
+			for _, name := range []string{"file-1", "file-2"} {
+				err := s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, name))
+				Ω(err).ShouldNot(HaveOccurred())
+			}
+		})
+
+		It("reports the objects created since the previous check without listing the bucket", func() {
+			var response check.Response
+			err := json.Unmarshal(session.Out.Contents(), &response)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(response).Should(Equal(check.Response{{Path: filepath.Join(directoryPrefix, "file-1")}}))
+
+			// A listing would not report the deleted previous version
+			upload("file-2")
+			err = s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-1"))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			checkRequest.Version = response[0]
+			stdin := &bytes.Buffer{}
+			err = json.NewEncoder(stdin).Encode(checkRequest)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			command := exec.Command(checkPath)
+			command.Stdin = stdin
+			command.Env = append(os.Environ(), "TMPDIR="+tmpDir)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Ω(err).ShouldNot(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			err = json.Unmarshal(session.Out.Contents(), &response)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(response).Should(Equal(check.Response{
+				{Path: filepath.Join(directoryPrefix, "file-1")},
+				{Path: filepath.Join(directoryPrefix, "file-2")},
+			}))
+		})
+	})
 })
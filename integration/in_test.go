@@ -2,6 +2,7 @@ package integration_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -101,7 +102,7 @@ var _ = Describe("in", func() {
 					UsePathStyle:    pathStyle,
 				},
 				Version: s3resource.Version{
-					Path: filepath.Join(directoryPrefix, "some-file-1"),
+					Path: filepath.Join(directoryPrefix, "some-file-2"),
 				},
 			}
 
@@ -113,7 +114,7 @@ var _ = Describe("in", func() {
 				err = os.WriteFile(tempFile.Name(), fmt.Appendf([]byte{}, "some-file-%d", i), 0755)
 				Ω(err).ShouldNot(HaveOccurred())
 
-				_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, fmt.Sprintf("some-file-%d", i)), tempFile.Name(), s3resource.NewUploadFileOptions())
+				_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, fmt.Sprintf("some-file-%d", i)), tempFile.Name(), s3resource.NewUploadFileOptions())
 				Ω(err).ShouldNot(HaveOccurred())
 			}
 
@@ -123,7 +124,7 @@ var _ = Describe("in", func() {
 
 		AfterEach(func() {
 			for i := range 3 {
-				err := s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, fmt.Sprintf("some-file-%d", i)))
+				err := s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, fmt.Sprintf("some-file-%d", i)))
 				Ω(err).ShouldNot(HaveOccurred())
 			}
 		})
@@ -232,28 +233,28 @@ var _ = Describe("in", func() {
 			Ω(err).ShouldNot(HaveOccurred())
 			tempFile.Close()
 
-			for i := range 3 {
+			for i := 1; i <= 3; i++ {
 				err = os.WriteFile(tempFile.Name(), fmt.Appendf([]byte{}, "some-file-%d", i), 0755)
 				Ω(err).ShouldNot(HaveOccurred())
 
-				_, err = s3client.UploadFile(versionedBucketName, filepath.Join(directoryPrefix, "some-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
+				_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "some-file"), tempFile.Name(), s3resource.NewUploadFileOptions())
 				Ω(err).ShouldNot(HaveOccurred())
 			}
 			err = os.Remove(tempFile.Name())
 			Ω(err).ShouldNot(HaveOccurred())
 
-			versions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "some-file"))
+			versions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "some-file"))
 			Ω(err).ShouldNot(HaveOccurred())
 			expectedVersion = versions[1]
 			inRequest.Version.VersionID = expectedVersion
 		})
 
 		AfterEach(func() {
-			fileVersions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "some-file"))
+			fileVersions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "some-file"))
 			Ω(err).ShouldNot(HaveOccurred())
 
 			for _, fileVersion := range fileVersions {
-				err := s3client.DeleteVersionedFile(versionedBucketName, filepath.Join(directoryPrefix, "some-file"), fileVersion)
+				err := s3client.DeleteVersionedFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "some-file"), fileVersion)
 				Ω(err).ShouldNot(HaveOccurred())
 			}
 		})
@@ -375,19 +376,19 @@ var _ = Describe("in", func() {
 			err = os.WriteFile(tempFile.Name(), []byte("some-file-1"), 0755)
 			Ω(err).ShouldNot(HaveOccurred())
 
-			_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "some-file-1"), tempFile.Name(), s3resource.NewUploadFileOptions())
+			_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "some-file-1"), tempFile.Name(), s3resource.NewUploadFileOptions())
 			Ω(err).ShouldNot(HaveOccurred())
 
 			err = os.Remove(tempFile.Name())
 			Ω(err).ShouldNot(HaveOccurred())
 
 			tags = map[string]string{"tag1": "value1", "tag2": "value2"}
-			err = s3client.SetTags(bucketName, filepath.Join(directoryPrefix, "some-file-1"), "", tags)
+			err = s3client.SetTags(context.Background(), bucketName, filepath.Join(directoryPrefix, "some-file-1"), "", tags)
 			Ω(err).ShouldNot(HaveOccurred())
 		})
 
 		AfterEach(func() {
-			err := s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "some-file-1"))
+			err := s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "some-file-1"))
 			Ω(err).ShouldNot(HaveOccurred())
 		})
 
@@ -417,7 +418,7 @@ var _ = Describe("in", func() {
 					Bucket:          bucketName,
 					RegionName:      regionName,
 					Endpoint:        endpoint,
-					Regexp:          filepath.Join(directoryPrefix, "archive-(.*)"),
+					Regexp:          filepath.Join(directoryPrefix, "archive-(.*).tar.bz2"),
 					UsePathStyle:    pathStyle,
 				},
 				Version: s3resource.Version{
@@ -450,12 +451,12 @@ var _ = Describe("in", func() {
 			Ω(err).ShouldNot(HaveOccurred())
 
 			// Upload to S3
-			_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "archive-1.tar.bz2"), archiveFile, s3resource.NewUploadFileOptions())
+			_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "archive-1.tar.bz2"), archiveFile, s3resource.NewUploadFileOptions())
 			Ω(err).ShouldNot(HaveOccurred())
 		})
 
 		AfterEach(func() {
-			err := s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "archive-1.tar.bz2"))
+			err := s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "archive-1.tar.bz2"))
 			Ω(err).ShouldNot(HaveOccurred())
 		})
 
@@ -530,12 +531,12 @@ var _ = Describe("in", func() {
 			Ω(err).ShouldNot(HaveOccurred())
 
 			// Upload to S3
-			_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "archive-1.tar.gz"), archiveFile, s3resource.NewUploadFileOptions())
+			_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "archive-1.tar.gz"), archiveFile, s3resource.NewUploadFileOptions())
 			Ω(err).ShouldNot(HaveOccurred())
 		})
 
 		AfterEach(func() {
-			err := s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "archive-1.tar.gz"))
+			err := s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "archive-1.tar.gz"))
 			Ω(err).ShouldNot(HaveOccurred())
 		})
 
@@ -570,7 +571,7 @@ var _ = Describe("in", func() {
 					Bucket:          bucketName,
 					RegionName:      regionName,
 					Endpoint:        endpoint,
-					Regexp:          filepath.Join(directoryPrefix, "file-(.*)"),
+					Regexp:          filepath.Join(directoryPrefix, "file-(.*).bz2"),
 					UsePathStyle:    pathStyle,
 				},
 				Version: s3resource.Version{
@@ -598,12 +599,12 @@ var _ = Describe("in", func() {
 
 			// Upload to S3
 			compressedFile := testFile + ".bz2"
-			_, err = s3client.UploadFile(bucketName, filepath.Join(directoryPrefix, "file-1.bz2"), compressedFile, s3resource.NewUploadFileOptions())
+			_, err = s3client.UploadFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-1.bz2"), compressedFile, s3resource.NewUploadFileOptions())
 			Ω(err).ShouldNot(HaveOccurred())
 		})
 
 		AfterEach(func() {
-			err := s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "file-1.bz2"))
+			err := s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-1.bz2"))
 			Ω(err).ShouldNot(HaveOccurred())
 		})
 
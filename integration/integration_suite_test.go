@@ -11,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	s3resource "github.com/concourse/s3-resource"
+	"github.com/concourse/s3-resource/integration/s3server"
 	"github.com/onsi/gomega/gexec"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -37,6 +38,9 @@ var (
 	s3client            s3resource.S3Client
 	s3Service           *s3.Client
 
+	// s3Server stands in for S3 when no endpoint is given
+	s3Server *s3server.Server
+
 	checkPath string
 	inPath    string
 	outPath   string
@@ -71,6 +75,7 @@ func getSessionTokenS3Client(awsConfig *aws.Config) (*s3.Client, s3resource.S3Cl
 	Ω(err).ShouldNot(HaveOccurred())
 
 	newAwsConfig, err := s3resource.NewAwsConfig(
+		context.Background(),
 		*resp.Credentials.AccessKeyId,
 		*resp.Credentials.SecretAccessKey,
 		*resp.Credentials.SessionToken,
@@ -79,6 +84,7 @@ func getSessionTokenS3Client(awsConfig *aws.Config) (*s3.Client, s3resource.S3Cl
 		false,
 		"",
 		false,
+		s3resource.AwsConfigOptions{},
 	)
 	Ω(err).ShouldNot(HaveOccurred())
 	s3client, err := s3resource.NewS3Client(
@@ -89,6 +95,7 @@ func getSessionTokenS3Client(awsConfig *aws.Config) (*s3.Client, s3resource.S3Cl
 		pathStyle,
 		skipS3Checksums,
 		"",
+		s3resource.S3ClientOptions{},
 	)
 	Ω(err).ShouldNot(HaveOccurred())
 
@@ -118,6 +125,20 @@ var _ = SynchronizedBeforeSuite(func() []byte {
 	inPath = sd.InPath
 	outPath = sd.OutPath
 
+	if accessKeyID == "" && endpoint == "" {
+		s3Server = s3server.New("access-key", "secret-key", "us-east-1")
+		s3Server.CreateBucket("bucket", false)
+		s3Server.CreateBucket("versioned-bucket", true)
+
+		accessKeyID = s3Server.AccessKeyID
+		secretAccessKey = s3Server.SecretAccessKey
+		bucketName = "bucket"
+		versionedBucketName = "versioned-bucket"
+		regionName = s3Server.Region
+		endpoint = s3Server.URL
+		pathStyle = true
+	}
+
 	if accessKeyID != "" {
 		Ω(accessKeyID).ShouldNot(BeEmpty(), "must specify $S3_TESTING_ACCESS_KEY_ID")
 		Ω(secretAccessKey).ShouldNot(BeEmpty(), "must specify $S3_TESTING_SECRET_ACCESS_KEY")
@@ -127,6 +148,7 @@ var _ = SynchronizedBeforeSuite(func() []byte {
 		Ω(endpoint).ShouldNot(BeEmpty(), "must specify $S3_ENDPOINT")
 
 		awsConfig, err = s3resource.NewAwsConfig(
+			context.Background(),
 			accessKeyID,
 			secretAccessKey,
 			sessionToken,
@@ -135,10 +157,16 @@ var _ = SynchronizedBeforeSuite(func() []byte {
 			false,
 			"",
 			false,
+			s3resource.AwsConfigOptions{},
 		)
 		Ω(err).ShouldNot(HaveOccurred())
 
-		s3Service = s3.NewFromConfig(*awsConfig)
+		s3Service = s3.NewFromConfig(*awsConfig, func(o *s3.Options) {
+			if endpoint != "" {
+				o.BaseEndpoint = aws.String(endpoint)
+			}
+			o.UsePathStyle = pathStyle
+		})
 		s3client, err = s3resource.NewS3Client(
 			io.Discard,
 			awsConfig,
@@ -147,6 +175,7 @@ var _ = SynchronizedBeforeSuite(func() []byte {
 			pathStyle,
 			skipS3Checksums,
 			"",
+			s3resource.S3ClientOptions{},
 		)
 		Ω(err).ShouldNot(HaveOccurred())
 	}
@@ -158,7 +187,11 @@ var _ = BeforeEach(func() {
 	}
 })
 
-var _ = SynchronizedAfterSuite(func() {}, func() {
+var _ = SynchronizedAfterSuite(func() {
+	if s3Server != nil {
+		s3Server.Close()
+	}
+}, func() {
 	gexec.CleanupBuildArtifacts()
 })
 
@@ -118,7 +118,7 @@ var _ = Describe("out", func() {
 		})
 
 		AfterEach(func() {
-			err := s3client.DeleteFile(bucketName, "content-typed-file")
+			err := s3client.DeleteFile(context.Background(), bucketName, "content-typed-file")
 			Ω(err).ShouldNot(HaveOccurred())
 		})
 
@@ -162,19 +162,19 @@ var _ = Describe("out", func() {
 		})
 
 		AfterEach(func() {
-			err := s3client.DeleteFile(bucketName, "uncontent-typed-file")
+			err := s3client.DeleteFile(context.Background(), bucketName, "uncontent-typed-file")
 			Ω(err).ShouldNot(HaveOccurred())
 		})
 
-		// http://docs.aws.amazon.com/AWSImportExport/latest/DG/FileExtensiontoMimeTypes.html
-		It("creates a file with the default S3 content-type for a unknown filename extension", func() {
+		// The SDK sends its default content-type with every upload
+		It("creates a file with the default SDK content-type for a unknown filename extension", func() {
 			response, err := s3Service.HeadObject(context.TODO(), &s3.HeadObjectInput{
 				Bucket: aws.String(bucketName),
 				Key:    aws.String("uncontent-typed-file"),
 			})
 			Ω(err).ShouldNot(HaveOccurred())
 
-			Expect(response.ContentType).To(Equal(aws.String("binary/octet-stream")))
+			Expect(response.ContentType).To(Equal(aws.String("application/octet-stream")))
 		})
 	})
 
@@ -219,7 +219,7 @@ var _ = Describe("out", func() {
 		})
 
 		AfterEach(func() {
-			err := s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "file-to-upload"))
+			err := s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "file-to-upload"))
 			Ω(err).ShouldNot(HaveOccurred())
 		})
 
@@ -251,12 +251,12 @@ var _ = Describe("out", func() {
 			})
 
 			AfterEach(func() {
-				err := s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "glob-file-to-upload"))
+				err := s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "glob-file-to-upload"))
 				Ω(err).ShouldNot(HaveOccurred())
 			})
 
 			It("uploads the file to the correct bucket and outputs the version", func() {
-				s3files, err := s3client.BucketFiles(bucketName, directoryPrefix)
+				s3files, err := s3client.BucketFiles(context.Background(), bucketName, directoryPrefix)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(s3files).Should(ConsistOf(filepath.Join(directoryPrefix, "glob-file-to-upload")))
@@ -300,7 +300,7 @@ var _ = Describe("out", func() {
 
 				resp, err := s3Service.GetObjectAcl(context.TODO(), params)
 				Ω(err).ShouldNot(HaveOccurred())
-				Ω(resp.Grants).Should(ContainElement(&expectedGrant))
+				Ω(resp.Grants).Should(ContainElement(expectedGrant))
 			})
 		})
 
@@ -309,6 +309,9 @@ var _ = Describe("out", func() {
 				if os.Getenv("S3_TESTING_NO_LARGE_UPLOAD") != "" {
 					Skip("'S3_TESTING_NO_LARGE_UPLOAD' is set, skipping.")
 				}
+				if s3Server != nil {
+					Skip("the S3 stand-in keeps objects in memory, skipping.")
+				}
 
 				path := filepath.Join(sourceDir, "large-file-to-upload")
 
@@ -341,12 +344,12 @@ var _ = Describe("out", func() {
 			})
 
 			AfterEach(func() {
-				err := s3client.DeleteFile(bucketName, filepath.Join(directoryPrefix, "large-file-to-upload"))
+				err := s3client.DeleteFile(context.Background(), bucketName, filepath.Join(directoryPrefix, "large-file-to-upload"))
 				Ω(err).ShouldNot(HaveOccurred())
 			})
 
 			It("uploads the file to the correct bucket and outputs the version", func() {
-				s3files, err := s3client.BucketFiles(bucketName, directoryPrefix)
+				s3files, err := s3client.BucketFiles(context.Background(), bucketName, directoryPrefix)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(s3files).Should(ConsistOf(filepath.Join(directoryPrefix, "large-file-to-upload")))
@@ -380,7 +383,7 @@ var _ = Describe("out", func() {
 			})
 
 			It("uploads the file to the correct bucket and outputs the version", func() {
-				s3files, err := s3client.BucketFiles(bucketName, directoryPrefix)
+				s3files, err := s3client.BucketFiles(context.Background(), bucketName, directoryPrefix)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(s3files).Should(ConsistOf(filepath.Join(directoryPrefix, "file-to-upload")))
@@ -439,7 +442,7 @@ var _ = Describe("out", func() {
 			})
 
 			It("reports that it failed to create a versioned object", func() {
-				Ω(session.Err).Should(gbytes.Say("object versioning not enabled"))
+				Ω(session.Err).Should(gbytes.Say("bucket is not versioned"))
 			})
 		})
 	})
@@ -452,11 +455,11 @@ var _ = Describe("out", func() {
 		})
 
 		AfterEach(func() {
-			fileVersions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload"))
+			fileVersions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload"))
 			Ω(err).ShouldNot(HaveOccurred())
 
 			for _, fileVersion := range fileVersions {
-				err := s3client.DeleteVersionedFile(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload"), fileVersion)
+				err := s3client.DeleteVersionedFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload"), fileVersion)
 				Ω(err).ShouldNot(HaveOccurred())
 			}
 		})
@@ -489,7 +492,7 @@ var _ = Describe("out", func() {
 			})
 
 			It("uploads the file to the correct bucket and outputs the version", func() {
-				s3files, err := s3client.BucketFiles(versionedBucketName, directoryPrefix)
+				s3files, err := s3client.BucketFiles(context.Background(), versionedBucketName, directoryPrefix)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(s3files).Should(ConsistOf(filepath.Join(directoryPrefix, "file-to-upload")))
@@ -500,7 +503,7 @@ var _ = Describe("out", func() {
 				err = json.NewDecoder(reader).Decode(&response)
 				Ω(err).ShouldNot(HaveOccurred())
 
-				versions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload"))
+				versions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload"))
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(response).Should(Equal(out.Response{
@@ -548,7 +551,7 @@ var _ = Describe("out", func() {
 			})
 
 			It("uploads the file to the correct bucket and outputs the version", func() {
-				s3files, err := s3client.BucketFiles(versionedBucketName, directoryPrefix)
+				s3files, err := s3client.BucketFiles(context.Background(), versionedBucketName, directoryPrefix)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(s3files).Should(ConsistOf(filepath.Join(directoryPrefix, "file-to-upload")))
@@ -559,7 +562,7 @@ var _ = Describe("out", func() {
 				err = json.NewDecoder(reader).Decode(&response)
 				Ω(err).ShouldNot(HaveOccurred())
 
-				versions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload"))
+				versions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload"))
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(response).Should(Equal(out.Response{
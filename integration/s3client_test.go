@@ -1,15 +1,21 @@
 package integration_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	s3resource "github.com/concourse/s3-resource"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -42,54 +48,54 @@ var _ = Describe("S3client", func() {
 		err := os.RemoveAll(tempDir)
 		Ω(err).ShouldNot(HaveOccurred())
 
-		fileOneVersions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-1"))
+		fileOneVersions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-1"))
 		Ω(err).ShouldNot(HaveOccurred())
 
 		for _, fileOneVersion := range fileOneVersions {
-			err := s3client.DeleteVersionedFile(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-1"), fileOneVersion)
+			err := s3client.DeleteVersionedFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-1"), fileOneVersion)
 			Ω(err).ShouldNot(HaveOccurred())
 		}
 
-		fileTwoVersions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-2"))
+		fileTwoVersions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-2"))
 		Ω(err).ShouldNot(HaveOccurred())
 
 		for _, fileTwoVersion := range fileTwoVersions {
-			err := s3client.DeleteVersionedFile(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-2"), fileTwoVersion)
+			err := s3client.DeleteVersionedFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-2"), fileTwoVersion)
 			Ω(err).ShouldNot(HaveOccurred())
 		}
 
-		fileThreeVersions, err := s3client.BucketFileVersions(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-3"))
+		fileThreeVersions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-3"))
 		Ω(err).ShouldNot(HaveOccurred())
 
 		for _, fileThreeVersion := range fileThreeVersions {
-			err := s3client.DeleteVersionedFile(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-3"), fileThreeVersion)
+			err := s3client.DeleteVersionedFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-3"), fileThreeVersion)
 			Ω(err).ShouldNot(HaveOccurred())
 		}
 	})
 
 	It("can interact with buckets", func() {
-		_, err := s3client.UploadFile(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-1"), tempFile.Name(), s3resource.NewUploadFileOptions())
+		_, err := s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-1"), tempFile.Name(), s3resource.NewUploadFileOptions())
 		Ω(err).ShouldNot(HaveOccurred())
 
-		_, err = s3client.UploadFile(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-2"), tempFile.Name(), s3resource.NewUploadFileOptions())
+		_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-2"), tempFile.Name(), s3resource.NewUploadFileOptions())
 		Ω(err).ShouldNot(HaveOccurred())
 
-		_, err = s3client.UploadFile(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-2"), tempFile.Name(), s3resource.NewUploadFileOptions())
+		_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-2"), tempFile.Name(), s3resource.NewUploadFileOptions())
 		Ω(err).ShouldNot(HaveOccurred())
 
 		tags := map[string]string{
 			"tag1": "value1",
 			"tag2": "value2",
 		}
-		err = s3client.SetTags(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-1"), "", tags)
+		err = s3client.SetTags(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-1"), "", tags)
 		Ω(err).ShouldNot(HaveOccurred())
 
 		options := s3resource.NewUploadFileOptions()
 		options.ServerSideEncryption = "AES256"
-		_, err = s3client.UploadFile(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-3"), tempFile.Name(), options)
+		_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-3"), tempFile.Name(), options)
 		Ω(err).ShouldNot(HaveOccurred())
 
-		files, err := s3client.BucketFiles(versionedBucketName, directoryPrefix)
+		files, err := s3client.BucketFiles(context.Background(), versionedBucketName, directoryPrefix)
 		Ω(err).ShouldNot(HaveOccurred())
 
 		Ω(files).Should(ConsistOf([]string{
@@ -98,14 +104,14 @@ var _ = Describe("S3client", func() {
 			filepath.Join(directoryPrefix, "file-to-upload-3"),
 		}))
 
-		err = s3client.DownloadFile(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-1"), "", filepath.Join(tempDir, "downloaded-file"))
+		err = s3client.DownloadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-1"), "", filepath.Join(tempDir, "downloaded-file"), s3resource.NewDownloadFileOptions())
 		Ω(err).ShouldNot(HaveOccurred())
 
 		read, err := os.ReadFile(filepath.Join(tempDir, "downloaded-file"))
 		Ω(err).ShouldNot(HaveOccurred())
 		Ω(read).Should(Equal([]byte("hello-" + runtime)))
 
-		err = s3client.DownloadTags(versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-1"), "", filepath.Join(tempDir, "tags.json"))
+		err = s3client.DownloadTags(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-1"), "", filepath.Join(tempDir, "tags.json"))
 		Ω(err).ShouldNot(HaveOccurred())
 
 		expectedTagsJSON, err := json.Marshal(tags)
@@ -121,7 +127,82 @@ var _ = Describe("S3client", func() {
 		})
 
 		Ω(err).ShouldNot(HaveOccurred())
-		Ω(resp.ServerSideEncryption).Should(Equal("AES256"))
+		Ω(resp.ServerSideEncryption).Should(Equal(types.ServerSideEncryptionAes256))
+	})
+
+	It("uploads and downloads large files in parts", func() {
+		contents := bytes.Repeat([]byte("hello-"+runtime), int(3*manager.MinUploadPartSize)/len("hello-"+runtime))
+		err := os.WriteFile(tempFile.Name(), contents, 0644)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		options := s3resource.NewUploadFileOptions()
+		options.PartSize = manager.MinUploadPartSize
+		_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-1"), tempFile.Name(), options)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		resp, err := s3Service.HeadObject(context.TODO(), &s3.HeadObjectInput{
+			Bucket: aws.String(versionedBucketName),
+			Key:    aws.String(filepath.Join(directoryPrefix, "file-to-upload-1")),
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(aws.ToString(resp.ETag)).Should(HaveSuffix(`-3"`))
+
+		downloadOptions := s3resource.NewDownloadFileOptions()
+		downloadOptions.PartSize = manager.MinUploadPartSize
+		err = s3client.DownloadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-1"), "", filepath.Join(tempDir, "downloaded-file"), downloadOptions)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		read, err := os.ReadFile(filepath.Join(tempDir, "downloaded-file"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(bytes.Equal(read, contents)).Should(BeTrue())
+	})
+
+	It("presigns URLs to versions", func() {
+		_, err := s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-2"), tempFile.Name(), s3resource.NewUploadFileOptions())
+		Ω(err).ShouldNot(HaveOccurred())
+
+		err = os.WriteFile(tempFile.Name(), []byte("goodbye-"+runtime), 0644)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-2"), tempFile.Name(), s3resource.NewUploadFileOptions())
+		Ω(err).ShouldNot(HaveOccurred())
+
+		versions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-2"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(versions).Should(HaveLen(2))
+
+		url, err := s3client.URL(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-2"), true, versions[1])
+		Ω(err).ShouldNot(HaveOccurred())
+
+		resp, err := http.Get(url)
+		Ω(err).ShouldNot(HaveOccurred())
+		defer resp.Body.Close()
+		Ω(resp.StatusCode).Should(Equal(http.StatusOK))
+
+		body, err := io.ReadAll(resp.Body)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(body)).Should(Equal("hello-" + runtime))
+	})
+
+	It("keeps the versions of deleted files", func() {
+		versionID, err := s3client.UploadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-3"), tempFile.Name(), s3resource.NewUploadFileOptions())
+		Ω(err).ShouldNot(HaveOccurred())
+
+		err = s3client.DeleteFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-3"))
+		Ω(err).ShouldNot(HaveOccurred())
+
+		files, err := s3client.BucketFiles(context.Background(), versionedBucketName, directoryPrefix)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(files).ShouldNot(ContainElement(filepath.Join(directoryPrefix, "file-to-upload-3")))
+
+		err = s3client.DownloadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-3"), "", filepath.Join(tempDir, "downloaded-file"), s3resource.NewDownloadFileOptions())
+		Ω(errors.Is(err, s3resource.ErrNotFound)).Should(BeTrue())
+
+		versions, err := s3client.BucketFileVersions(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-3"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(versions).Should(Equal([]string{versionID}))
+
+		err = s3client.DownloadFile(context.Background(), versionedBucketName, filepath.Join(directoryPrefix, "file-to-upload-3"), versionID, filepath.Join(tempDir, "downloaded-file"), s3resource.NewDownloadFileOptions())
+		Ω(err).ShouldNot(HaveOccurred())
 	})
 
 	Context("when using a sessionToken", func() {
@@ -133,7 +214,7 @@ var _ = Describe("S3client", func() {
 		})
 
 		It("can interact with buckets", func() {
-			_, err := s3client.BucketFiles(versionedBucketName, directoryPrefix)
+			_, err := s3client.BucketFiles(context.Background(), versionedBucketName, directoryPrefix)
 			Ω(err).ShouldNot(HaveOccurred())
 		})
 	})
@@ -0,0 +1,259 @@
+package s3server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// signingAlgorithm is the only algorithm requests may be signed with
+const signingAlgorithm = "AWS4-HMAC-SHA256"
+
+var errSignatureDoesNotMatch = newError(http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided. Check your key and signing method.")
+
+// signature is a Signature Version 4 signature, from the Authorization
+// header of a request or the query of a presigned URL
+type signature struct {
+	accessKeyID   string
+	scope         string
+	date          string
+	region        string
+	service       string
+	signedHeaders []string
+	signature     string
+	signedAt      string
+	payloadHash   string
+}
+
+// authenticate checks the signature of the request, given in its
+// Authorization header or in the query of a presigned URL. Requests with
+// neither are anonymous.
+func (server *Server) authenticate(r *http.Request) (bool, error) {
+	var (
+		sig *signature
+		err error
+	)
+	if r.Header.Get("Authorization") != "" {
+		sig, err = headerSignature(r)
+	} else if r.URL.Query().Has("X-Amz-Credential") {
+		sig, err = querySignature(r)
+	} else {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if sig.accessKeyID != server.AccessKeyID {
+		return false, newError(http.StatusForbidden, "InvalidAccessKeyId", "The AWS Access Key Id you provided does not exist in our records.")
+	}
+
+	// Requests without a payload hash, as SQS makes them, sign the hash of
+	// their body
+	if sig.payloadHash == "" {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return false, err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.Sum256(body)
+		sig.payloadHash = hex.EncodeToString(sum[:])
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		canonicalURI(r, sig.service),
+		canonicalQuery(r),
+		canonicalHeaders(r, sig.signedHeaders),
+		strings.Join(sig.signedHeaders, ";"),
+		sig.payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	stringToSign := strings.Join([]string{
+		signingAlgorithm,
+		sig.signedAt,
+		sig.scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + server.SecretAccessKey)
+	for _, part := range []string{sig.date, sig.region, sig.service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	expected := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	if !hmac.Equal([]byte(expected), []byte(sig.signature)) {
+		return false, errSignatureDoesNotMatch
+	}
+	return false, nil
+}
+
+// headerSignature parses the signature in the Authorization header, such as
+// "AWS4-HMAC-SHA256 Credential=..., SignedHeaders=..., Signature=..."
+func headerSignature(r *http.Request) (*signature, error) {
+	errMalformed := newError(http.StatusBadRequest, "AuthorizationHeaderMalformed", "The authorization header is malformed.")
+
+	algorithm, fields, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || algorithm != signingAlgorithm {
+		return nil, errMalformed
+	}
+
+	values := map[string]string{}
+	for _, field := range strings.Split(fields, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		values[name] = value
+	}
+
+	sig := &signature{
+		signature:   values["Signature"],
+		signedAt:    r.Header.Get("X-Amz-Date"),
+		payloadHash: r.Header.Get("X-Amz-Content-Sha256"),
+	}
+	if values["SignedHeaders"] != "" {
+		sig.signedHeaders = strings.Split(values["SignedHeaders"], ";")
+	}
+	if !sig.parseCredential(values["Credential"]) || sig.signature == "" || sig.signedAt == "" {
+		return nil, errMalformed
+	}
+	return sig, nil
+}
+
+// querySignature parses the signature in the query of a presigned URL,
+// checking that the URL has not expired
+func querySignature(r *http.Request) (*signature, error) {
+	query := r.URL.Query()
+
+	signedAt, err := time.Parse("20060102T150405Z", query.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "AuthorizationQueryParametersError", "X-Amz-Date must be in the ISO8601 Long Format.")
+	}
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "AuthorizationQueryParametersError", "X-Amz-Expires should be a number.")
+	}
+	if time.Now().After(signedAt.Add(time.Duration(expires) * time.Second)) {
+		return nil, newError(http.StatusForbidden, "AccessDenied", "Request has expired")
+	}
+
+	sig := &signature{
+		signedHeaders: strings.Split(query.Get("X-Amz-SignedHeaders"), ";"),
+		signature:     query.Get("X-Amz-Signature"),
+		signedAt:      query.Get("X-Amz-Date"),
+		// Presigned URLs don't sign their payload, unless they name its hash
+		payloadHash: "UNSIGNED-PAYLOAD",
+	}
+	if hash := query.Get("X-Amz-Content-Sha256"); hash != "" {
+		sig.payloadHash = hash
+	}
+	if query.Get("X-Amz-Algorithm") != signingAlgorithm || !sig.parseCredential(query.Get("X-Amz-Credential")) || sig.signature == "" {
+		return nil, newError(http.StatusBadRequest, "AuthorizationQueryParametersError", "Query-string authentication requires the X-Amz-Algorithm, X-Amz-Credential, X-Amz-Signature, X-Amz-Date, X-Amz-SignedHeaders, and X-Amz-Expires parameters.")
+	}
+	return sig, nil
+}
+
+// parseCredential parses a credential such as
+// "access-key/20060102/us-east-1/s3/aws4_request"
+func (sig *signature) parseCredential(credential string) bool {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[4] != "aws4_request" {
+		return false
+	}
+
+	sig.accessKeyID = parts[0]
+	sig.date = parts[1]
+	sig.region = parts[2]
+	sig.service = parts[3]
+	sig.scope = strings.Join(parts[1:], "/")
+	return true
+}
+
+// canonicalURI is the path of the request as it was sent. Services other
+// than S3 sign the path encoded a second time.
+func canonicalURI(r *http.Request, service string) string {
+	path, _, _ := strings.Cut(r.RequestURI, "?")
+	if path == "" {
+		path = "/"
+	}
+	if service != "s3" {
+		path = strings.ReplaceAll(path, "%", "%25")
+	}
+	return path
+}
+
+// canonicalQuery is the query of the request without its signature, with
+// each name and value encoded, sorted by name then value
+func canonicalQuery(r *http.Request) string {
+	var params [][2]string
+	for name, values := range r.URL.Query() {
+		if name == "X-Amz-Signature" {
+			continue
+		}
+		for _, value := range values {
+			params = append(params, [2]string{uriEncode(name), uriEncode(value)})
+		}
+	}
+	slices.SortFunc(params, func(a, b [2]string) int {
+		if c := strings.Compare(a[0], b[0]); c != 0 {
+			return c
+		}
+		return strings.Compare(a[1], b[1])
+	})
+
+	encoded := make([]string, len(params))
+	for i, param := range params {
+		encoded[i] = param[0] + "=" + param[1]
+	}
+	return strings.Join(encoded, "&")
+}
+
+// canonicalHeaders lists the signed headers with their values trimmed, one
+// per line
+func canonicalHeaders(r *http.Request, signedHeaders []string) string {
+	var headers strings.Builder
+	for _, name := range signedHeaders {
+		var value string
+		switch name {
+		case "host":
+			value = r.Host
+		case "content-length":
+			value = strconv.FormatInt(r.ContentLength, 10)
+		default:
+			var values []string
+			for _, v := range r.Header.Values(name) {
+				values = append(values, strings.Join(strings.Fields(v), " "))
+			}
+			value = strings.Join(values, ",")
+		}
+		fmt.Fprintf(&headers, "%s:%s\n", name, value)
+	}
+	return headers.String()
+}
+
+// uriEncode percent-encodes all but the unreserved characters of s
+func uriEncode(s string) string {
+	var encoded strings.Builder
+	for _, b := range []byte(s) {
+		if 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' || '0' <= b && b <= '9' || b == '-' || b == '_' || b == '.' || b == '~' {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return encoded.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
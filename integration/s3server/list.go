@@ -0,0 +1,289 @@
+package s3server
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type listBucketResult struct {
+	XMLName               xml.Name       `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name                  string         `xml:"Name"`
+	Prefix                string         `xml:"Prefix"`
+	Delimiter             string         `xml:"Delimiter,omitempty"`
+	StartAfter            string         `xml:"StartAfter,omitempty"`
+	ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+	EncodingType          string         `xml:"EncodingType,omitempty"`
+	MaxKeys               int            `xml:"MaxKeys"`
+	KeyCount              int            `xml:"KeyCount"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	Contents              []listedObject `xml:"Contents"`
+	CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+}
+
+type listedObject struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int    `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type commonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type listVersionsResult struct {
+	XMLName             xml.Name        `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListVersionsResult"`
+	Name                string          `xml:"Name"`
+	Prefix              string          `xml:"Prefix"`
+	Delimiter           string          `xml:"Delimiter,omitempty"`
+	KeyMarker           string          `xml:"KeyMarker"`
+	VersionIDMarker     string          `xml:"VersionIdMarker"`
+	NextKeyMarker       string          `xml:"NextKeyMarker,omitempty"`
+	NextVersionIDMarker string          `xml:"NextVersionIdMarker,omitempty"`
+	EncodingType        string          `xml:"EncodingType,omitempty"`
+	MaxKeys             int             `xml:"MaxKeys"`
+	IsTruncated         bool            `xml:"IsTruncated"`
+	Versions            []listedVersion `xml:"Version"`
+	DeleteMarkers       []listedVersion `xml:"DeleteMarker"`
+	CommonPrefixes      []commonPrefix  `xml:"CommonPrefixes"`
+}
+
+type listedVersion struct {
+	Key          string `xml:"Key"`
+	VersionID    string `xml:"VersionId"`
+	IsLatest     bool   `xml:"IsLatest"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag,omitempty"`
+	Size         *int   `xml:"Size,omitempty"`
+	StorageClass string `xml:"StorageClass,omitempty"`
+}
+
+// listing walks the keys of a bucket in order, rolling up the keys which
+// contain the delimiter after the prefix into common prefixes
+type listing struct {
+	prefix    string
+	delimiter string
+	maxKeys   int
+	encode    func(string) string
+}
+
+func newListing(r *http.Request) (listing, error) {
+	query := r.URL.Query()
+
+	listing := listing{
+		prefix:    query.Get("prefix"),
+		delimiter: query.Get("delimiter"),
+		maxKeys:   1000,
+		encode:    func(s string) string { return s },
+	}
+
+	if maxKeys := query.Get("max-keys"); maxKeys != "" {
+		n, err := strconv.Atoi(maxKeys)
+		if err != nil || n < 0 {
+			return listing, newError(http.StatusBadRequest, "InvalidArgument", "Provided max-keys not an integer or within integer range")
+		}
+		listing.maxKeys = min(n, 1000)
+	}
+
+	switch encodingType := query.Get("encoding-type"); encodingType {
+	case "":
+	case "url":
+		listing.encode = url.QueryEscape
+	default:
+		return listing, newError(http.StatusBadRequest, "InvalidArgument", "Invalid Encoding Method specified in Request")
+	}
+
+	return listing, nil
+}
+
+// entry is the name a key is listed under: the key itself, or the common
+// prefix it is rolled up into
+func (listing listing) entry(key string) (string, bool) {
+	if listing.delimiter == "" {
+		return key, false
+	}
+
+	rest := strings.TrimPrefix(key, listing.prefix)
+	i := strings.Index(rest, listing.delimiter)
+	if i < 0 {
+		return key, false
+	}
+
+	return listing.prefix + rest[:i+len(listing.delimiter)], true
+}
+
+func (listing listing) keys(bucket *bucket) []string {
+	var keys []string
+	for key := range bucket.objects {
+		if strings.HasPrefix(key, listing.prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (server *Server) listObjectsV2(w http.ResponseWriter, r *http.Request, bucketName string, bucket *bucket) error {
+	listing, err := newListing(r)
+	if err != nil {
+		return err
+	}
+
+	query := r.URL.Query()
+	result := listBucketResult{
+		Name:              bucketName,
+		Prefix:            listing.encode(listing.prefix),
+		Delimiter:         listing.encode(listing.delimiter),
+		StartAfter:        listing.encode(query.Get("start-after")),
+		ContinuationToken: query.Get("continuation-token"),
+		MaxKeys:           listing.maxKeys,
+		Contents:          []listedObject{},
+		CommonPrefixes:    []commonPrefix{},
+	}
+	if query.Get("encoding-type") != "" {
+		result.EncodingType = query.Get("encoding-type")
+	}
+
+	// The continuation token is the last entry listed, and entries are in
+	// the same order as the keys they come from
+	after := query.Get("start-after")
+	if token := query.Get("continuation-token"); token != "" {
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return newError(http.StatusBadRequest, "InvalidArgument", "The continuation token provided is incorrect")
+		}
+		after = string(decoded)
+	}
+
+	last := ""
+	for _, key := range listing.keys(bucket) {
+		latest := bucket.objects[key][0]
+		if latest.deleteMarker {
+			continue
+		}
+
+		entry, isPrefix := listing.entry(key)
+		if entry <= after || entry == last {
+			continue
+		}
+
+		if result.KeyCount == listing.maxKeys {
+			result.IsTruncated = true
+			result.NextContinuationToken = base64.StdEncoding.EncodeToString([]byte(last))
+			break
+		}
+
+		if isPrefix {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: listing.encode(entry)})
+		} else {
+			result.Contents = append(result.Contents, listedObject{
+				Key:          listing.encode(key),
+				LastModified: timestamp(latest.lastModified),
+				ETag:         latest.etag,
+				Size:         len(latest.data),
+				StorageClass: latest.storageClass,
+			})
+		}
+		result.KeyCount++
+		last = entry
+	}
+
+	return writeXML(w, result)
+}
+
+func (server *Server) listObjectVersions(w http.ResponseWriter, r *http.Request, bucketName string, bucket *bucket) error {
+	listing, err := newListing(r)
+	if err != nil {
+		return err
+	}
+
+	query := r.URL.Query()
+	keyMarker := query.Get("key-marker")
+	versionIDMarker := query.Get("version-id-marker")
+
+	result := listVersionsResult{
+		Name:            bucketName,
+		Prefix:          listing.encode(listing.prefix),
+		Delimiter:       listing.encode(listing.delimiter),
+		KeyMarker:       listing.encode(keyMarker),
+		VersionIDMarker: versionIDMarker,
+		MaxKeys:         listing.maxKeys,
+		Versions:        []listedVersion{},
+		DeleteMarkers:   []listedVersion{},
+		CommonPrefixes:  []commonPrefix{},
+	}
+	if query.Get("encoding-type") != "" {
+		result.EncodingType = query.Get("encoding-type")
+	}
+
+	count := 0
+	lastKey, lastVersionID, lastPrefix := "", "", ""
+	truncate := func() {
+		result.IsTruncated = true
+		result.NextKeyMarker = listing.encode(lastKey)
+		result.NextVersionIDMarker = lastVersionID
+	}
+
+keys:
+	for _, key := range listing.keys(bucket) {
+		entry, isPrefix := listing.entry(key)
+		if isPrefix {
+			if entry <= keyMarker || entry == lastPrefix {
+				continue
+			}
+			if count == listing.maxKeys {
+				truncate()
+				break
+			}
+
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: listing.encode(entry)})
+			count++
+			lastKey, lastVersionID, lastPrefix = entry, "", entry
+			continue
+		}
+
+		if key < keyMarker || (key == keyMarker && versionIDMarker == "") {
+			continue
+		}
+
+		// A version marker resumes after that version of the key marker
+		skipping := key == keyMarker
+		for i, version := range bucket.objects[key] {
+			if skipping {
+				skipping = version.versionID != versionIDMarker
+				continue
+			}
+			if count == listing.maxKeys {
+				truncate()
+				break keys
+			}
+
+			listed := listedVersion{
+				Key:          listing.encode(key),
+				VersionID:    version.versionID,
+				IsLatest:     i == 0,
+				LastModified: timestamp(version.lastModified),
+			}
+			if version.deleteMarker {
+				result.DeleteMarkers = append(result.DeleteMarkers, listed)
+			} else {
+				size := len(version.data)
+				listed.ETag = version.etag
+				listed.Size = &size
+				listed.StorageClass = version.storageClass
+				result.Versions = append(result.Versions, listed)
+			}
+			count++
+			lastKey, lastVersionID = key, version.versionID
+		}
+	}
+
+	return writeXML(w, result)
+}
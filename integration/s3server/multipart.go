@@ -0,0 +1,376 @@
+package s3server
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minPartSize is the smallest size of the parts of an upload besides the last
+const minPartSize = 5 * 1024 * 1024
+
+type upload struct {
+	id         string
+	bucketName string
+	initiated  time.Time
+	// object holds the settings of the object the upload creates
+	object            *object
+	checksumAlgorithm string
+	parts             map[int]*part
+}
+
+type part struct {
+	data         []byte
+	etag         string
+	checksum     string
+	lastModified time.Time
+}
+
+func (server *Server) findUpload(r *http.Request, key string) (*upload, error) {
+	upload, ok := server.uploads[r.URL.Query().Get("uploadId")]
+	if !ok || upload.object.key != key {
+		return nil, errNoSuchUpload
+	}
+	return upload, nil
+}
+
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ InitiateMultipartUploadResult"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+func (server *Server) createMultipartUpload(w http.ResponseWriter, r *http.Request, bucketName string, bucket *bucket, key string) error {
+	object, err := server.newObject(r, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	upload := &upload{
+		id:                server.nextVersionID(),
+		bucketName:        bucketName,
+		initiated:         time.Now(),
+		object:            object,
+		checksumAlgorithm: r.Header.Get("x-amz-checksum-algorithm"),
+		parts:             map[int]*part{},
+	}
+	server.uploads[upload.id] = upload
+
+	if upload.checksumAlgorithm != "" {
+		w.Header().Set("x-amz-checksum-algorithm", upload.checksumAlgorithm)
+	}
+	object.encryption.writeHeaders(w)
+
+	return writeXML(w, initiateMultipartUploadResult{
+		Bucket:   bucketName,
+		Key:      key,
+		UploadID: upload.id,
+	})
+}
+
+func (server *Server) uploadPart(w http.ResponseWriter, r *http.Request, key string) error {
+	upload, err := server.findUpload(r, key)
+	if err != nil {
+		return err
+	}
+
+	number, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil || number < 1 || number > 10000 {
+		return newError(http.StatusBadRequest, "InvalidArgument", "Part number must be an integer between 1 and 10000, inclusive")
+	}
+
+	data, checksum, err := readBody(r)
+	if err != nil {
+		return err
+	}
+
+	part := &part{
+		data:         data,
+		etag:         etag(data),
+		checksum:     checksum,
+		lastModified: time.Now(),
+	}
+	upload.parts[number] = part
+
+	w.Header().Set("ETag", part.etag)
+	if checksum != "" && upload.checksumAlgorithm != "" {
+		w.Header().Set("x-amz-checksum-"+strings.ToLower(upload.checksumAlgorithm), checksum)
+	}
+	upload.object.encryption.writeHeaders(w)
+	return nil
+}
+
+type completeMultipartUpload struct {
+	Parts []struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	} `xml:"Part"`
+}
+
+type completeMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CompleteMultipartUploadResult"`
+	Location string   `xml:"Location"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	ETag     string   `xml:"ETag"`
+}
+
+func (server *Server) completeMultipartUpload(w http.ResponseWriter, r *http.Request, bucketName string, bucket *bucket, key string) error {
+	upload, err := server.findUpload(r, key)
+	if err != nil {
+		return err
+	}
+
+	var request completeMultipartUpload
+	if err := readXML(r, &request); err != nil {
+		return err
+	}
+	if len(request.Parts) == 0 {
+		return newError(http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+
+	var (
+		data bytes.Buffer
+		md5s []byte
+	)
+	for i, requested := range request.Parts {
+		if i > 0 && requested.PartNumber <= request.Parts[i-1].PartNumber {
+			return newError(http.StatusBadRequest, "InvalidPartOrder", "The list of parts was not in ascending order. Parts must be ordered by part number.")
+		}
+
+		part, ok := upload.parts[requested.PartNumber]
+		if !ok || strings.Trim(requested.ETag, `"`) != strings.Trim(part.etag, `"`) {
+			return newError(http.StatusBadRequest, "InvalidPart", "One or more of the specified parts could not be found. The part may not have been uploaded, or the specified entity tag may not match the part's entity tag.")
+		}
+		if i < len(request.Parts)-1 && len(part.data) < minPartSize {
+			return newError(http.StatusBadRequest, "EntityTooSmall", "Your proposed upload is smaller than the minimum allowed object size.")
+		}
+
+		data.Write(part.data)
+		sum, _ := hex.DecodeString(strings.Trim(part.etag, `"`))
+		md5s = append(md5s, sum...)
+	}
+
+	sum := md5.Sum(md5s)
+	object := upload.object
+	object.data = data.Bytes()
+	object.etag = fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(sum[:]), len(request.Parts))
+	object.lastModified = time.Now()
+
+	bucket.add(object)
+	server.notify(bucketName, "ObjectCreated:CompleteMultipartUpload", object)
+	delete(server.uploads, upload.id)
+
+	if object.versionID != "null" {
+		w.Header().Set("x-amz-version-id", object.versionID)
+	}
+	object.encryption.writeHeaders(w)
+
+	return writeXML(w, completeMultipartUploadResult{
+		Location: server.objectURL(bucketName, key),
+		Bucket:   bucketName,
+		Key:      key,
+		ETag:     object.etag,
+	})
+}
+
+func (server *Server) abortMultipartUpload(w http.ResponseWriter, r *http.Request, key string) error {
+	upload, err := server.findUpload(r, key)
+	if err != nil {
+		return err
+	}
+
+	delete(server.uploads, upload.id)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type listMultipartUploadsResult struct {
+	XMLName            xml.Name       `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListMultipartUploadsResult"`
+	Bucket             string         `xml:"Bucket"`
+	Prefix             string         `xml:"Prefix"`
+	KeyMarker          string         `xml:"KeyMarker"`
+	UploadIDMarker     string         `xml:"UploadIdMarker"`
+	NextKeyMarker      string         `xml:"NextKeyMarker,omitempty"`
+	NextUploadIDMarker string         `xml:"NextUploadIdMarker,omitempty"`
+	MaxUploads         int            `xml:"MaxUploads"`
+	IsTruncated        bool           `xml:"IsTruncated"`
+	Uploads            []listedUpload `xml:"Upload"`
+}
+
+type listedUpload struct {
+	Key               string `xml:"Key"`
+	UploadID          string `xml:"UploadId"`
+	Initiated         string `xml:"Initiated"`
+	StorageClass      string `xml:"StorageClass"`
+	ChecksumAlgorithm string `xml:"ChecksumAlgorithm,omitempty"`
+}
+
+func (server *Server) listMultipartUploads(w http.ResponseWriter, r *http.Request, bucketName string) error {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	keyMarker := query.Get("key-marker")
+	uploadIDMarker := query.Get("upload-id-marker")
+
+	maxUploads, err := maxParam(query.Get("max-uploads"), "max-uploads")
+	if err != nil {
+		return err
+	}
+
+	var uploads []*upload
+	for _, upload := range server.uploads {
+		key := upload.object.key
+		if upload.bucketName != bucketName || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if key < keyMarker || (key == keyMarker && (uploadIDMarker == "" || upload.id <= uploadIDMarker)) {
+			continue
+		}
+		uploads = append(uploads, upload)
+	}
+
+	// Uploads are listed by key, and then by when they were initiated
+	slices.SortFunc(uploads, func(a, b *upload) int {
+		return strings.Compare(a.object.key+"\x00"+a.id, b.object.key+"\x00"+b.id)
+	})
+
+	result := listMultipartUploadsResult{
+		Bucket:         bucketName,
+		Prefix:         prefix,
+		KeyMarker:      keyMarker,
+		UploadIDMarker: uploadIDMarker,
+		MaxUploads:     maxUploads,
+		Uploads:        []listedUpload{},
+	}
+	if len(uploads) > maxUploads {
+		uploads = uploads[:maxUploads]
+		result.IsTruncated = true
+	}
+
+	for _, upload := range uploads {
+		result.Uploads = append(result.Uploads, listedUpload{
+			Key:               upload.object.key,
+			UploadID:          upload.id,
+			Initiated:         timestamp(upload.initiated),
+			StorageClass:      upload.object.storageClass,
+			ChecksumAlgorithm: upload.checksumAlgorithm,
+		})
+		result.NextKeyMarker = upload.object.key
+		result.NextUploadIDMarker = upload.id
+	}
+
+	return writeXML(w, result)
+}
+
+type listPartsResult struct {
+	XMLName              xml.Name     `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListPartsResult"`
+	Bucket               string       `xml:"Bucket"`
+	Key                  string       `xml:"Key"`
+	UploadID             string       `xml:"UploadId"`
+	PartNumberMarker     int          `xml:"PartNumberMarker"`
+	NextPartNumberMarker int          `xml:"NextPartNumberMarker"`
+	MaxParts             int          `xml:"MaxParts"`
+	IsTruncated          bool         `xml:"IsTruncated"`
+	StorageClass         string       `xml:"StorageClass"`
+	ChecksumAlgorithm    string       `xml:"ChecksumAlgorithm,omitempty"`
+	Parts                []listedPart `xml:"Part"`
+}
+
+type listedPart struct {
+	PartNumber     int    `xml:"PartNumber"`
+	LastModified   string `xml:"LastModified"`
+	ETag           string `xml:"ETag"`
+	Size           int    `xml:"Size"`
+	ChecksumCRC32  string `xml:"ChecksumCRC32,omitempty"`
+	ChecksumCRC32C string `xml:"ChecksumCRC32C,omitempty"`
+	ChecksumSHA1   string `xml:"ChecksumSHA1,omitempty"`
+	ChecksumSHA256 string `xml:"ChecksumSHA256,omitempty"`
+}
+
+func (server *Server) listParts(w http.ResponseWriter, r *http.Request, bucketName string, key string) error {
+	upload, err := server.findUpload(r, key)
+	if err != nil {
+		return err
+	}
+
+	query := r.URL.Query()
+	maxParts, err := maxParam(query.Get("max-parts"), "max-parts")
+	if err != nil {
+		return err
+	}
+
+	marker := 0
+	if query.Get("part-number-marker") != "" {
+		marker, err = strconv.Atoi(query.Get("part-number-marker"))
+		if err != nil {
+			return newError(http.StatusBadRequest, "InvalidArgument", "Provided part-number-marker not an integer or within integer range")
+		}
+	}
+
+	var numbers []int
+	for number := range upload.parts {
+		if number > marker {
+			numbers = append(numbers, number)
+		}
+	}
+	slices.Sort(numbers)
+
+	result := listPartsResult{
+		Bucket:            bucketName,
+		Key:               key,
+		UploadID:          upload.id,
+		PartNumberMarker:  marker,
+		MaxParts:          maxParts,
+		StorageClass:      upload.object.storageClass,
+		ChecksumAlgorithm: upload.checksumAlgorithm,
+		Parts:             []listedPart{},
+	}
+	if len(numbers) > maxParts {
+		numbers = numbers[:maxParts]
+		result.IsTruncated = true
+	}
+
+	for _, number := range numbers {
+		part := upload.parts[number]
+		listed := listedPart{
+			PartNumber:   number,
+			LastModified: timestamp(part.lastModified),
+			ETag:         part.etag,
+			Size:         len(part.data),
+		}
+		switch strings.ToUpper(upload.checksumAlgorithm) {
+		case "CRC32":
+			listed.ChecksumCRC32 = part.checksum
+		case "CRC32C":
+			listed.ChecksumCRC32C = part.checksum
+		case "SHA1":
+			listed.ChecksumSHA1 = part.checksum
+		case "SHA256":
+			listed.ChecksumSHA256 = part.checksum
+		}
+		result.Parts = append(result.Parts, listed)
+		result.NextPartNumberMarker = number
+	}
+
+	return writeXML(w, result)
+}
+
+func maxParam(value string, name string) (int, error) {
+	if value == "" {
+		return 1000, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, newError(http.StatusBadRequest, "InvalidArgument", fmt.Sprintf("Provided %s not an integer or within integer range", name))
+	}
+	return min(n, 1000), nil
+}
@@ -0,0 +1,365 @@
+package s3server
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// version finds a version of key, or its latest if versionID is empty
+func (bucket *bucket) version(key string, versionID string) (*object, error) {
+	versions := bucket.objects[key]
+	if versionID == "" {
+		if len(versions) == 0 || versions[0].deleteMarker {
+			return nil, errNoSuchKey
+		}
+		return versions[0], nil
+	}
+
+	for _, version := range versions {
+		if version.versionID == versionID {
+			return version, nil
+		}
+	}
+
+	return nil, errNoSuchVersion
+}
+
+// add makes latest the latest version of its key. Without versioning it
+// replaces the "null" version.
+func (bucket *bucket) add(latest *object) {
+	versions := bucket.objects[latest.key]
+	if !bucket.versioned {
+		for i, version := range versions {
+			if version.versionID == "null" {
+				versions = append(versions[:i], versions[i+1:]...)
+				break
+			}
+		}
+	}
+
+	bucket.objects[latest.key] = append([]*object{latest}, versions...)
+}
+
+func (server *Server) newObject(r *http.Request, bucket *bucket, key string) (*object, error) {
+	if r.Header.Get("x-amz-object-lock-mode") != "" || r.Header.Get("x-amz-object-lock-legal-hold") != "" {
+		return nil, newError(http.StatusBadRequest, "InvalidRequest", "Bucket is missing Object Lock Configuration")
+	}
+
+	object := &object{
+		key:          key,
+		versionID:    "null",
+		lastModified: time.Now(),
+		contentType:  r.Header.Get("Content-Type"),
+		storageClass: r.Header.Get("x-amz-storage-class"),
+		metadata:     map[string]string{},
+		acl:          r.Header.Get("x-amz-acl"),
+		tags:         map[string]string{},
+		encryption: encryption{
+			serverSide:     r.Header.Get("x-amz-server-side-encryption"),
+			kmsKeyID:       r.Header.Get("x-amz-server-side-encryption-aws-kms-key-id"),
+			customerKeyMD5: r.Header.Get("x-amz-server-side-encryption-customer-key-MD5"),
+		},
+	}
+	if bucket.versioned {
+		object.versionID = server.nextVersionID()
+	}
+	if object.contentType == "" {
+		object.contentType = "binary/octet-stream"
+	}
+	if object.storageClass == "" {
+		object.storageClass = "STANDARD"
+	}
+	if object.encryption.serverSide == "aws:kms" && object.encryption.kmsKeyID == "" {
+		object.encryption.kmsKeyID = "aws/s3"
+	}
+
+	for name, values := range r.Header {
+		if metadataKey, ok := strings.CutPrefix(strings.ToLower(name), "x-amz-meta-"); ok {
+			object.metadata[metadataKey] = values[0]
+		}
+	}
+
+	return object, nil
+}
+
+func (object *object) writeHeaders(w http.ResponseWriter) {
+	header := w.Header()
+	header.Set("ETag", object.etag)
+	header.Set("Content-Type", object.contentType)
+	header.Set("Last-Modified", object.lastModified.UTC().Format(http.TimeFormat))
+	header.Set("Accept-Ranges", "bytes")
+	if object.versionID != "null" {
+		header.Set("x-amz-version-id", object.versionID)
+	}
+	if object.storageClass != "STANDARD" {
+		header.Set("x-amz-storage-class", object.storageClass)
+	}
+	for metadataKey, value := range object.metadata {
+		header.Set("x-amz-meta-"+metadataKey, value)
+	}
+	object.encryption.writeHeaders(w)
+}
+
+func (encryption encryption) writeHeaders(w http.ResponseWriter) {
+	header := w.Header()
+	if encryption.serverSide != "" {
+		header.Set("x-amz-server-side-encryption", encryption.serverSide)
+	}
+	if encryption.kmsKeyID != "" {
+		header.Set("x-amz-server-side-encryption-aws-kms-key-id", encryption.kmsKeyID)
+	}
+	if encryption.customerKeyMD5 != "" {
+		header.Set("x-amz-server-side-encryption-customer-algorithm", "AES256")
+		header.Set("x-amz-server-side-encryption-customer-key-MD5", encryption.customerKeyMD5)
+	}
+}
+
+func (server *Server) putObject(w http.ResponseWriter, r *http.Request, bucketName string, bucket *bucket, key string) error {
+	object, err := server.newObject(r, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	object.data, _, err = readBody(r)
+	if err != nil {
+		return err
+	}
+	object.etag = etag(object.data)
+
+	bucket.add(object)
+	server.notify(bucketName, "ObjectCreated:Put", object)
+
+	w.Header().Set("ETag", object.etag)
+	if object.versionID != "null" {
+		w.Header().Set("x-amz-version-id", object.versionID)
+	}
+	object.encryption.writeHeaders(w)
+	return nil
+}
+
+func (server *Server) getObject(w http.ResponseWriter, r *http.Request, bucket *bucket, key string, anonymous bool) error {
+	versionID := r.URL.Query().Get("versionId")
+	object, err := bucket.version(key, versionID)
+	if err != nil {
+		if versions := bucket.objects[key]; err == errNoSuchKey && len(versions) > 0 {
+			w.Header().Set("x-amz-delete-marker", "true")
+			w.Header().Set("x-amz-version-id", versions[0].versionID)
+		}
+		return err
+	}
+
+	if object.deleteMarker {
+		w.Header().Set("x-amz-delete-marker", "true")
+		w.Header().Set("Allow", "DELETE")
+		return newError(http.StatusMethodNotAllowed, "MethodNotAllowed", "The specified method is not allowed against this resource.")
+	}
+
+	if anonymous && object.acl != "public-read" && object.acl != "public-read-write" {
+		return errAccessDenied
+	}
+
+	customerKeyMD5 := r.Header.Get("x-amz-server-side-encryption-customer-key-MD5")
+	if object.encryption.customerKeyMD5 != "" && customerKeyMD5 == "" {
+		return newError(http.StatusBadRequest, "InvalidRequest", "The object was stored using a form of Server Side Encryption. The correct parameters must be provided to retrieve the object.")
+	}
+	if customerKeyMD5 != object.encryption.customerKeyMD5 {
+		return errAccessDenied
+	}
+
+	object.writeHeaders(w)
+
+	// ServeContent answers range and conditional requests, which the
+	// downloader uses to fetch parts of the same object
+	http.ServeContent(w, r, key, object.lastModified, bytes.NewReader(object.data))
+	return nil
+}
+
+func (server *Server) deleteObject(w http.ResponseWriter, r *http.Request, bucket *bucket, key string) error {
+	versionID := r.URL.Query().Get("versionId")
+
+	if versionID != "" {
+		versions := bucket.objects[key]
+		for i, version := range versions {
+			if version.versionID != versionID {
+				continue
+			}
+
+			bucket.objects[key] = append(versions[:i:i], versions[i+1:]...)
+			if len(bucket.objects[key]) == 0 {
+				delete(bucket.objects, key)
+			}
+			if version.deleteMarker {
+				w.Header().Set("x-amz-delete-marker", "true")
+			}
+			break
+		}
+
+		w.Header().Set("x-amz-version-id", versionID)
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	if !bucket.versioned {
+		delete(bucket.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	marker := &object{
+		key:          key,
+		versionID:    server.nextVersionID(),
+		deleteMarker: true,
+		lastModified: time.Now(),
+	}
+	bucket.add(marker)
+
+	w.Header().Set("x-amz-delete-marker", "true")
+	w.Header().Set("x-amz-version-id", marker.versionID)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type versioningConfiguration struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ VersioningConfiguration"`
+	Status  string   `xml:"Status,omitempty"`
+}
+
+func (server *Server) getBucketVersioning(w http.ResponseWriter, bucket *bucket) error {
+	configuration := versioningConfiguration{}
+	if bucket.versioned {
+		configuration.Status = "Enabled"
+	}
+	return writeXML(w, configuration)
+}
+
+type tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	Tags    []tag    `xml:"TagSet>Tag"`
+}
+
+type tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+func (server *Server) taggedVersion(r *http.Request, bucket *bucket, key string) (*object, error) {
+	object, err := bucket.version(key, r.URL.Query().Get("versionId"))
+	if err != nil {
+		return nil, err
+	}
+	if object.deleteMarker {
+		return nil, newError(http.StatusMethodNotAllowed, "MethodNotAllowed", "The specified method is not allowed against this resource.")
+	}
+	return object, nil
+}
+
+func (server *Server) putObjectTagging(w http.ResponseWriter, r *http.Request, bucket *bucket, key string) error {
+	object, err := server.taggedVersion(r, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	var request tagging
+	if err := readXML(r, &request); err != nil {
+		return err
+	}
+
+	object.tags = map[string]string{}
+	for _, tag := range request.Tags {
+		object.tags[tag.Key] = tag.Value
+	}
+
+	if object.versionID != "null" {
+		w.Header().Set("x-amz-version-id", object.versionID)
+	}
+	return nil
+}
+
+func (server *Server) getObjectTagging(w http.ResponseWriter, r *http.Request, bucket *bucket, key string) error {
+	object, err := server.taggedVersion(r, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	response := tagging{Tags: []tag{}}
+	for key, value := range object.tags {
+		response.Tags = append(response.Tags, tag{Key: key, Value: value})
+	}
+
+	if object.versionID != "null" {
+		w.Header().Set("x-amz-version-id", object.versionID)
+	}
+	return writeXML(w, response)
+}
+
+type accessControlPolicy struct {
+	XMLName xml.Name `xml:"AccessControlPolicy"`
+	Owner   owner    `xml:"Owner"`
+	Grants  []grant  `xml:"AccessControlList>Grant"`
+}
+
+type owner struct {
+	ID string `xml:"ID"`
+}
+
+type grant struct {
+	Grantee    grantee `xml:"Grantee"`
+	Permission string  `xml:"Permission"`
+}
+
+type grantee struct {
+	XMLNS string `xml:"xmlns:xsi,attr"`
+	Type  string `xml:"xsi:type,attr"`
+	ID    string `xml:"ID,omitempty"`
+	URI   string `xml:"URI,omitempty"`
+}
+
+const (
+	ownerID           = "s3server"
+	allUsers          = "http://acs.amazonaws.com/groups/global/AllUsers"
+	authenticatedUser = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+)
+
+// cannedGrants are the grants of the canned ACLs besides those of the owner
+var cannedGrants = map[string][]grant{
+	"public-read": {
+		{Grantee: grantee{Type: "Group", URI: allUsers}, Permission: "READ"},
+	},
+	"public-read-write": {
+		{Grantee: grantee{Type: "Group", URI: allUsers}, Permission: "READ"},
+		{Grantee: grantee{Type: "Group", URI: allUsers}, Permission: "WRITE"},
+	},
+	"authenticated-read": {
+		{Grantee: grantee{Type: "Group", URI: authenticatedUser}, Permission: "READ"},
+	},
+}
+
+func (server *Server) getObjectACL(w http.ResponseWriter, r *http.Request, bucket *bucket, key string) error {
+	object, err := server.taggedVersion(r, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	policy := accessControlPolicy{
+		Owner: owner{ID: ownerID},
+		Grants: []grant{
+			{Grantee: grantee{Type: "CanonicalUser", ID: ownerID}, Permission: "FULL_CONTROL"},
+		},
+	}
+	policy.Grants = append(policy.Grants, cannedGrants[object.acl]...)
+
+	for i := range policy.Grants {
+		policy.Grants[i].Grantee.XMLNS = "http://www.w3.org/2001/XMLSchema-instance"
+	}
+
+	return writeXML(w, policy)
+}
+
+// objectURL is the location of an object, as given in responses
+func (server *Server) objectURL(bucketName string, key string) string {
+	return server.URL + "/" + bucketName + "/" + (&url.URL{Path: key}).EscapedPath()
+}
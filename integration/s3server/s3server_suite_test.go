@@ -0,0 +1,13 @@
+package s3server_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestS3Server(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "S3 Server Suite")
+}
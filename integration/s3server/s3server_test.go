@@ -0,0 +1,312 @@
+package s3server_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/smithy-go"
+	"github.com/concourse/s3-resource/integration/s3server"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		ctx    context.Context
+		server *s3server.Server
+		client *s3.Client
+	)
+
+	newClient := func(accessKeyID string) *s3.Client {
+		return s3.New(s3.Options{
+			Region:       server.Region,
+			BaseEndpoint: aws.String(server.URL),
+			UsePathStyle: true,
+			Credentials:  credentials.NewStaticCredentialsProvider(accessKeyID, server.SecretAccessKey, ""),
+		})
+	}
+
+	put := func(bucket string, key string, contents string) string {
+		output, err := client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader([]byte(contents)),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return aws.ToString(output.VersionId)
+	}
+
+	errorCode := func(err error) string {
+		var apiErr smithy.APIError
+		Expect(errors.As(err, &apiErr)).To(BeTrue())
+		return apiErr.ErrorCode()
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = s3server.New("access-key", "secret-key", "us-east-1")
+		server.CreateBucket("bucket", false)
+		server.CreateBucket("versioned-bucket", true)
+		client = newClient(server.AccessKeyID)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("lists objects in pages, rolling up common prefixes", func() {
+		for _, key := range []string{"a/1", "a/2", "b", "c/1", "d"} {
+			put("bucket", key, key)
+		}
+
+		var (
+			keys     []string
+			prefixes []string
+		)
+		paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+			Bucket:    aws.String("bucket"),
+			Delimiter: aws.String("/"),
+			MaxKeys:   aws.Int32(1),
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(aws.ToInt32(page.KeyCount)).To(Equal(int32(1)))
+
+			for _, object := range page.Contents {
+				keys = append(keys, aws.ToString(object.Key))
+			}
+			for _, prefix := range page.CommonPrefixes {
+				prefixes = append(prefixes, aws.ToString(prefix.Prefix))
+			}
+		}
+
+		Expect(keys).To(Equal([]string{"b", "d"}))
+		Expect(prefixes).To(Equal([]string{"a/", "c/"}))
+	})
+
+	It("lists versions and delete markers in pages", func() {
+		first := put("versioned-bucket", "file", "first")
+		second := put("versioned-bucket", "file", "second")
+		other := put("versioned-bucket", "other", "other")
+
+		_, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String("versioned-bucket"),
+			Key:    aws.String("file"),
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		var (
+			versions      []string
+			deleteMarkers int
+		)
+		input := &s3.ListObjectVersionsInput{
+			Bucket:  aws.String("versioned-bucket"),
+			MaxKeys: aws.Int32(2),
+		}
+		for {
+			page, err := client.ListObjectVersions(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, version := range page.Versions {
+				versions = append(versions, aws.ToString(version.VersionId))
+			}
+			for _, marker := range page.DeleteMarkers {
+				Expect(aws.ToBool(marker.IsLatest)).To(BeTrue())
+				deleteMarkers++
+			}
+
+			if !aws.ToBool(page.IsTruncated) {
+				break
+			}
+			input.KeyMarker = page.NextKeyMarker
+			input.VersionIdMarker = page.NextVersionIdMarker
+		}
+
+		Expect(versions).To(Equal([]string{second, first, other}))
+		Expect(deleteMarkers).To(Equal(1))
+
+		_, err = client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String("versioned-bucket"),
+			Key:    aws.String("file"),
+		})
+		Expect(errorCode(err)).To(Equal("NoSuchKey"))
+
+		output, err := client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket:    aws.String("versioned-bucket"),
+			Key:       aws.String("file"),
+			VersionId: aws.String(first),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		contents, err := io.ReadAll(output.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(contents)).To(Equal("first"))
+	})
+
+	It("replaces objects in buckets without versioning", func() {
+		Expect(put("bucket", "file", "first")).To(BeEmpty())
+		put("bucket", "file", "second")
+
+		output, err := client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{Bucket: aws.String("bucket")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(output.Versions).To(HaveLen(1))
+		Expect(aws.ToString(output.Versions[0].VersionId)).To(Equal("null"))
+	})
+
+	It("rejects parts smaller than the minimum besides the last", func() {
+		upload, err := client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("file"),
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		var parts []types.CompletedPart
+		for number := int32(1); number <= 2; number++ {
+			part, err := client.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:     aws.String("bucket"),
+				Key:        aws.String("file"),
+				UploadId:   upload.UploadId,
+				PartNumber: aws.Int32(number),
+				Body:       bytes.NewReader([]byte(fmt.Sprintf("part-%d", number))),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			parts = append(parts, types.CompletedPart{ETag: part.ETag, PartNumber: aws.Int32(number)})
+		}
+
+		_, err = client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String("bucket"),
+			Key:             aws.String("file"),
+			UploadId:        upload.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		})
+		Expect(errorCode(err)).To(Equal("EntityTooSmall"))
+
+		listed, err := client.ListParts(ctx, &s3.ListPartsInput{
+			Bucket:   aws.String("bucket"),
+			Key:      aws.String("file"),
+			UploadId: upload.UploadId,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(listed.Parts).To(HaveLen(2))
+	})
+
+	It("only serves public objects without credentials", func() {
+		put("bucket", "private", "private")
+		_, err := client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("public"),
+			Body:   bytes.NewReader([]byte("public")),
+			ACL:    types.ObjectCannedACLPublicRead,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := http.Get(server.URL + "/bucket/public")
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		resp, err = http.Get(server.URL + "/bucket/private")
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("rejects other access keys", func() {
+		_, err := newClient("other-key").HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("file"),
+		})
+		Expect(err).To(HaveOccurred())
+
+		_, err = newClient("other-key").ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: aws.String("bucket")})
+		Expect(errorCode(err)).To(Equal("InvalidAccessKeyId"))
+	})
+
+	It("rejects requests signed with another secret key", func() {
+		otherClient := s3.New(s3.Options{
+			Region:       server.Region,
+			BaseEndpoint: aws.String(server.URL),
+			UsePathStyle: true,
+			Credentials:  credentials.NewStaticCredentialsProvider(server.AccessKeyID, "other-secret-key", ""),
+		})
+
+		_, err := otherClient.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: aws.String("bucket")})
+		Expect(errorCode(err)).To(Equal("SignatureDoesNotMatch"))
+	})
+
+	It("serves presigned URLs only as they were signed", func() {
+		first := put("versioned-bucket", "file", "first")
+		put("versioned-bucket", "file", "second")
+
+		request, err := s3.NewPresignClient(client).PresignGetObject(ctx, &s3.GetObjectInput{
+			Bucket:    aws.String("versioned-bucket"),
+			Key:       aws.String("file"),
+			VersionId: aws.String(first),
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		get := func(url string) (int, string) {
+			resp, err := http.Get(url)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			return resp.StatusCode, string(body)
+		}
+
+		status, body := get(request.URL)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(Equal("first"))
+
+		// Asking for the latest version instead changes the signed query
+		tampered := strings.Replace(request.URL, "versionId="+first, "versionId=null", 1)
+		Expect(tampered).ToNot(Equal(request.URL))
+		status, body = get(tampered)
+		Expect(status).To(Equal(http.StatusForbidden))
+		Expect(body).To(ContainSubstring("<Code>SignatureDoesNotMatch</Code>"))
+	})
+
+	It("sends the ObjectCreated events of a bucket to the queues notified of them", func() {
+		queueURL := server.CreateQueue("events")
+		server.NotifyQueue("versioned-bucket", queueURL)
+
+		sqsClient := sqs.New(sqs.Options{
+			Region:       server.Region,
+			BaseEndpoint: aws.String(server.URL),
+			Credentials:  credentials.NewStaticCredentialsProvider(server.AccessKeyID, server.SecretAccessKey, ""),
+		})
+
+		put("bucket", "not-notified", "contents")
+		versionID := put("versioned-bucket", "some file", "contents")
+
+		output, err := sqsClient.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(queueURL),
+			MaxNumberOfMessages: 10,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(output.Messages).To(HaveLen(1))
+		Expect(aws.ToString(output.Messages[0].Body)).To(ContainSubstring(`"eventName":"ObjectCreated:Put"`))
+		Expect(aws.ToString(output.Messages[0].Body)).To(ContainSubstring(`"key":"some+file"`))
+		Expect(aws.ToString(output.Messages[0].Body)).To(ContainSubstring(`"versionId":"` + versionID + `"`))
+
+		// Received messages are hidden until they are deleted or time out
+		output, err = sqsClient.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: aws.String(queueURL)})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(output.Messages).To(BeEmpty())
+
+		_, err = sqsClient.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: aws.String(queueURL + "-missing")})
+		Expect(errorCode(err)).To(Equal("QueueDoesNotExist"))
+	})
+})
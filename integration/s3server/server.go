@@ -0,0 +1,409 @@
+// Package s3server is an in-process stand-in for S3, so that the integration
+// suite can run the check, in and out binaries without AWS.
+//
+// It keeps buckets in memory and serves the path-style requests the resource
+// makes: objects with versioning, delete markers, tags, canned ACLs and
+// multipart uploads, and presigned URLs. It also serves SQS queues which
+// receive the ObjectCreated event notifications of buckets. Requests must be
+// signed with its access key, in their Authorization header or their query.
+package s3server
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is an S3 stand-in listening on a local port
+type Server struct {
+	// URL is the endpoint of the server, for path-style requests
+	URL string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	Region          string
+
+	server *httptest.Server
+
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	uploads     map[string]*upload
+	lastVersion int64
+	lastRequest int64
+
+	queues        map[string]*queue
+	notifications map[string][]string
+	lastMessage   int64
+	lastReceipt   int64
+	lastSequencer int64
+}
+
+type bucket struct {
+	versioned bool
+	// objects holds the versions of each key, newest first
+	objects map[string][]*object
+}
+
+type object struct {
+	key          string
+	versionID    string
+	deleteMarker bool
+	lastModified time.Time
+
+	data         []byte
+	etag         string
+	contentType  string
+	storageClass string
+	metadata     map[string]string
+	acl          string
+	tags         map[string]string
+	encryption   encryption
+}
+
+// encryption is how an object is encrypted on the server
+type encryption struct {
+	serverSide     string
+	kmsKeyID       string
+	customerKeyMD5 string
+}
+
+// New starts a server which accepts requests made with accessKeyID and
+// secretAccessKey in region
+func New(accessKeyID string, secretAccessKey string, region string) *Server {
+	server := &Server{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		Region:          region,
+		buckets:         map[string]*bucket{},
+		uploads:         map[string]*upload{},
+		queues:          map[string]*queue{},
+		notifications:   map[string][]string{},
+	}
+
+	server.server = httptest.NewServer(http.HandlerFunc(server.serveHTTP))
+	server.URL = server.server.URL
+
+	return server
+}
+
+// Close shuts the server down, dropping its buckets
+func (server *Server) Close() {
+	server.server.Close()
+}
+
+// CreateBucket creates an empty bucket, with versioning enabled if versioned
+// is set
+func (server *Server) CreateBucket(name string, versioned bool) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.buckets[name] = &bucket{
+		versioned: versioned,
+		objects:   map[string][]*object{},
+	}
+}
+
+// s3Error is an error response, as S3 returns them
+type s3Error struct {
+	XMLName  xml.Name `xml:"Error"`
+	Status   int      `xml:"-"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource,omitempty"`
+}
+
+func (err *s3Error) Error() string {
+	return fmt.Sprintf("%s: %s", err.Code, err.Message)
+}
+
+func newError(status int, code string, message string) *s3Error {
+	return &s3Error{Status: status, Code: code, Message: message}
+}
+
+var (
+	errAccessDenied   = newError(http.StatusForbidden, "AccessDenied", "Access Denied")
+	errNoSuchBucket   = newError(http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+	errNoSuchKey      = newError(http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+	errNoSuchVersion  = newError(http.StatusNotFound, "NoSuchVersion", "The specified version does not exist.")
+	errNoSuchUpload   = newError(http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist.")
+	errNotImplemented = newError(http.StatusNotImplemented, "NotImplemented", "A header or query you provided implies functionality that is not implemented.")
+)
+
+func (server *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.lastRequest++
+	w.Header().Set("x-amz-request-id", strconv.FormatInt(server.lastRequest, 16))
+
+	if operation, ok := strings.CutPrefix(r.Header.Get("X-Amz-Target"), "AmazonSQS."); ok {
+		server.serveSQS(w, r, operation)
+		return
+	}
+
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	err := server.handle(w, r, bucketName, key)
+	if err == nil {
+		return
+	}
+
+	var s3Err *s3Error
+	if !errors.As(err, &s3Err) {
+		s3Err = newError(http.StatusInternalServerError, "InternalError", err.Error())
+	}
+	s3Err.Resource = r.URL.Path
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(s3Err.Status)
+	if r.Method != http.MethodHead {
+		xml.NewEncoder(w).Encode(s3Err)
+	}
+}
+
+func (server *Server) handle(w http.ResponseWriter, r *http.Request, bucketName string, key string) error {
+	anonymous, err := server.authenticate(r)
+	if err != nil {
+		return err
+	}
+
+	bucket, ok := server.buckets[bucketName]
+	if !ok {
+		return errNoSuchBucket
+	}
+
+	// The SDK names some operations in the query, which S3 ignores
+	query := r.URL.Query()
+	query.Del("x-id")
+
+	if anonymous {
+		// Only objects with a public ACL can be read without credentials
+		if key == "" || (r.Method != http.MethodGet && r.Method != http.MethodHead) || len(query) > 0 {
+			return errAccessDenied
+		}
+		return server.getObject(w, r, bucket, key, true)
+	}
+
+	if key == "" {
+		switch {
+		case r.Method == http.MethodHead:
+			w.Header().Set("x-amz-bucket-region", server.Region)
+			return nil
+		case r.Method != http.MethodGet:
+			return errNotImplemented
+		case query.Has("versioning"):
+			return server.getBucketVersioning(w, bucket)
+		case query.Has("versions"):
+			return server.listObjectVersions(w, r, bucketName, bucket)
+		case query.Has("uploads"):
+			return server.listMultipartUploads(w, r, bucketName)
+		case query.Get("list-type") == "2":
+			return server.listObjectsV2(w, r, bucketName, bucket)
+		default:
+			return errNotImplemented
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		switch {
+		case query.Has("tagging"):
+			return server.getObjectTagging(w, r, bucket, key)
+		case query.Has("acl"):
+			return server.getObjectACL(w, r, bucket, key)
+		case query.Has("uploadId"):
+			return server.listParts(w, r, bucketName, key)
+		case query.Has("attributes"), query.Has("retention"), query.Has("legal-hold"):
+			return errNotImplemented
+		default:
+			return server.getObject(w, r, bucket, key, false)
+		}
+	case http.MethodPut:
+		switch {
+		case query.Has("tagging"):
+			return server.putObjectTagging(w, r, bucket, key)
+		case query.Has("uploadId"):
+			return server.uploadPart(w, r, key)
+		case len(query) > 0, r.Header.Get("x-amz-copy-source") != "":
+			return errNotImplemented
+		default:
+			return server.putObject(w, r, bucketName, bucket, key)
+		}
+	case http.MethodPost:
+		switch {
+		case query.Has("uploads"):
+			return server.createMultipartUpload(w, r, bucketName, bucket, key)
+		case query.Has("uploadId"):
+			return server.completeMultipartUpload(w, r, bucketName, bucket, key)
+		default:
+			return errNotImplemented
+		}
+	case http.MethodDelete:
+		if query.Has("uploadId") {
+			return server.abortMultipartUpload(w, r, key)
+		}
+		return server.deleteObject(w, r, bucket, key)
+	default:
+		return errNotImplemented
+	}
+}
+
+func (server *Server) nextVersionID() string {
+	server.lastVersion++
+	return fmt.Sprintf("%020d", server.lastVersion)
+}
+
+// readBody reads the payload of a request, decoding the aws-chunked encoding
+// that streamed uploads use, and checks it against the checksums which came
+// with it. It returns the checksum of the payload, if there was one.
+func readBody(r *http.Request) ([]byte, string, error) {
+	var (
+		body     []byte
+		trailers http.Header
+		err      error
+	)
+	if strings.HasPrefix(r.Header.Get("x-amz-content-sha256"), "STREAMING-") || strings.Contains(r.Header.Get("Content-Encoding"), "aws-chunked") {
+		body, trailers, err = readChunked(bufio.NewReader(r.Body))
+	} else {
+		body, err = io.ReadAll(r.Body)
+	}
+	if err != nil {
+		return nil, "", newError(http.StatusBadRequest, "IncompleteBody", err.Error())
+	}
+
+	if contentMD5 := r.Header.Get("Content-MD5"); contentMD5 != "" {
+		sum := md5.Sum(body)
+		if contentMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+			return nil, "", newError(http.StatusBadRequest, "BadDigest", "The Content-MD5 you specified did not match what we received.")
+		}
+	}
+
+	for _, header := range []http.Header{r.Header, trailers} {
+		for name, values := range header {
+			algorithm, ok := strings.CutPrefix(strings.ToLower(name), "x-amz-checksum-")
+			if !ok || algorithm == "algorithm" || algorithm == "type" {
+				continue
+			}
+
+			checksumHash := newChecksumHash(algorithm)
+			if checksumHash == nil {
+				// CRC64NVME is not in the standard library
+				return body, values[0], nil
+			}
+
+			checksumHash.Write(body)
+			if values[0] != base64.StdEncoding.EncodeToString(checksumHash.Sum(nil)) {
+				return nil, "", newError(http.StatusBadRequest, "BadDigest", fmt.Sprintf("The %s you specified did not match the calculated checksum.", strings.ToUpper(algorithm)))
+			}
+			return body, values[0], nil
+		}
+	}
+
+	return body, "", nil
+}
+
+// readChunked decodes a body in the aws-chunked encoding: chunks of
+// "<size in hex>[;chunk-signature=...]\r\n<data>\r\n", ending with an empty
+// chunk and any trailing headers
+func readChunked(reader *bufio.Reader) ([]byte, http.Header, error) {
+	var body bytes.Buffer
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, nil, err
+		}
+
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid chunk size %q", sizeHex)
+		}
+		if size == 0 {
+			break
+		}
+
+		if _, err := io.CopyN(&body, reader, size); err != nil {
+			return nil, nil, err
+		}
+		if _, err := reader.Discard(2); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	trailers := http.Header{}
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+
+		name, value, _ := strings.Cut(line, ":")
+		trailers.Add(name, strings.TrimSpace(value))
+
+		if err != nil {
+			break
+		}
+	}
+
+	return body.Bytes(), trailers, nil
+}
+
+func newChecksumHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case "crc32":
+		return crc32.NewIEEE()
+	case "crc32c":
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case "sha1":
+		return sha1.New()
+	case "sha256":
+		return sha256.New()
+	default:
+		return nil
+	}
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func writeXML(w http.ResponseWriter, value any) error {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, xml.Header)
+	return xml.NewEncoder(w).Encode(value)
+}
+
+func readXML(r *http.Request, value any) error {
+	body, _, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(body, value); err != nil {
+		return newError(http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	return nil
+}
+
+// timestamp formats times as S3 does in XML documents
+func timestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
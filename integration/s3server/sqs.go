@@ -0,0 +1,310 @@
+package s3server
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// accountID is the account in the URLs of queues
+const accountID = "123456789012"
+
+// defaultVisibilityTimeout is how long received messages are hidden from
+// other receives, as for queues created with the default attributes
+const defaultVisibilityTimeout = 30 * time.Second
+
+type queue struct {
+	messages []*message
+}
+
+type message struct {
+	id            string
+	body          string
+	receiptHandle string
+	visibleAt     time.Time
+}
+
+// CreateQueue creates an empty SQS queue, returning its URL
+func (server *Server) CreateQueue(name string) string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.queues[name] = &queue{}
+	return server.queueURL(name)
+}
+
+// NotifyQueue sends the ObjectCreated event notifications of bucketName to
+// the queue at queueURL, as S3 event notifications do
+func (server *Server) NotifyQueue(bucketName string, queueURL string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.notifications[bucketName] = append(server.notifications[bucketName], queueURL)
+}
+
+// ExpireVisibility makes the messages received from the queue at queueURL
+// visible again, as once their visibility timeout has passed
+func (server *Server) ExpireVisibility(queueURL string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	queue, err := server.findQueue(queueURL)
+	if err != nil {
+		return
+	}
+	for _, message := range queue.messages {
+		message.visibleAt = time.Time{}
+	}
+}
+
+func (server *Server) queueURL(name string) string {
+	return server.URL + "/" + accountID + "/" + name
+}
+
+// findQueue finds the queue at queueURL
+func (server *Server) findQueue(queueURL string) (*queue, error) {
+	prefix := server.queueURL("")
+	name, ok := strings.CutPrefix(queueURL, prefix)
+	if !ok {
+		return nil, errQueueDoesNotExist
+	}
+
+	queue, ok := server.queues[name]
+	if !ok {
+		return nil, errQueueDoesNotExist
+	}
+	return queue, nil
+}
+
+func (queue *queue) send(server *Server, body string) string {
+	server.lastMessage++
+	id := fmt.Sprintf("%08x-0000-4000-8000-%012x", server.lastMessage, server.lastMessage)
+	queue.messages = append(queue.messages, &message{id: id, body: body})
+	return id
+}
+
+// s3Event is an S3 event notification, as S3 sends them to queues
+type s3Event struct {
+	Records []s3EventRecord `json:"Records"`
+}
+
+type s3EventRecord struct {
+	EventVersion string `json:"eventVersion"`
+	EventSource  string `json:"eventSource"`
+	AWSRegion    string `json:"awsRegion"`
+	EventTime    string `json:"eventTime"`
+	EventName    string `json:"eventName"`
+	S3           struct {
+		SchemaVersion string `json:"s3SchemaVersion"`
+		Bucket        struct {
+			Name string `json:"name"`
+			ARN  string `json:"arn"`
+		} `json:"bucket"`
+		Object struct {
+			Key       string `json:"key"`
+			Size      int    `json:"size"`
+			ETag      string `json:"eTag"`
+			VersionID string `json:"versionId,omitempty"`
+			Sequencer string `json:"sequencer"`
+		} `json:"object"`
+	} `json:"s3"`
+}
+
+// notify sends an event notification of object to the queues notified of
+// the events of bucketName
+func (server *Server) notify(bucketName string, eventName string, object *object) {
+	queueURLs := server.notifications[bucketName]
+	if len(queueURLs) == 0 {
+		return
+	}
+
+	server.lastSequencer++
+
+	record := s3EventRecord{
+		EventVersion: "2.1",
+		EventSource:  "aws:s3",
+		AWSRegion:    server.Region,
+		EventTime:    timestamp(object.lastModified),
+		EventName:    eventName,
+	}
+	record.S3.SchemaVersion = "1.0"
+	record.S3.Bucket.Name = bucketName
+	record.S3.Bucket.ARN = "arn:aws:s3:::" + bucketName
+	// Keys are URL encoded, with spaces as "+"
+	record.S3.Object.Key = url.QueryEscape(object.key)
+	record.S3.Object.Size = len(object.data)
+	record.S3.Object.ETag = strings.Trim(object.etag, `"`)
+	if object.versionID != "null" {
+		record.S3.Object.VersionID = object.versionID
+	}
+	record.S3.Object.Sequencer = fmt.Sprintf("%016X", server.lastSequencer)
+
+	body, _ := json.Marshal(s3Event{Records: []s3EventRecord{record}})
+	for _, queueURL := range queueURLs {
+		if queue, err := server.findQueue(queueURL); err == nil {
+			queue.send(server, string(body))
+		}
+	}
+}
+
+// sqsError is an error response, as SQS returns them with its JSON protocol
+type sqsError struct {
+	Status  int    `json:"-"`
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+func (err *sqsError) Error() string {
+	return fmt.Sprintf("%s: %s", err.Type, err.Message)
+}
+
+func newSQSError(status int, code string, message string) *sqsError {
+	return &sqsError{Status: status, Type: "com.amazonaws.sqs#" + code, Message: message}
+}
+
+var (
+	errQueueDoesNotExist     = newSQSError(http.StatusBadRequest, "QueueDoesNotExist", "The specified queue does not exist.")
+	errInvalidAction         = newSQSError(http.StatusBadRequest, "InvalidAction", "The action or operation requested is invalid.")
+	errMissingAuthentication = newSQSError(http.StatusBadRequest, "MissingAuthenticationToken", "Request is missing Authentication Token")
+	errInvalidClientToken    = newSQSError(http.StatusForbidden, "InvalidClientTokenId", "The security token included in the request is invalid.")
+	errSignatureMismatch     = newSQSError(http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.")
+)
+
+// serveSQS serves the requests of the SQS JSON protocol, which name their
+// operation in the X-Amz-Target header
+func (server *Server) serveSQS(w http.ResponseWriter, r *http.Request, operation string) {
+	response, err := server.handleSQS(r, operation)
+	if err != nil {
+		sqsErr, ok := err.(*sqsError)
+		if !ok {
+			sqsErr = newSQSError(http.StatusInternalServerError, "InternalFailure", err.Error())
+		}
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.WriteHeader(sqsErr.Status)
+		json.NewEncoder(w).Encode(sqsErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (server *Server) handleSQS(r *http.Request, operation string) (any, error) {
+	if anonymous, err := server.authenticate(r); anonymous {
+		return nil, errMissingAuthentication
+	} else if err == errSignatureDoesNotMatch {
+		return nil, errSignatureMismatch
+	} else if err != nil {
+		return nil, errInvalidClientToken
+	}
+
+	var request struct {
+		QueueURL            string `json:"QueueUrl"`
+		MessageBody         string `json:"MessageBody"`
+		MaxNumberOfMessages int    `json:"MaxNumberOfMessages"`
+		VisibilityTimeout   *int   `json:"VisibilityTimeout"`
+		Entries             []struct {
+			ID            string `json:"Id"`
+			ReceiptHandle string `json:"ReceiptHandle"`
+		} `json:"Entries"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, newSQSError(http.StatusBadRequest, "MalformedInput", err.Error())
+	}
+
+	queue, err := server.findQueue(request.QueueURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation {
+	case "SendMessage":
+		body := request.MessageBody
+		sum := md5.Sum([]byte(body))
+		return map[string]string{
+			"MessageId":        queue.send(server, body),
+			"MD5OfMessageBody": hex.EncodeToString(sum[:]),
+		}, nil
+
+	case "ReceiveMessage":
+		maxMessages := request.MaxNumberOfMessages
+		if maxMessages == 0 {
+			maxMessages = 1
+		}
+		if maxMessages < 1 || maxMessages > 10 {
+			return nil, newSQSError(http.StatusBadRequest, "InvalidParameterValue", "Value for parameter MaxNumberOfMessages is invalid. Reason: Must be between 1 and 10, if provided.")
+		}
+		visibilityTimeout := defaultVisibilityTimeout
+		if request.VisibilityTimeout != nil {
+			visibilityTimeout = time.Duration(*request.VisibilityTimeout) * time.Second
+		}
+
+		// Messages are received at once, however long the request waits
+		now := time.Now()
+		messages := []map[string]string{}
+		for _, message := range queue.messages {
+			if len(messages) == maxMessages {
+				break
+			}
+			if now.Before(message.visibleAt) {
+				continue
+			}
+
+			server.lastReceipt++
+			message.receiptHandle = fmt.Sprintf("%s#%d", message.id, server.lastReceipt)
+			message.visibleAt = now.Add(visibilityTimeout)
+
+			sum := md5.Sum([]byte(message.body))
+			messages = append(messages, map[string]string{
+				"MessageId":     message.id,
+				"ReceiptHandle": message.receiptHandle,
+				"MD5OfBody":     hex.EncodeToString(sum[:]),
+				"Body":          message.body,
+			})
+		}
+		return map[string]any{"Messages": messages}, nil
+
+	case "DeleteMessageBatch":
+		if len(request.Entries) == 0 || len(request.Entries) > 10 {
+			return nil, newSQSError(http.StatusBadRequest, "TooManyEntriesInBatchRequest", "Maximum number of entries per request are 10.")
+		}
+
+		successful := []map[string]string{}
+		failed := []map[string]any{}
+		for _, entry := range request.Entries {
+			if queue.delete(entry.ReceiptHandle) {
+				successful = append(successful, map[string]string{"Id": entry.ID})
+			} else {
+				failed = append(failed, map[string]any{
+					"Id":          entry.ID,
+					"Code":        "ReceiptHandleIsInvalid",
+					"Message":     "The input receipt handle is invalid.",
+					"SenderFault": true,
+				})
+			}
+		}
+		return map[string]any{"Successful": successful, "Failed": failed}, nil
+
+	default:
+		return nil, errInvalidAction
+	}
+}
+
+// delete deletes the received message receiptHandle was given for
+func (queue *queue) delete(receiptHandle string) bool {
+	id, _, _ := strings.Cut(receiptHandle, "#")
+	for i, message := range queue.messages {
+		if message.id == id && message.receiptHandle != "" {
+			queue.messages = append(queue.messages[:i], queue.messages[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
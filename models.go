@@ -1,12 +1,22 @@
 package s3resource
 
 import (
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
+var accountIDPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
 type Source struct {
 	AccessKeyID         string `json:"access_key_id"`
 	SecretAccessKey     string `json:"secret_access_key"`
@@ -18,27 +28,83 @@ type Source struct {
 	Private             bool   `json:"private"`
 	RegionName          string `json:"region_name"`
 	UseAwsCredsProvider bool   `json:"enable_aws_creds_provider"`
+	AwsProfile          string `json:"aws_profile"`
+	AwsConfig           string `json:"aws_config"`
+	AwsCredentials      string `json:"aws_credentials"`
 	//Deprecated: Not needed since upgrading to the v2 AWS Go SDK
-	CloudfrontURL        string `json:"cloudfront_url"`
-	Endpoint             string `json:"endpoint"`
-	DisableSSL           bool   `json:"disable_ssl"`
-	ServerSideEncryption string `json:"server_side_encryption"`
-	SSEKMSKeyId          string `json:"sse_kms_key_id"`
-	UseV2Signing         bool   `json:"use_v2_signing"`
-	SkipSSLVerification  bool   `json:"skip_ssl_verification"`
-	CABundle             string `json:"ca_bundle"`
-	SkipDownload         bool   `json:"skip_download"`
-	InitialVersion       string `json:"initial_version"`
-	InitialPath          string `json:"initial_path"`
-	InitialContentText   string `json:"initial_content_text"`
-	InitialContentBinary string `json:"initial_content_binary"`
-	DisableMultipart     bool   `json:"disable_multipart"`
-	UsePathStyle         bool   `json:"use_path_style"`
-	SkipS3Checksums      bool   `json:"skip_s3_checksums"`
-	ChecksumAlgorithm    string `json:"checksum_algorithm"`
+	CloudfrontURL        string               `json:"cloudfront_url"`
+	Endpoint             string               `json:"endpoint"`
+	DisableSSL           bool                 `json:"disable_ssl"`
+	ServerSideEncryption string               `json:"server_side_encryption"`
+	SSEKMSKeyId          string               `json:"sse_kms_key_id"`
+	SSECustomerKey       string               `json:"sse_customer_key"`
+	SSECustomerAlgorithm string               `json:"sse_customer_algorithm"`
+	ClientSideEncryption ClientSideEncryption `json:"client_side_encryption"`
+	ObjectLockMode       string               `json:"object_lock_mode"`
+	ObjectLockRetention  string               `json:"object_lock_retention"`
+	ObjectLockLegalHold  bool                 `json:"object_lock_legal_hold"`
+	StorageClass         string               `json:"storage_class"`
+	RequesterPays        bool                 `json:"requester_pays"`
+	ExpectedBucketOwner  string               `json:"expected_bucket_owner"`
+	UseV2Signing         bool                 `json:"use_v2_signing"`
+	SkipSSLVerification  bool                 `json:"skip_ssl_verification"`
+	CABundle             string               `json:"ca_bundle"`
+	ClientCert           string               `json:"client_cert"`
+	ClientKey            string               `json:"client_key"`
+	TLSMinVersion        string               `json:"tls_min_version"`
+	TLSCipherSuites      []string             `json:"tls_cipher_suites"`
+	HTTPProxy            string               `json:"http_proxy"`
+	HTTPSProxy           string               `json:"https_proxy"`
+	NoProxy              string               `json:"no_proxy"`
+	ProxyUsername        string               `json:"proxy_username"`
+	ProxyPassword        string               `json:"proxy_password"`
+	ProxyCABundle        string               `json:"proxy_ca_bundle"`
+	SkipDownload         bool                 `json:"skip_download"`
+	InitialVersion       string               `json:"initial_version"`
+	InitialPath          string               `json:"initial_path"`
+	InitialContentText   string               `json:"initial_content_text"`
+	InitialContentBinary string               `json:"initial_content_binary"`
+	DisableMultipart     bool                 `json:"disable_multipart"`
+	UsePathStyle         bool                 `json:"use_path_style"`
+	UseAccelerate        bool                 `json:"use_accelerate_endpoint"`
+	UseDualStack         bool                 `json:"use_dualstack"`
+	UseFIPS              bool                 `json:"use_fips"`
+	SkipS3Checksums      bool                 `json:"skip_s3_checksums"`
+	ChecksumAlgorithm    string               `json:"checksum_algorithm"`
+	UploadPartSize       string               `json:"upload_part_size"`
+	UploadConcurrency    int                  `json:"upload_concurrency"`
+	DownloadPartSize     string               `json:"download_part_size"`
+	DownloadConcurrency  int                  `json:"download_concurrency"`
+
+	MaxBandwidth                string     `json:"max_bandwidth"`
+	Retry                       Retry      `json:"retry"`
+	Timeouts                    Timeouts   `json:"timeouts"`
+	ResumeUploads               bool       `json:"resume_uploads"`
+	AbortIncompleteUploadsAfter string     `json:"abort_incomplete_uploads_after"`
+	Debug                       DebugLevel `json:"debug"`
+
+	Progress         ProgressMode `json:"progress"`
+	ProgressInterval string       `json:"progress_interval"`
+
+	Preflight bool `json:"preflight"`
+
+	Provider    Provider `json:"provider"`
+	DisableACLs bool     `json:"disable_acls"`
+
+	Inventory Inventory `json:"inventory"`
+
+	SQSQueueURL        string `json:"sqs_queue_url"`
+	SQSListingInterval string `json:"sqs_listing_interval"`
+
+	// givenFields are the fields given in the JSON the source was decoded
+	// from, which the provider doesn't override
+	givenFields map[string]bool
 }
 
 func (source Source) IsValid() (bool, string) {
+	// the provider's settings are validated with the ones which are given
+	source.ApplyProvider()
+
 	if source.Regexp != "" && source.VersionedFile != "" {
 		return false, "please specify either regexp or versioned_file"
 	}
@@ -51,6 +117,10 @@ func (source Source) IsValid() (bool, string) {
 		return false, "please use initial_version when versioned_file is set"
 	}
 
+	if source.AwsProfile != "" && source.AccessKeyID != "" {
+		return false, "please use either access_key_id or aws_profile but not both"
+	}
+
 	if source.SkipSSLVerification && source.CABundle != "" {
 		return false, "please do not use ca_bundle when skip_ssl_verification is set"
 	}
@@ -64,25 +134,134 @@ func (source Source) IsValid() (bool, string) {
 		return false, "please specify initial_version or initial_path if initial content is set"
 	}
 
-	// Validate checksum algorithm if specified
-	if source.ChecksumAlgorithm != "" {
-		validAlgorithms := types.ChecksumAlgorithm("").Values()
+	if source.SSECustomerKey != "" {
+		if source.ServerSideEncryption != "" || source.SSEKMSKeyId != "" {
+			return false, "please do not use server_side_encryption or sse_kms_key_id when sse_customer_key is set"
+		}
+
+		key, err := base64.StdEncoding.DecodeString(source.SSECustomerKey)
+		if err != nil || len(key) != 32 {
+			return false, "sse_customer_key must be a base64 encoded 256-bit key"
+		}
+	}
+
+	if source.SSECustomerAlgorithm != "" {
+		if source.SSECustomerKey == "" {
+			return false, "please specify sse_customer_key when sse_customer_algorithm is set"
+		}
+
+		if source.SSECustomerAlgorithm != DefaultSSECustomerAlgorithm {
+			return false, "sse_customer_algorithm must be " + DefaultSSECustomerAlgorithm
+		}
+	}
+
+	if source.ClientSideEncryption.Key != "" {
+		if source.ClientSideEncryption.KMSKeyID != "" {
+			return false, "please use either client_side_encryption.key or client_side_encryption.kms_key_id but not both"
+		}
+
+		key, err := base64.StdEncoding.DecodeString(source.ClientSideEncryption.Key)
+		if err != nil || len(key) != 32 {
+			return false, "client_side_encryption.key must be a base64 encoded 256-bit key"
+		}
+	}
+
+	if ok, message := ValidateObjectLock(source.ObjectLockMode, source.ObjectLockRetention); !ok {
+		return false, message
+	}
+
+	if ok, message := ValidateStorageClass(source.StorageClass); !ok {
+		return false, message
+	}
+
+	if source.UseAccelerate {
+		if source.UsePathStyle {
+			return false, "use_accelerate_endpoint cannot be used with use_path_style"
+		}
+		if source.UseFIPS {
+			return false, "use_accelerate_endpoint cannot be used with use_fips"
+		}
+	}
+
+	if source.Endpoint != "" && (source.UseAccelerate || source.UseDualStack || source.UseFIPS) {
+		return false, "use_accelerate_endpoint, use_dualstack and use_fips cannot be used with endpoint"
+	}
+
+	if ok, message := source.Retry.validate(); !ok {
+		return false, message
+	}
+
+	if ok, message := source.Timeouts.validate(); !ok {
+		return false, message
+	}
+
+	if ok, message := source.validateTLS(); !ok {
+		return false, message
+	}
+
+	if ok, message := source.validateProxy(); !ok {
+		return false, message
+	}
 
-		valid := false
-		for _, alg := range validAlgorithms {
-			if string(alg) == source.ChecksumAlgorithm {
-				valid = true
-				break
-			}
+	if ok, message := source.validateProvider(); !ok {
+		return false, message
+	}
+
+	if ok, message := source.validateSQS(); !ok {
+		return false, message
+	}
+
+	if source.Inventory.IsEnabled() {
+		if source.Regexp == "" {
+			return false, "inventory can only be used with regexp"
+		}
+		if source.Inventory.Prefix == "" {
+			return false, "please specify inventory.prefix, the path of the inventory configuration in its destination bucket"
+		}
+	}
+
+	if source.Progress != "" {
+		if ok, message := validateOneOf("progress", string(source.Progress), progressModes); !ok {
+			return false, message
+		}
+	}
+
+	if source.ProgressInterval != "" {
+		if _, err := ParseProgressInterval(source.ProgressInterval); err != nil {
+			return false, "progress_interval must be a duration (e.g. 30s) or a percentage (e.g. 10%)"
 		}
+	}
 
-		if !valid {
-			validValues := make([]string, len(validAlgorithms))
-			for i, alg := range validAlgorithms {
-				validValues[i] = string(alg)
-			}
-			sort.Strings(validValues)
-			return false, "checksum_algorithm must be one of: " + strings.Join(validValues, ", ")
+	if source.Debug != "" {
+		if ok, message := validateOneOf("debug", string(source.Debug), debugLevels); !ok {
+			return false, message
+		}
+	}
+
+	if source.ExpectedBucketOwner != "" && !accountIDPattern.MatchString(source.ExpectedBucketOwner) {
+		return false, "expected_bucket_owner must be a 12 digit AWS account ID"
+	}
+
+	if ok, message := ValidateTransfer(source.UploadPartSize, source.UploadConcurrency, source.DownloadPartSize, source.DownloadConcurrency); !ok {
+		return false, message
+	}
+
+	if source.MaxBandwidth != "" {
+		if bandwidth, err := ParseByteSize(source.MaxBandwidth); err != nil || bandwidth <= 0 {
+			return false, "max_bandwidth must be a positive number of bytes per second (e.g. 50MiB)"
+		}
+	}
+
+	if source.AbortIncompleteUploadsAfter != "" {
+		if d, err := ParseDurationOrDays(source.AbortIncompleteUploadsAfter); err != nil || d <= 0 {
+			return false, "abort_incomplete_uploads_after must be a positive duration (e.g. 24h or 7d)"
+		}
+	}
+
+	// Validate checksum algorithm if specified
+	if source.ChecksumAlgorithm != "" {
+		if ok, message := validateOneOf("checksum_algorithm", source.ChecksumAlgorithm, types.ChecksumAlgorithm("").Values()); !ok {
+			return false, message
 		}
 
 		if source.SkipS3Checksums {
@@ -93,6 +272,275 @@ func (source Source) IsValid() (bool, string) {
 	return true, ""
 }
 
+func (source Source) validateProxy() (bool, string) {
+	proxies := []struct{ field, proxy string }{
+		{"http_proxy", source.HTTPProxy},
+		{"https_proxy", source.HTTPSProxy},
+	}
+	for _, p := range proxies {
+		if p.proxy == "" {
+			continue
+		}
+		if _, err := parseProxyURL(p.proxy); err != nil {
+			return false, fmt.Sprintf("%s must be a proxy URL such as http://proxy.example.com:3128: %s", p.field, err)
+		}
+	}
+
+	if (source.ProxyUsername != "" || source.ProxyPassword != "") && source.HTTPProxy == "" && source.HTTPSProxy == "" {
+		return false, "proxy_username and proxy_password require http_proxy or https_proxy"
+	}
+
+	if source.ProxyCABundle != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(source.ProxyCABundle)) {
+		return false, "proxy_ca_bundle must contain at least one PEM encoded certificate"
+	}
+
+	return true, ""
+}
+
+func (source Source) validateSQS() (bool, string) {
+	if source.SQSQueueURL != "" {
+		u, err := url.Parse(source.SQSQueueURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return false, "sqs_queue_url must be the URL of an SQS queue, e.g. https://sqs.us-east-1.amazonaws.com/123456789012/queue-name"
+		}
+	}
+
+	if source.SQSListingInterval != "" {
+		if source.SQSQueueURL == "" {
+			return false, "sqs_listing_interval requires sqs_queue_url"
+		}
+		if d, err := time.ParseDuration(source.SQSListingInterval); err != nil || d <= 0 {
+			return false, "sqs_listing_interval must be a positive duration (e.g. 1h)"
+		}
+	}
+
+	return true, ""
+}
+
+// Retry configures how failed S3 and STS requests are retried
+type Retry struct {
+	MaxAttempts int    `json:"max_attempts"`
+	MaxBackoff  string `json:"max_backoff"`
+	Mode        string `json:"mode"`
+}
+
+func (retry Retry) validate() (bool, string) {
+	if retry.MaxAttempts < 0 {
+		return false, "retry.max_attempts must be at least 1"
+	}
+
+	if retry.MaxBackoff != "" {
+		if d, err := time.ParseDuration(retry.MaxBackoff); err != nil || d <= 0 {
+			return false, "retry.max_backoff must be a positive duration (e.g. 20s)"
+		}
+	}
+
+	if retry.Mode != "" {
+		return validateOneOf("retry.mode", retry.Mode, []aws.RetryMode{aws.RetryModeStandard, aws.RetryModeAdaptive})
+	}
+
+	return true, ""
+}
+
+// Timeouts limit how long S3 and STS calls may take. Connect and Request
+// apply to each HTTP request, where Request only covers waiting for the
+// response headers, while Operation applies to the whole check, get or put.
+type Timeouts struct {
+	Connect   string `json:"connect"`
+	Request   string `json:"request"`
+	Operation string `json:"operation"`
+}
+
+func (timeouts Timeouts) validate() (bool, string) {
+	durations := []struct{ field, value string }{
+		{"timeouts.connect", timeouts.Connect},
+		{"timeouts.request", timeouts.Request},
+		{"timeouts.operation", timeouts.Operation},
+	}
+	for _, duration := range durations {
+		if duration.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(duration.value); err != nil || d <= 0 {
+			return false, duration.field + " must be a positive duration (e.g. 30s)"
+		}
+	}
+
+	return true, ""
+}
+
+// Inventory points at the reports of an S3 Inventory configuration of the
+// bucket. Prefix is the path under which S3 writes the dated manifests,
+// i.e. "<destination prefix>/<source bucket>/<configuration ID>". Bucket is
+// the destination bucket and defaults to the bucket of the source.
+type Inventory struct {
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix"`
+}
+
+func (inventory Inventory) IsEnabled() bool {
+	return inventory.Bucket != "" || inventory.Prefix != ""
+}
+
+// ClientSideEncryption configures encryption of objects before they are
+// uploaded. Each object is encrypted with its own data key, which is wrapped
+// either by a KMS key or by a static key.
+type ClientSideEncryption struct {
+	KMSKeyID string `json:"kms_key_id"`
+	Key      string `json:"key"`
+}
+
+// ValidateStorageClass checks that storageClass, if given, is one of the
+// storage classes known to the SDK
+func ValidateStorageClass(storageClass string) (bool, string) {
+	if storageClass == "" {
+		return true, ""
+	}
+
+	return validateOneOf("storage_class", storageClass, types.StorageClass("").Values())
+}
+
+// ValidateRestoreTier checks that tier is one of the retrieval tiers for
+// restoring archived objects
+func ValidateRestoreTier(tier string) (bool, string) {
+	return validateOneOf("restore_tier", tier, types.Tier("").Values())
+}
+
+// validateOneOf checks that value is one of the values of an SDK enum type
+func validateOneOf[T ~string](field string, value string, validValues []T) (bool, string) {
+	for _, validValue := range validValues {
+		if string(validValue) == value {
+			return true, ""
+		}
+	}
+
+	names := make([]string, len(validValues))
+	for i, validValue := range validValues {
+		names[i] = string(validValue)
+	}
+	sort.Strings(names)
+
+	return false, field + " must be one of: " + strings.Join(names, ", ")
+}
+
+// ValidateObjectLock checks that an Object Lock mode and retention period are
+// valid and given together, as S3 requires both to place an object under
+// retention.
+func ValidateObjectLock(mode string, retention string) (bool, string) {
+	if mode == "" && retention == "" {
+		return true, ""
+	}
+
+	if mode == "" || retention == "" {
+		return false, "please specify both object_lock_mode and object_lock_retention"
+	}
+
+	if ok, message := validateOneOf("object_lock_mode", mode, types.ObjectLockMode("").Values()); !ok {
+		return false, message
+	}
+
+	if _, err := ParseObjectLockRetention(retention); err != nil {
+		return false, err.Error()
+	}
+
+	return true, ""
+}
+
+// ParseObjectLockRetention parses a retention period. Besides the units
+// understood by time.ParseDuration, whole days can be given with a "d"
+// suffix (e.g. "30d").
+func ParseObjectLockRetention(retention string) (time.Duration, error) {
+	duration, err := ParseDurationOrDays(retention)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("object_lock_retention must be a positive duration (e.g. 720h or 30d): %s", retention)
+	}
+
+	return duration, nil
+}
+
+// ParseDurationOrDays parses a duration understood by time.ParseDuration, or
+// a number of whole days with a "d" suffix (e.g. "30d")
+func ParseDurationOrDays(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+const (
+	// MinUploadPartSize and MaxUploadPartSize are the S3 limits on the size
+	// of every part of a multipart upload but the last
+	MinUploadPartSize = 5 * 1024 * 1024
+	MaxUploadPartSize = 5 * 1024 * 1024 * 1024
+)
+
+var byteSizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"KiB": 1024,
+	"MiB": 1024 * 1024,
+	"GiB": 1024 * 1024 * 1024,
+}
+
+// ParseByteSize parses a number of bytes with an optional unit, such as
+// "16MiB" or "100MB"
+func ParseByteSize(size string) (int64, error) {
+	trimmed := strings.TrimSpace(size)
+	unitStart := strings.IndexFunc(trimmed, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	if unitStart == -1 {
+		unitStart = len(trimmed)
+	}
+
+	multiplier, ok := byteSizeUnits[strings.TrimSpace(trimmed[unitStart:])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size: %s", size)
+	}
+
+	n, err := strconv.ParseInt(trimmed[:unitStart], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size: %s", size)
+	}
+
+	return n * multiplier, nil
+}
+
+// ValidateTransfer checks the part sizes and concurrency of uploads and
+// downloads. Empty sizes and zero concurrency select the defaults.
+func ValidateTransfer(uploadPartSize string, uploadConcurrency int, downloadPartSize string, downloadConcurrency int) (bool, string) {
+	if uploadPartSize != "" {
+		size, err := ParseByteSize(uploadPartSize)
+		if err != nil || size < MinUploadPartSize || size > MaxUploadPartSize {
+			return false, "upload_part_size must be between 5MiB and 5GiB"
+		}
+	}
+
+	if downloadPartSize != "" {
+		size, err := ParseByteSize(downloadPartSize)
+		if err != nil || size <= 0 {
+			return false, "download_part_size must be a positive size (e.g. 64MiB)"
+		}
+	}
+
+	if uploadConcurrency < 0 {
+		return false, "upload_concurrency must be at least 1"
+	}
+	if downloadConcurrency < 0 {
+		return false, "download_concurrency must be at least 1"
+	}
+
+	return true, ""
+}
+
 type Version struct {
 	Path      string `json:"path,omitempty"`
 	VersionID string `json:"version_id,omitempty"`
@@ -0,0 +1,32 @@
+package s3resource_test
+
+import (
+	s3resource "github.com/concourse/s3-resource"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseByteSize", func() {
+	DescribeTable("parsing sizes",
+		func(size string, expected int64) {
+			Expect(s3resource.ParseByteSize(size)).To(Equal(expected))
+		},
+		Entry("bytes", "1024", int64(1024)),
+		Entry("bytes with a unit", "1024B", int64(1024)),
+		Entry("decimal units", "100MB", int64(100*1000*1000)),
+		Entry("binary units", "16MiB", int64(16*1024*1024)),
+		Entry("a space before the unit", "5 GiB", int64(5*1024*1024*1024)),
+	)
+
+	DescribeTable("rejecting invalid sizes",
+		func(size string) {
+			_, err := s3resource.ParseByteSize(size)
+			Expect(err).To(MatchError("invalid size: " + size))
+		},
+		Entry("empty", ""),
+		Entry("an unknown unit", "16XB"),
+		Entry("no number", "MiB"),
+		Entry("a fraction", "1.5GiB"),
+	)
+})
@@ -0,0 +1,489 @@
+package s3resource
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// abortUploadTimeout bounds aborting a failed upload, which may happen after
+// the step was asked to stop
+const abortUploadTimeout = 30 * time.Second
+
+// inverted NVME polynomial as required by crc64.MakeTable
+const crc64NVME = 0x9a6c_9329_ac4b_c9b5
+
+// uploadSettingsKey is the metadata key under which resumable uploads record
+// a digest of the settings they were started with
+const uploadSettingsKey = "s3-resource-upload-settings"
+
+// resumableUpload is an incomplete multipart upload whose parts match the
+// file being uploaded
+type resumableUpload struct {
+	uploadID          string
+	checksumAlgorithm types.ChecksumAlgorithm
+	partSize          int64
+	parts             []types.CompletedPart
+}
+
+// listMultipartUploads returns the incomplete multipart uploads of the keys
+// starting with prefix
+func (client *s3client) listMultipartUploads(ctx context.Context, bucketName string, prefix string) ([]types.MultipartUpload, error) {
+	paginator := s3.NewListMultipartUploadsPaginator(client.client, &s3.ListMultipartUploadsInput{
+		Bucket:              aws.String(bucketName),
+		Prefix:              aws.String(prefix),
+		RequestPayer:        client.requestPayer,
+		ExpectedBucketOwner: client.expectedBucketOwner,
+	})
+
+	var uploads []types.MultipartUpload
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing multipart uploads: %w", err)
+		}
+		uploads = append(uploads, page.Uploads...)
+	}
+
+	return uploads, nil
+}
+
+// abortStaleUploads aborts the incomplete multipart uploads of remotePath
+// which were started more than olderThan ago. Uploads of other keys may be
+// in progress in other pipelines, so they are left alone.
+func (client *s3client) abortStaleUploads(ctx context.Context, bucketName string, remotePath string, olderThan time.Duration) error {
+	uploads, err := client.listMultipartUploads(ctx, bucketName, remotePath)
+	if err != nil {
+		return err
+	}
+
+	threshold := time.Now().Add(-olderThan)
+	for _, upload := range uploads {
+		if aws.ToString(upload.Key) != remotePath || upload.Initiated == nil || !upload.Initiated.Before(threshold) {
+			continue
+		}
+
+		_, err := client.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:              aws.String(bucketName),
+			Key:                 upload.Key,
+			UploadId:            upload.UploadId,
+			RequestPayer:        client.requestPayer,
+			ExpectedBucketOwner: client.expectedBucketOwner,
+		})
+		if err != nil {
+			return fmt.Errorf("error aborting multipart upload of %s: %w", aws.ToString(upload.Key), err)
+		}
+
+		fmt.Fprintf(client.progressOutput, "aborted multipart upload of %s started at %s\n", aws.ToString(upload.Key), upload.Initiated.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// abortFailedUpload aborts the multipart upload which failed with err, so that
+// its parts are not left in the bucket. The upload may have failed because
+// ctx was cancelled, so the abort is given a context of its own.
+func (client *s3client) abortFailedUpload(ctx context.Context, uploadInput *s3.PutObjectInput, err error) {
+	var failure manager.MultiUploadFailure
+	if !errors.As(err, &failure) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), abortUploadTimeout)
+	defer cancel()
+
+	_, abortErr := client.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:              uploadInput.Bucket,
+		Key:                 uploadInput.Key,
+		UploadId:            aws.String(failure.UploadID()),
+		RequestPayer:        client.requestPayer,
+		ExpectedBucketOwner: client.expectedBucketOwner,
+	})
+	if abortErr != nil {
+		fmt.Fprintf(client.progressOutput, "error aborting multipart upload %s: %s\n", failure.UploadID(), abortErr)
+	}
+}
+
+// uploadSettings is a digest of the settings the object of uploadInput is
+// created with. S3 returns neither the settings nor the metadata of
+// incomplete uploads, so the digest is recorded in the metadata of the
+// upload and compared once it has been completed.
+func uploadSettings(uploadInput *s3.PutObjectInput) string {
+	var metadata []string
+	for key, value := range uploadInput.Metadata {
+		if key != uploadSettingsKey {
+			metadata = append(metadata, key+"="+value)
+		}
+	}
+	sort.Strings(metadata)
+
+	var retainUntilDate string
+	if uploadInput.ObjectLockRetainUntilDate != nil {
+		retainUntilDate = uploadInput.ObjectLockRetainUntilDate.Format(time.RFC3339)
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		string(uploadInput.ACL),
+		aws.ToString(uploadInput.ContentType),
+		string(uploadInput.ServerSideEncryption),
+		aws.ToString(uploadInput.SSEKMSKeyId),
+		aws.ToString(uploadInput.SSECustomerKeyMD5),
+		string(uploadInput.StorageClass),
+		string(uploadInput.ObjectLockMode),
+		retainUntilDate,
+		string(uploadInput.ObjectLockLegalHoldStatus),
+		strings.Join(metadata, "\x00"),
+	}, "\x01")))
+	return hex.EncodeToString(sum[:])
+}
+
+// resumedWithSettings checks that the object completed from a resumed upload
+// recorded the settings of uploadInput. Otherwise the upload was started
+// with other settings, and the object is deleted from versioned buckets so
+// that it is uploaded again without leaving a version with those settings.
+func (client *s3client) resumedWithSettings(ctx context.Context, uploadInput *s3.PutObjectInput, versionID string) (bool, error) {
+	input := &s3.HeadObjectInput{
+		Bucket:               uploadInput.Bucket,
+		Key:                  uploadInput.Key,
+		SSECustomerAlgorithm: uploadInput.SSECustomerAlgorithm,
+		SSECustomerKey:       uploadInput.SSECustomerKey,
+		SSECustomerKeyMD5:    uploadInput.SSECustomerKeyMD5,
+		RequestPayer:         client.requestPayer,
+		ExpectedBucketOwner:  client.expectedBucketOwner,
+	}
+	if versionID != "" {
+		input.VersionId = aws.String(versionID)
+	}
+
+	output, err := client.client.HeadObject(ctx, input)
+	if err != nil {
+		return false, fmt.Errorf("error checking resumed upload: %w", err)
+	}
+	if output.Metadata[uploadSettingsKey] == uploadInput.Metadata[uploadSettingsKey] {
+		return true, nil
+	}
+
+	fmt.Fprintln(client.progressOutput, "the resumed upload was started with other settings, uploading the file again")
+	if versionID == "" {
+		return false, nil
+	}
+
+	_, err = client.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:              uploadInput.Bucket,
+		Key:                 uploadInput.Key,
+		VersionId:           aws.String(versionID),
+		RequestPayer:        client.requestPayer,
+		ExpectedBucketOwner: client.expectedBucketOwner,
+	})
+	if err != nil {
+		fmt.Fprintf(client.progressOutput, "error deleting version %s of the resumed upload: %s\n", versionID, err)
+	}
+	return false, nil
+}
+
+// findResumableUpload returns the most recent incomplete multipart upload to
+// uploadInput's key whose parts all match file, or nil if there is none
+func (client *s3client) findResumableUpload(ctx context.Context, uploadInput *s3.PutObjectInput, file io.ReaderAt, size int64) (*resumableUpload, error) {
+	uploads, err := client.listMultipartUploads(ctx, aws.ToString(uploadInput.Bucket), aws.ToString(uploadInput.Key))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(uploads, func(i, j int) bool {
+		return aws.ToTime(uploads[i].Initiated).After(aws.ToTime(uploads[j].Initiated))
+	})
+
+	// Part ETags are only the MD5 of their contents without KMS or SSE-C
+	checkETags := uploadInput.SSECustomerKey == nil &&
+		!strings.HasPrefix(string(uploadInput.ServerSideEncryption), "aws:kms")
+
+	for _, upload := range uploads {
+		if aws.ToString(upload.Key) != aws.ToString(uploadInput.Key) {
+			continue
+		}
+		if uploadInput.ChecksumAlgorithm != "" && upload.ChecksumAlgorithm != uploadInput.ChecksumAlgorithm {
+			continue
+		}
+		if uploadInput.StorageClass != "" && upload.StorageClass != "" && upload.StorageClass != uploadInput.StorageClass {
+			continue
+		}
+
+		parts, err := client.listParts(ctx, uploadInput, aws.ToString(upload.UploadId))
+		if err != nil {
+			return nil, err
+		}
+
+		partSize, ok, err := verifyUploadedParts(file, size, parts, upload.ChecksumAlgorithm, checkETags)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		completed := make([]types.CompletedPart, len(parts))
+		for i, part := range parts {
+			completed[i] = types.CompletedPart{
+				PartNumber:        part.PartNumber,
+				ETag:              part.ETag,
+				ChecksumCRC32:     part.ChecksumCRC32,
+				ChecksumCRC32C:    part.ChecksumCRC32C,
+				ChecksumCRC64NVME: part.ChecksumCRC64NVME,
+				ChecksumSHA1:      part.ChecksumSHA1,
+				ChecksumSHA256:    part.ChecksumSHA256,
+			}
+		}
+
+		return &resumableUpload{
+			uploadID:          aws.ToString(upload.UploadId),
+			checksumAlgorithm: upload.ChecksumAlgorithm,
+			partSize:          partSize,
+			parts:             completed,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func (client *s3client) listParts(ctx context.Context, uploadInput *s3.PutObjectInput, uploadID string) ([]types.Part, error) {
+	paginator := s3.NewListPartsPaginator(client.client, &s3.ListPartsInput{
+		Bucket:               uploadInput.Bucket,
+		Key:                  uploadInput.Key,
+		UploadId:             aws.String(uploadID),
+		SSECustomerAlgorithm: uploadInput.SSECustomerAlgorithm,
+		SSECustomerKey:       uploadInput.SSECustomerKey,
+		SSECustomerKeyMD5:    uploadInput.SSECustomerKeyMD5,
+		RequestPayer:         client.requestPayer,
+		ExpectedBucketOwner:  client.expectedBucketOwner,
+	})
+
+	var parts []types.Part
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing parts of multipart upload: %w", err)
+		}
+		parts = append(parts, page.Parts...)
+	}
+
+	return parts, nil
+}
+
+// verifyUploadedParts checks that every uploaded part has the size and
+// contents of the matching range of file, assuming all but the last part
+// have the size of the largest one. It returns that part size.
+func verifyUploadedParts(file io.ReaderAt, size int64, parts []types.Part, algorithm types.ChecksumAlgorithm, checkETags bool) (int64, bool, error) {
+	if len(parts) == 0 {
+		return 0, false, nil
+	}
+
+	var partSize int64
+	for _, part := range parts {
+		partSize = max(partSize, aws.ToInt64(part.Size))
+	}
+
+	partCount := (size + partSize - 1) / partSize
+	if partSize < MinUploadPartSize || partCount > int64(manager.MaxUploadParts) {
+		return 0, false, nil
+	}
+
+	for _, part := range parts {
+		number := int64(aws.ToInt32(part.PartNumber))
+		if number < 1 || number > partCount {
+			return 0, false, nil
+		}
+
+		offset := (number - 1) * partSize
+		length := min(partSize, size-offset)
+		if aws.ToInt64(part.Size) != length {
+			return 0, false, nil
+		}
+
+		expectedChecksum := partChecksum(part, algorithm)
+		checksumHash := newChecksumHash(algorithm)
+		if expectedChecksum == nil || checksumHash == nil {
+			if !checkETags {
+				return 0, false, nil
+			}
+			checksumHash = nil
+		}
+
+		md5Hash := md5.New()
+		writers := []io.Writer{md5Hash}
+		if checksumHash != nil {
+			writers = append(writers, checksumHash)
+		}
+
+		_, err := io.Copy(io.MultiWriter(writers...), io.NewSectionReader(file, offset, length))
+		if err != nil {
+			return 0, false, err
+		}
+
+		if checksumHash != nil {
+			if base64.StdEncoding.EncodeToString(checksumHash.Sum(nil)) != *expectedChecksum {
+				return 0, false, nil
+			}
+		} else if strings.Trim(aws.ToString(part.ETag), `"`) != hex.EncodeToString(md5Hash.Sum(nil)) {
+			return 0, false, nil
+		}
+	}
+
+	return partSize, true, nil
+}
+
+func newChecksumHash(algorithm types.ChecksumAlgorithm) hash.Hash {
+	switch algorithm {
+	case types.ChecksumAlgorithmCrc32:
+		return crc32.NewIEEE()
+	case types.ChecksumAlgorithmCrc32c:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case types.ChecksumAlgorithmCrc64nvme:
+		return crc64.New(crc64.MakeTable(crc64NVME))
+	case types.ChecksumAlgorithmSha1:
+		return sha1.New()
+	case types.ChecksumAlgorithmSha256:
+		return sha256.New()
+	}
+	return nil
+}
+
+func partChecksum(part types.Part, algorithm types.ChecksumAlgorithm) *string {
+	switch algorithm {
+	case types.ChecksumAlgorithmCrc32:
+		return part.ChecksumCRC32
+	case types.ChecksumAlgorithmCrc32c:
+		return part.ChecksumCRC32C
+	case types.ChecksumAlgorithmCrc64nvme:
+		return part.ChecksumCRC64NVME
+	case types.ChecksumAlgorithmSha1:
+		return part.ChecksumSHA1
+	case types.ChecksumAlgorithmSha256:
+		return part.ChecksumSHA256
+	}
+	return nil
+}
+
+// resumeUpload uploads the parts missing from upload and completes it
+func (client *s3client) resumeUpload(ctx context.Context, uploadInput *s3.PutObjectInput, upload *resumableUpload, file io.ReaderAt, size int64, concurrency int, progress progressReporter) (*s3.CompleteMultipartUploadOutput, error) {
+	uploaded := map[int32]bool{}
+	for _, part := range upload.parts {
+		uploaded[aws.ToInt32(part.PartNumber)] = true
+		progress.Add(min(upload.partSize, size-int64(aws.ToInt32(part.PartNumber)-1)*upload.partSize))
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		parts    = upload.parts
+		numbers  = make(chan int32)
+	)
+
+	for range max(concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range numbers {
+				offset := int64(number-1) * upload.partSize
+				length := min(upload.partSize, size-offset)
+
+				var body io.ReadSeeker = io.NewSectionReader(file, offset, length)
+				if client.bandwidth != nil {
+					body = throttledSectionReader{io.NewSectionReader(file, offset, length), client.bandwidth, ctx}
+				}
+
+				output, err := client.client.UploadPart(ctx, &s3.UploadPartInput{
+					Bucket:               uploadInput.Bucket,
+					Key:                  uploadInput.Key,
+					UploadId:             aws.String(upload.uploadID),
+					PartNumber:           aws.Int32(number),
+					Body:                 body,
+					ContentLength:        aws.Int64(length),
+					ChecksumAlgorithm:    upload.checksumAlgorithm,
+					SSECustomerAlgorithm: uploadInput.SSECustomerAlgorithm,
+					SSECustomerKey:       uploadInput.SSECustomerKey,
+					SSECustomerKeyMD5:    uploadInput.SSECustomerKeyMD5,
+					RequestPayer:         client.requestPayer,
+					ExpectedBucketOwner:  client.expectedBucketOwner,
+				})
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("error uploading part %d: %w", number, err)
+					}
+				} else {
+					parts = append(parts, types.CompletedPart{
+						PartNumber:        aws.Int32(number),
+						ETag:              output.ETag,
+						ChecksumCRC32:     output.ChecksumCRC32,
+						ChecksumCRC32C:    output.ChecksumCRC32C,
+						ChecksumCRC64NVME: output.ChecksumCRC64NVME,
+						ChecksumSHA1:      output.ChecksumSHA1,
+						ChecksumSHA256:    output.ChecksumSHA256,
+					})
+					progress.Add(length)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	partCount := int32((size + upload.partSize - 1) / upload.partSize)
+	for number := int32(1); number <= partCount; number++ {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		if !uploaded[number] {
+			numbers <- number
+		}
+	}
+	close(numbers)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	sort.Slice(parts, func(i, j int) bool {
+		return aws.ToInt32(parts[i].PartNumber) < aws.ToInt32(parts[j].PartNumber)
+	})
+
+	output, err := client.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:               uploadInput.Bucket,
+		Key:                  uploadInput.Key,
+		UploadId:             aws.String(upload.uploadID),
+		MultipartUpload:      &types.CompletedMultipartUpload{Parts: parts},
+		SSECustomerAlgorithm: uploadInput.SSECustomerAlgorithm,
+		SSECustomerKey:       uploadInput.SSECustomerKey,
+		SSECustomerKeyMD5:    uploadInput.SSECustomerKeyMD5,
+		RequestPayer:         client.requestPayer,
+		ExpectedBucketOwner:  client.expectedBucketOwner,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error completing multipart upload: %w", err)
+	}
+
+	return output, nil
+}
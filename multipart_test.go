@@ -0,0 +1,296 @@
+package s3resource
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resuming multipart uploads", func() {
+	const partSize = MinUploadPartSize
+
+	var file []byte
+
+	BeforeEach(func() {
+		// Two full parts and a short last part
+		file = make([]byte, 2*partSize+1024)
+		for i := range file {
+			file[i] = byte(i % 251)
+		}
+	})
+
+	part := func(number int32, data []byte) types.Part {
+		etag := md5.Sum(data)
+		return types.Part{
+			PartNumber: aws.Int32(number),
+			Size:       aws.Int64(int64(len(data))),
+			ETag:       aws.String(`"` + hex.EncodeToString(etag[:]) + `"`),
+		}
+	}
+
+	withCRC32 := func(p types.Part, data []byte) types.Part {
+		sum := crc32.NewIEEE()
+		sum.Write(data)
+		p.ChecksumCRC32 = aws.String(base64.StdEncoding.EncodeToString(sum.Sum(nil)))
+		return p
+	}
+
+	It("resumes when the uploaded parts match the file", func() {
+		parts := []types.Part{
+			part(1, file[:partSize]),
+			part(3, file[2*partSize:]),
+		}
+
+		size, ok, err := verifyUploadedParts(bytes.NewReader(file), int64(len(file)), parts, "", true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(size).To(Equal(int64(partSize)))
+	})
+
+	It("verifies parts against their checksums", func() {
+		parts := []types.Part{
+			withCRC32(part(1, file[:partSize]), file[:partSize]),
+			withCRC32(part(2, file[partSize:2*partSize]), file[partSize:2*partSize]),
+		}
+
+		_, ok, err := verifyUploadedParts(bytes.NewReader(file), int64(len(file)), parts, types.ChecksumAlgorithmCrc32, false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+
+	It("does not resume when a part's contents differ", func() {
+		changed := bytes.Clone(file[:partSize])
+		changed[0] = 'x'
+
+		_, ok, err := verifyUploadedParts(bytes.NewReader(file), int64(len(file)), []types.Part{part(1, changed)}, "", true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("does not resume when a part's checksum differs", func() {
+		parts := []types.Part{
+			withCRC32(part(1, file[:partSize]), file[partSize:2*partSize]),
+		}
+
+		_, ok, err := verifyUploadedParts(bytes.NewReader(file), int64(len(file)), parts, types.ChecksumAlgorithmCrc32, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("does not resume when the file has a different size", func() {
+		parts := []types.Part{
+			part(1, file[:partSize]),
+			part(3, file[2*partSize:]),
+		}
+
+		_, ok, err := verifyUploadedParts(bytes.NewReader(file[:len(file)-1]), int64(len(file)-1), parts, "", true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("does not resume when parts can't be verified", func() {
+		parts := []types.Part{part(1, file[:partSize])}
+
+		_, ok, err := verifyUploadedParts(bytes.NewReader(file), int64(len(file)), parts, "", false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("Cancelling multipart uploads", func() {
+	var (
+		client   *s3client
+		ctx      context.Context
+		cancel   context.CancelFunc
+		aborted  chan string
+		filePath string
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		aborted = make(chan string, 1)
+
+		server := NewTestServer(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			switch {
+			case r.Method == http.MethodGet && query.Has("uploads"):
+				// There are no incomplete uploads to resume
+				fmt.Fprint(w, `<ListMultipartUploadsResult><Bucket>bucket</Bucket><IsTruncated>false</IsTruncated></ListMultipartUploadsResult>`)
+			case r.Method == http.MethodPost && query.Has("uploads"):
+				fmt.Fprint(w, `<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>key</Key><UploadId>some-upload-id</UploadId></InitiateMultipartUploadResult>`)
+			case r.Method == http.MethodPut && query.Has("partNumber"):
+				// The step is aborted while the parts are being uploaded
+				cancel()
+				io.Copy(io.Discard, r.Body)
+			case r.Method == http.MethodDelete && query.Has("uploadId"):
+				aborted <- query.Get("uploadId")
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.WriteHeader(http.StatusNotImplemented)
+			}
+		})
+		client = NewTestS3Client(io.Discard, NewTestAwsConfig(""), server.URL, S3ClientOptions{}).(*s3client)
+
+		filePath = filepath.Join(GinkgoT().TempDir(), "file")
+		Expect(os.WriteFile(filePath, make([]byte, 2*MinUploadPartSize), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("aborts the upload when its context is cancelled", func() {
+		_, err := client.UploadFile(ctx, "bucket", "key", filePath, NewUploadFileOptions())
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+
+		Expect(aborted).To(Receive(Equal("some-upload-id")))
+	})
+
+	It("leaves the parts to be resumed when resuming uploads", func() {
+		options := NewUploadFileOptions()
+		options.ResumeUpload = true
+
+		_, err := client.UploadFile(ctx, "bucket", "key", filePath, options)
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+
+		Expect(aborted).ToNot(Receive())
+	})
+})
+
+var _ = Describe("Multipart uploads in a bucket", func() {
+	var (
+		ctx      context.Context
+		client   *s3client
+		output   *bytes.Buffer
+		file     []byte
+		filePath string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server := NewTestS3Server("us-east-1")
+		server.CreateBucket("bucket", true)
+
+		output = &bytes.Buffer{}
+		client = NewTestS3Client(output, NewTestAwsConfig("us-east-1"), server.URL, S3ClientOptions{}).(*s3client)
+
+		file = make([]byte, 2*MinUploadPartSize+1024)
+		for i := range file {
+			file[i] = byte(i % 251)
+		}
+		filePath = filepath.Join(GinkgoT().TempDir(), "file")
+		Expect(os.WriteFile(filePath, file, 0644)).To(Succeed())
+	})
+
+	// startUpload starts an upload of key with the first part of file, as an
+	// interrupted put leaves it
+	startUpload := func(input *s3.CreateMultipartUploadInput) string {
+		input.Bucket = aws.String("bucket")
+		created, err := client.client.CreateMultipartUpload(ctx, input)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = client.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String("bucket"),
+			Key:        input.Key,
+			UploadId:   created.UploadId,
+			PartNumber: aws.Int32(1),
+			Body:       bytes.NewReader(file[:MinUploadPartSize]),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return aws.ToString(created.UploadId)
+	}
+
+	versionCount := func(key string) int {
+		versions, err := client.client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+			Bucket: aws.String("bucket"),
+			Prefix: aws.String(key),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return len(versions.Versions)
+	}
+
+	resumeOptions := func(contentType string) UploadFileOptions {
+		options := NewUploadFileOptions()
+		options.ResumeUpload = true
+		options.ContentType = contentType
+		return options
+	}
+
+	It("resumes an upload started with the same settings", func() {
+		input := &s3.PutObjectInput{
+			Key:         aws.String("files/abc-1.tgz"),
+			ACL:         types.ObjectCannedACLPrivate,
+			ContentType: aws.String("application/gzip"),
+		}
+		startUpload(&s3.CreateMultipartUploadInput{
+			Key:         input.Key,
+			ACL:         input.ACL,
+			ContentType: input.ContentType,
+			Metadata:    map[string]string{uploadSettingsKey: uploadSettings(input)},
+		})
+
+		_, err := client.UploadFile(ctx, "bucket", "files/abc-1.tgz", filePath, resumeOptions("application/gzip"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(output.String()).To(ContainSubstring("resuming multipart upload with 1 parts already uploaded"))
+		Expect(output.String()).ToNot(ContainSubstring("other settings"))
+		Expect(versionCount("files/abc-1.tgz")).To(Equal(1))
+	})
+
+	It("uploads again when the resumed upload was started with other settings", func() {
+		startUpload(&s3.CreateMultipartUploadInput{
+			Key:         aws.String("files/abc-1.tgz"),
+			ContentType: aws.String("text/plain"),
+		})
+
+		versionID, err := client.UploadFile(ctx, "bucket", "files/abc-1.tgz", filePath, resumeOptions("application/gzip"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(output.String()).To(ContainSubstring("resuming multipart upload with 1 parts already uploaded"))
+		Expect(output.String()).To(ContainSubstring("the resumed upload was started with other settings, uploading the file again"))
+
+		head, err := client.client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket:    aws.String("bucket"),
+			Key:       aws.String("files/abc-1.tgz"),
+			VersionId: aws.String(versionID),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(aws.ToString(head.ContentType)).To(Equal("application/gzip"))
+		Expect(versionCount("files/abc-1.tgz")).To(Equal(1))
+	})
+
+	It("aborts only the stale uploads of the uploaded key", func() {
+		stale := startUpload(&s3.CreateMultipartUploadInput{Key: aws.String("files/abc-1.tgz")})
+		startUpload(&s3.CreateMultipartUploadInput{Key: aws.String("files/abc-1.tgz.sig")})
+		startUpload(&s3.CreateMultipartUploadInput{Key: aws.String("files/abc-2.tgz")})
+		time.Sleep(10 * time.Millisecond)
+
+		options := NewUploadFileOptions()
+		options.AbortIncompleteUploadsAfter = time.Millisecond
+		_, err := client.UploadFile(ctx, "bucket", "files/abc-1.tgz", filePath, options)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(output.String()).To(ContainSubstring("aborted multipart upload of files/abc-1.tgz"))
+
+		uploads, err := client.listMultipartUploads(ctx, "bucket", "files/")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(uploads).To(HaveLen(2))
+		for _, upload := range uploads {
+			Expect(aws.ToString(upload.UploadId)).ToNot(Equal(stale))
+		}
+	})
+})
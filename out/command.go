@@ -1,6 +1,7 @@
 package out
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -8,13 +9,17 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	s3resource "github.com/concourse/s3-resource"
 	"github.com/concourse/s3-resource/versions"
 	"github.com/fatih/color"
 )
 
-var ErrObjectVersioningNotEnabled = errors.New("object versioning not enabled")
+var ErrObjectVersioningNotEnabled = &s3resource.Error{
+	Kind: s3resource.ErrNotVersioned,
+	Err:  errors.New("object versioning not enabled"),
+}
 var ErrorColor = color.New(color.FgWhite, color.BgRed, color.Bold)
 var BlinkingErrorColor = color.New(color.BlinkSlow, color.FgWhite, color.BgRed, color.Bold)
 
@@ -34,16 +39,20 @@ func NewCommand(stderr io.Writer, s3client s3resource.S3Client) *Command {
 	}
 }
 
-func (command *Command) Run(sourceDir string, request Request) (Response, error) {
+func (command *Command) Run(ctx context.Context, sourceDir string, request Request) (Response, error) {
 	if request.Params.From != "" || request.Params.To != "" || request.Source.UseV2Signing {
 		command.printDeprecationWarning()
 	}
 
+	for _, note := range request.Source.ApplyProvider() {
+		fmt.Fprintln(command.stderr, note)
+	}
+
 	if ok, message := request.Source.IsValid(); !ok {
-		return Response{}, errors.New(message)
+		return Response{}, s3resource.NewConfigError(message)
 	}
 	if request.Params.File != "" && request.Params.From != "" {
-		return Response{}, errors.New("contains both file and from")
+		return Response{}, s3resource.NewConfigError("contains both file and from")
 	}
 
 	localPath, err := command.match(request.Params, sourceDir)
@@ -57,6 +66,9 @@ func (command *Command) Run(sourceDir string, request Request) (Response, error)
 
 	options := s3resource.NewUploadFileOptions()
 
+	if request.Source.DisableACLs {
+		options.Acl = ""
+	}
 	if request.Params.Acl != "" {
 		options.Acl = request.Params.Acl
 	}
@@ -66,7 +78,31 @@ func (command *Command) Run(sourceDir string, request Request) (Response, error)
 	options.KmsKeyId = request.Source.SSEKMSKeyId
 	options.DisableMultipart = request.Source.DisableMultipart
 
+	options.StorageClass = request.Source.StorageClass
+	if request.Params.StorageClass != "" {
+		if ok, message := s3resource.ValidateStorageClass(request.Params.StorageClass); !ok {
+			return Response{}, s3resource.NewConfigError(message)
+		}
+		options.StorageClass = request.Params.StorageClass
+	}
+
+	err = command.objectLock(request, &options)
+	if err != nil {
+		return Response{}, err
+	}
+
+	err = command.transfer(request, &options)
+	if err != nil {
+		return Response{}, err
+	}
+
+	err = command.preflight(ctx, request, remotePath, options)
+	if err != nil {
+		return Response{}, err
+	}
+
 	versionID, err := command.s3client.UploadFile(
+		ctx,
 		bucketName,
 		remotePath,
 		localPath,
@@ -88,17 +124,160 @@ func (command *Command) Run(sourceDir string, request Request) (Response, error)
 		version.Path = remotePath
 	}
 
-	url, err := command.s3client.URL(bucketName, remotePath, request.Source.Private, versionID)
+	url, err := command.s3client.URL(ctx, bucketName, remotePath, request.Source.Private, versionID)
 	if err != nil {
 		return Response{}, err
 	}
 
+	metadata := command.metadata(url, remotePath, request.Source.Private)
+	if options.StorageClass != "" {
+		metadata = append(metadata, s3resource.MetadataPair{
+			Name:  "storage_class",
+			Value: options.StorageClass,
+		})
+	}
+	if options.ObjectLockMode != "" {
+		metadata = append(metadata,
+			s3resource.MetadataPair{
+				Name:  "object_lock_mode",
+				Value: options.ObjectLockMode,
+			},
+			s3resource.MetadataPair{
+				Name:  "object_lock_retain_until_date",
+				Value: options.ObjectLockRetainUntilDate.Format(time.RFC3339),
+			},
+		)
+	}
+	if options.ObjectLockLegalHold {
+		metadata = append(metadata, s3resource.MetadataPair{
+			Name:  "object_lock_legal_hold",
+			Value: "ON",
+		})
+	}
+
 	return Response{
 		Version:  version,
-		Metadata: command.metadata(url, remotePath, request.Source.Private),
+		Metadata: metadata,
 	}, nil
 }
 
+// transfer sets the part size and concurrency of the upload from the params,
+// falling back to the defaults in the source, and how incomplete multipart
+// uploads are handled
+func (command *Command) transfer(request Request, options *s3resource.UploadFileOptions) error {
+	partSize := request.Source.UploadPartSize
+	if request.Params.UploadPartSize != "" {
+		partSize = request.Params.UploadPartSize
+	}
+	concurrency := request.Source.UploadConcurrency
+	if request.Params.UploadConcurrency != 0 {
+		concurrency = request.Params.UploadConcurrency
+	}
+
+	if ok, message := s3resource.ValidateTransfer(partSize, concurrency, "", 0); !ok {
+		return s3resource.NewConfigError(message)
+	}
+
+	if partSize != "" {
+		options.PartSize, _ = s3resource.ParseByteSize(partSize)
+	}
+	options.Concurrency = concurrency
+
+	options.ResumeUpload = request.Source.ResumeUploads
+	if request.Source.AbortIncompleteUploadsAfter != "" {
+		options.AbortIncompleteUploadsAfter, _ = s3resource.ParseDurationOrDays(request.Source.AbortIncompleteUploadsAfter)
+	}
+
+	return nil
+}
+
+// objectLock sets the Object Lock options of the upload from the params,
+// falling back to the defaults in the source
+func (command *Command) objectLock(request Request, options *s3resource.UploadFileOptions) error {
+	mode := request.Source.ObjectLockMode
+	retention := request.Source.ObjectLockRetention
+	if request.Params.ObjectLockMode != "" || request.Params.ObjectLockRetention != "" {
+		mode = request.Params.ObjectLockMode
+		retention = request.Params.ObjectLockRetention
+	}
+
+	if ok, message := s3resource.ValidateObjectLock(mode, retention); !ok {
+		return s3resource.NewConfigError(message)
+	}
+
+	if mode != "" {
+		duration, err := s3resource.ParseObjectLockRetention(retention)
+		if err != nil {
+			return err
+		}
+
+		retainUntilDate := time.Now().Add(duration).UTC()
+		options.ObjectLockMode = mode
+		options.ObjectLockRetainUntilDate = &retainUntilDate
+	}
+
+	options.ObjectLockLegalHold = request.Source.ObjectLockLegalHold
+	if request.Params.ObjectLockLegalHold != nil {
+		options.ObjectLockLegalHold = *request.Params.ObjectLockLegalHold
+	}
+
+	return nil
+}
+
+// preflight checks the bucket before uploading to it. An upload to an
+// unversioned bucket would overwrite the versioned_file, so versioning is
+// always checked first, and with source.preflight so is everything else the
+// upload needs.
+func (command *Command) preflight(ctx context.Context, request Request, remotePath string, options s3resource.UploadFileOptions) error {
+	if !request.Source.Preflight && request.Source.VersionedFile == "" {
+		return nil
+	}
+
+	preflightOptions := s3resource.PreflightOptions{
+		Versioned: request.Source.VersionedFile != "",
+	}
+	if request.Source.Preflight {
+		preflightOptions.Identity = true
+		preflightOptions.Actions = uploadActions(request.Source, options)
+		preflightOptions.Prefix = remotePath
+	}
+
+	report := command.s3client.Preflight(ctx, request.Source.Bucket, preflightOptions)
+	if request.Source.Preflight {
+		report.Print(command.stderr)
+	}
+
+	return report.Err()
+}
+
+// uploadActions are the IAM actions an upload with options needs, as listed
+// in the README
+func uploadActions(source s3resource.Source, options s3resource.UploadFileOptions) []string {
+	actions := []string{"s3:PutObject"}
+	if options.Acl != "" {
+		actions = append(actions, "s3:PutObjectAcl")
+		if source.VersionedFile != "" {
+			actions = append(actions, "s3:PutObjectVersionAcl")
+		}
+	}
+	if options.ResumeUpload || options.AbortIncompleteUploadsAfter > 0 {
+		actions = append(actions, "s3:ListBucketMultipartUploads")
+	}
+	if options.ResumeUpload {
+		actions = append(actions, "s3:ListMultipartUploadParts", "s3:GetObject")
+	}
+	if options.AbortIncompleteUploadsAfter > 0 {
+		actions = append(actions, "s3:AbortMultipartUpload")
+	}
+	if options.ObjectLockMode != "" {
+		actions = append(actions, "s3:PutObjectRetention")
+	}
+	if options.ObjectLockLegalHold {
+		actions = append(actions, "s3:PutObjectLegalHold")
+	}
+	return actions
+}
+
 func (command *Command) remotePath(request Request, localPath string, sourceDir string) string {
 	if request.Source.VersionedFile != "" {
 		return request.Source.VersionedFile
@@ -1,8 +1,12 @@
 package out_test
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
 	"os"
 	"path/filepath"
+	"time"
 
 	s3resource "github.com/concourse/s3-resource"
 	"github.com/concourse/s3-resource/fakes"
@@ -66,7 +70,7 @@ var _ = Describe("Out Command", func() {
 				request.Params.From = "foo.tgz"
 				createFile("foo.tgz")
 
-				_, err := command.Run(sourceDir, request)
+				_, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Expect(stderr.Contents()).To(ContainSubstring("WARNING:"))
@@ -77,7 +81,7 @@ var _ = Describe("Out Command", func() {
 				request.Params.From = "a/(.*).tgz"
 				createFile("a/file.tgz")
 
-				_, err := command.Run(sourceDir, request)
+				_, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 			})
 
@@ -86,7 +90,7 @@ var _ = Describe("Out Command", func() {
 				createFile("a/file1.tgz")
 				createFile("a/file2.tgz")
 
-				_, err := command.Run(sourceDir, request)
+				_, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).Should(HaveOccurred())
 			})
 
@@ -95,7 +99,7 @@ var _ = Describe("Out Command", func() {
 				createFile("a/file1.tgz")
 				createFile("a/file2.tgz")
 
-				_, err := command.Run(sourceDir, request)
+				_, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).Should(HaveOccurred())
 			})
 		})
@@ -105,7 +109,7 @@ var _ = Describe("Out Command", func() {
 				request.Params.File = "a/*.tgz"
 				createFile("a/file.tgz")
 
-				_, err := command.Run(sourceDir, request)
+				_, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Expect(stderr.Contents()).NotTo(ContainSubstring("WARNING:"))
@@ -115,7 +119,7 @@ var _ = Describe("Out Command", func() {
 				request.Params.File = "a/*.tgz"
 				createFile("a/file.tgz")
 
-				_, err := command.Run(sourceDir, request)
+				_, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 			})
 
@@ -124,7 +128,7 @@ var _ = Describe("Out Command", func() {
 				createFile("a/file1.tgz")
 				createFile("a/file2.tgz")
 
-				_, err := command.Run(sourceDir, request)
+				_, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).Should(HaveOccurred())
 			})
 
@@ -133,7 +137,7 @@ var _ = Describe("Out Command", func() {
 				createFile("a/file1.tgz")
 				createFile("a/file2.tgz")
 
-				_, err := command.Run(sourceDir, request)
+				_, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).Should(HaveOccurred())
 			})
 
@@ -141,11 +145,11 @@ var _ = Describe("Out Command", func() {
 				request.Params.File = "a/*.tgz"
 				createFile("a/file.tgz")
 
-				_, err := command.Run(sourceDir, request)
+				_, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(s3client.UploadFileCallCount()).Should(Equal(1))
-				bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
+				_, bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
 
 				Ω(bucketName).Should(Equal("bucket-name"))
 				Ω(remotePath).Should(Equal("file.tgz"))
@@ -162,11 +166,11 @@ var _ = Describe("Out Command", func() {
 			})
 
 			It("applies the specfied acl", func() {
-				_, err := command.Run(sourceDir, request)
+				_, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(s3client.UploadFileCallCount()).Should(Equal(1))
-				bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
+				_, bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
 
 				Ω(bucketName).Should(Equal("bucket-name"))
 				Ω(remotePath).Should(Equal("file.tgz"))
@@ -174,6 +178,30 @@ var _ = Describe("Out Command", func() {
 				Ω(options).Should(Equal(s3resource.UploadFileOptions{Acl: "public-read"}))
 
 			})
+
+			It("applies the specified acl even if acls are disabled", func() {
+				request.Source.DisableACLs = true
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				_, _, _, _, options := s3client.UploadFileArgsForCall(0)
+				Ω(options).Should(Equal(s3resource.UploadFileOptions{Acl: "public-read"}))
+			})
+		})
+
+		Context("when acls are disabled", func() {
+			It("does not set an acl", func() {
+				request.Params.File = "a/*.tgz"
+				request.Source.DisableACLs = true
+				createFile("a/file.tgz")
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				_, _, _, _, options := s3client.UploadFileArgsForCall(0)
+				Ω(options).Should(Equal(s3resource.UploadFileOptions{}))
+			})
 		})
 
 		Context("when uploading the file with a To param", func() {
@@ -184,7 +212,7 @@ var _ = Describe("Out Command", func() {
 			})
 
 			It("prints the deprecation warning", func() {
-				_, err := command.Run(sourceDir, request)
+				_, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Expect(stderr.Contents()).To(ContainSubstring("WARNING:"))
@@ -192,11 +220,11 @@ var _ = Describe("Out Command", func() {
 			})
 
 			It("uploads the file", func() {
-				_, err := command.Run(sourceDir, request)
+				_, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(s3client.UploadFileCallCount()).Should(Equal(1))
-				bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
+				_, bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
 
 				Ω(bucketName).Should(Equal("bucket-name"))
 				Ω(remotePath).Should(Equal("a-folder/file.tgz"))
@@ -213,11 +241,11 @@ var _ = Describe("Out Command", func() {
 			})
 
 			It("uploads the file to the root", func() {
-				_, err := command.Run(sourceDir, request)
+				_, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(s3client.UploadFileCallCount()).Should(Equal(1))
-				bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
+				_, bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
 
 				Ω(bucketName).Should(Equal("bucket-name"))
 				Ω(remotePath).Should(Equal("file.tgz"))
@@ -234,11 +262,11 @@ var _ = Describe("Out Command", func() {
 			})
 
 			It("uploads the file to the correct location", func() {
-				response, err := command.Run(sourceDir, request)
+				response, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(s3client.UploadFileCallCount()).Should(Equal(1))
-				bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
+				_, bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
 
 				Ω(bucketName).Should(Equal("bucket-name"))
 				Ω(remotePath).Should(Equal("folder-123/file.tgz"))
@@ -265,12 +293,12 @@ var _ = Describe("Out Command", func() {
 				request.Source.VersionedFile = remoteFileName
 				createFile(localFileName)
 
-				response, err := command.Run(sourceDir, request)
+				response, err := command.Run(context.Background(), sourceDir, request)
 
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(s3client.UploadFileCallCount()).Should(Equal(1))
-				bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
+				_, bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
 
 				Ω(bucketName).Should(Equal("bucket-name"))
 				Ω(remotePath).Should(Equal(remoteFileName))
@@ -282,6 +310,40 @@ var _ = Describe("Out Command", func() {
 				Ω(response.Metadata[0].Name).Should(Equal("filename"))
 				Ω(response.Metadata[0].Value).Should(Equal(remoteFileName))
 			})
+
+			It("checks that the bucket is versioned before uploading", func() {
+				request.Params.File = "versioned-file.tgz"
+				request.Source.VersionedFile = "versioned-file.tgz"
+				createFile("versioned-file.tgz")
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(s3client.PreflightCallCount()).Should(Equal(1))
+				_, bucketName, options := s3client.PreflightArgsForCall(0)
+				Ω(bucketName).Should(Equal("bucket-name"))
+				Ω(options).Should(Equal(s3resource.PreflightOptions{Versioned: true}))
+				Ω(stderr.Contents()).Should(BeEmpty())
+			})
+
+			It("does not upload to a bucket which is not versioned", func() {
+				request.Params.File = "versioned-file.tgz"
+				request.Source.VersionedFile = "versioned-file.tgz"
+				createFile("versioned-file.tgz")
+
+				s3client.PreflightReturns(s3resource.PreflightReport{
+					{Check: "bucket", Detail: "bucket-name"},
+					{Check: "versioning", Err: &s3resource.Error{
+						Kind: s3resource.ErrNotVersioned,
+						Err:  errors.New("bucket is not versioned"),
+					}},
+				})
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Ω(err).Should(MatchError("preflight check versioning failed: bucket is not versioned"))
+				Ω(errors.Is(err, s3resource.ErrNotVersioned)).Should(BeTrue())
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
 		})
 
 		Context("when using regexp", func() {
@@ -290,12 +352,12 @@ var _ = Describe("Out Command", func() {
 				request.Source.Regexp = "a-folder/some-file-(.*).tgz"
 				createFile("my/special-file.tgz")
 
-				response, err := command.Run(sourceDir, request)
+				response, err := command.Run(context.Background(), sourceDir, request)
 				Expect(err).ToNot(HaveOccurred())
 
 				Ω(s3client.UploadFileCallCount()).Should(Equal(1))
 
-				bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
+				_, bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
 				Expect(bucketName).To(Equal("bucket-name"))
 				Expect(remotePath).To(Equal("a-folder/special-file.tgz"))
 				Expect(localPath).To(Equal(filepath.Join(sourceDir, "my/special-file.tgz")))
@@ -308,7 +370,7 @@ var _ = Describe("Out Command", func() {
 
 		Describe("output metadata", func() {
 			BeforeEach(func() {
-				s3client.URLStub = func(bucketName string, remotePath string, private bool, versionID string) (string, error) {
+				s3client.URLStub = func(_ context.Context, bucketName string, remotePath string, private bool, versionID string) (string, error) {
 					return "http://example.com/" + filepath.Join(bucketName, remotePath), nil
 				}
 			})
@@ -318,11 +380,11 @@ var _ = Describe("Out Command", func() {
 				request.Params.To = "a-folder/"
 				createFile("a/file.tgz")
 
-				response, err := command.Run(sourceDir, request)
+				response, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(s3client.URLCallCount()).Should(Equal(1))
-				bucketName, remotePath, private, versionID := s3client.URLArgsForCall(0)
+				_, bucketName, remotePath, private, versionID := s3client.URLArgsForCall(0)
 				Ω(bucketName).Should(Equal("bucket-name"))
 				Ω(remotePath).Should(Equal("a-folder/file.tgz"))
 				Ω(private).Should(Equal(false))
@@ -343,7 +405,7 @@ var _ = Describe("Out Command", func() {
 				request.Params.To = "a-folder/"
 				createFile("a/file.tgz")
 
-				response, err := command.Run(sourceDir, request)
+				response, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(response.Metadata).Should(HaveLen(1))
@@ -359,11 +421,11 @@ var _ = Describe("Out Command", func() {
 			})
 
 			It("applies the specfied content-type", func() {
-				_, err := command.Run(sourceDir, request)
+				_, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(s3client.UploadFileCallCount()).Should(Equal(1))
-				_, _, _, options := s3client.UploadFileArgsForCall(0)
+				_, _, _, _, options := s3client.UploadFileArgsForCall(0)
 
 				Ω(options.ContentType).Should(Equal("application/customtype"))
 			})
@@ -376,11 +438,11 @@ var _ = Describe("Out Command", func() {
 			})
 
 			It("no content-type specified leaves an empty content-type", func() {
-				_, err := command.Run(sourceDir, request)
+				_, err := command.Run(context.Background(), sourceDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(s3client.UploadFileCallCount()).Should(Equal(1))
-				_, _, _, options := s3client.UploadFileArgsForCall(0)
+				_, _, _, _, options := s3client.UploadFileArgsForCall(0)
 
 				Ω(options.ContentType).Should(Equal(""))
 			})
@@ -393,11 +455,11 @@ var _ = Describe("Out Command", func() {
 				request.Source.DisableMultipart = true
 				createFile("my/special-file.tgz")
 
-				response, err := command.Run(sourceDir, request)
+				response, err := command.Run(context.Background(), sourceDir, request)
 				Expect(err).ToNot(HaveOccurred())
 				Ω(s3client.UploadFileCallCount()).Should(Equal(1))
 
-				bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
+				_, bucketName, remotePath, localPath, options := s3client.UploadFileArgsForCall(0)
 				Expect(bucketName).To(Equal("bucket-name"))
 				Expect(remotePath).To(Equal("a-folder/special-file.tgz"))
 				Expect(localPath).To(Equal(filepath.Join(sourceDir, "my/special-file.tgz")))
@@ -408,5 +470,494 @@ var _ = Describe("Out Command", func() {
 			})
 		})
 
+		Context("when specifying a storage class", func() {
+			BeforeEach(func() {
+				request.Params.File = "my/special-file.tgz"
+				request.Source.Regexp = "a-folder/some-file-(.*).tgz"
+				createFile("my/special-file.tgz")
+			})
+
+			It("uploads the file with the storage class from the params", func() {
+				request.Source.StorageClass = "STANDARD_IA"
+				request.Params.StorageClass = "GLACIER_IR"
+
+				response, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, _, _, _, options := s3client.UploadFileArgsForCall(0)
+				Expect(options.StorageClass).To(Equal("GLACIER_IR"))
+				Expect(response.Metadata).To(ContainElement(s3resource.MetadataPair{Name: "storage_class", Value: "GLACIER_IR"}))
+			})
+
+			It("falls back to the storage class in the source", func() {
+				request.Source.StorageClass = "STANDARD_IA"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, _, _, _, options := s3client.UploadFileArgsForCall(0)
+				Expect(options.StorageClass).To(Equal("STANDARD_IA"))
+			})
+
+			It("errors if the storage class in the params is not valid", func() {
+				request.Params.StorageClass = "COLD"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError(ContainSubstring("storage_class must be one of: ")))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if the storage class in the source is not valid", func() {
+				request.Source.StorageClass = "COLD"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError(ContainSubstring("storage_class must be one of: ")))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+		})
+
+		Context("when tuning the transfer", func() {
+			BeforeEach(func() {
+				request.Params.File = "my/special-file.tgz"
+				request.Source.Regexp = "a-folder/some-file-(.*).tgz"
+				createFile("my/special-file.tgz")
+			})
+
+			It("uploads the file with the part size and concurrency from the params", func() {
+				request.Source.UploadPartSize = "16MiB"
+				request.Source.UploadConcurrency = 2
+				request.Params.UploadPartSize = "64MiB"
+				request.Params.UploadConcurrency = 16
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, _, _, _, options := s3client.UploadFileArgsForCall(0)
+				Expect(options.PartSize).To(Equal(int64(64 * 1024 * 1024)))
+				Expect(options.Concurrency).To(Equal(16))
+			})
+
+			It("falls back to the part size and concurrency in the source", func() {
+				request.Source.UploadPartSize = "16MiB"
+				request.Source.UploadConcurrency = 2
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, _, _, _, options := s3client.UploadFileArgsForCall(0)
+				Expect(options.PartSize).To(Equal(int64(16 * 1024 * 1024)))
+				Expect(options.Concurrency).To(Equal(2))
+			})
+
+			It("errors if the part size is below the S3 minimum", func() {
+				request.Params.UploadPartSize = "1MiB"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("upload_part_size must be between 5MiB and 5GiB"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if the part size in the source is not a size", func() {
+				request.Source.UploadPartSize = "lots"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("upload_part_size must be between 5MiB and 5GiB"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("resumes uploads and aborts stale ones when configured in the source", func() {
+				request.Source.ResumeUploads = true
+				request.Source.AbortIncompleteUploadsAfter = "7d"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, _, _, _, options := s3client.UploadFileArgsForCall(0)
+				Expect(options.ResumeUpload).To(BeTrue())
+				Expect(options.AbortIncompleteUploadsAfter).To(Equal(7 * 24 * time.Hour))
+			})
+
+			It("errors if the bandwidth limit is not a size", func() {
+				request.Source.MaxBandwidth = "fast"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("max_bandwidth must be a positive number of bytes per second (e.g. 50MiB)"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if the stale upload threshold is not a duration", func() {
+				request.Source.AbortIncompleteUploadsAfter = "a week"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("abort_incomplete_uploads_after must be a positive duration (e.g. 24h or 7d)"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if the concurrency is negative", func() {
+				request.Params.UploadConcurrency = -1
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("upload_concurrency must be at least 1"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+		})
+
+		Context("when specifying object lock settings", func() {
+			BeforeEach(func() {
+				request.Params.File = "my/special-file.tgz"
+				request.Source.Regexp = "a-folder/some-file-(.*).tgz"
+				createFile("my/special-file.tgz")
+			})
+
+			It("uploads the file with the retention from the params", func() {
+				request.Params.ObjectLockMode = "COMPLIANCE"
+				request.Params.ObjectLockRetention = "30d"
+
+				response, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).ToNot(HaveOccurred())
+				Ω(s3client.UploadFileCallCount()).Should(Equal(1))
+
+				_, _, _, _, options := s3client.UploadFileArgsForCall(0)
+				Expect(options.ObjectLockMode).To(Equal("COMPLIANCE"))
+				Expect(*options.ObjectLockRetainUntilDate).To(BeTemporally("~", time.Now().Add(30*24*time.Hour), time.Minute))
+				Expect(options.ObjectLockLegalHold).To(BeFalse())
+
+				Expect(response.Metadata).To(ContainElement(s3resource.MetadataPair{Name: "object_lock_mode", Value: "COMPLIANCE"}))
+				Expect(response.Metadata).To(ContainElement(s3resource.MetadataPair{
+					Name:  "object_lock_retain_until_date",
+					Value: options.ObjectLockRetainUntilDate.Format(time.RFC3339),
+				}))
+			})
+
+			It("falls back to the settings in the source", func() {
+				request.Source.ObjectLockMode = "GOVERNANCE"
+				request.Source.ObjectLockRetention = "24h"
+				request.Source.ObjectLockLegalHold = true
+
+				response, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, _, _, _, options := s3client.UploadFileArgsForCall(0)
+				Expect(options.ObjectLockMode).To(Equal("GOVERNANCE"))
+				Expect(*options.ObjectLockRetainUntilDate).To(BeTemporally("~", time.Now().Add(24*time.Hour), time.Minute))
+				Expect(options.ObjectLockLegalHold).To(BeTrue())
+
+				Expect(response.Metadata).To(ContainElement(s3resource.MetadataPair{Name: "object_lock_legal_hold", Value: "ON"}))
+			})
+
+			It("lets the params override the source", func() {
+				legalHold := false
+				request.Source.ObjectLockMode = "GOVERNANCE"
+				request.Source.ObjectLockRetention = "24h"
+				request.Source.ObjectLockLegalHold = true
+				request.Params.ObjectLockMode = "COMPLIANCE"
+				request.Params.ObjectLockRetention = "48h"
+				request.Params.ObjectLockLegalHold = &legalHold
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, _, _, _, options := s3client.UploadFileArgsForCall(0)
+				Expect(options.ObjectLockMode).To(Equal("COMPLIANCE"))
+				Expect(*options.ObjectLockRetainUntilDate).To(BeTemporally("~", time.Now().Add(48*time.Hour), time.Minute))
+				Expect(options.ObjectLockLegalHold).To(BeFalse())
+			})
+
+			It("errors if the mode is given without a retention", func() {
+				request.Params.ObjectLockMode = "COMPLIANCE"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("please specify both object_lock_mode and object_lock_retention"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if the mode is not valid", func() {
+				request.Params.ObjectLockMode = "FOREVER"
+				request.Params.ObjectLockRetention = "30d"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("object_lock_mode must be one of: COMPLIANCE, GOVERNANCE"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if the retention is not a duration", func() {
+				request.Params.ObjectLockMode = "COMPLIANCE"
+				request.Params.ObjectLockRetention = "a month"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError(ContainSubstring("object_lock_retention must be a positive duration")))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+		})
+
+		Context("when specifying a customer-provided encryption key", func() {
+			BeforeEach(func() {
+				request.Params.File = "my/special-file.tgz"
+				request.Source.Regexp = "a-folder/some-file-(.*).tgz"
+				createFile("my/special-file.tgz")
+			})
+
+			It("errors if the key is not 256 bits", func() {
+				request.Source.SSECustomerKey = base64.StdEncoding.EncodeToString([]byte("too-short"))
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("sse_customer_key must be a base64 encoded 256-bit key"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if server side encryption is also set", func() {
+				request.Source.SSECustomerKey = base64.StdEncoding.EncodeToString(make([]byte, 32))
+				request.Source.ServerSideEncryption = "AES256"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError(ContainSubstring("please do not use server_side_encryption")))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+		})
+
+		Context("when specifying an endpoint variant", func() {
+			BeforeEach(func() {
+				request.Params.File = "my/special-file.tgz"
+				request.Source.Regexp = "a-folder/some-file-(.*).tgz"
+				createFile("my/special-file.tgz")
+			})
+
+			It("errors if transfer acceleration is used with path style", func() {
+				request.Source.UseAccelerate = true
+				request.Source.UsePathStyle = true
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("use_accelerate_endpoint cannot be used with use_path_style"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if transfer acceleration is used with FIPS", func() {
+				request.Source.UseAccelerate = true
+				request.Source.UseFIPS = true
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("use_accelerate_endpoint cannot be used with use_fips"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if a custom endpoint is also set", func() {
+				request.Source.UseDualStack = true
+				request.Source.Endpoint = "https://minio.example.com"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError(ContainSubstring("cannot be used with endpoint")))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+		})
+
+		Context("when specifying retry and timeout options", func() {
+			BeforeEach(func() {
+				request.Params.File = "my/special-file.tgz"
+				request.Source.Regexp = "a-folder/some-file-(.*).tgz"
+				createFile("my/special-file.tgz")
+			})
+
+			It("errors if the retry mode is unknown", func() {
+				request.Source.Retry.Mode = "eager"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("retry.mode must be one of: adaptive, standard"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if a timeout is not a duration", func() {
+				request.Source.Timeouts.Request = "10"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("timeouts.request must be a positive duration (e.g. 30s)"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+		})
+
+		Context("when specifying logging options", func() {
+			BeforeEach(func() {
+				request.Params.File = "my/special-file.tgz"
+				request.Source.Regexp = "a-folder/some-file-(.*).tgz"
+				createFile("my/special-file.tgz")
+			})
+
+			It("errors if the progress mode is unknown", func() {
+				request.Source.Progress = "dots"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("progress must be one of: bar, lines, none"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if the progress interval is neither a duration nor a percentage", func() {
+				request.Source.ProgressInterval = "10"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("progress_interval must be a duration (e.g. 30s) or a percentage (e.g. 10%)"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if the debug level is unknown", func() {
+				request.Source.Debug = "bodies"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("debug must be one of: requests, signing"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+		})
+
+		Context("when specifying TLS options", func() {
+			BeforeEach(func() {
+				request.Params.File = "my/special-file.tgz"
+				request.Source.Regexp = "a-folder/some-file-(.*).tgz"
+				createFile("my/special-file.tgz")
+			})
+
+			It("errors if a client certificate is given without a key", func() {
+				request.Source.ClientCert = "-----BEGIN CERTIFICATE-----"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("please specify both client_cert and client_key"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if the client certificate is not PEM", func() {
+				request.Source.ClientCert = "not a certificate"
+				request.Source.ClientKey = "not a key"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError(ContainSubstring("client_cert and client_key must be a PEM encoded certificate and its private key")))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if the TLS version is unknown", func() {
+				request.Source.TLSMinVersion = "1.4"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("tls_min_version must be one of: 1.0, 1.1, 1.2, 1.3"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if cipher suites are given for TLS 1.3", func() {
+				request.Source.TLSMinVersion = "1.3"
+				request.Source.TLSCipherSuites = []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("tls_cipher_suites cannot be configured for TLS 1.3"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+		})
+
+		Context("when specifying a proxy", func() {
+			BeforeEach(func() {
+				request.Params.File = "my/special-file.tgz"
+				request.Source.Regexp = "a-folder/some-file-(.*).tgz"
+				createFile("my/special-file.tgz")
+			})
+
+			It("errors if the proxy is not a URL", func() {
+				request.Source.HTTPSProxy = "ftp://proxy.example.com"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError(ContainSubstring("https_proxy must be a proxy URL")))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("errors if proxy credentials are given without a proxy", func() {
+				request.Source.ProxyUsername = "user"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("proxy_username and proxy_password require http_proxy or https_proxy"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+		})
+
+		Context("when specifying an expected bucket owner", func() {
+			BeforeEach(func() {
+				request.Params.File = "my/special-file.tgz"
+				request.Source.Regexp = "a-folder/some-file-(.*).tgz"
+				createFile("my/special-file.tgz")
+			})
+
+			It("errors if it is not an account ID", func() {
+				request.Source.ExpectedBucketOwner = "my-account"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("expected_bucket_owner must be a 12 digit AWS account ID"))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+
+			It("uploads the file", func() {
+				request.Source.ExpectedBucketOwner = "123456789012"
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).ToNot(HaveOccurred())
+				Ω(s3client.UploadFileCallCount()).Should(Equal(1))
+			})
+		})
+
+		Context("when running preflight checks", func() {
+			BeforeEach(func() {
+				request.Params.File = "my/special-file.tgz"
+				request.Source.Regexp = "a-folder/some-file-(.*).tgz"
+				request.Source.Preflight = true
+				createFile("my/special-file.tgz")
+			})
+
+			It("does not check anything without versioned_file or preflight", func() {
+				request.Source.Preflight = false
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).ToNot(HaveOccurred())
+				Ω(s3client.PreflightCallCount()).Should(Equal(0))
+			})
+
+			It("checks the actions the upload needs and prints a report", func() {
+				request.Source.ResumeUploads = true
+				request.Source.ObjectLockLegalHold = true
+				s3client.PreflightReturns(s3resource.PreflightReport{
+					{Check: "bucket", Detail: "bucket-name in us-east-1"},
+					{Check: "s3:PutObject", Skipped: true, Detail: "can only be checked by changing the bucket"},
+				})
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).ToNot(HaveOccurred())
+
+				Ω(s3client.PreflightCallCount()).Should(Equal(1))
+				_, _, options := s3client.PreflightArgsForCall(0)
+				Expect(options).To(Equal(s3resource.PreflightOptions{
+					Identity: true,
+					Actions: []string{
+						"s3:PutObject",
+						"s3:PutObjectAcl",
+						"s3:ListBucketMultipartUploads",
+						"s3:ListMultipartUploadParts",
+						"s3:GetObject",
+						"s3:PutObjectLegalHold",
+					},
+					Prefix: "a-folder/special-file.tgz",
+				}))
+
+				Expect(stderr).To(gbytes.Say(`ok\s+bucket\s+bucket-name in us-east-1`))
+				Expect(stderr).To(gbytes.Say(`skipped\s+s3:PutObject\s+can only be checked by changing the bucket`))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(1))
+			})
+
+			It("does not upload if a check fails", func() {
+				s3client.PreflightReturns(s3resource.PreflightReport{
+					{Check: "bucket", Err: &s3resource.Error{
+						Kind:      s3resource.ErrAccessDenied,
+						Operation: "HeadBucket",
+						Err:       errors.New("forbidden"),
+					}},
+				})
+
+				_, err := command.Run(context.Background(), sourceDir, request)
+				Expect(err).To(MatchError("preflight check bucket failed: forbidden"))
+				Expect(errors.Is(err, s3resource.ErrAccessDenied)).To(BeTrue())
+				Expect(stderr).To(gbytes.Say(`failed\s+bucket\s+forbidden`))
+				Ω(s3client.UploadFileCallCount()).Should(Equal(0))
+			})
+		})
 	})
 })
@@ -10,11 +10,19 @@ type Request struct {
 }
 
 type Params struct {
-	From        string `json:"from"`
-	File        string `json:"file"`
-	To          string `json:"to"`
-	Acl         string `json:"acl"`
-	ContentType string `json:"content_type"`
+	From         string `json:"from"`
+	File         string `json:"file"`
+	To           string `json:"to"`
+	Acl          string `json:"acl"`
+	ContentType  string `json:"content_type"`
+	StorageClass string `json:"storage_class"`
+
+	ObjectLockMode      string `json:"object_lock_mode"`
+	ObjectLockRetention string `json:"object_lock_retention"`
+	ObjectLockLegalHold *bool  `json:"object_lock_legal_hold"`
+
+	UploadPartSize    string `json:"upload_part_size"`
+	UploadConcurrency int    `json:"upload_concurrency"`
 }
 
 type Response struct {
@@ -0,0 +1,235 @@
+package s3resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// PreflightOptions are what Preflight checks besides the bucket
+type PreflightOptions struct {
+	// Identity checks the credentials with STS GetCallerIdentity. It is
+	// skipped for S3 compatible endpoints and anonymous credentials.
+	Identity bool
+
+	// Versioned checks that versioning is enabled on the bucket, as
+	// versioned_file requires
+	Versioned bool
+
+	// Actions are the IAM actions a step requires. Those which can be
+	// checked without changing the bucket are tried on Prefix, or on Key and
+	// VersionID for the actions on objects.
+	Actions   []string
+	Prefix    string
+	Key       string
+	VersionID string
+}
+
+// PreflightResult is the outcome of a single check
+type PreflightResult struct {
+	// Check is "credentials", "bucket", "versioning" or an IAM action
+	Check string
+	// Detail is what was found, or why the check was skipped
+	Detail  string
+	Skipped bool
+	Err     error
+}
+
+// PreflightReport is the outcome of every check, in the order they ran
+type PreflightReport []PreflightResult
+
+// Err joins the errors of the checks which failed, or is nil if none did
+func (report PreflightReport) Err() error {
+	var errs []error
+	for _, result := range report {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("preflight check %s failed: %w", result.Check, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Print writes a line for each check, and the hint of each check which
+// failed
+func (report PreflightReport) Print(output io.Writer) {
+	fmt.Fprintln(output, "preflight checks:")
+	for _, result := range report {
+		status, detail := "ok", result.Detail
+		if result.Skipped {
+			status = "skipped"
+		}
+		if result.Err != nil {
+			status, detail = "failed", result.Err.Error()
+		}
+
+		fmt.Fprintf(output, "  %-8s %-30s %s\n", status, result.Check, detail)
+		if hint := Hint(result.Err); hint != "" {
+			fmt.Fprintf(output, "  %-8s %-30s hint: %s\n", "", "", hint)
+		}
+	}
+}
+
+// actionProbes try IAM actions with requests which don't change the bucket.
+// A probe which returns an empty detail is skipped.
+var actionProbes = map[string]func(ctx context.Context, client *s3client, bucketName string, options PreflightOptions) (string, error){
+	"s3:ListBucket": func(ctx context.Context, client *s3client, bucketName string, options PreflightOptions) (string, error) {
+		_, err := client.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:              aws.String(bucketName),
+			Prefix:              aws.String(options.Prefix),
+			MaxKeys:             aws.Int32(1),
+			RequestPayer:        client.requestPayer,
+			ExpectedBucketOwner: client.expectedBucketOwner,
+		})
+		return "listed " + describePrefix(options.Prefix), err
+	},
+	"s3:ListBucketVersions": func(ctx context.Context, client *s3client, bucketName string, options PreflightOptions) (string, error) {
+		_, err := client.client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+			Bucket:              aws.String(bucketName),
+			Prefix:              aws.String(options.Prefix),
+			MaxKeys:             aws.Int32(1),
+			RequestPayer:        client.requestPayer,
+			ExpectedBucketOwner: client.expectedBucketOwner,
+		})
+		return "listed the versions of " + describePrefix(options.Prefix), err
+	},
+	"s3:ListBucketMultipartUploads": func(ctx context.Context, client *s3client, bucketName string, options PreflightOptions) (string, error) {
+		_, err := client.client.ListMultipartUploads(ctx, &s3.ListMultipartUploadsInput{
+			Bucket:              aws.String(bucketName),
+			Prefix:              aws.String(options.Prefix),
+			MaxUploads:          aws.Int32(1),
+			RequestPayer:        client.requestPayer,
+			ExpectedBucketOwner: client.expectedBucketOwner,
+		})
+		return "listed the multipart uploads of " + describePrefix(options.Prefix), err
+	},
+	"s3:GetObject": func(ctx context.Context, client *s3client, bucketName string, options PreflightOptions) (string, error) {
+		if options.Key == "" {
+			return "", nil
+		}
+		_, err := client.headObject(ctx, bucketName, options.Key, "")
+		return "read " + options.Key, err
+	},
+	"s3:GetObjectVersion": func(ctx context.Context, client *s3client, bucketName string, options PreflightOptions) (string, error) {
+		if options.Key == "" || options.VersionID == "" {
+			return "", nil
+		}
+		_, err := client.headObject(ctx, bucketName, options.Key, options.VersionID)
+		return fmt.Sprintf("read version %s of %s", options.VersionID, options.Key), err
+	},
+}
+
+func describePrefix(prefix string) string {
+	if prefix == "" {
+		return "the bucket"
+	}
+	return prefix
+}
+
+// Preflight checks the credentials, that the bucket exists in the configured
+// region, and what options asks for. It makes no changes to the bucket, so
+// it can be run before any that would.
+func (client *s3client) Preflight(ctx context.Context, bucketName string, options PreflightOptions) PreflightReport {
+	var report PreflightReport
+
+	if options.Identity {
+		report = append(report, client.checkIdentity(ctx))
+	}
+
+	bucket := client.checkBucket(ctx, bucketName)
+	report = append(report, bucket)
+	if bucket.Err != nil {
+		// Every other check would fail the same way
+		return report
+	}
+
+	if options.Versioned {
+		versioning := PreflightResult{Check: "versioning", Detail: "enabled"}
+		versioned, err := client.getBucketVersioning(ctx, bucketName)
+		if err != nil {
+			versioning.Err = err
+		} else if !versioned {
+			versioning.Err = &Error{Kind: ErrNotVersioned, Err: errors.New("bucket is not versioned")}
+		}
+		report = append(report, versioning)
+	}
+
+	for _, action := range options.Actions {
+		result := PreflightResult{Check: action}
+
+		probe, ok := actionProbes[action]
+		if !ok {
+			result.Skipped = true
+			result.Detail = "can only be checked by changing the bucket"
+		} else {
+			result.Detail, result.Err = probe(ctx, client, bucketName, options)
+			if result.Detail == "" {
+				result.Skipped = true
+				result.Detail = "no object to check"
+			}
+		}
+
+		report = append(report, result)
+	}
+
+	return report
+}
+
+func (client *s3client) checkIdentity(ctx context.Context) PreflightResult {
+	result := PreflightResult{Check: "credentials"}
+
+	if client.sts == nil {
+		result.Skipped = true
+		result.Detail = "can only be checked with AWS credentials and endpoints"
+		return result
+	}
+
+	identity, err := client.sts.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Detail = aws.ToString(identity.Arn)
+	return result
+}
+
+func (client *s3client) checkBucket(ctx context.Context, bucketName string) PreflightResult {
+	result := PreflightResult{Check: "bucket"}
+
+	region := client.client.Options().Region
+	bucket, err := client.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket:              aws.String(bucketName),
+		ExpectedBucketOwner: client.expectedBucketOwner,
+	})
+	if err != nil {
+		// S3 redirects requests made to the wrong region, naming the region
+		// of the bucket
+		var responseErr *awshttp.ResponseError
+		if errors.As(err, &responseErr) && responseErr.Response != nil {
+			bucketRegion := responseErr.Response.Header.Get("X-Amz-Bucket-Region")
+			if bucketRegion != "" && bucketRegion != region {
+				err = &Error{
+					Kind:      ErrInvalidConfig,
+					Service:   "S3",
+					Operation: "HeadBucket",
+					Err:       fmt.Errorf("bucket %s is in region %s, but region_name is %s", bucketName, bucketRegion, region),
+				}
+			}
+		}
+
+		result.Err = err
+		return result
+	}
+
+	result.Detail = bucketName
+	if bucketRegion := aws.ToString(bucket.BucketRegion); bucketRegion != "" {
+		result.Detail = fmt.Sprintf("%s in %s", bucketName, bucketRegion)
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package s3resource
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Preflight", func() {
+	var (
+		endpoint string
+		client   S3Client
+
+		bucketStatus int
+		versioning   string
+	)
+
+	BeforeEach(func() {
+		bucketStatus = http.StatusOK
+		versioning = "Enabled"
+
+		server := NewTestServer(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/xml")
+			query := r.URL.Query()
+			switch {
+			case r.Method == http.MethodHead && r.URL.Path == "/bucket":
+				w.Header().Set("X-Amz-Bucket-Region", "eu-west-1")
+				w.WriteHeader(bucketStatus)
+			case query.Has("versioning"):
+				io.WriteString(w, `<VersioningConfiguration><Status>`+versioning+`</Status></VersioningConfiguration>`)
+			case query.Get("list-type") == "2":
+				w.WriteHeader(http.StatusForbidden)
+				io.WriteString(w, `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+			case query.Has("versions"):
+				io.WriteString(w, `<ListVersionsResult></ListVersionsResult>`)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+
+		endpoint = server.URL
+		client = NewTestS3Client(io.Discard, NewTestAwsConfig("eu-west-1"), endpoint, S3ClientOptions{})
+	})
+
+	It("reports each check", func() {
+		report := client.Preflight(context.Background(), "bucket", PreflightOptions{
+			Identity:  true,
+			Versioned: true,
+			Actions:   []string{"s3:ListBucketVersions", "s3:ListBucket", "s3:PutObject", "s3:GetObject"},
+		})
+
+		Expect(report).To(HaveLen(7))
+		Expect(report[0]).To(Equal(PreflightResult{Check: "credentials", Skipped: true, Detail: "can only be checked with AWS credentials and endpoints"}))
+		Expect(report[1]).To(Equal(PreflightResult{Check: "bucket", Detail: "bucket in eu-west-1"}))
+		Expect(report[2]).To(Equal(PreflightResult{Check: "versioning", Detail: "enabled"}))
+		Expect(report[3]).To(Equal(PreflightResult{Check: "s3:ListBucketVersions", Detail: "listed the versions of the bucket"}))
+		Expect(report[4].Check).To(Equal("s3:ListBucket"))
+		Expect(errors.Is(report[4].Err, ErrAccessDenied)).To(BeTrue())
+		Expect(report[5]).To(Equal(PreflightResult{Check: "s3:PutObject", Skipped: true, Detail: "can only be checked by changing the bucket"}))
+		Expect(report[6]).To(Equal(PreflightResult{Check: "s3:GetObject", Skipped: true, Detail: "no object to check"}))
+
+		err := report.Err()
+		Expect(err).To(MatchError(ContainSubstring("preflight check s3:ListBucket failed: ")))
+		Expect(Hint(err)).To(ContainSubstring("s3:ListBucket permission"))
+
+		output := &bytes.Buffer{}
+		report.Print(output)
+		Expect(output.String()).To(MatchRegexp(`ok\s+bucket\s+bucket in eu-west-1\n`))
+		Expect(output.String()).To(MatchRegexp(`failed\s+s3:ListBucket\s+.*AccessDenied`))
+		Expect(output.String()).To(MatchRegexp(`\s+hint: the credentials need the s3:ListBucket permission`))
+	})
+
+	It("reports buckets which are not versioned", func() {
+		versioning = "Suspended"
+
+		report := client.Preflight(context.Background(), "bucket", PreflightOptions{Versioned: true})
+		Expect(report).To(HaveLen(2))
+		Expect(report.Err()).To(MatchError("preflight check versioning failed: bucket is not versioned"))
+		Expect(errors.Is(report.Err(), ErrNotVersioned)).To(BeTrue())
+	})
+
+	It("reports the region of the bucket", func() {
+		bucketStatus = http.StatusMovedPermanently
+
+		client = NewTestS3Client(io.Discard, NewTestAwsConfig("us-east-1"), endpoint, S3ClientOptions{})
+
+		report := client.Preflight(context.Background(), "bucket", PreflightOptions{Versioned: true})
+		Expect(report).To(HaveLen(1))
+		Expect(report.Err()).To(MatchError("preflight check bucket failed: bucket bucket is in region eu-west-1, but region_name is us-east-1"))
+		Expect(errors.Is(report.Err(), ErrInvalidConfig)).To(BeTrue())
+		Expect(Hint(report.Err())).To(ContainSubstring("region_name"))
+	})
+
+	It("has no error when no check failed", func() {
+		Expect(PreflightReport{}.Err()).ToNot(HaveOccurred())
+	})
+})
@@ -0,0 +1,318 @@
+package s3resource
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vbauerster/mpb/v8"
+	"github.com/vbauerster/mpb/v8/decor"
+	"golang.org/x/time/rate"
+)
+
+// ProgressMode is how the progress of uploads and downloads is reported
+type ProgressMode string
+
+const (
+	// ProgressBar redraws a progress bar in place, which suits terminals
+	ProgressBar ProgressMode = "bar"
+	// ProgressLines prints a line with the throughput and ETA every
+	// progress interval, which suits build logs
+	ProgressLines ProgressMode = "lines"
+	// ProgressNone reports nothing
+	ProgressNone ProgressMode = "none"
+)
+
+var progressModes = []ProgressMode{ProgressBar, ProgressLines, ProgressNone}
+
+// DefaultProgressInterval prints a line for every tenth of a transfer, so
+// that the log of a transfer is the same size however long it takes
+const DefaultProgressInterval = "10%"
+
+// progressInterval is how often ProgressLines prints a line: after a
+// duration, or after a percentage of the transfer
+type progressInterval struct {
+	duration time.Duration
+	percent  int64
+}
+
+// ParseProgressInterval parses a duration such as "30s" or a percentage
+// such as "10%"
+func ParseProgressInterval(interval string) (progressInterval, error) {
+	if percent, ok := strings.CutSuffix(interval, "%"); ok {
+		value, err := strconv.ParseInt(percent, 10, 64)
+		if err != nil || value <= 0 || value > 100 {
+			return progressInterval{}, fmt.Errorf("invalid percentage: %s", interval)
+		}
+		return progressInterval{percent: value}, nil
+	}
+
+	duration, err := time.ParseDuration(interval)
+	if err != nil {
+		return progressInterval{}, err
+	}
+	if duration <= 0 {
+		return progressInterval{}, fmt.Errorf("invalid duration: %s", interval)
+	}
+	return progressInterval{duration: duration}, nil
+}
+
+// progressReporter reports the bytes transferred of a single upload or
+// download
+type progressReporter interface {
+	// Add records that n more bytes were transferred
+	Add(n int64)
+	// Complete records that the transfer has finished, which an empty file
+	// never reports otherwise
+	Complete()
+	// Wait stops reporting, whether or not the transfer completed
+	Wait()
+}
+
+// transfers holds how the clients report and throttle their uploads and
+// downloads
+type transfers struct {
+	progressOutput   io.Writer
+	progressMode     ProgressMode
+	progressInterval progressInterval
+
+	// bandwidth is shared by all the parts of every transfer, or nil if
+	// transfers are not throttled
+	bandwidth *rate.Limiter
+}
+
+func newTransfers(progressOutput io.Writer, options S3ClientOptions) (transfers, error) {
+	settings := transfers{
+		progressOutput: progressOutput,
+		progressMode:   options.Progress,
+	}
+
+	progressInterval := options.ProgressInterval
+	if progressInterval == "" {
+		progressInterval = DefaultProgressInterval
+	}
+	interval, err := ParseProgressInterval(progressInterval)
+	if err != nil {
+		return transfers{}, fmt.Errorf("error parsing progress interval: %w", err)
+	}
+	settings.progressInterval = interval
+
+	if options.MaxBandwidth != "" {
+		bytesPerSecond, err := ParseByteSize(options.MaxBandwidth)
+		if err != nil {
+			return transfers{}, fmt.Errorf("error parsing max bandwidth: %w", err)
+		}
+		settings.bandwidth = newBandwidthLimiter(bytesPerSecond)
+	}
+
+	return settings, nil
+}
+
+func (settings transfers) newProgress(total int64) progressReporter {
+	switch settings.progressMode {
+	case ProgressNone:
+		return noProgress{}
+	case ProgressLines:
+		return newLineProgress(settings.progressOutput, total, settings.progressInterval)
+	default:
+		return newBarProgress(settings.progressOutput, total)
+	}
+}
+
+// progressReader reports the bytes read through it
+type progressReader struct {
+	io.Reader
+	progress progressReporter
+}
+
+func (pr progressReader) Read(p []byte) (int, error) {
+	n, err := pr.Reader.Read(p)
+	pr.progress.Add(int64(n))
+	return n, err
+}
+
+type noProgress struct{}
+
+func (noProgress) Add(int64) {}
+func (noProgress) Complete() {}
+func (noProgress) Wait()     {}
+
+type barProgress struct {
+	bar *mpb.Bar
+}
+
+func newBarProgress(output io.Writer, total int64) barProgress {
+	pg := mpb.New(mpb.WithWidth(80), mpb.WithOutput(output), mpb.WithAutoRefresh())
+	bar := pg.New(total, mpb.BarStyle(),
+		mpb.PrependDecorators(
+			decor.Counters(decor.SizeB1024(0), "% .2f / % .2f"),
+		),
+		mpb.AppendDecorators(
+			decor.NewPercentage("%d - "),
+			decor.AverageSpeed(decor.SizeB1024(0), "% .2f"),
+		),
+	)
+	return barProgress{bar}
+}
+
+func (progress barProgress) Add(n int64) {
+	progress.bar.IncrInt64(n)
+}
+
+func (progress barProgress) Complete() {
+	// See https://github.com/vbauerster/mpb/issues/7
+	progress.bar.SetTotal(-1, true)
+}
+
+func (progress barProgress) Wait() {
+	// A bar which did not complete, e.g. because the transfer failed, would
+	// be waited for forever
+	if !progress.bar.Completed() {
+		progress.bar.Abort(false)
+	}
+	progress.bar.Wait()
+}
+
+// lineProgress prints a line with the bytes transferred, the throughput and
+// the ETA after each interval, and once the transfer completes
+type lineProgress struct {
+	output   io.Writer
+	total    int64
+	interval progressInterval
+	start    time.Time
+
+	mu          sync.Mutex
+	transferred int64
+	nextLine    int64
+	finished    bool
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+func newLineProgress(output io.Writer, total int64, interval progressInterval) *lineProgress {
+	progress := &lineProgress{
+		output:   output,
+		total:    total,
+		interval: interval,
+		start:    time.Now(),
+		stop:     make(chan struct{}),
+	}
+	progress.nextLine = progress.step()
+
+	if interval.duration > 0 {
+		progress.done.Add(1)
+		go progress.tick()
+	}
+
+	return progress
+}
+
+// step is how many bytes are transferred between lines when printing after
+// a percentage, or 0 when printing after a duration
+func (progress *lineProgress) step() int64 {
+	if progress.interval.percent == 0 {
+		return 0
+	}
+	return max(progress.total*progress.interval.percent/100, 1)
+}
+
+func (progress *lineProgress) tick() {
+	defer progress.done.Done()
+
+	ticker := time.NewTicker(progress.interval.duration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			progress.mu.Lock()
+			progress.printLine()
+			progress.mu.Unlock()
+		case <-progress.stop:
+			return
+		}
+	}
+}
+
+func (progress *lineProgress) Add(n int64) {
+	progress.mu.Lock()
+	defer progress.mu.Unlock()
+
+	progress.transferred += n
+	if progress.transferred >= progress.total {
+		progress.complete()
+		return
+	}
+
+	step := progress.step()
+	if step > 0 && progress.transferred >= progress.nextLine {
+		progress.printLine()
+		progress.nextLine = (progress.transferred/step + 1) * step
+	}
+}
+
+func (progress *lineProgress) Complete() {
+	progress.mu.Lock()
+	defer progress.mu.Unlock()
+
+	progress.complete()
+}
+
+func (progress *lineProgress) complete() {
+	if progress.finished {
+		return
+	}
+	progress.finished = true
+
+	elapsed := time.Since(progress.start)
+	fmt.Fprintf(progress.output, "% .2f in %s (% .2f/s)\n",
+		decor.SizeB1024(progress.transferred),
+		elapsed.Round(time.Second),
+		decor.SizeB1024(bytesPerSecond(progress.transferred, elapsed)),
+	)
+}
+
+func (progress *lineProgress) Wait() {
+	close(progress.stop)
+	progress.done.Wait()
+}
+
+// printLine prints the progress so far, unless the transfer has finished
+func (progress *lineProgress) printLine() {
+	if progress.finished {
+		return
+	}
+
+	elapsed := time.Since(progress.start)
+	speed := bytesPerSecond(progress.transferred, elapsed)
+
+	eta := "unknown"
+	if speed > 0 {
+		remaining := time.Duration(float64(progress.total-progress.transferred) / float64(speed) * float64(time.Second))
+		eta = remaining.Round(time.Second).String()
+	}
+
+	percent := int64(100)
+	if progress.total > 0 {
+		percent = progress.transferred * 100 / progress.total
+	}
+
+	fmt.Fprintf(progress.output, "% .2f / % .2f (%d%%), % .2f/s, ETA %s\n",
+		decor.SizeB1024(progress.transferred),
+		decor.SizeB1024(progress.total),
+		percent,
+		decor.SizeB1024(speed),
+		eta,
+	)
+}
+
+func bytesPerSecond(n int64, elapsed time.Duration) int64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return int64(float64(n) / elapsed.Seconds())
+}
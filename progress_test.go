@@ -0,0 +1,88 @@
+package s3resource
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Progress", func() {
+	DescribeTable("ParseProgressInterval",
+		func(interval string, expected progressInterval, valid bool) {
+			parsed, err := ParseProgressInterval(interval)
+			if !valid {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed).To(Equal(expected))
+		},
+		Entry("a duration", "30s", progressInterval{duration: 30 * time.Second}, true),
+		Entry("a percentage", "10%", progressInterval{percent: 10}, true),
+		Entry("a zero duration", "0s", progressInterval{}, false),
+		Entry("a zero percentage", "0%", progressInterval{}, false),
+		Entry("over 100%", "150%", progressInterval{}, false),
+		Entry("a number", "10", progressInterval{}, false),
+	)
+
+	Describe("lines", func() {
+		var output *bytes.Buffer
+
+		BeforeEach(func() {
+			output = &bytes.Buffer{}
+		})
+
+		lines := func() []string {
+			return strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+		}
+
+		It("prints a line after each percentage and once complete", func() {
+			progress := newLineProgress(output, 1000, progressInterval{percent: 25})
+			for range 10 {
+				progress.Add(100)
+			}
+			progress.Complete()
+			progress.Wait()
+
+			Expect(lines()).To(HaveLen(4))
+			Expect(lines()[0]).To(HavePrefix("300.00 b / 1000.00 b (30%), "))
+			Expect(lines()[0]).To(ContainSubstring("/s, ETA "))
+			Expect(lines()[1]).To(HavePrefix("500.00 b / 1000.00 b (50%), "))
+			Expect(lines()[2]).To(HavePrefix("800.00 b / 1000.00 b (80%), "))
+			Expect(lines()[3]).To(MatchRegexp(`^1000.00 b in \d+s \(.+/s\)$`))
+		})
+
+		It("prints a line after each duration", func() {
+			progress := newLineProgress(output, 1000, progressInterval{duration: 10 * time.Millisecond})
+			progress.Add(100)
+
+			Eventually(func() string {
+				progress.mu.Lock()
+				defer progress.mu.Unlock()
+				return output.String()
+			}).Should(HavePrefix("100.00 b / 1000.00 b (10%), "))
+			progress.Wait()
+		})
+
+		It("prints the final line for empty files", func() {
+			progress := newLineProgress(output, 0, progressInterval{percent: 10})
+			progress.Complete()
+			progress.Wait()
+
+			Expect(lines()).To(HaveLen(1))
+			Expect(lines()[0]).To(HavePrefix("0.00 b in "))
+		})
+
+		It("prints nothing more once stopped", func() {
+			progress := newLineProgress(output, 1000, progressInterval{duration: time.Millisecond})
+			progress.Wait()
+
+			printed := output.Len()
+			progress.Add(10)
+			Consistently(output.Len, "20ms").Should(Equal(printed))
+		})
+	})
+})
@@ -6,7 +6,7 @@ import (
 
 type progressWriterAt struct {
 	io.WriterAt
-	io.Writer
+	progress progressReporter
 }
 
 func (pwa progressWriterAt) WriteAt(p []byte, off int64) (int, error) {
@@ -15,6 +15,6 @@ func (pwa progressWriterAt) WriteAt(p []byte, off int64) (int, error) {
 		return n, err
 	}
 
-	pwa.Write(p)
+	pwa.progress.Add(int64(n))
 	return n, err
 }
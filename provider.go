@@ -0,0 +1,187 @@
+package s3resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Provider is a service with an S3 compatible API, whose profile sets the
+// settings it needs
+type Provider string
+
+const (
+	ProviderAWS          Provider = "aws"
+	ProviderMinIO        Provider = "minio"
+	ProviderGCS          Provider = "gcs"
+	ProviderR2           Provider = "r2"
+	ProviderB2           Provider = "b2"
+	ProviderWasabi       Provider = "wasabi"
+	ProviderCeph         Provider = "ceph"
+	ProviderDigitalOcean Provider = "digitalocean"
+)
+
+var providers = []Provider{
+	ProviderAWS,
+	ProviderMinIO,
+	ProviderGCS,
+	ProviderR2,
+	ProviderB2,
+	ProviderWasabi,
+	ProviderCeph,
+	ProviderDigitalOcean,
+}
+
+// providerProfile holds the values a provider gives the source fields which
+// are not set
+type providerProfile struct {
+	// endpoint is the endpoint of the provider, where %s is replaced by
+	// region_name. It is empty for providers which are self-hosted or have an
+	// endpoint per account.
+	endpoint string
+	region   string
+
+	usePathStyle     bool
+	skipS3Checksums  bool
+	disableMultipart bool
+	disableACLs      bool
+
+	// private is set for providers which don't serve objects from their API
+	// endpoint without a signature
+	private bool
+}
+
+var providerProfiles = map[Provider]providerProfile{
+	ProviderAWS: {},
+	ProviderMinIO: {
+		usePathStyle: true,
+	},
+	ProviderGCS: {
+		endpoint:        "https://storage.googleapis.com",
+		skipS3Checksums: true,
+		// GCS returns `InvalidArgument` on multipart uploads
+		disableMultipart: true,
+	},
+	ProviderR2: {
+		region:          "auto",
+		skipS3Checksums: true,
+		disableACLs:     true,
+		private:         true,
+	},
+	ProviderB2: {
+		endpoint:        "https://s3.%s.backblazeb2.com",
+		skipS3Checksums: true,
+	},
+	ProviderWasabi: {
+		endpoint:        "https://s3.%s.wasabisys.com",
+		region:          "us-east-1",
+		skipS3Checksums: true,
+	},
+	ProviderCeph: {
+		usePathStyle:    true,
+		skipS3Checksums: true,
+	},
+	ProviderDigitalOcean: {
+		endpoint:        "https://%s.digitaloceanspaces.com",
+		skipS3Checksums: true,
+	},
+}
+
+// UnmarshalJSON records the fields which are given, so that ApplyProvider
+// doesn't override them even when they are given their zero value
+func (source *Source) UnmarshalJSON(data []byte) error {
+	type plainSource Source
+	if err := json.Unmarshal(data, (*plainSource)(source)); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	source.givenFields = make(map[string]bool, len(fields))
+	for field := range fields {
+		source.givenFields[field] = true
+	}
+
+	return nil
+}
+
+// ApplyProvider sets the fields which are not given to the values of the
+// profile of the provider, and returns a note for each of them. A field is
+// given when it was in the JSON the source was decoded from, or when it
+// isn't empty.
+func (source *Source) ApplyProvider() []string {
+	provider := source.Provider
+	if provider == "" && strings.Contains(source.Endpoint, "storage.googleapis.com") {
+		// GCS was detected by its endpoint before there were providers
+		provider = ProviderGCS
+	}
+
+	profile, ok := providerProfiles[provider]
+	if !ok {
+		return nil
+	}
+
+	var notes []string
+	set := func(field string, value any) {
+		notes = append(notes, fmt.Sprintf("provider %s sets %s: %v", provider, field, value))
+	}
+
+	if !source.givenFields["region_name"] && source.RegionName == "" && profile.region != "" {
+		source.RegionName = profile.region
+		set("region_name", source.RegionName)
+	}
+
+	if !source.givenFields["endpoint"] && source.Endpoint == "" && profile.endpoint != "" {
+		if !strings.Contains(profile.endpoint, "%s") {
+			source.Endpoint = profile.endpoint
+			set("endpoint", source.Endpoint)
+		} else if source.RegionName != "" {
+			source.Endpoint = fmt.Sprintf(profile.endpoint, source.RegionName)
+			set("endpoint", source.Endpoint)
+		}
+	}
+
+	defaults := []struct {
+		field string
+		value *bool
+		apply bool
+	}{
+		{"use_path_style", &source.UsePathStyle, profile.usePathStyle},
+		{"skip_s3_checksums", &source.SkipS3Checksums, profile.skipS3Checksums},
+		{"disable_multipart", &source.DisableMultipart, profile.disableMultipart},
+		{"disable_acls", &source.DisableACLs, profile.disableACLs},
+		{"private", &source.Private, profile.private},
+	}
+	for _, setting := range defaults {
+		if !source.givenFields[setting.field] && !*setting.value && setting.apply {
+			*setting.value = true
+			set(setting.field, true)
+		}
+	}
+
+	return notes
+}
+
+// validateProvider checks that the provider is known and that its endpoint
+// is given or can be worked out from region_name
+func (source Source) validateProvider() (bool, string) {
+	if source.Provider == "" {
+		return true, ""
+	}
+
+	if ok, message := validateOneOf("provider", string(source.Provider), providers); !ok {
+		return false, message
+	}
+
+	if source.Provider != ProviderAWS && source.Endpoint == "" {
+		if strings.Contains(providerProfiles[source.Provider].endpoint, "%s") {
+			return false, fmt.Sprintf("provider %s requires endpoint or region_name", source.Provider)
+		}
+		return false, fmt.Sprintf("provider %s requires endpoint", source.Provider)
+	}
+
+	return true, ""
+}
@@ -0,0 +1,103 @@
+package s3resource_test
+
+import (
+	"encoding/json"
+
+	s3resource "github.com/concourse/s3-resource"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Provider", func() {
+	unmarshal := func(data string) s3resource.Source {
+		var source s3resource.Source
+		Expect(json.Unmarshal([]byte(data), &source)).To(Succeed())
+		source.ApplyProvider()
+		return source
+	}
+
+	It("sets the fields which are not given from the profile", func() {
+		source := unmarshal(`{"provider": "minio", "endpoint": "https://minio.example.com"}`)
+		Expect(source.UsePathStyle).To(BeTrue())
+		Expect(source.SkipS3Checksums).To(BeFalse())
+		Expect(source.Endpoint).To(Equal("https://minio.example.com"))
+	})
+
+	It("lets the fields which are given override the profile", func() {
+		source := unmarshal(`{"provider": "ceph", "endpoint": "https://ceph.example.com", "use_path_style": false}`)
+		Expect(source.UsePathStyle).To(BeFalse())
+		Expect(source.SkipS3Checksums).To(BeTrue())
+	})
+
+	DescribeTable("working out the endpoint and region",
+		func(data string, endpoint string, region string) {
+			source := unmarshal(data)
+			Expect(source.Endpoint).To(Equal(endpoint))
+			Expect(source.RegionName).To(Equal(region))
+		},
+		Entry("gcs", `{"provider": "gcs"}`, "https://storage.googleapis.com", ""),
+		Entry("r2", `{"provider": "r2", "endpoint": "https://account.r2.cloudflarestorage.com"}`, "https://account.r2.cloudflarestorage.com", "auto"),
+		Entry("b2", `{"provider": "b2", "region_name": "us-west-004"}`, "https://s3.us-west-004.backblazeb2.com", "us-west-004"),
+		Entry("wasabi", `{"provider": "wasabi"}`, "https://s3.us-east-1.wasabisys.com", "us-east-1"),
+		Entry("digitalocean", `{"provider": "digitalocean", "region_name": "nyc3"}`, "https://nyc3.digitaloceanspaces.com", "nyc3"),
+		Entry("aws", `{"provider": "aws", "region_name": "eu-west-1"}`, "", "eu-west-1"),
+	)
+
+	It("signs the URLs of providers which don't serve public objects from their endpoint", func() {
+		source := unmarshal(`{"provider": "r2", "endpoint": "https://account.r2.cloudflarestorage.com"}`)
+		Expect(source.Private).To(BeTrue())
+		Expect(source.DisableACLs).To(BeTrue())
+	})
+
+	It("applies the profile to a source which wasn't decoded", func() {
+		source := s3resource.Source{Provider: s3resource.ProviderR2, Endpoint: "https://account.r2.cloudflarestorage.com", DisableACLs: true}
+		notes := source.ApplyProvider()
+		Expect(source.RegionName).To(Equal("auto"))
+		Expect(source.SkipS3Checksums).To(BeTrue())
+		Expect(source.Private).To(BeTrue())
+		Expect(notes).To(Equal([]string{
+			"provider r2 sets region_name: auto",
+			"provider r2 sets skip_s3_checksums: true",
+			"provider r2 sets private: true",
+		}))
+	})
+
+	It("reports nothing once the profile is applied", func() {
+		source := unmarshal(`{"provider": "wasabi"}`)
+		Expect(source.ApplyProvider()).To(BeEmpty())
+	})
+
+	It("doesn't override the fields which are given their zero value", func() {
+		var source s3resource.Source
+		Expect(json.Unmarshal([]byte(`{"provider": "r2", "endpoint": "https://account.r2.cloudflarestorage.com", "private": false}`), &source)).To(Succeed())
+		Expect(source.ApplyProvider()).ToNot(ContainElement(ContainSubstring("private")))
+		Expect(source.Private).To(BeFalse())
+	})
+
+	It("detects GCS by its endpoint", func() {
+		source := unmarshal(`{"endpoint": "https://storage.googleapis.com"}`)
+		Expect(source.DisableMultipart).To(BeTrue())
+		Expect(source.SkipS3Checksums).To(BeTrue())
+	})
+
+	It("leaves the source alone without a provider", func() {
+		source := s3resource.Source{Endpoint: "https://s3.example.com", Bucket: "bucket-name"}
+		Expect(source.ApplyProvider()).To(BeEmpty())
+		Expect(source).To(Equal(s3resource.Source{Endpoint: "https://s3.example.com", Bucket: "bucket-name"}))
+	})
+
+	DescribeTable("validating",
+		func(data string, message string) {
+			var source s3resource.Source
+			Expect(json.Unmarshal([]byte(data), &source)).To(Succeed())
+			ok, actual := source.IsValid()
+			Expect(ok).To(Equal(message == ""))
+			Expect(actual).To(Equal(message))
+		},
+		Entry("a known provider", `{"provider": "minio", "endpoint": "https://minio.example.com"}`, ""),
+		Entry("an unknown provider", `{"provider": "s4"}`, "provider must be one of: aws, b2, ceph, digitalocean, gcs, minio, r2, wasabi"),
+		Entry("no endpoint", `{"provider": "minio"}`, "provider minio requires endpoint"),
+		Entry("no endpoint or region", `{"provider": "b2"}`, "provider b2 requires endpoint or region_name"),
+	)
+})
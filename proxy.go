@@ -0,0 +1,92 @@
+package s3resource
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// parseProxyURL parses a proxy URL, which like in the HTTP_PROXY environment
+// variable defaults to the http scheme
+func parseProxyURL(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("missing proxy host")
+	}
+
+	return proxyURL, nil
+}
+
+// proxyFunc returns the proxy to use for each request according to options,
+// in place of the proxy settings from the environment
+func proxyFunc(options AwsConfigOptions) (func(*http.Request) (*url.URL, error), error) {
+	withAuth := func(proxy string) (string, error) {
+		if proxy == "" {
+			return "", nil
+		}
+
+		proxyURL, err := parseProxyURL(proxy)
+		if err != nil {
+			return "", fmt.Errorf("error parsing proxy URL: %w", err)
+		}
+		if options.ProxyUsername != "" {
+			proxyURL.User = url.UserPassword(options.ProxyUsername, options.ProxyPassword)
+		}
+
+		return proxyURL.String(), nil
+	}
+
+	httpProxy, err := withAuth(options.HTTPProxy)
+	if err != nil {
+		return nil, err
+	}
+	httpsProxy, err := withAuth(options.HTTPSProxy)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := (&httpproxy.Config{
+		HTTPProxy:  httpProxy,
+		HTTPSProxy: httpsProxy,
+		NoProxy:    options.NoProxy,
+	}).ProxyFunc()
+
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}, nil
+}
+
+// proxyRootCAs returns the pool of roots trusted by the transport with the
+// proxy CA added to it, starting from the system roots if none are set yet
+func proxyRootCAs(rootCAs *x509.CertPool, proxyCABundle string) (*x509.CertPool, error) {
+	if rootCAs == nil {
+		systemRoots, err := x509.SystemCertPool()
+		if err != nil {
+			systemRoots = x509.NewCertPool()
+		}
+		rootCAs = systemRoots
+	}
+
+	if !rootCAs.AppendCertsFromPEM([]byte(proxyCABundle)) {
+		return nil, fmt.Errorf("failed to load proxy CA bundle PEM")
+	}
+
+	return rootCAs, nil
+}
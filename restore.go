@@ -0,0 +1,154 @@
+package s3resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	DefaultRestoreTier    = string(types.TierStandard)
+	DefaultRestoreDays    = 1
+	DefaultRestoreTimeout = 12 * time.Hour
+
+	restorePollInterval = time.Minute
+)
+
+// ObjectArchivedError is returned when an object cannot be downloaded because
+// it has transitioned to an archive tier and has not been restored
+type ObjectArchivedError struct {
+	StorageClass      string
+	RestoreInProgress bool
+}
+
+func (err ObjectArchivedError) Error() string {
+	if err.RestoreInProgress {
+		return fmt.Sprintf("object is archived in %s and its restore is still in progress", err.StorageClass)
+	}
+	return fmt.Sprintf("object is archived in %s and must be restored before it can be downloaded", err.StorageClass)
+}
+
+type RestoreObjectOptions struct {
+	// Tier is the retrieval tier: Standard, Bulk or Expedited
+	Tier string
+	// Days is how long the restored copy is kept. It does not apply to
+	// objects archived by S3 Intelligent-Tiering.
+	Days    int32
+	Timeout time.Duration
+}
+
+func NewRestoreObjectOptions() RestoreObjectOptions {
+	return RestoreObjectOptions{
+		Tier:    DefaultRestoreTier,
+		Days:    DefaultRestoreDays,
+		Timeout: DefaultRestoreTimeout,
+	}
+}
+
+// archivedObjectError returns an ObjectArchivedError if the object can only
+// be downloaded after it is restored
+func archivedObjectError(object *s3.HeadObjectOutput) error {
+	storageClass := string(object.StorageClass)
+	if object.ArchiveStatus != "" {
+		storageClass = string(object.ArchiveStatus)
+	} else if object.StorageClass != types.StorageClassGlacier && object.StorageClass != types.StorageClassDeepArchive {
+		return nil
+	}
+
+	restored, inProgress := restoreStatus(object.Restore)
+	if restored {
+		return nil
+	}
+
+	return ObjectArchivedError{
+		StorageClass:      storageClass,
+		RestoreInProgress: inProgress,
+	}
+}
+
+// restoreStatus parses the x-amz-restore header, e.g.
+// `ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`
+func restoreStatus(restore *string) (restored bool, inProgress bool) {
+	if restore == nil {
+		return false, false
+	}
+
+	if strings.Contains(*restore, `ongoing-request="true"`) {
+		return false, true
+	}
+
+	return strings.Contains(*restore, `ongoing-request="false"`), false
+}
+
+// RestoreObject restores an archived object and waits until the restored copy
+// can be downloaded, or options.Timeout has passed
+func (client *s3client) RestoreObject(ctx context.Context, bucketName string, remotePath string, versionID string, options RestoreObjectOptions) error {
+	object, err := client.headObject(ctx, bucketName, remotePath, versionID)
+	if err != nil {
+		return err
+	}
+
+	var archivedErr ObjectArchivedError
+	if !errors.As(archivedObjectError(object), &archivedErr) {
+		return nil
+	}
+
+	if !archivedErr.RestoreInProgress {
+		restoreRequest := &types.RestoreRequest{
+			GlacierJobParameters: &types.GlacierJobParameters{
+				Tier: types.Tier(options.Tier),
+			},
+		}
+		if object.ArchiveStatus == "" {
+			restoreRequest.Days = aws.Int32(options.Days)
+		}
+
+		restoreObject := &s3.RestoreObjectInput{
+			Bucket:              aws.String(bucketName),
+			Key:                 aws.String(remotePath),
+			RestoreRequest:      restoreRequest,
+			RequestPayer:        client.requestPayer,
+			ExpectedBucketOwner: client.expectedBucketOwner,
+		}
+		if versionID != "" {
+			restoreObject.VersionId = aws.String(versionID)
+		}
+
+		_, err = client.client.RestoreObject(ctx, restoreObject)
+		var apiErr smithy.APIError
+		if err != nil && !(errors.As(err, &apiErr) && apiErr.ErrorCode() == "RestoreAlreadyInProgress") {
+			return fmt.Errorf("error restoring object: %w", err)
+		}
+	}
+
+	fmt.Fprintf(client.progressOutput, "waiting for %s restore of %s from %s\n", options.Tier, remotePath, archivedErr.StorageClass)
+
+	deadline := time.Now().Add(options.Timeout)
+	for {
+		object, err := client.headObject(ctx, bucketName, remotePath, versionID)
+		if err != nil {
+			return err
+		}
+
+		if restored, _ := restoreStatus(object.Restore); restored {
+			return nil
+		}
+
+		if time.Now().Add(restorePollInterval).After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for restore of %s", options.Timeout, remotePath)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(restorePollInterval):
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package s3resource
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Archived objects", func() {
+	DescribeTable("detecting objects which must be restored",
+		func(object *s3.HeadObjectOutput, expected error) {
+			if expected == nil {
+				Expect(archivedObjectError(object)).To(BeNil())
+			} else {
+				Expect(archivedObjectError(object)).To(Equal(expected))
+			}
+		},
+		Entry("a standard object",
+			&s3.HeadObjectOutput{StorageClass: types.StorageClassStandard},
+			nil,
+		),
+		Entry("an instant retrieval object",
+			&s3.HeadObjectOutput{StorageClass: types.StorageClassGlacierIr},
+			nil,
+		),
+		Entry("an archived object",
+			&s3.HeadObjectOutput{StorageClass: types.StorageClassGlacier},
+			ObjectArchivedError{StorageClass: "GLACIER"},
+		),
+		Entry("a deep archived object being restored",
+			&s3.HeadObjectOutput{
+				StorageClass: types.StorageClassDeepArchive,
+				Restore:      aws.String(`ongoing-request="true"`),
+			},
+			ObjectArchivedError{StorageClass: "DEEP_ARCHIVE", RestoreInProgress: true},
+		),
+		Entry("a restored object",
+			&s3.HeadObjectOutput{
+				StorageClass: types.StorageClassGlacier,
+				Restore:      aws.String(`ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`),
+			},
+			nil,
+		),
+		Entry("an object archived by intelligent tiering",
+			&s3.HeadObjectOutput{
+				StorageClass:  types.StorageClassIntelligentTiering,
+				ArchiveStatus: types.ArchiveStatusDeepArchiveAccess,
+			},
+			ObjectArchivedError{StorageClass: "DEEP_ARCHIVE_ACCESS"},
+		),
+	)
+})
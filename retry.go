@@ -0,0 +1,29 @@
+package s3resource
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+// newRetryer returns a retryer making up to maxAttempts attempts, waiting at
+// most maxBackoff (if positive) between them. The adaptive mode also slows
+// down requests when S3 throttles them with SlowDown errors.
+func newRetryer(maxAttempts int, maxBackoff time.Duration, mode aws.RetryMode) func() aws.Retryer {
+	standardOptions := func(o *retry.StandardOptions) {
+		o.MaxAttempts = maxAttempts
+		if maxBackoff > 0 {
+			o.MaxBackoff = maxBackoff
+		}
+	}
+
+	return func() aws.Retryer {
+		if mode == aws.RetryModeAdaptive {
+			return retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+				o.StandardOptions = append(o.StandardOptions, standardOptions)
+			})
+		}
+		return retry.NewStandard(standardOptions)
+	}
+}
@@ -2,15 +2,19 @@ package s3resource
 
 import (
 	"context"
+	"crypto/md5"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -20,40 +24,154 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
-	"github.com/vbauerster/mpb/v8"
-	"github.com/vbauerster/mpb/v8/decor"
+	"github.com/aws/smithy-go/middleware"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
 //counterfeiter:generate -o fakes . S3Client
 type S3Client interface {
-	BucketFiles(bucketName string, prefixHint string) ([]string, error)
-	BucketFileVersions(bucketName string, remotePath string) ([]string, error)
+	BucketFiles(ctx context.Context, bucketName string, prefixHint string) ([]string, error)
+	BucketFileVersions(ctx context.Context, bucketName string, remotePath string) ([]string, error)
 
-	ChunkedBucketList(bucketName string, prefix string, continuationToken *string) (BucketListChunk, error)
+	ChunkedBucketList(ctx context.Context, bucketName string, prefix string, continuationToken *string) (BucketListChunk, error)
+	BucketFilesModifiedSince(ctx context.Context, bucketName string, prefix string, since time.Time) ([]string, error)
 
-	UploadFile(bucketName string, remotePath string, localPath string, options UploadFileOptions) (string, error)
-	DownloadFile(bucketName string, remotePath string, versionID string, localPath string) error
+	UploadFile(ctx context.Context, bucketName string, remotePath string, localPath string, options UploadFileOptions) (string, error)
+	DownloadFile(ctx context.Context, bucketName string, remotePath string, versionID string, localPath string, options DownloadFileOptions) error
+	OpenFile(ctx context.Context, bucketName string, remotePath string) (io.ReadCloser, error)
+	RestoreObject(ctx context.Context, bucketName string, remotePath string, versionID string, options RestoreObjectOptions) error
 
-	SetTags(bucketName string, remotePath string, versionID string, tags map[string]string) error
-	DownloadTags(bucketName string, remotePath string, versionID string, localPath string) error
+	SetTags(ctx context.Context, bucketName string, remotePath string, versionID string, tags map[string]string) error
+	DownloadTags(ctx context.Context, bucketName string, remotePath string, versionID string, localPath string) error
+	DownloadObjectLock(ctx context.Context, bucketName string, remotePath string, versionID string, localPath string) error
 
-	DeleteFile(bucketName string, remotePath string) error
-	DeleteVersionedFile(bucketName string, remotePath string, versionID string) error
+	DeleteFile(ctx context.Context, bucketName string, remotePath string) error
+	DeleteVersionedFile(ctx context.Context, bucketName string, remotePath string, versionID string) error
 
-	URL(bucketName string, remotePath string, private bool, versionID string) (string, error)
+	URL(ctx context.Context, bucketName string, remotePath string, private bool, versionID string) (string, error)
+
+	Preflight(ctx context.Context, bucketName string, options PreflightOptions) PreflightReport
 }
 
+// DefaultSSECustomerAlgorithm is the only algorithm S3 supports for SSE-C
+const DefaultSSECustomerAlgorithm = "AES256"
+
 // 12 retries works out to ~5 mins of total backoff time, though AWS randomizes
 // the backoff to some extent so it may be as low as 4 or as high as 8 minutes
 const MaxRetries = 12
 
 type s3client struct {
-	client         *s3.Client
-	progressOutput io.Writer
+	client *s3.Client
+	transfers
+
+	sseCustomerAlgorithm *string
+	sseCustomerKey       *string
+	sseCustomerKeyMD5    *string
+
+	keyWrapper keyWrapper
+
+	requestPayer        types.RequestPayer
+	expectedBucketOwner *string
+
+	// sts checks the credentials in Preflight, or is nil for S3 compatible
+	// endpoints and anonymous credentials
+	sts *sts.Client
+}
+
+// AwsConfigOptions holds settings of the HTTP client shared by the S3 and
+// STS clients
+type AwsConfigOptions struct {
+	// Profile names a profile from the shared config. SharedConfig and
+	// SharedCredentials are the contents of a shared config and credentials
+	// file, which replace the files usually read from ~/.aws.
+	Profile           string
+	SharedConfig      string
+	SharedCredentials string
+
+	// HTTPProxy, HTTPSProxy and NoProxy replace the proxy settings from the
+	// environment if any of them is given. They take the same values as the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+
+	// ProxyUsername and ProxyPassword authenticate with the proxies using
+	// basic auth
+	ProxyUsername string
+	ProxyPassword string
+
+	// ProxyCABundle is trusted in addition to the system roots or CABundle,
+	// for proxies whose certificate it signed
+	ProxyCABundle string
+
+	// ClientCert and ClientKey are a PEM encoded certificate and private key
+	// presented to endpoints which require mutual TLS
+	ClientCert string
+	ClientKey  string
+
+	// TLSMinVersion (e.g. "1.2") and TLSCipherSuites (IANA names) restrict
+	// the TLS connections made
+	TLSMinVersion   string
+	TLSCipherSuites []string
+
+	// RetryMaxAttempts defaults to MaxRetries. RetryMaxBackoff is a duration
+	// such as "20s", and RetryMode is either "standard" or "adaptive".
+	RetryMaxAttempts int
+	RetryMaxBackoff  string
+	RetryMode        string
+
+	// ConnectTimeout limits establishing connections, and RequestTimeout
+	// waiting for the response headers once a request was sent, so that
+	// transferring large bodies isn't cut short. Both are durations such as
+	// "30s".
+	ConnectTimeout string
+	RequestTimeout string
+
+	// Debug logs the requests made with the config to stderr, with their
+	// credentials, signatures and SSE-C keys redacted
+	Debug DebugLevel
+}
+
+// S3ClientOptions holds settings which apply to every request the client
+// makes rather than to a single upload or download.
+type S3ClientOptions struct {
+	// SSECustomerKey is the base64 encoded key used for server-side
+	// encryption with customer-provided keys (SSE-C).
+	SSECustomerKey       string
+	SSECustomerAlgorithm string
+
+	// ClientSideEncryptionKey is a base64 encoded 256-bit key used to wrap
+	// the data keys of objects encrypted on the client. It is ignored if
+	// ClientSideEncryptionKMSKeyID is set.
+	ClientSideEncryptionKey      string
+	ClientSideEncryptionKMSKeyID string
+
+	// RequesterPays acknowledges that the requester, rather than the bucket
+	// owner, is charged for requests to a Requester Pays bucket.
+	RequesterPays bool
+	// ExpectedBucketOwner is the account ID which must own the bucket for a
+	// request to succeed.
+	ExpectedBucketOwner string
+
+	// UseAccelerateEndpoint, UseDualStack and UseFIPS select the AWS S3
+	// endpoint variant. They cannot be combined with a custom endpoint.
+	UseAccelerateEndpoint bool
+	UseDualStack          bool
+	UseFIPS               bool
+
+	// MaxBandwidth limits the bytes per second uploaded or downloaded across
+	// all parts, e.g. "50MiB". Transfers are not throttled if it is empty.
+	MaxBandwidth string
+
+	// Progress defaults to ProgressBar. ProgressInterval is how often
+	// ProgressLines prints a line, e.g. "30s" or "10%", and defaults to
+	// DefaultProgressInterval.
+	Progress         ProgressMode
+	ProgressInterval string
 }
 
 type UploadFileOptions struct {
@@ -63,6 +181,38 @@ type UploadFileOptions struct {
 	ContentType          string
 	DisableMultipart     bool
 	ChecksumAlgorithm    string
+	StorageClass         string
+
+	// ObjectLockMode and ObjectLockRetainUntilDate must be set together
+	ObjectLockMode            string
+	ObjectLockRetainUntilDate *time.Time
+	ObjectLockLegalHold       bool
+
+	// PartSize is the size of the parts of multipart uploads. It grows
+	// automatically when the file would need more parts than S3 allows.
+	PartSize    int64
+	Concurrency int
+
+	// ResumeUpload resumes an incomplete multipart upload of the same file
+	// to the same key, and leaves the parts of failed uploads to be resumed
+	ResumeUpload bool
+	// AbortIncompleteUploadsAfter aborts the incomplete multipart uploads
+	// next to the uploaded object which are older than this, if not zero
+	AbortIncompleteUploadsAfter time.Duration
+}
+
+// DownloadFileOptions controls how objects are split into ranged GETs
+// downloaded in parallel
+type DownloadFileOptions struct {
+	PartSize    int64
+	Concurrency int
+}
+
+// ObjectLock describes the Object Lock state of an object version
+type ObjectLock struct {
+	Mode            string     `json:"mode,omitempty"`
+	RetainUntilDate *time.Time `json:"retain_until_date,omitempty"`
+	LegalHold       string     `json:"legal_hold,omitempty"`
 }
 
 func NewUploadFileOptions() UploadFileOptions {
@@ -71,13 +221,25 @@ func NewUploadFileOptions() UploadFileOptions {
 	}
 }
 
+func NewDownloadFileOptions() DownloadFileOptions {
+	return DownloadFileOptions{
+		PartSize:    manager.DefaultDownloadPartSize,
+		Concurrency: manager.DefaultDownloadConcurrency,
+	}
+}
+
 func NewS3Client(
 	progressOutput io.Writer,
 	awsConfig *aws.Config,
 	endpoint string,
 	disableSSL, usePathStyle, skipS3Checksums bool,
 	checksumAlgorithm string,
+	options S3ClientOptions,
 ) (S3Client, error) {
+	if isFileEndpoint(endpoint) {
+		return newFileClient(progressOutput, endpoint, options)
+	}
+
 	s3Opts := []func(*s3.Options){}
 
 	if endpoint != "" {
@@ -108,15 +270,74 @@ func NewS3Client(
 		})
 	}
 
-	client := s3.NewFromConfig(*awsConfig, s3Opts...)
+	s3Opts = append(s3Opts, func(o *s3.Options) {
+		o.UseAccelerate = options.UseAccelerateEndpoint
+		if options.UseDualStack {
+			o.EndpointOptions.UseDualStackEndpoint = aws.DualStackEndpointStateEnabled
+		}
+		if options.UseFIPS {
+			o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+		}
+	})
 
-	return &s3client{
-		client:         client,
-		progressOutput: progressOutput,
-	}, nil
+	transfers, err := newTransfers(progressOutput, options)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &s3client{
+		client:    s3.NewFromConfig(*awsConfig, s3Opts...),
+		transfers: transfers,
+	}
+
+	if endpoint == "" && !aws.IsCredentialsProvider(awsConfig.Credentials, aws.AnonymousCredentials{}) {
+		client.sts = sts.NewFromConfig(*awsConfig)
+	}
+
+	if options.RequesterPays {
+		client.requestPayer = types.RequestPayerRequester
+	}
+	if options.ExpectedBucketOwner != "" {
+		client.expectedBucketOwner = aws.String(options.ExpectedBucketOwner)
+	}
+
+	if options.SSECustomerKey != "" {
+		key, err := base64.StdEncoding.DecodeString(options.SSECustomerKey)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding sse customer key: %w", err)
+		}
+
+		algorithm := options.SSECustomerAlgorithm
+		if algorithm == "" {
+			algorithm = DefaultSSECustomerAlgorithm
+		}
+
+		keyMD5 := md5.Sum(key)
+		client.sseCustomerAlgorithm = aws.String(algorithm)
+		client.sseCustomerKey = aws.String(options.SSECustomerKey)
+		client.sseCustomerKeyMD5 = aws.String(base64.StdEncoding.EncodeToString(keyMD5[:]))
+	}
+
+	if options.ClientSideEncryptionKMSKeyID != "" {
+		client.keyWrapper = kmsKeyWrapper{
+			client: kms.NewFromConfig(*awsConfig),
+			keyID:  options.ClientSideEncryptionKMSKeyID,
+		}
+	} else if options.ClientSideEncryptionKey != "" {
+		key, err := base64.StdEncoding.DecodeString(options.ClientSideEncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding client-side encryption key: %w", err)
+		}
+		client.keyWrapper = staticKeyWrapper{key: key}
+	}
+
+	return client, nil
 }
 
+// NewAwsConfig loads the AWS config of a source. Credentials are checked, and
+// a role assumed, with ctx so that the command can still be interrupted.
 func NewAwsConfig(
+	ctx context.Context,
 	accessKey string,
 	secretKey string,
 	sessionToken string,
@@ -125,22 +346,28 @@ func NewAwsConfig(
 	skipSSLVerification bool,
 	caBundle string,
 	useAwsCredsProvider bool,
+	options AwsConfigOptions,
 ) (*aws.Config, error) {
 	var creds aws.CredentialsProvider
 
-	if roleToAssume == "" && !useAwsCredsProvider {
+	// A profile or inline shared config may resolve credentials through any
+	// mechanism the SDK understands (credential_process, SSO, etc.), so only
+	// fall back to anonymous access when none of them are given.
+	usesSharedConfig := options.Profile != "" || options.SharedConfig != "" || options.SharedCredentials != ""
+
+	if roleToAssume == "" && !useAwsCredsProvider && !usesSharedConfig {
 		creds = aws.AnonymousCredentials{}
 	}
 
 	if accessKey != "" && secretKey != "" {
 		creds = aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(accessKey, secretKey, sessionToken))
-		_, err := creds.Retrieve(context.Background())
+		_, err := creds.Retrieve(ctx)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	if len(regionName) == 0 {
+	if len(regionName) == 0 && !usesSharedConfig {
 		regionName = "us-east-1"
 	}
 
@@ -171,20 +398,154 @@ func NewAwsConfig(
 		}
 	}
 
-	cfg, err := config.LoadDefaultConfig(context.Background(),
-		config.WithRegion(regionName),
+	if options.ClientCert != "" || options.ClientKey != "" {
+		certificate, err := tls.X509KeyPair([]byte(options.ClientCert), []byte(options.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		httpClient = httpClient.WithTransportOptions(func(tr *http.Transport) {
+			if tr.TLSClientConfig == nil {
+				tr.TLSClientConfig = &tls.Config{}
+			}
+			tr.TLSClientConfig.Certificates = []tls.Certificate{certificate}
+		})
+	}
+	if options.TLSMinVersion != "" {
+		version, err := ParseTLSVersion(options.TLSMinVersion)
+		if err != nil {
+			return nil, err
+		}
+		httpClient = httpClient.WithTransportOptions(func(tr *http.Transport) {
+			if tr.TLSClientConfig == nil {
+				tr.TLSClientConfig = &tls.Config{}
+			}
+			tr.TLSClientConfig.MinVersion = version
+		})
+	}
+	if len(options.TLSCipherSuites) > 0 {
+		cipherSuites, err := ParseCipherSuites(options.TLSCipherSuites)
+		if err != nil {
+			return nil, err
+		}
+		httpClient = httpClient.WithTransportOptions(func(tr *http.Transport) {
+			if tr.TLSClientConfig == nil {
+				tr.TLSClientConfig = &tls.Config{}
+			}
+			tr.TLSClientConfig.CipherSuites = cipherSuites
+		})
+	}
+
+	if options.HTTPProxy != "" || options.HTTPSProxy != "" || options.NoProxy != "" {
+		proxy, err := proxyFunc(options)
+		if err != nil {
+			return nil, err
+		}
+		httpClient = httpClient.WithTransportOptions(func(tr *http.Transport) {
+			tr.Proxy = proxy
+		})
+	}
+	if options.ProxyCABundle != "" {
+		if !x509.NewCertPool().AppendCertsFromPEM([]byte(options.ProxyCABundle)) {
+			return nil, fmt.Errorf("failed to load proxy CA bundle PEM")
+		}
+		httpClient = httpClient.WithTransportOptions(func(tr *http.Transport) {
+			if tr.TLSClientConfig == nil {
+				tr.TLSClientConfig = &tls.Config{}
+			}
+			// The bundle was checked above, so this can't fail
+			tr.TLSClientConfig.RootCAs, _ = proxyRootCAs(tr.TLSClientConfig.RootCAs, options.ProxyCABundle)
+		})
+	}
+
+	if options.ConnectTimeout != "" {
+		timeout, err := time.ParseDuration(options.ConnectTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing connect timeout: %w", err)
+		}
+		httpClient = httpClient.WithDialerOptions(func(d *net.Dialer) {
+			d.Timeout = timeout
+		}).WithTransportOptions(func(tr *http.Transport) {
+			tr.TLSHandshakeTimeout = timeout
+		})
+	}
+	if options.RequestTimeout != "" {
+		timeout, err := time.ParseDuration(options.RequestTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing request timeout: %w", err)
+		}
+		httpClient = httpClient.WithTransportOptions(func(tr *http.Transport) {
+			tr.ResponseHeaderTimeout = timeout
+		})
+	}
+
+	maxAttempts := MaxRetries
+	if options.RetryMaxAttempts > 0 {
+		maxAttempts = options.RetryMaxAttempts
+	}
+	var maxBackoff time.Duration
+	if options.RetryMaxBackoff != "" {
+		var err error
+		maxBackoff, err = time.ParseDuration(options.RetryMaxBackoff)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing retry max backoff: %w", err)
+		}
+	}
+
+	loadOpts := []func(*config.LoadOptions) error{
+		config.WithAPIOptions([]func(*middleware.Stack) error{addClassifyErrors}),
 		config.WithHTTPClient(httpClient),
-		config.WithRetryMaxAttempts(MaxRetries),
+		config.WithRetryer(newRetryer(maxAttempts, maxBackoff, aws.RetryMode(options.RetryMode))),
 		config.WithCredentialsProvider(creds),
-	)
+	}
+	if options.Debug != "" {
+		loadOpts = append(loadOpts,
+			config.WithClientLogMode(options.Debug.clientLogMode()),
+			config.WithLogger(newRedactingLogger(os.Stderr)),
+		)
+	}
+	if regionName != "" {
+		loadOpts = append(loadOpts, config.WithRegion(regionName))
+	}
+	if options.Profile != "" {
+		loadOpts = append(loadOpts, config.WithSharedConfigProfile(options.Profile))
+	}
+
+	// The SDK only reads shared config from files, so inline contents are
+	// written to temporary files that can be removed once the config is loaded
+	if options.SharedConfig != "" {
+		path, err := writeSharedConfigFile("aws-config", options.SharedConfig)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(path)
+		loadOpts = append(loadOpts, config.WithSharedConfigFiles([]string{path}))
+	}
+	if options.SharedCredentials != "" {
+		path, err := writeSharedConfigFile("aws-credentials", options.SharedCredentials)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(path)
+		loadOpts = append(loadOpts, config.WithSharedCredentialsFiles([]string{path}))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("error loading default AWS config: %w", err)
 	}
 
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	// The SDK only records these when it builds the retryer itself
+	cfg.RetryMaxAttempts = maxAttempts
+	cfg.RetryMode = aws.RetryMode(options.RetryMode)
+
 	if roleToAssume != "" {
 		stsClient := sts.NewFromConfig(cfg)
 		stsCreds := stscreds.NewAssumeRoleProvider(stsClient, roleToAssume)
-		roleCreds, err := stsCreds.Retrieve(context.TODO())
+		roleCreds, err := stsCreds.Retrieve(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("error assuming role: %w", err)
 		}
@@ -196,11 +557,31 @@ func NewAwsConfig(
 		))
 	}
 
+	// Credentials which can't be found or refreshed fail with ErrAuth
+	if cfg.Credentials != nil && !aws.IsCredentialsProvider(cfg.Credentials, aws.AnonymousCredentials{}) {
+		cfg.Credentials = authErrorProvider{cfg.Credentials}
+	}
+
 	return &cfg, nil
 }
 
+func writeSharedConfigFile(pattern string, contents string) (string, error) {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("error creating shared config file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(contents); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("error writing shared config file: %w", err)
+	}
+
+	return file.Name(), nil
+}
+
 // BucketFiles returns all the files in bucketName immediately under directoryPrefix
-func (client *s3client) BucketFiles(bucketName string, directoryPrefix string) ([]string, error) {
+func (client *s3client) BucketFiles(ctx context.Context, bucketName string, directoryPrefix string) ([]string, error) {
 	if !strings.HasSuffix(directoryPrefix, "/") {
 		directoryPrefix = directoryPrefix + "/"
 	}
@@ -210,7 +591,7 @@ func (client *s3client) BucketFiles(bucketName string, directoryPrefix string) (
 		paths             []string
 	)
 	for continuationToken, truncated = nil, true; truncated; {
-		s3ListChunk, err := client.ChunkedBucketList(bucketName, directoryPrefix, continuationToken)
+		s3ListChunk, err := client.ChunkedBucketList(ctx, bucketName, directoryPrefix, continuationToken)
 		if err != nil {
 			return []string{}, err
 		}
@@ -221,17 +602,17 @@ func (client *s3client) BucketFiles(bucketName string, directoryPrefix string) (
 	return paths, nil
 }
 
-func (client *s3client) BucketFileVersions(bucketName string, remotePath string) ([]string, error) {
-	isBucketVersioned, err := client.getBucketVersioning(bucketName)
+func (client *s3client) BucketFileVersions(ctx context.Context, bucketName string, remotePath string) ([]string, error) {
+	isBucketVersioned, err := client.getBucketVersioning(ctx, bucketName)
 	if err != nil {
 		return []string{}, err
 	}
 
 	if !isBucketVersioned {
-		return []string{}, errors.New("bucket is not versioned")
+		return []string{}, &Error{Kind: ErrNotVersioned, Err: errors.New("bucket is not versioned")}
 	}
 
-	bucketFiles, err := client.getVersionedBucketContents(bucketName, remotePath)
+	bucketFiles, err := client.getVersionedBucketContents(ctx, bucketName, remotePath)
 
 	if err != nil {
 		return []string{}, err
@@ -260,14 +641,16 @@ type BucketListChunk struct {
 // the subdirectories in `CommonPrefixes`. If the returned chunk does not
 // include all the files and subdirectories, the `Truncated` flag will be set
 // to `true` and the `ContinuationToken` can be used to retrieve the next chunk.
-func (client *s3client) ChunkedBucketList(bucketName string, prefix string, continuationToken *string) (BucketListChunk, error) {
+func (client *s3client) ChunkedBucketList(ctx context.Context, bucketName string, prefix string, continuationToken *string) (BucketListChunk, error) {
 	params := &s3.ListObjectsV2Input{
-		Bucket:            aws.String(bucketName),
-		ContinuationToken: continuationToken,
-		Delimiter:         aws.String("/"),
-		Prefix:            aws.String(prefix),
+		Bucket:              aws.String(bucketName),
+		ContinuationToken:   continuationToken,
+		Delimiter:           aws.String("/"),
+		Prefix:              aws.String(prefix),
+		RequestPayer:        client.requestPayer,
+		ExpectedBucketOwner: client.expectedBucketOwner,
 	}
-	response, err := client.client.ListObjectsV2(context.TODO(), params)
+	response, err := client.client.ListObjectsV2(ctx, params)
 	if err != nil {
 		return BucketListChunk{}, err
 	}
@@ -290,12 +673,40 @@ func (client *s3client) ChunkedBucketList(bucketName string, prefix string, cont
 	}, nil
 }
 
-func (client *s3client) UploadFile(bucketName string, remotePath string, localPath string, options UploadFileOptions) (string, error) {
-	uploader := manager.NewUploader(client.client)
+// BucketFilesModifiedSince returns all the files in bucketName under prefix,
+// however deep, which were last modified at or after since
+func (client *s3client) BucketFilesModifiedSince(ctx context.Context, bucketName string, prefix string, since time.Time) ([]string, error) {
+	params := &s3.ListObjectsV2Input{
+		Bucket:              aws.String(bucketName),
+		Prefix:              aws.String(prefix),
+		RequestPayer:        client.requestPayer,
+		ExpectedBucketOwner: client.expectedBucketOwner,
+	}
 
-	if client.isGCSHost() {
-		// GCS returns `InvalidArgument` on multipart uploads
-		uploader.MaxUploadParts = 1
+	paths := []string{}
+	paginator := s3.NewListObjectsV2Paginator(client.client, params)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return []string{}, err
+		}
+		for _, object := range page.Contents {
+			if !aws.ToTime(object.LastModified).Before(since) {
+				paths = append(paths, *object.Key)
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+func (client *s3client) UploadFile(ctx context.Context, bucketName string, remotePath string, localPath string, options UploadFileOptions) (string, error) {
+	uploader := manager.NewUploader(client.client)
+	if options.PartSize > 0 {
+		uploader.PartSize = options.PartSize
+	}
+	if options.Concurrency > 0 {
+		uploader.Concurrency = options.Concurrency
 	}
 
 	stat, err := os.Stat(localPath)
@@ -310,12 +721,30 @@ func (client *s3client) UploadFile(bucketName string, remotePath string, localPa
 
 	defer localFile.Close()
 
-	// Automatically adjust partsize for larger files.
 	fSize := stat.Size()
+
+	// Size of the object as stored, which grows when encrypted on the client
+	uploadSize := fSize
+
+	var (
+		envelope         *encryptionEnvelope
+		envelopeMetadata map[string]string
+	)
+	if client.keyWrapper != nil {
+		objectEnvelope, metadata, err := newEncryptionEnvelope(ctx, client.keyWrapper, fSize)
+		if err != nil {
+			return "", err
+		}
+		envelope = &objectEnvelope
+		envelopeMetadata = metadata
+		uploadSize = envelope.ciphertextSize(fSize)
+	}
+
+	// Automatically adjust partsize for larger files.
 	if !options.DisableMultipart {
-		if fSize > int64(uploader.MaxUploadParts)*uploader.PartSize {
-			partSize := fSize / int64(uploader.MaxUploadParts)
-			if fSize%int64(uploader.MaxUploadParts) != 0 {
+		if uploadSize > int64(uploader.MaxUploadParts)*uploader.PartSize {
+			partSize := uploadSize / int64(uploader.MaxUploadParts)
+			if uploadSize%int64(uploader.MaxUploadParts) != 0 {
 				partSize++
 			}
 			uploader.PartSize = partSize
@@ -323,20 +752,19 @@ func (client *s3client) UploadFile(bucketName string, remotePath string, localPa
 	} else {
 		uploader.MaxUploadParts = 1
 		uploader.Concurrency = 1
-		uploader.PartSize = fSize + 1
-		if fSize <= manager.MinUploadPartSize {
+		uploader.PartSize = uploadSize + 1
+		if uploadSize <= manager.MinUploadPartSize {
 			uploader.PartSize = manager.MinUploadPartSize
 		}
 	}
 
-	progress := client.newProgressBar(fSize)
-	defer progress.Wait()
-
 	uploadInput := &s3.PutObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(remotePath),
-		Body:   progress.ProxyReader(localFile),
-		ACL:    types.ObjectCannedACL(options.Acl),
+		Bucket:              aws.String(bucketName),
+		Key:                 aws.String(remotePath),
+		ACL:                 types.ObjectCannedACL(options.Acl),
+		Metadata:            envelopeMetadata,
+		RequestPayer:        client.requestPayer,
+		ExpectedBucketOwner: client.expectedBucketOwner,
 	}
 	if options.ServerSideEncryption != "" {
 		uploadInput.ServerSideEncryption = types.ServerSideEncryption(options.ServerSideEncryption)
@@ -350,17 +778,96 @@ func (client *s3client) UploadFile(bucketName string, remotePath string, localPa
 	if options.ChecksumAlgorithm != "" {
 		uploadInput.ChecksumAlgorithm = types.ChecksumAlgorithm(options.ChecksumAlgorithm)
 	}
+	if options.StorageClass != "" {
+		uploadInput.StorageClass = types.StorageClass(options.StorageClass)
+	}
+	if options.ObjectLockMode != "" {
+		uploadInput.ObjectLockMode = types.ObjectLockMode(options.ObjectLockMode)
+		uploadInput.ObjectLockRetainUntilDate = options.ObjectLockRetainUntilDate
+	}
+	if options.ObjectLockLegalHold {
+		uploadInput.ObjectLockLegalHoldStatus = types.ObjectLockLegalHoldStatusOn
+	}
+	if (options.ObjectLockMode != "" || options.ObjectLockLegalHold) && uploadInput.ChecksumAlgorithm == "" {
+		// S3 rejects Object Lock uploads which do not carry a checksum
+		uploadInput.ChecksumAlgorithm = types.ChecksumAlgorithmCrc32
+	}
+	if client.sseCustomerKey != nil {
+		// The uploader copies these onto each part of a multipart upload
+		uploadInput.SSECustomerAlgorithm = client.sseCustomerAlgorithm
+		uploadInput.SSECustomerKey = client.sseCustomerKey
+		uploadInput.SSECustomerKeyMD5 = client.sseCustomerKeyMD5
+	}
+
+	if options.AbortIncompleteUploadsAfter > 0 {
+		err = client.abortStaleUploads(ctx, bucketName, remotePath, options.AbortIncompleteUploadsAfter)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Objects encrypted on the client can't be resumed, as each attempt
+	// encrypts them with a new data key, nor can objects with a retention
+	// period, as each attempt retains them until a later date
+	var resumable *resumableUpload
+	if options.ResumeUpload && envelope == nil && uploadInput.ObjectLockMode == "" && uploader.MaxUploadParts > 1 {
+		uploader.LeavePartsOnError = true
 
-	uploadOutput, err := uploader.Upload(context.TODO(), uploadInput)
+		uploadInput.Metadata = map[string]string{uploadSettingsKey: uploadSettings(uploadInput)}
+
+		resumable, err = client.findResumableUpload(ctx, uploadInput, localFile, fSize)
+		if err != nil {
+			return "", err
+		}
+		if resumable != nil {
+			fmt.Fprintf(client.progressOutput, "resuming multipart upload with %d parts already uploaded\n", len(resumable.parts))
+		}
+	}
+
+	if resumable != nil {
+		progress := client.newProgress(fSize)
+		completeOutput, err := client.resumeUpload(ctx, uploadInput, resumable, localFile, fSize, uploader.Concurrency, progress)
+		progress.Wait()
+		if err != nil {
+			return "", err
+		}
+
+		versionID := aws.ToString(completeOutput.VersionId)
+		resumed, err := client.resumedWithSettings(ctx, uploadInput, versionID)
+		if err != nil {
+			return "", err
+		}
+		if resumed {
+			return versionID, nil
+		}
+	}
+
+	progress := client.newProgress(fSize)
+	defer progress.Wait()
+
+	var body io.Reader = progressReader{localFile, progress}
+	if client.bandwidth != nil {
+		body = throttledReader{body, client.bandwidth, ctx}
+	}
+	if envelope != nil {
+		body = envelope.encrypt(body)
+	}
+	uploadInput.Body = body
+
+	// The uploader aborts a failed multipart upload with the context of the
+	// upload, which fails once it has been cancelled
+	keepParts := uploader.LeavePartsOnError
+	uploader.LeavePartsOnError = true
+
+	uploadOutput, err := uploader.Upload(ctx, uploadInput)
 	if err != nil {
+		if !keepParts {
+			client.abortFailedUpload(ctx, uploadInput, err)
+		}
 		return "", err
 	}
 
-	// Have to manually complete the progress bar for empty files
-	// See https://github.com/vbauerster/mpb/issues/7
-	if fSize == 0 {
-		progress.SetTotal(-1, true)
-	}
+	progress.Complete()
 
 	if uploadOutput.VersionID != nil {
 		return *uploadOutput.VersionID, nil
@@ -369,56 +876,158 @@ func (client *s3client) UploadFile(bucketName string, remotePath string, localPa
 	return "", nil
 }
 
-func (client *s3client) DownloadFile(bucketName string, remotePath string, versionID string, localPath string) error {
+func (client *s3client) headObject(ctx context.Context, bucketName string, remotePath string, versionID string) (*s3.HeadObjectOutput, error) {
 	headObject := &s3.HeadObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(remotePath),
+		Bucket:              aws.String(bucketName),
+		Key:                 aws.String(remotePath),
+		RequestPayer:        client.requestPayer,
+		ExpectedBucketOwner: client.expectedBucketOwner,
 	}
 
 	if versionID != "" {
 		headObject.VersionId = aws.String(versionID)
 	}
+	if client.sseCustomerKey != nil {
+		headObject.SSECustomerAlgorithm = client.sseCustomerAlgorithm
+		headObject.SSECustomerKey = client.sseCustomerKey
+		headObject.SSECustomerKeyMD5 = client.sseCustomerKeyMD5
+	}
 
-	object, err := client.client.HeadObject(context.TODO(), headObject)
+	return client.client.HeadObject(ctx, headObject)
+}
+
+// OpenFile reads the latest version of remotePath. It is meant for files
+// written by S3 itself, such as inventory reports, so the object is neither
+// restored nor decrypted on the client.
+func (client *s3client) OpenFile(ctx context.Context, bucketName string, remotePath string) (io.ReadCloser, error) {
+	object, err := client.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:              aws.String(bucketName),
+		Key:                 aws.String(remotePath),
+		RequestPayer:        client.requestPayer,
+		ExpectedBucketOwner: client.expectedBucketOwner,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return object.Body, nil
+}
+
+func (client *s3client) DownloadFile(ctx context.Context, bucketName string, remotePath string, versionID string, localPath string, options DownloadFileOptions) error {
+	object, err := client.headObject(ctx, bucketName, remotePath, versionID)
 	if err != nil {
 		return err
 	}
 
-	progress := client.newProgressBar(*object.ContentLength)
+	if err := archivedObjectError(object); err != nil {
+		return err
+	}
+
+	// Encrypted objects are downloaded next to the destination and then
+	// decrypted into it, as the downloader writes parts out of order
+	var envelope *encryptionEnvelope
+	if isClientSideEncrypted(object.Metadata) {
+		objectEnvelope, err := openEncryptionEnvelope(ctx, client.keyWrapper, object.Metadata)
+		if err != nil {
+			return err
+		}
+		envelope = &objectEnvelope
+	}
+
+	progress := client.newProgress(*object.ContentLength)
 	defer progress.Wait()
 
 	downloader := manager.NewDownloader(client.client)
+	if options.PartSize > 0 {
+		downloader.PartSize = options.PartSize
+	}
+	if options.Concurrency > 0 {
+		downloader.Concurrency = options.Concurrency
+	}
 
-	localFile, err := os.Create(localPath)
-	if err != nil {
-		return err
+	var localFile *os.File
+	if envelope != nil {
+		localFile, err = os.CreateTemp(filepath.Dir(localPath), ".s3-resource-encrypted-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(localFile.Name())
+	} else {
+		localFile, err = os.Create(localPath)
+		if err != nil {
+			return err
+		}
 	}
 	defer localFile.Close()
 
 	getObject := &s3.GetObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(remotePath),
+		Bucket:              aws.String(bucketName),
+		Key:                 aws.String(remotePath),
+		RequestPayer:        client.requestPayer,
+		ExpectedBucketOwner: client.expectedBucketOwner,
 	}
 
 	if versionID != "" {
 		getObject.VersionId = aws.String(versionID)
 	}
+	if client.sseCustomerKey != nil {
+		getObject.SSECustomerAlgorithm = client.sseCustomerAlgorithm
+		getObject.SSECustomerKey = client.sseCustomerKey
+		getObject.SSECustomerKeyMD5 = client.sseCustomerKeyMD5
+	}
+
+	// Every part must come from the same object for them to be combined
+	getObject.IfMatch = object.ETag
+
+	var writerAt io.WriterAt = localFile
+	if client.bandwidth != nil {
+		writerAt = throttledWriterAt{localFile, client.bandwidth, ctx}
+	}
+	written := &writtenRanges{WriterAt: writerAt}
+	writer := progressWriterAt{written, progress}
 
-	_, err = downloader.Download(context.TODO(), progressWriterAt{localFile, progress.ProxyWriter(io.Discard)}, getObject)
+	_, err = downloader.Download(ctx, writer, getObject)
+	if err != nil {
+		err = client.resumeDownload(ctx, getObject, writer, written, *object.ContentLength, err)
+		if err != nil {
+			// Never leave a partially downloaded file behind
+			os.Remove(localFile.Name())
+			return err
+		}
+	}
+
+	if envelope != nil {
+		if err := client.decryptFile(*envelope, localFile, localPath); err != nil {
+			return err
+		}
+	}
+
+	progress.Complete()
+
+	return nil
+}
+
+func (client *s3client) decryptFile(envelope encryptionEnvelope, ciphertext *os.File, localPath string) error {
+	if _, err := ciphertext.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	plaintext, err := os.Create(localPath)
 	if err != nil {
 		return err
 	}
+	defer plaintext.Close()
 
-	// Have to manually complete the progress bar for empty files
-	// See https://github.com/vbauerster/mpb/issues/7
-	if *object.ContentLength == 0 {
-		progress.SetTotal(-1, true)
+	if err := envelope.decrypt(plaintext, ciphertext); err != nil {
+		// Never leave partially decrypted, unauthenticated data behind
+		os.Remove(localPath)
+		return err
 	}
 
 	return nil
 }
 
-func (client *s3client) SetTags(bucketName string, remotePath string, versionID string, tags map[string]string) error {
+func (client *s3client) SetTags(ctx context.Context, bucketName string, remotePath string, versionID string, tags map[string]string) error {
 	var tagSet []types.Tag
 	for key, value := range tags {
 		tagSet = append(tagSet, types.Tag{
@@ -428,28 +1037,32 @@ func (client *s3client) SetTags(bucketName string, remotePath string, versionID
 	}
 
 	putObjectTagging := &s3.PutObjectTaggingInput{
-		Bucket:  aws.String(bucketName),
-		Key:     aws.String(remotePath),
-		Tagging: &types.Tagging{TagSet: tagSet},
+		Bucket:              aws.String(bucketName),
+		Key:                 aws.String(remotePath),
+		Tagging:             &types.Tagging{TagSet: tagSet},
+		RequestPayer:        client.requestPayer,
+		ExpectedBucketOwner: client.expectedBucketOwner,
 	}
 	if versionID != "" {
 		putObjectTagging.VersionId = aws.String(versionID)
 	}
 
-	_, err := client.client.PutObjectTagging(context.TODO(), putObjectTagging)
+	_, err := client.client.PutObjectTagging(ctx, putObjectTagging)
 	return err
 }
 
-func (client *s3client) DownloadTags(bucketName string, remotePath string, versionID string, localPath string) error {
+func (client *s3client) DownloadTags(ctx context.Context, bucketName string, remotePath string, versionID string, localPath string) error {
 	getObjectTagging := &s3.GetObjectTaggingInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(remotePath),
+		Bucket:              aws.String(bucketName),
+		Key:                 aws.String(remotePath),
+		RequestPayer:        client.requestPayer,
+		ExpectedBucketOwner: client.expectedBucketOwner,
 	}
 	if versionID != "" {
 		getObjectTagging.VersionId = aws.String(versionID)
 	}
 
-	objectTagging, err := client.client.GetObjectTagging(context.TODO(), getObjectTagging)
+	objectTagging, err := client.client.GetObjectTagging(ctx, getObjectTagging)
 	if err != nil {
 		return err
 	}
@@ -467,7 +1080,31 @@ func (client *s3client) DownloadTags(bucketName string, remotePath string, versi
 	return os.WriteFile(localPath, tagsJSON, 0644)
 }
 
-func (client *s3client) URL(bucketName string, remotePath string, private bool, versionID string) (string, error) {
+// DownloadObjectLock writes the Object Lock state of the object to localPath.
+// S3 only includes the state when the caller is allowed to read it, so
+// without the s3:GetObjectRetention and s3:GetObjectLegalHold permissions the
+// object appears to be unlocked.
+func (client *s3client) DownloadObjectLock(ctx context.Context, bucketName string, remotePath string, versionID string, localPath string) error {
+	object, err := client.headObject(ctx, bucketName, remotePath, versionID)
+	if err != nil {
+		return err
+	}
+
+	objectLock := ObjectLock{
+		Mode:            string(object.ObjectLockMode),
+		RetainUntilDate: object.ObjectLockRetainUntilDate,
+		LegalHold:       string(object.ObjectLockLegalHoldStatus),
+	}
+
+	objectLockJSON, err := json.Marshal(objectLock)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(localPath, objectLockJSON, 0644)
+}
+
+func (client *s3client) URL(ctx context.Context, bucketName string, remotePath string, private bool, versionID string) (string, error) {
 	if !private {
 		var endpoint *string
 		clientOptions := client.client.Options()
@@ -476,7 +1113,11 @@ func (client *s3client) URL(bucketName string, remotePath string, private bool,
 			endpoint = clientOptions.BaseEndpoint
 		}
 
-		if endpoint == nil {
+		useDualStack := clientOptions.EndpointOptions.UseDualStackEndpoint == aws.DualStackEndpointStateEnabled
+		useFIPS := clientOptions.EndpointOptions.UseFIPSEndpoint == aws.FIPSEndpointStateEnabled
+
+		// The endpoint variants are only resolved when no endpoint is given
+		if endpoint == nil && !clientOptions.UseAccelerate && !useDualStack && !useFIPS {
 			endpoint = aws.String(fmt.Sprintf("https://s3.%s.amazonaws.com", clientOptions.Region))
 		}
 
@@ -484,11 +1125,15 @@ func (client *s3client) URL(bucketName string, remotePath string, private bool,
 		// (e.g. https://bucket-name.s3.us-west-2.amazonaws.com). It will not
 		// include the key/remotePath if you provide it.
 		url, err := client.client.Options().EndpointResolverV2.ResolveEndpoint(
-			context.Background(),
+			ctx,
 			s3.EndpointParameters{
-				Endpoint: endpoint,
-				Bucket:   &bucketName,
-				Region:   &clientOptions.Region, //Not used to make the final URL string but is required
+				Endpoint:       endpoint,
+				Bucket:         &bucketName,
+				Region:         &clientOptions.Region, //Not used to make the final URL string but is required
+				Accelerate:     aws.Bool(clientOptions.UseAccelerate),
+				UseDualStack:   aws.Bool(useDualStack),
+				UseFIPS:        aws.Bool(useFIPS),
+				ForcePathStyle: aws.Bool(clientOptions.UsePathStyle),
 			})
 
 		if err != nil {
@@ -504,16 +1149,25 @@ func (client *s3client) URL(bucketName string, remotePath string, private bool,
 	}
 
 	getObjectInput := &s3.GetObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(remotePath),
+		Bucket:              aws.String(bucketName),
+		Key:                 aws.String(remotePath),
+		RequestPayer:        client.requestPayer,
+		ExpectedBucketOwner: client.expectedBucketOwner,
 	}
 
 	if versionID != "" {
 		getObjectInput.VersionId = aws.String(versionID)
 	}
+	if client.sseCustomerKey != nil {
+		// The key is signed as a header, so it must also be sent by whoever
+		// uses the presigned URL
+		getObjectInput.SSECustomerAlgorithm = client.sseCustomerAlgorithm
+		getObjectInput.SSECustomerKey = client.sseCustomerKey
+		getObjectInput.SSECustomerKeyMD5 = client.sseCustomerKeyMD5
+	}
 
 	presign := s3.NewPresignClient(client.client)
-	request, err := presign.PresignGetObject(context.TODO(), getObjectInput, func(po *s3.PresignOptions) {
+	request, err := presign.PresignGetObject(ctx, getObjectInput, func(po *s3.PresignOptions) {
 		po.Expires = 24 * time.Hour
 	})
 
@@ -524,31 +1178,36 @@ func (client *s3client) URL(bucketName string, remotePath string, private bool,
 	return request.URL, nil
 }
 
-func (client *s3client) DeleteVersionedFile(bucketName string, remotePath string, versionID string) error {
-	_, err := client.client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
-		Bucket:    aws.String(bucketName),
-		Key:       aws.String(remotePath),
-		VersionId: aws.String(versionID),
+func (client *s3client) DeleteVersionedFile(ctx context.Context, bucketName string, remotePath string, versionID string) error {
+	_, err := client.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:              aws.String(bucketName),
+		Key:                 aws.String(remotePath),
+		VersionId:           aws.String(versionID),
+		RequestPayer:        client.requestPayer,
+		ExpectedBucketOwner: client.expectedBucketOwner,
 	})
 
 	return err
 }
 
-func (client *s3client) DeleteFile(bucketName string, remotePath string) error {
-	_, err := client.client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(remotePath),
+func (client *s3client) DeleteFile(ctx context.Context, bucketName string, remotePath string) error {
+	_, err := client.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:              aws.String(bucketName),
+		Key:                 aws.String(remotePath),
+		RequestPayer:        client.requestPayer,
+		ExpectedBucketOwner: client.expectedBucketOwner,
 	})
 
 	return err
 }
 
-func (client *s3client) getBucketVersioning(bucketName string) (bool, error) {
+func (client *s3client) getBucketVersioning(ctx context.Context, bucketName string) (bool, error) {
 	params := &s3.GetBucketVersioningInput{
-		Bucket: aws.String(bucketName),
+		Bucket:              aws.String(bucketName),
+		ExpectedBucketOwner: client.expectedBucketOwner,
 	}
 
-	resp, err := client.client.GetBucketVersioning(context.TODO(), params)
+	resp, err := client.client.GetBucketVersioning(ctx, params)
 	if err != nil {
 		return false, err
 	}
@@ -556,15 +1215,17 @@ func (client *s3client) getBucketVersioning(bucketName string) (bool, error) {
 	return resp.Status == types.BucketVersioningStatusEnabled, nil
 }
 
-func (client *s3client) getVersionedBucketContents(bucketName string, prefix string) (map[string][]types.ObjectVersion, error) {
+func (client *s3client) getVersionedBucketContents(ctx context.Context, bucketName string, prefix string) (map[string][]types.ObjectVersion, error) {
 	versionedBucketContents := map[string][]types.ObjectVersion{}
 	keyMarker := ""
 	versionMarker := ""
 	for {
 
 		params := &s3.ListObjectVersionsInput{
-			Bucket: aws.String(bucketName),
-			Prefix: aws.String(prefix),
+			Bucket:              aws.String(bucketName),
+			Prefix:              aws.String(prefix),
+			RequestPayer:        client.requestPayer,
+			ExpectedBucketOwner: client.expectedBucketOwner,
 		}
 
 		if keyMarker != "" {
@@ -574,7 +1235,7 @@ func (client *s3client) getVersionedBucketContents(bucketName string, prefix str
 			params.VersionIdMarker = aws.String(versionMarker)
 		}
 
-		listObjectVersionsResponse, err := client.client.ListObjectVersions(context.TODO(), params)
+		listObjectVersionsResponse, err := client.client.ListObjectVersions(ctx, params)
 		if err != nil {
 			return versionedBucketContents, err
 		}
@@ -611,21 +1272,3 @@ func (client *s3client) getVersionedBucketContents(bucketName string, prefix str
 
 	return versionedBucketContents, nil
 }
-
-func (client *s3client) newProgressBar(total int64) *mpb.Bar {
-	pg := mpb.New(mpb.WithWidth(80), mpb.WithOutput(client.progressOutput), mpb.WithAutoRefresh())
-	bar := pg.New(total, mpb.BarStyle(),
-		mpb.PrependDecorators(
-			decor.Counters(decor.SizeB1024(0), "% .2f / % .2f"),
-		),
-		mpb.AppendDecorators(
-			decor.NewPercentage("%d - "),
-			decor.AverageSpeed(decor.SizeB1024(0), "% .2f"),
-		),
-	)
-	return bar
-}
-
-func (client *s3client) isGCSHost() bool {
-	return (client.client.Options().BaseEndpoint != nil && strings.Contains(*client.client.Options().BaseEndpoint, "storage.googleapis.com"))
-}
@@ -3,11 +3,23 @@ package s3resource_test
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -22,7 +34,7 @@ var _ = Describe("S3Resource", func() {
 				accessKey := "access-key"
 				secretKey := "secret-key"
 				sessionToken := "session-token"
-				cfg, err := s3resource.NewAwsConfig(accessKey, secretKey, sessionToken, "", "", false, "", false)
+				cfg, err := s3resource.NewAwsConfig(context.Background(), accessKey, secretKey, sessionToken, "", "", false, "", false, s3resource.AwsConfigOptions{})
 				Expect(err).ToNot(HaveOccurred())
 				Expect(cfg).ToNot(BeNil())
 
@@ -35,9 +47,19 @@ var _ = Describe("S3Resource", func() {
 			})
 		})
 
+		Context("There is a role to assume", func() {
+			It("stops loading the config and assuming the role when the context is cancelled", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				_, err := s3resource.NewAwsConfig(ctx, "access-key", "secret-key", "", "arn:aws:iam::123456789012:role/some-role", "", false, "", false, s3resource.AwsConfigOptions{})
+				Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+			})
+		})
+
 		Context("There are no static credentials or role to assume", func() {
 			It("uses the anonymous credentials", func() {
-				cfg, err := s3resource.NewAwsConfig("", "", "", "", "", false, "", false)
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{})
 				Expect(err).ToNot(HaveOccurred())
 				Expect(cfg).ToNot(BeNil())
 				Expect(cfg.Credentials).ToNot(BeNil())
@@ -47,7 +69,7 @@ var _ = Describe("S3Resource", func() {
 
 		Context("Set to use the Aws Default Credential Provider", func() {
 			It("uses the Aws Default Credential Provider", func() {
-				cfg, err := s3resource.NewAwsConfig("", "", "", "", "", false, "", true)
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", true, s3resource.AwsConfigOptions{})
 				Expect(err).ToNot(HaveOccurred())
 				Expect(cfg).ToNot(BeNil())
 				Expect(cfg.Credentials).ToNot(BeNil())
@@ -55,23 +77,86 @@ var _ = Describe("S3Resource", func() {
 			})
 		})
 
+		Context("A profile is given with inline shared config", func() {
+			It("uses the credentials of the profile", func() {
+				sharedCredentials := "[build]\n" +
+					"aws_access_key_id = profile-access-key\n" +
+					"aws_secret_access_key = profile-secret-key\n"
+
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{
+					Profile:           "build",
+					SharedCredentials: sharedCredentials,
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cfg).ToNot(BeNil())
+
+				creds, err := cfg.Credentials.Retrieve(context.TODO())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(creds.AccessKeyID).To(Equal("profile-access-key"))
+				Expect(creds.SecretAccessKey).To(Equal("profile-secret-key"))
+			})
+
+			It("uses credential_process from the profile", func() {
+				sharedConfig := "[profile build]\n" +
+					`credential_process = echo '{"Version": 1, "AccessKeyId": "process-access-key", "SecretAccessKey": "process-secret-key"}'` + "\n"
+
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{
+					Profile:      "build",
+					SharedConfig: sharedConfig,
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cfg).ToNot(BeNil())
+
+				creds, err := cfg.Credentials.Retrieve(context.TODO())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(creds.AccessKeyID).To(Equal("process-access-key"))
+				Expect(creds.SecretAccessKey).To(Equal("process-secret-key"))
+			})
+
+			It("uses the region of the profile when no region is given", func() {
+				sharedConfig := "[profile build]\n" +
+					"region = eu-west-2\n"
+
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{
+					Profile:      "build",
+					SharedConfig: sharedConfig,
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cfg).ToNot(BeNil())
+				Expect(cfg.Region).To(Equal("eu-west-2"))
+			})
+
+			It("prefers the given region over the region of the profile", func() {
+				sharedConfig := "[profile build]\n" +
+					"region = eu-west-2\n"
+
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "ca-central-1", false, "", false, s3resource.AwsConfigOptions{
+					Profile:      "build",
+					SharedConfig: sharedConfig,
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cfg).ToNot(BeNil())
+				Expect(cfg.Region).To(Equal("ca-central-1"))
+			})
+		})
+
 		Context("default values", func() {
 			It("sets RetryMaxAttempts", func() {
-				cfg, err := s3resource.NewAwsConfig("", "", "", "", "", false, "", false)
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{})
 				Expect(err).ToNot(HaveOccurred())
 				Expect(cfg).ToNot(BeNil())
 				Expect(cfg.RetryMaxAttempts).To(Equal(s3resource.MaxRetries))
 			})
 
 			It("sets region to us-east-1", func() {
-				cfg, err := s3resource.NewAwsConfig("", "", "", "", "", false, "", false)
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{})
 				Expect(err).ToNot(HaveOccurred())
 				Expect(cfg).ToNot(BeNil())
 				Expect(cfg.Region).To(Equal("us-east-1"))
 			})
 
 			It("uses aws buildable http client", func() {
-				cfg, err := s3resource.NewAwsConfig("", "", "", "", "", false, "", false)
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{})
 				Expect(err).ToNot(HaveOccurred())
 				Expect(cfg).ToNot(BeNil())
 				_, ok := cfg.HTTPClient.(*awshttp.BuildableClient)
@@ -79,7 +164,7 @@ var _ = Describe("S3Resource", func() {
 			})
 
 			It("does not skip ssl verification", func() {
-				cfg, err := s3resource.NewAwsConfig("", "", "", "", "", false, "", false)
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{})
 				Expect(err).ToNot(HaveOccurred())
 				Expect(cfg).ToNot(BeNil())
 				client, ok := cfg.HTTPClient.(*awshttp.BuildableClient)
@@ -90,7 +175,7 @@ var _ = Describe("S3Resource", func() {
 
 		Context("Region is specified", func() {
 			It("sets the region", func() {
-				cfg, err := s3resource.NewAwsConfig("", "", "", "", "ca-central-1", false, "", false)
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "ca-central-1", false, "", false, s3resource.AwsConfigOptions{})
 				Expect(err).ToNot(HaveOccurred())
 				Expect(cfg).ToNot(BeNil())
 				Expect(cfg.Region).To(Equal("ca-central-1"))
@@ -99,7 +184,7 @@ var _ = Describe("S3Resource", func() {
 
 		Context("SSL verification is skipped", func() {
 			It("creates an http client that skips SSL verification", func() {
-				cfg, err := s3resource.NewAwsConfig("", "", "", "", "", true, "", false)
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", true, "", false, s3resource.AwsConfigOptions{})
 				Expect(err).ToNot(HaveOccurred())
 				Expect(cfg).ToNot(BeNil())
 
@@ -125,7 +210,7 @@ var _ = Describe("S3Resource", func() {
 				"-----END CERTIFICATE-----\n"
 
 			It("creates an http client that respects the ca_bundle option", func() {
-				cfg, err := s3resource.NewAwsConfig("", "", "", "", "", false, certificate, false)
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, certificate, false, s3resource.AwsConfigOptions{})
 				Expect(err).ToNot(HaveOccurred())
 				Expect(cfg).ToNot(BeNil())
 
@@ -149,6 +234,160 @@ var _ = Describe("S3Resource", func() {
 				}
 				Expect(found).To(BeTrue())
 			})
+
+			It("adds the proxy CA to the trusted roots", func() {
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{
+					HTTPSProxy:    "https://proxy.example.com:3129",
+					ProxyCABundle: certificate,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				block, _ := pem.Decode([]byte(certificate))
+				crt, err := x509.ParseCertificate(block.Bytes)
+				Expect(err).ToNot(HaveOccurred())
+
+				client, ok := cfg.HTTPClient.(*awshttp.BuildableClient)
+				Expect(ok).To(BeTrue())
+				Expect(client.GetTransport().TLSClientConfig.RootCAs.Subjects()).To(ContainElement(crt.RawSubject))
+			})
+
+			It("errors when the proxy CA is not PEM", func() {
+				_, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{
+					ProxyCABundle: "not a certificate",
+				})
+				Expect(err).To(MatchError("failed to load proxy CA bundle PEM"))
+			})
+		})
+
+		Context("TLS options are given", func() {
+			transport := func(options s3resource.AwsConfigOptions) *http.Transport {
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, options)
+				Expect(err).ToNot(HaveOccurred())
+
+				client, ok := cfg.HTTPClient.(*awshttp.BuildableClient)
+				Expect(ok).To(BeTrue())
+				return client.GetTransport()
+			}
+
+			It("presents the client certificate", func() {
+				certPEM, keyPEM := generateClientCertificate()
+
+				tr := transport(s3resource.AwsConfigOptions{ClientCert: certPEM, ClientKey: keyPEM})
+				Expect(tr.TLSClientConfig.Certificates).To(HaveLen(1))
+			})
+
+			It("errors when the client key does not match", func() {
+				certPEM, _ := generateClientCertificate()
+				_, otherKeyPEM := generateClientCertificate()
+
+				_, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{
+					ClientCert: certPEM,
+					ClientKey:  otherKeyPEM,
+				})
+				Expect(err).To(MatchError(ContainSubstring("error loading client certificate")))
+			})
+
+			It("restricts the TLS version and cipher suites", func() {
+				tr := transport(s3resource.AwsConfigOptions{
+					TLSMinVersion:   "1.2",
+					TLSCipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+				})
+				Expect(tr.TLSClientConfig.MinVersion).To(Equal(uint16(tls.VersionTLS12)))
+				Expect(tr.TLSClientConfig.CipherSuites).To(Equal([]uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}))
+			})
+
+			It("rejects insecure cipher suites", func() {
+				_, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{
+					TLSCipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"},
+				})
+				Expect(err).To(MatchError(ContainSubstring("unknown or insecure cipher suite: TLS_RSA_WITH_RC4_128_SHA")))
+			})
+		})
+
+		Context("retry and timeout options are given", func() {
+			It("retries up to the given attempts", func() {
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{
+					RetryMaxAttempts: 3,
+					RetryMaxBackoff:  "5s",
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cfg.RetryMaxAttempts).To(Equal(3))
+				Expect(cfg.Retryer().MaxAttempts()).To(Equal(3))
+			})
+
+			It("uses the adaptive retry mode", func() {
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{
+					RetryMode: "adaptive",
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cfg.Retryer()).To(BeAssignableToTypeOf(&retry.AdaptiveMode{}))
+				Expect(cfg.Retryer().MaxAttempts()).To(Equal(s3resource.MaxRetries))
+			})
+
+			It("sets the connect and request timeouts", func() {
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{
+					ConnectTimeout: "5s",
+					RequestTimeout: "2m",
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				client, ok := cfg.HTTPClient.(*awshttp.BuildableClient)
+				Expect(ok).To(BeTrue())
+				Expect(client.GetDialer().Timeout).To(Equal(5 * time.Second))
+				Expect(client.GetTransport().TLSHandshakeTimeout).To(Equal(5 * time.Second))
+				Expect(client.GetTransport().ResponseHeaderTimeout).To(Equal(2 * time.Minute))
+				Expect(client.GetTimeout()).To(BeZero())
+			})
+		})
+
+		Context("a proxy is configured", func() {
+			proxyFor := func(cfg *aws.Config, rawURL string) *url.URL {
+				client, ok := cfg.HTTPClient.(*awshttp.BuildableClient)
+				Expect(ok).To(BeTrue())
+
+				req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				proxyURL, err := client.GetTransport().Proxy(req)
+				Expect(err).ToNot(HaveOccurred())
+				return proxyURL
+			}
+
+			It("sends requests through the proxy for their scheme", func() {
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{
+					HTTPProxy:  "http-proxy.example.com:3128",
+					HTTPSProxy: "https://https-proxy.example.com:3129",
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(proxyFor(cfg, "http://minio.example.com/bucket").String()).To(Equal("http://http-proxy.example.com:3128"))
+				Expect(proxyFor(cfg, "https://s3.amazonaws.com/bucket").String()).To(Equal("https://https-proxy.example.com:3129"))
+			})
+
+			It("does not proxy requests to hosts in no_proxy", func() {
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{
+					HTTPSProxy: "https://proxy.example.com:3129",
+					NoProxy:    ".internal.example.com",
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(proxyFor(cfg, "https://minio.internal.example.com/bucket")).To(BeNil())
+				Expect(proxyFor(cfg, "https://s3.amazonaws.com/bucket")).ToNot(BeNil())
+			})
+
+			It("authenticates with the proxy", func() {
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{
+					HTTPSProxy:    "https://proxy.example.com:3129",
+					ProxyUsername: "user",
+					ProxyPassword: "p@ss",
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				proxyURL := proxyFor(cfg, "https://s3.amazonaws.com/bucket")
+				Expect(proxyURL.User.Username()).To(Equal("user"))
+				password, _ := proxyURL.User.Password()
+				Expect(password).To(Equal("p@ss"))
+			})
 		})
 	})
 
@@ -161,7 +400,7 @@ var _ = Describe("S3Resource", func() {
 			)
 
 			BeforeEach(func() {
-				cfg, err := s3resource.NewAwsConfig("", "", "", "", "", false, "", false)
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{})
 				Expect(err).ToNot(HaveOccurred())
 
 				s3client, err = s3resource.NewS3Client(
@@ -172,6 +411,7 @@ var _ = Describe("S3Resource", func() {
 					true,
 					true,
 					"",
+					s3resource.S3ClientOptions{},
 				)
 			})
 
@@ -181,7 +421,7 @@ var _ = Describe("S3Resource", func() {
 				})
 
 				It("Omits the versionId from the url if the object isn't versioned", func() {
-					url, err := s3client.URL("bucketName", "remotePath", private, versionID)
+					url, err := s3client.URL(context.Background(), "bucketName", "remotePath", private, versionID)
 					Expect(err).NotTo(HaveOccurred())
 					Expect(url).To(Equal("https://fake-s3/bucketName/remotePath"))
 				})
@@ -192,12 +432,132 @@ var _ = Describe("S3Resource", func() {
 					})
 
 					It("Correctly sets the versionId on the url", func() {
-						url, err := s3client.URL("bucketName", "remotePath", private, versionID)
+						url, err := s3client.URL(context.Background(), "bucketName", "remotePath", private, versionID)
 						Expect(err).NotTo(HaveOccurred())
 						Expect(url).To(Equal("https://fake-s3/bucketName/remotePath?versionId=some-version"))
 					})
 				})
 			})
+
+			Context("private with a customer-provided encryption key", func() {
+				BeforeEach(func() {
+					s3client = s3resource.NewTestS3Client(io.Discard, s3resource.NewTestAwsConfig(""), "fake-s3", s3resource.S3ClientOptions{
+						SSECustomerKey: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("k"), 32)),
+					})
+				})
+
+				It("signs the encryption headers into the presigned url", func() {
+					url, err := s3client.URL(context.Background(), "bucketName", "remotePath", true, "")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(url).To(ContainSubstring("x-amz-server-side-encryption-customer-algorithm"))
+					Expect(url).To(ContainSubstring("x-amz-server-side-encryption-customer-key"))
+				})
+			})
+
+			DescribeTable("public with an endpoint",
+				func(usePathStyle bool, expected string) {
+					cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{})
+					Expect(err).ToNot(HaveOccurred())
+
+					s3client, err := s3resource.NewS3Client(io.Discard, cfg, "https://minio.example.com:9000", false, usePathStyle, false, "", s3resource.S3ClientOptions{})
+					Expect(err).ToNot(HaveOccurred())
+
+					url, err := s3client.URL(context.Background(), "bucket-name", "remotePath", false, "")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(url).To(Equal(expected))
+				},
+				Entry("path-style", true, "https://minio.example.com:9000/bucket-name/remotePath"),
+				Entry("virtual-hosted", false, "https://bucket-name.minio.example.com:9000/remotePath"),
+			)
+
+			DescribeTable("public with an endpoint variant",
+				func(options s3resource.S3ClientOptions, expected string) {
+					cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "us-west-2", false, "", false, s3resource.AwsConfigOptions{})
+					Expect(err).ToNot(HaveOccurred())
+
+					s3client, err := s3resource.NewS3Client(io.Discard, cfg, "", false, false, false, "", options)
+					Expect(err).ToNot(HaveOccurred())
+
+					url, err := s3client.URL(context.Background(), "bucket-name", "remotePath", false, "")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(url).To(Equal(expected))
+				},
+				Entry("transfer acceleration",
+					s3resource.S3ClientOptions{UseAccelerateEndpoint: true},
+					"https://bucket-name.s3-accelerate.amazonaws.com/remotePath",
+				),
+				Entry("dual-stack",
+					s3resource.S3ClientOptions{UseDualStack: true},
+					"https://bucket-name.s3.dualstack.us-west-2.amazonaws.com/remotePath",
+				),
+				Entry("FIPS",
+					s3resource.S3ClientOptions{UseFIPS: true},
+					"https://bucket-name.s3-fips.us-west-2.amazonaws.com/remotePath",
+				),
+				Entry("dual-stack transfer acceleration",
+					s3resource.S3ClientOptions{UseAccelerateEndpoint: true, UseDualStack: true},
+					"https://bucket-name.s3-accelerate.dualstack.amazonaws.com/remotePath",
+				),
+			)
+
+			Context("private in a requester pays bucket", func() {
+				BeforeEach(func() {
+					s3client = s3resource.NewTestS3Client(io.Discard, s3resource.NewTestAwsConfig(""), "fake-s3", s3resource.S3ClientOptions{
+						RequesterPays:       true,
+						ExpectedBucketOwner: "123456789012",
+					})
+				})
+
+				It("signs the request payer and bucket owner headers into the presigned url", func() {
+					url, err := s3client.URL(context.Background(), "bucketName", "remotePath", true, "")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(url).To(ContainSubstring("x-amz-request-payer"))
+					Expect(url).To(ContainSubstring("x-amz-expected-bucket-owner"))
+				})
+			})
+		})
+
+		Context("the customer-provided encryption key is not base64 encoded", func() {
+			It("returns an error", func() {
+				cfg, err := s3resource.NewAwsConfig(context.Background(), "", "", "", "", "", false, "", false, s3resource.AwsConfigOptions{})
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = s3resource.NewS3Client(
+					io.Discard,
+					cfg,
+					"fake-s3",
+					false,
+					true,
+					true,
+					"",
+					s3resource.S3ClientOptions{SSECustomerKey: "not base64!"},
+				)
+				Expect(err).To(MatchError(ContainSubstring("error decoding sse customer key")))
+			})
 		})
 	})
 })
+
+// generateClientCertificate returns a PEM encoded self-signed certificate and
+// its private key
+func generateClientCertificate() (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "s3-resource"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).ToNot(HaveOccurred())
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).ToNot(HaveOccurred())
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return string(certPEM), string(keyPEM)
+}
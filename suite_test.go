@@ -1,8 +1,15 @@
-package s3resource_test
+package s3resource
 
 import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/concourse/s3-resource/integration/s3server"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -11,3 +18,43 @@ func TestResource(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Resource Suite")
 }
+
+// The test servers accept the requests signed with these credentials
+const (
+	TestAccessKey = "access-key"
+	TestSecretKey = "secret-key"
+)
+
+// NewTestServer starts a server of handler, which is closed once the spec
+// has run
+func NewTestServer(handler http.HandlerFunc) *httptest.Server {
+	server := httptest.NewServer(handler)
+	DeferCleanup(server.Close)
+	return server
+}
+
+// NewTestS3Server starts an S3 stand-in in region, which is closed once the
+// spec has run
+func NewTestS3Server(region string) *s3server.Server {
+	server := s3server.New(TestAccessKey, TestSecretKey, region)
+	DeferCleanup(server.Close)
+	return server
+}
+
+// NewTestAwsConfig loads the config of the clients of test servers, which
+// make each request once so that errors are returned as they are served
+func NewTestAwsConfig(region string) *aws.Config {
+	cfg, err := NewAwsConfig(context.Background(), TestAccessKey, TestSecretKey, "", "", region, false, "", false, AwsConfigOptions{
+		RetryMaxAttempts: 1,
+	})
+	Expect(err).ToNot(HaveOccurred())
+	return cfg
+}
+
+// NewTestS3Client creates a client of the test server at endpoint, which
+// writes its progress to output
+func NewTestS3Client(output io.Writer, cfg *aws.Config, endpoint string, options S3ClientOptions) S3Client {
+	client, err := NewS3Client(output, cfg, endpoint, false, true, true, "", options)
+	Expect(err).ToNot(HaveOccurred())
+	return client
+}
@@ -0,0 +1,80 @@
+package s3resource
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion parses a TLS version such as "1.2"
+func ParseTLSVersion(version string) (uint16, error) {
+	if v, ok := tlsVersions[version]; ok {
+		return v, nil
+	}
+
+	names := make([]string, 0, len(tlsVersions))
+	for name := range tlsVersions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return 0, fmt.Errorf("tls_min_version must be one of: %s", strings.Join(names, ", "))
+}
+
+// ParseCipherSuites parses the IANA names of TLS 1.0-1.2 cipher suites, e.g.
+// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. Cipher suites with known security
+// issues are rejected.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	secure := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		secure[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := secure[name]
+		if !ok {
+			return nil, fmt.Errorf("tls_cipher_suites contains an unknown or insecure cipher suite: %s", name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// validateTLS checks the client certificate and the TLS version and cipher
+// suites of a source
+func (source Source) validateTLS() (bool, string) {
+	if (source.ClientCert == "") != (source.ClientKey == "") {
+		return false, "please specify both client_cert and client_key"
+	}
+	if source.ClientCert != "" {
+		if _, err := tls.X509KeyPair([]byte(source.ClientCert), []byte(source.ClientKey)); err != nil {
+			return false, fmt.Sprintf("client_cert and client_key must be a PEM encoded certificate and its private key: %s", err)
+		}
+	}
+
+	if source.TLSMinVersion != "" {
+		version, err := ParseTLSVersion(source.TLSMinVersion)
+		if err != nil {
+			return false, err.Error()
+		}
+		if version == tls.VersionTLS13 && len(source.TLSCipherSuites) > 0 {
+			return false, "tls_cipher_suites cannot be configured for TLS 1.3"
+		}
+	}
+
+	if _, err := ParseCipherSuites(source.TLSCipherSuites); err != nil {
+		return false, err.Error()
+	}
+
+	return true, ""
+}
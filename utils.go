@@ -1,17 +1,79 @@
 package s3resource
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/mitchellh/colorstring"
 )
 
 func Fatal(doing string, err error) {
 	Sayf(colorstring.Color("[red]error %s: %s\n"), doing, err)
+	if hint := Hint(err); hint != "" {
+		Sayf(colorstring.Color("[yellow]hint: %s\n"), hint)
+	}
 	os.Exit(1)
 }
 
 func Sayf(message string, args ...any) {
 	fmt.Fprintf(os.Stderr, message, args...)
 }
+
+// NewCommandContext returns a context which is cancelled when the process
+// receives SIGTERM or SIGINT, e.g. when the build is aborted, or once timeout
+// has passed. An empty timeout never expires. A second signal kills the
+// process without waiting for the command to clean up.
+func NewCommandContext(timeout string) (context.Context, context.CancelFunc, error) {
+	var duration time.Duration
+	if timeout != "" {
+		var err error
+		duration, err = time.ParseDuration(timeout)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			cancel(fmt.Errorf("interrupted by %s", sig))
+		case <-ctx.Done():
+		}
+	}()
+
+	stop := func() {
+		signal.Stop(signals)
+		cancel(context.Canceled)
+	}
+
+	if duration > 0 {
+		timeoutCtx, cancelTimeout := context.WithTimeoutCause(ctx, duration, fmt.Errorf("operation timed out after %s", duration))
+		return timeoutCtx, func() {
+			cancelTimeout()
+			stop()
+		}, nil
+	}
+
+	return ctx, stop, nil
+}
+
+// CommandError prefixes err with the reason ctx was cancelled, as the errors
+// of cancelled requests only say that their context was cancelled
+func CommandError(ctx context.Context, err error) error {
+	cause := context.Cause(ctx)
+	if cause == nil || errors.Is(err, cause) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", cause, err)
+}
@@ -0,0 +1,40 @@
+package s3resource_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	s3resource "github.com/concourse/s3-resource"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewCommandContext", func() {
+	It("is cancelled once the operation timeout has passed", func() {
+		ctx, cancel, err := s3resource.NewCommandContext("10ms")
+		Expect(err).ToNot(HaveOccurred())
+		defer cancel()
+
+		Eventually(ctx.Done()).Should(BeClosed())
+
+		err = s3resource.CommandError(ctx, fmt.Errorf("operation error S3: PutObject: %w", ctx.Err()))
+		Expect(err).To(MatchError("operation timed out after 10ms: operation error S3: PutObject: context deadline exceeded"))
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	})
+
+	It("never times out without an operation timeout", func() {
+		ctx, cancel, err := s3resource.NewCommandContext("")
+		Expect(err).ToNot(HaveOccurred())
+		defer cancel()
+
+		Consistently(ctx.Done(), "50ms").ShouldNot(BeClosed())
+		Expect(s3resource.CommandError(ctx, errors.New("some error"))).To(MatchError("some error"))
+	})
+
+	It("errors when the operation timeout is not a duration", func() {
+		_, _, err := s3resource.NewCommandContext("10")
+		Expect(err).To(HaveOccurred())
+	})
+})
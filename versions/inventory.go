@@ -0,0 +1,299 @@
+package versions
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	s3resource "github.com/concourse/s3-resource"
+)
+
+// ErrNoInventory is returned when no inventory report has been delivered
+// yet, e.g. in the first day or two after the inventory was configured
+var ErrNoInventory = errors.New("no inventory report found")
+
+// inventoryDatePattern matches the folders S3 writes the manifest of each
+// inventory report to, e.g. "2024-01-02T01-00Z/"
+var inventoryDatePattern = regexp.MustCompile(`/[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}-[0-9]{2}Z/$`)
+
+type inventoryManifest struct {
+	SourceBucket string `json:"sourceBucket"`
+	// CreationTimestamp is when the report started, in milliseconds since
+	// the epoch
+	CreationTimestamp string `json:"creationTimestamp"`
+	FileFormat        string `json:"fileFormat"`
+	FileSchema        string `json:"fileSchema"`
+	Files             []struct {
+		Key string `json:"key"`
+	} `json:"files"`
+}
+
+// GetMatchingPathsFromInventory gets the paths in the bucket of `source`
+// which match `source.Regexp`, as GetMatchingPathsFromBucket does, but reads
+// them from the latest report of `source.Inventory` instead of listing the
+// whole bucket.
+//
+// Objects created since the report started are found by listing the prefix
+// of `source.Regexp` and keeping the objects modified since, unless the
+// regexp has no prefix, as that lists the whole bucket. Objects deleted since
+// the report are still returned, see GetInventoryFileVersions.
+func GetMatchingPathsFromInventory(ctx context.Context, client s3resource.S3Client, source s3resource.Source) ([]string, error) {
+	reportedPaths, listedPaths, err := readInventoryPaths(ctx, client, source)
+	if err != nil {
+		return []string{}, err
+	}
+
+	// Objects replaced since the report are both in it and listed
+	matchingPaths := append(reportedPaths, listedPaths...)
+	slices.Sort(matchingPaths)
+	return slices.Compact(matchingPaths), nil
+}
+
+// readInventoryPaths returns the matching paths in the report, and those of
+// the objects modified since which were listed
+func readInventoryPaths(ctx context.Context, client s3resource.S3Client, source s3resource.Source) ([]string, []string, error) {
+	matcher, err := newPathMatcher(source.Regexp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	inventoryBucket := source.Inventory.Bucket
+	if inventoryBucket == "" {
+		inventoryBucket = source.Bucket
+	}
+
+	manifest, err := latestInventoryManifest(ctx, client, inventoryBucket, source.Inventory.Prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if manifest.SourceBucket != source.Bucket {
+		return nil, nil, s3resource.NewConfigError(fmt.Sprintf("inventory.prefix points at an inventory of bucket %s rather than %s", manifest.SourceBucket, source.Bucket))
+	}
+
+	// The columns of CSV reports are only named in the manifest
+	columns := map[string]int{}
+	switch manifest.FileFormat {
+	case "CSV":
+		for i, column := range strings.Split(manifest.FileSchema, ",") {
+			columns[strings.TrimSpace(column)] = i
+		}
+		if _, ok := columns["Key"]; !ok {
+			return nil, nil, fmt.Errorf("inventory report has no Key column: %s", manifest.FileSchema)
+		}
+	case "ORC", "Parquet":
+	default:
+		return nil, nil, s3resource.NewConfigError(fmt.Sprintf("inventory reports in %s format are not supported", manifest.FileFormat))
+	}
+
+	reportedPaths := []string{}
+	for _, file := range manifest.Files {
+		paths, err := readInventoryFile(ctx, client, inventoryBucket, file.Key, manifest.FileFormat, columns, matcher)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading inventory file %s: %w", file.Key, err)
+		}
+		reportedPaths = append(reportedPaths, paths...)
+	}
+
+	milliseconds, err := strconv.ParseInt(manifest.CreationTimestamp, 10, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("inventory manifest has an invalid creationTimestamp: %q", manifest.CreationTimestamp)
+	}
+
+	// Without a prefix the whole bucket would be listed, which the inventory
+	// is there to avoid, so new objects are found in the next report
+	listedPaths := []string{}
+	if matcher.prefix == "" {
+		return reportedPaths, listedPaths, nil
+	}
+
+	newPaths, err := client.BucketFilesModifiedSince(ctx, source.Bucket, matcher.prefix, time.UnixMilli(milliseconds))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, path := range newPaths {
+		if matcher.MatchString(path) {
+			listedPaths = append(listedPaths, path)
+		}
+	}
+
+	return reportedPaths, listedPaths, nil
+}
+
+// objectExists reports whether remotePath is in bucketName, where it comes
+// first in the listing of the keys starting with it
+func objectExists(ctx context.Context, client s3resource.S3Client, bucketName string, remotePath string) (bool, error) {
+	chunk, err := client.ChunkedBucketList(ctx, bucketName, remotePath, nil)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(chunk.Paths, remotePath), nil
+}
+
+// latestInventoryManifest reads the manifest of the latest report under
+// prefix, skipping reports whose manifest has not been written yet
+func latestInventoryManifest(ctx context.Context, client s3resource.S3Client, bucketName string, prefix string) (inventoryManifest, error) {
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+
+	var (
+		continuationToken *string
+		truncated         bool
+		reports           []string
+	)
+	for continuationToken, truncated = nil, true; truncated; {
+		chunk, err := client.ChunkedBucketList(ctx, bucketName, prefix, continuationToken)
+		if err != nil {
+			return inventoryManifest{}, err
+		}
+		truncated = chunk.Truncated
+		continuationToken = chunk.ContinuationToken
+
+		for _, commonPrefix := range chunk.CommonPrefixes {
+			if inventoryDatePattern.MatchString(commonPrefix) {
+				reports = append(reports, commonPrefix)
+			}
+		}
+	}
+
+	// The dated folders sort in the order the reports were delivered
+	slices.Sort(reports)
+	for i := len(reports) - 1; i >= 0; i-- {
+		file, err := client.OpenFile(ctx, bucketName, reports[i]+"manifest.json")
+		if errors.Is(err, s3resource.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return inventoryManifest{}, err
+		}
+
+		var manifest inventoryManifest
+		err = json.NewDecoder(file).Decode(&manifest)
+		file.Close()
+		if err != nil {
+			return inventoryManifest{}, fmt.Errorf("error reading inventory manifest %s: %w", reports[i]+"manifest.json", err)
+		}
+		return manifest, nil
+	}
+
+	return inventoryManifest{}, fmt.Errorf("%w in s3://%s/%s", ErrNoInventory, bucketName, prefix)
+}
+
+// inventoryRow is a version of an object listed in an inventory report
+type inventoryRow struct {
+	key            string
+	isLatest       bool
+	isDeleteMarker bool
+}
+
+// readInventoryFile returns the paths of the latest versions in a file of an
+// inventory report which match matcher
+func readInventoryFile(ctx context.Context, client s3resource.S3Client, bucketName string, key string, format string, columns map[string]int, matcher pathMatcher) ([]string, error) {
+	file, err := client.OpenFile(ctx, bucketName, key)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	paths := []string{}
+	add := func(row inventoryRow) {
+		// Inventories of versioned buckets list every version
+		if row.isLatest && !row.isDeleteMarker && matcher.MatchString(row.key) {
+			paths = append(paths, row.key)
+		}
+	}
+
+	switch format {
+	case "ORC", "Parquet":
+		local, size, err := spoolInventoryFile(file)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(local.Name())
+		defer local.Close()
+
+		if format == "ORC" {
+			err = readORCInventoryFile(local, size, add)
+		} else {
+			err = readParquetInventoryFile(local, size, add)
+		}
+		if err != nil {
+			return nil, err
+		}
+	default:
+		if err := readCSVInventoryFile(file, key, columns, add); err != nil {
+			return nil, err
+		}
+	}
+
+	return paths, nil
+}
+
+// spoolInventoryFile copies a file to a temporary file, as ORC and Parquet
+// files are read starting from their end
+func spoolInventoryFile(file io.Reader) (*os.File, int64, error) {
+	local, err := os.CreateTemp("", "inventory")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size, err := io.Copy(local, file)
+	if err != nil {
+		local.Close()
+		os.Remove(local.Name())
+		return nil, 0, err
+	}
+	return local, size, nil
+}
+
+// readCSVInventoryFile reads the rows of a CSV file, gzipped if key ends in
+// ".gz", whose columns are named in columns
+func readCSVInventoryFile(file io.Reader, key string, columns map[string]int, add func(inventoryRow)) error {
+	var contents io.Reader = file
+	if strings.HasSuffix(key, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		contents = gzipReader
+	}
+
+	reader := csv.NewReader(contents)
+	reader.FieldsPerRecord = len(columns)
+	reader.ReuseRecord = true
+
+	isLatest, hasIsLatest := columns["IsLatest"]
+	isDeleteMarker, hasIsDeleteMarker := columns["IsDeleteMarker"]
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Keys are URL encoded in CSV reports
+		path, err := url.QueryUnescape(record[columns["Key"]])
+		if err != nil {
+			return err
+		}
+		add(inventoryRow{
+			key:            path,
+			isLatest:       !hasIsLatest || record[isLatest] == "true",
+			isDeleteMarker: hasIsDeleteMarker && record[isDeleteMarker] == "true",
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package versions_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+
+	"github.com/golang/snappy"
+	. "github.com/onsi/gomega"
+)
+
+// The encoders below write the subset of ORC which the reader of inventory
+// reports supports, so that reports can be made in the tests.
+
+// protobufField is a field of a protobuf message. Values are uint64, string,
+// []byte for messages and []uint64 for packed integers.
+type protobufField struct {
+	number uint64
+	value  any
+}
+
+func protobuf(fields ...protobufField) []byte {
+	var data []byte
+	for _, field := range fields {
+		switch value := field.value.(type) {
+		case uint64:
+			data = binary.AppendUvarint(data, field.number<<3)
+			data = binary.AppendUvarint(data, value)
+		case []uint64:
+			var packed []byte
+			for _, v := range value {
+				packed = binary.AppendUvarint(packed, v)
+			}
+			data = binary.AppendUvarint(data, field.number<<3|2)
+			data = binary.AppendUvarint(data, uint64(len(packed)))
+			data = append(data, packed...)
+		case string:
+			data = binary.AppendUvarint(data, field.number<<3|2)
+			data = binary.AppendUvarint(data, uint64(len(value)))
+			data = append(data, value...)
+		case []byte:
+			data = binary.AppendUvarint(data, field.number<<3|2)
+			data = binary.AppendUvarint(data, uint64(len(value)))
+			data = append(data, value...)
+		}
+	}
+	return data
+}
+
+// orcStream is a stream of a column in a stripe, before it is compressed
+type orcStream struct {
+	kind   uint64
+	column uint64
+	data   []byte
+}
+
+// orcStripe is a stripe of numRows rows, with the encoding of each column
+// and the dictionary size of those which have a dictionary
+type orcStripe struct {
+	numRows         uint64
+	encodings       []uint64
+	dictionarySizes map[int]uint64
+	streams         []orcStream
+}
+
+// orcFile writes a file of the columns of inventory reports whose types are
+// in columns, compressed with compression
+func orcFile(compression uint64, columns map[string]uint64, names []string, stripes ...orcStripe) []byte {
+	var file bytes.Buffer
+	file.WriteString("ORC")
+
+	var (
+		stripeInformation []protobufField
+		numRows           uint64
+	)
+	for _, stripe := range stripes {
+		offset := uint64(file.Len())
+
+		var streams []protobufField
+		for _, stream := range stripe.streams {
+			compressed := compressORC(compression, stream.data)
+			file.Write(compressed)
+			streams = append(streams, protobufField{1, protobuf(
+				protobufField{1, stream.kind},
+				protobufField{2, stream.column},
+				protobufField{3, uint64(len(compressed))},
+			)})
+		}
+		dataLength := uint64(file.Len()) - offset
+
+		footerFields := streams
+		for column, kind := range stripe.encodings {
+			encoding := []protobufField{{1, kind}}
+			if size, ok := stripe.dictionarySizes[column]; ok {
+				encoding = append(encoding, protobufField{2, size})
+			}
+			footerFields = append(footerFields, protobufField{2, protobuf(encoding...)})
+		}
+		footer := compressORC(compression, protobuf(footerFields...))
+		file.Write(footer)
+
+		stripeInformation = append(stripeInformation, protobufField{3, protobuf(
+			protobufField{1, offset},
+			protobufField{2, uint64(0)},
+			protobufField{3, dataLength},
+			protobufField{4, uint64(len(footer))},
+			protobufField{5, stripe.numRows},
+		)})
+		numRows += stripe.numRows
+	}
+
+	root := []protobufField{{1, uint64(12)}}
+	var subtypes []uint64
+	for i, name := range names {
+		subtypes = append(subtypes, uint64(i+1))
+		root = append(root, protobufField{3, name})
+	}
+	root = append(root, protobufField{2, subtypes})
+
+	footerFields := []protobufField{
+		{1, uint64(3)},
+		{2, uint64(file.Len() - 3)},
+	}
+	footerFields = append(footerFields, stripeInformation...)
+	footerFields = append(footerFields, protobufField{4, protobuf(root...)})
+	for _, name := range names {
+		footerFields = append(footerFields, protobufField{4, protobuf(protobufField{1, columns[name]})})
+	}
+	footerFields = append(footerFields, protobufField{6, numRows})
+
+	footer := compressORC(compression, protobuf(footerFields...))
+	file.Write(footer)
+
+	postscript := protobuf(
+		protobufField{1, uint64(len(footer))},
+		protobufField{2, compression},
+		protobufField{3, uint64(256 * 1024)},
+		protobufField{4, []uint64{0, 12}},
+		protobufField{8000, "ORC"},
+	)
+	file.Write(postscript)
+	file.WriteByte(byte(len(postscript)))
+	return file.Bytes()
+}
+
+// compressORC compresses data into a single chunk, or keeps it as it is in a
+// chunk marked as original when it does not get smaller
+func compressORC(compression uint64, data []byte) []byte {
+	var compressed []byte
+	switch compression {
+	case 0:
+		return data
+	case 1:
+		var buffer bytes.Buffer
+		writer, err := flate.NewWriter(&buffer, flate.BestCompression)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = writer.Write(data)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(writer.Close()).Should(Succeed())
+		compressed = buffer.Bytes()
+	case 2:
+		compressed = snappy.Encode(nil, data)
+	}
+
+	header := len(compressed) << 1
+	if len(compressed) >= len(data) {
+		compressed = data
+		header = len(data)<<1 | 1
+	}
+	return append([]byte{byte(header), byte(header >> 8), byte(header >> 16)}, compressed...)
+}
+
+// orcBytes encodes bytes as literals of byte run length encoding
+func orcBytes(values ...byte) []byte {
+	var data []byte
+	for len(values) > 0 {
+		n := min(len(values), 128)
+		data = append(data, byte(0x100-n))
+		data = append(data, values[:n]...)
+		values = values[n:]
+	}
+	return data
+}
+
+// orcBooleans packs booleans from the most significant bit, and encodes them
+// with byte run length encoding
+func orcBooleans(values ...bool) []byte {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, value := range values {
+		if value {
+			packed[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return orcBytes(packed...)
+}
+
+// orcDirectV2 encodes values of up to 8 bits as a DIRECT run of version 2 of
+// integer run length encoding
+func orcDirectV2(values ...uint64) []byte {
+	length := len(values) - 1
+	data := []byte{1<<6 | 7<<1 | byte(length>>8), byte(length)}
+	for _, value := range values {
+		data = append(data, byte(value))
+	}
+	return data
+}
+
+// orcLiteralsV1 encodes values as literals of version 1 of integer run
+// length encoding
+func orcLiteralsV1(values ...uint64) []byte {
+	data := []byte{byte(0x100 - len(values))}
+	for _, value := range values {
+		data = binary.AppendUvarint(data, value)
+	}
+	return data
+}
@@ -0,0 +1,789 @@
+package versions
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+)
+
+// orcMagic ends the postscript of ORC files
+const orcMagic = "ORC"
+
+// orcCompressionNames names the compression kinds of ORC
+var orcCompressionNames = map[uint64]string{
+	0: "NONE",
+	1: "ZLIB",
+	2: "SNAPPY",
+	3: "LZO",
+	4: "LZ4",
+	5: "ZSTD",
+}
+
+// The compression kinds, type kinds, stream kinds and column encodings of
+// ORC which inventory reports use
+const (
+	orcNone   = 0
+	orcZlib   = 1
+	orcSnappy = 2
+
+	orcBoolean = 0
+	orcString  = 7
+	orcVarchar = 16
+	orcChar    = 17
+
+	orcPresent        = 0
+	orcData           = 1
+	orcLength         = 2
+	orcDictionaryData = 3
+
+	orcDirect       = 0
+	orcDictionary   = 1
+	orcDirectV2     = 2
+	orcDictionaryV2 = 3
+)
+
+// readORCInventoryFile reads the rows of an ORC file of an inventory report.
+// Its schema is a struct with the fields key, is_latest and is_delete_marker.
+func readORCInventoryFile(file io.ReaderAt, size int64, add func(inventoryRow)) error {
+	// The file ends with its postscript, and the length of the postscript
+	tail := make([]byte, min(size, 256))
+	if _, err := file.ReadAt(tail, size-int64(len(tail))); err != nil {
+		return err
+	}
+	if len(tail) == 0 || int(tail[len(tail)-1]) > len(tail)-1 {
+		return errors.New("not an orc file")
+	}
+	postscript, err := readProtobuf(tail[len(tail)-1-int(tail[len(tail)-1]) : len(tail)-1])
+	if err != nil || postscript.string(8000) != orcMagic {
+		return errors.New("not an orc file")
+	}
+	compression := postscript.uint(2)
+	if compression != orcNone && compression != orcZlib && compression != orcSnappy {
+		name, ok := orcCompressionNames[compression]
+		if !ok {
+			name = fmt.Sprintf("kind %d", compression)
+		}
+		return fmt.Errorf("orc files compressed with %s are not supported", name)
+	}
+
+	footerLength := int64(postscript.uint(1))
+	footerEnd := size - 1 - int64(tail[len(tail)-1])
+	if footerLength > footerEnd {
+		return errors.New("orc footer is truncated")
+	}
+	footer, err := readORCMessage(file, footerEnd-footerLength, footerLength, compression)
+	if err != nil {
+		return fmt.Errorf("error reading orc footer: %w", err)
+	}
+
+	// The first type is the struct of the rows, whose fields are the columns
+	// of the other types
+	types := footer.messages(4)
+	if len(types) == 0 {
+		return errors.New("orc file has no types")
+	}
+	names := types[0].strings(3)
+	fieldColumns := types[0].uints(2)
+	columns := map[string]int{}
+	for i, name := range names {
+		if i >= len(fieldColumns) || fieldColumns[i] >= uint64(len(types)) {
+			return fmt.Errorf("orc field %s has no type", name)
+		}
+		columns[name] = int(fieldColumns[i])
+	}
+	key, ok := columns["key"]
+	if !ok {
+		return errors.New("orc file has no key column")
+	}
+	if kind := types[key].uint(1); kind != orcString && kind != orcVarchar && kind != orcChar {
+		return fmt.Errorf("orc key column has type %d rather than string", kind)
+	}
+	for _, name := range []string{"is_latest", "is_delete_marker"} {
+		if column, ok := columns[name]; ok && types[column].uint(1) != orcBoolean {
+			return fmt.Errorf("orc %s column has type %d rather than boolean", name, types[column].uint(1))
+		}
+	}
+
+	for _, stripe := range footer.messages(3) {
+		if err := readORCStripe(file, stripe, compression, columns, add); err != nil {
+			return fmt.Errorf("error reading orc stripe: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// orcStripe holds the streams of the columns of a stripe, decompressed, and
+// their encodings
+type orcStripe struct {
+	numRows   int
+	streams   map[[2]uint64][]byte
+	encodings []protobufMessage
+}
+
+func readORCStripe(file io.ReaderAt, information protobufMessage, compression uint64, columns map[string]int, add func(inventoryRow)) error {
+	offset := int64(information.uint(1))
+	footerOffset := offset + int64(information.uint(2)) + int64(information.uint(3))
+	footer, err := readORCMessage(file, footerOffset, int64(information.uint(4)), compression)
+	if err != nil {
+		return err
+	}
+
+	stripe := orcStripe{
+		numRows:   int(information.uint(5)),
+		streams:   map[[2]uint64][]byte{},
+		encodings: footer.messages(2),
+	}
+
+	// The streams follow each other from the start of the stripe, only the
+	// ones of the columns read are read
+	wanted := map[uint64]bool{}
+	for _, column := range columns {
+		wanted[uint64(column)] = true
+	}
+	for _, stream := range footer.messages(1) {
+		length := int64(stream.uint(3))
+		if column := stream.uint(2); wanted[column] {
+			data, err := readORCStream(file, offset, length, compression)
+			if err != nil {
+				return err
+			}
+			stripe.streams[[2]uint64{column, stream.uint(1)}] = data
+		}
+		offset += length
+	}
+
+	keys, err := stripe.strings(columns["key"])
+	if err != nil {
+		return fmt.Errorf("error reading key column: %w", err)
+	}
+	isLatest := make([]*bool, stripe.numRows)
+	if column, ok := columns["is_latest"]; ok {
+		if isLatest, err = stripe.booleans(column); err != nil {
+			return fmt.Errorf("error reading is_latest column: %w", err)
+		}
+	}
+	isDeleteMarker := make([]*bool, stripe.numRows)
+	if column, ok := columns["is_delete_marker"]; ok {
+		if isDeleteMarker, err = stripe.booleans(column); err != nil {
+			return fmt.Errorf("error reading is_delete_marker column: %w", err)
+		}
+	}
+
+	for row, key := range keys {
+		if key == nil {
+			continue
+		}
+		add(inventoryRow{
+			key:            *key,
+			isLatest:       isLatest[row] == nil || *isLatest[row],
+			isDeleteMarker: isDeleteMarker[row] != nil && *isDeleteMarker[row],
+		})
+	}
+	return nil
+}
+
+// present returns which rows of column have a value, or nil if they all do
+func (stripe orcStripe) present(column int) ([]bool, int, error) {
+	data, ok := stripe.streams[[2]uint64{uint64(column), orcPresent}]
+	if !ok {
+		return nil, stripe.numRows, nil
+	}
+
+	present, err := decodeORCBooleans(data, stripe.numRows)
+	if err != nil {
+		return nil, 0, err
+	}
+	count := 0
+	for _, p := range present {
+		if p {
+			count++
+		}
+	}
+	return present, count, nil
+}
+
+// strings returns the values of a string column, nil where they are null
+func (stripe orcStripe) strings(column int) ([]*string, error) {
+	present, count, err := stripe.present(column)
+	if err != nil {
+		return nil, err
+	}
+	if column >= len(stripe.encodings) {
+		return nil, errors.New("column has no encoding")
+	}
+	encoding := stripe.encodings[column]
+	stream := func(kind uint64) []byte {
+		return stripe.streams[[2]uint64{uint64(column), kind}]
+	}
+
+	var values []string
+	switch kind := encoding.uint(1); kind {
+	case orcDirect, orcDirectV2:
+		lengths, err := decodeORCUints(stream(orcLength), count, kind == orcDirectV2)
+		if err != nil {
+			return nil, err
+		}
+		values, err = splitORCStrings(stream(orcData), lengths)
+		if err != nil {
+			return nil, err
+		}
+
+	case orcDictionary, orcDictionaryV2:
+		lengths, err := decodeORCUints(stream(orcLength), int(encoding.uint(2)), kind == orcDictionaryV2)
+		if err != nil {
+			return nil, err
+		}
+		dictionary, err := splitORCStrings(stream(orcDictionaryData), lengths)
+		if err != nil {
+			return nil, err
+		}
+		indices, err := decodeORCUints(stream(orcData), count, kind == orcDictionaryV2)
+		if err != nil {
+			return nil, err
+		}
+		for _, index := range indices {
+			if index >= uint64(len(dictionary)) {
+				return nil, fmt.Errorf("dictionary index %d is out of range", index)
+			}
+			values = append(values, dictionary[index])
+		}
+
+	default:
+		return nil, fmt.Errorf("orc column encoding %d is not supported", kind)
+	}
+
+	result := make([]*string, stripe.numRows)
+	for row := range result {
+		if present == nil || present[row] {
+			result[row] = &values[0]
+			values = values[1:]
+		}
+	}
+	return result, nil
+}
+
+// booleans returns the values of a boolean column, nil where they are null
+func (stripe orcStripe) booleans(column int) ([]*bool, error) {
+	present, count, err := stripe.present(column)
+	if err != nil {
+		return nil, err
+	}
+	values, err := decodeORCBooleans(stripe.streams[[2]uint64{uint64(column), orcData}], count)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*bool, stripe.numRows)
+	for row := range result {
+		if present == nil || present[row] {
+			result[row] = &values[0]
+			values = values[1:]
+		}
+	}
+	return result, nil
+}
+
+func splitORCStrings(data []byte, lengths []uint64) ([]string, error) {
+	values := make([]string, 0, len(lengths))
+	for _, length := range lengths {
+		if length > uint64(len(data)) {
+			return nil, errors.New("string data is truncated")
+		}
+		values = append(values, string(data[:length]))
+		data = data[length:]
+	}
+	return values, nil
+}
+
+// decodeORCBytes decodes count bytes encoded with byte run length encoding
+func decodeORCBytes(data []byte, count int) ([]byte, error) {
+	values := make([]byte, 0, count)
+	for len(values) < count {
+		if len(data) == 0 {
+			return nil, errors.New("encoded bytes are truncated")
+		}
+		control := data[0]
+		data = data[1:]
+
+		if control < 0x80 {
+			// A run of at least 3 of the same byte
+			if len(data) == 0 {
+				return nil, errors.New("encoded bytes are truncated")
+			}
+			for range int(control) + 3 {
+				values = append(values, data[0])
+			}
+			data = data[1:]
+			continue
+		}
+
+		literals := 0x100 - int(control)
+		if literals > len(data) {
+			return nil, errors.New("encoded bytes are truncated")
+		}
+		values = append(values, data[:literals]...)
+		data = data[literals:]
+	}
+	return values[:count], nil
+}
+
+// decodeORCBooleans decodes count booleans, packed from the most significant
+// bit into bytes which are run length encoded
+func decodeORCBooleans(data []byte, count int) ([]bool, error) {
+	packed, err := decodeORCBytes(data, (count+7)/8)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]bool, count)
+	for i := range values {
+		values[i] = packed[i/8]&(0x80>>(i%8)) != 0
+	}
+	return values, nil
+}
+
+// decodeORCUints decodes count unsigned integers encoded with version 1 of
+// integer run length encoding, or version 2 if v2 is set
+func decodeORCUints(data []byte, count int, v2 bool) ([]uint64, error) {
+	values := make([]uint64, 0, count)
+	for len(values) < count {
+		if len(data) == 0 {
+			return nil, errors.New("encoded integers are truncated")
+		}
+
+		var (
+			run []uint64
+			n   int
+			err error
+		)
+		if v2 {
+			run, n, err = decodeORCRunV2(data)
+		} else {
+			run, n, err = decodeORCRunV1(data)
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, run...)
+		data = data[n:]
+	}
+	return values[:count], nil
+}
+
+// decodeORCRunV1 decodes a run or a sequence of literals, returning how many
+// bytes they took
+func decodeORCRunV1(data []byte) ([]uint64, int, error) {
+	control := data[0]
+	pos := 1
+
+	if control < 0x80 {
+		// A run of at least 3 values, each the one before plus delta
+		if pos >= len(data) {
+			return nil, 0, errors.New("encoded integers are truncated")
+		}
+		delta := int64(int8(data[pos]))
+		pos++
+		base, n := binary.Uvarint(data[pos:])
+		if n <= 0 {
+			return nil, 0, errors.New("encoded integers are truncated")
+		}
+		pos += n
+
+		values := make([]uint64, int(control)+3)
+		for i := range values {
+			values[i] = uint64(int64(base) + int64(i)*delta)
+		}
+		return values, pos, nil
+	}
+
+	values := make([]uint64, 0x100-int(control))
+	for i := range values {
+		value, n := binary.Uvarint(data[pos:])
+		if n <= 0 {
+			return nil, 0, errors.New("encoded integers are truncated")
+		}
+		values[i] = value
+		pos += n
+	}
+	return values, pos, nil
+}
+
+// The sub-encodings of version 2 of integer run length encoding
+const (
+	orcShortRepeat = 0
+	orcDirectRun   = 1
+	orcPatchedBase = 2
+	orcDeltaRun    = 3
+)
+
+// decodeORCRunV2 decodes a run of integers, returning how many bytes it took
+func decodeORCRunV2(data []byte) ([]uint64, int, error) {
+	errTruncated := errors.New("encoded integers are truncated")
+
+	var err error
+	switch data[0] >> 6 {
+	case orcShortRepeat:
+		width := int(data[0]>>3&0x07) + 1
+		if len(data) < 1+width {
+			return nil, 0, errTruncated
+		}
+		value := readORCBigEndian(data[1 : 1+width])
+
+		values := make([]uint64, int(data[0]&0x07)+3)
+		for i := range values {
+			values[i] = value
+		}
+		return values, 1 + width, nil
+
+	case orcDirectRun:
+		if len(data) < 2 {
+			return nil, 0, errTruncated
+		}
+		width := decodeORCBitWidth(data[0] >> 1 & 0x1f)
+		length := (int(data[0]&0x01)<<8 | int(data[1])) + 1
+
+		reader := bitReader{data: data[2:]}
+		values := make([]uint64, length)
+		for i := range values {
+			if values[i], err = reader.read(width); err != nil {
+				return nil, 0, err
+			}
+		}
+		return values, 2 + reader.bytesRead(), nil
+
+	case orcPatchedBase:
+		if len(data) < 4 {
+			return nil, 0, errTruncated
+		}
+		width := decodeORCBitWidth(data[0] >> 1 & 0x1f)
+		length := (int(data[0]&0x01)<<8 | int(data[1])) + 1
+		baseWidth := int(data[2]>>5&0x07) + 1
+		patchWidth := decodeORCBitWidth(data[2] & 0x1f)
+		patchGapWidth := int(data[3]>>5&0x07) + 1
+		patchListLength := int(data[3] & 0x1f)
+		pos := 4
+
+		// The base is in sign and magnitude form
+		if len(data) < pos+baseWidth {
+			return nil, 0, errTruncated
+		}
+		base := int64(readORCBigEndian(data[pos : pos+baseWidth]))
+		signBit := int64(1) << (8*baseWidth - 1)
+		if base&signBit != 0 {
+			base = -(base &^ signBit)
+		}
+		pos += baseWidth
+
+		reader := bitReader{data: data[pos:]}
+		values := make([]uint64, length)
+		for i := range values {
+			if values[i], err = reader.read(width); err != nil {
+				return nil, 0, err
+			}
+		}
+		pos += reader.bytesRead()
+
+		// Each patch holds the bits of a value above width, and the gap
+		// from the previous patched value
+		reader = bitReader{data: data[pos:]}
+		patchEntryWidth := closestORCFixedBits(patchGapWidth + patchWidth)
+		index := 0
+		for range patchListLength {
+			entry, err := reader.read(patchEntryWidth)
+			if err != nil {
+				return nil, 0, err
+			}
+			index += int(entry >> patchWidth)
+			if index >= length {
+				return nil, 0, errors.New("patch is out of range")
+			}
+			values[index] |= (entry & (1<<patchWidth - 1)) << width
+		}
+		pos += reader.bytesRead()
+
+		for i := range values {
+			values[i] = uint64(base + int64(values[i]))
+		}
+		return values, pos, nil
+
+	default:
+		if len(data) < 2 {
+			return nil, 0, errTruncated
+		}
+		width := 0
+		if code := data[0] >> 1 & 0x1f; code != 0 {
+			width = decodeORCBitWidth(code)
+		}
+		length := (int(data[0]&0x01)<<8 | int(data[1])) + 1
+		pos := 2
+
+		base, n := binary.Uvarint(data[pos:])
+		if n <= 0 {
+			return nil, 0, errTruncated
+		}
+		pos += n
+		deltaBase, n := binary.Varint(data[pos:])
+		if n <= 0 {
+			return nil, 0, errTruncated
+		}
+		pos += n
+
+		values := make([]uint64, length)
+		values[0] = base
+		if length > 1 {
+			values[1] = uint64(int64(base) + deltaBase)
+		}
+
+		// Without a width every delta is the base one, otherwise the other
+		// deltas are packed with its sign
+		reader := bitReader{data: data[pos:]}
+		for i := 2; i < length; i++ {
+			delta := deltaBase
+			if width > 0 {
+				magnitude, err := reader.read(width)
+				if err != nil {
+					return nil, 0, err
+				}
+				delta = int64(magnitude)
+				if deltaBase < 0 {
+					delta = -delta
+				}
+			}
+			values[i] = uint64(int64(values[i-1]) + delta)
+		}
+		return values, pos + reader.bytesRead(), nil
+	}
+}
+
+// decodeORCBitWidth decodes the 5 bit codes of widths
+func decodeORCBitWidth(code byte) int {
+	if code < 24 {
+		return int(code) + 1
+	}
+	return [...]int{26, 28, 30, 32, 40, 48, 56, 64}[code-24]
+}
+
+// closestORCFixedBits rounds width up to a width which can be encoded
+func closestORCFixedBits(width int) int {
+	switch {
+	case width == 0:
+		return 1
+	case width <= 24:
+		return width
+	}
+	for _, fixed := range []int{26, 28, 30, 32, 40, 48, 56} {
+		if width <= fixed {
+			return fixed
+		}
+	}
+	return 64
+}
+
+func readORCBigEndian(data []byte) uint64 {
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+// bitReader reads values packed from the most significant bit
+type bitReader struct {
+	data []byte
+	bit  int
+}
+
+func (reader *bitReader) read(width int) (uint64, error) {
+	if reader.bit+width > 8*len(reader.data) {
+		return 0, errors.New("encoded integers are truncated")
+	}
+	var value uint64
+	for range width {
+		b := reader.data[reader.bit/8] >> (7 - reader.bit%8) & 1
+		value = value<<1 | uint64(b)
+		reader.bit++
+	}
+	return value, nil
+}
+
+// bytesRead is how many bytes the values read took, as runs are padded to
+// whole bytes
+func (reader *bitReader) bytesRead() int {
+	return (reader.bit + 7) / 8
+}
+
+// readORCMessage reads a protobuf message which is compressed as streams are
+func readORCMessage(file io.ReaderAt, offset int64, length int64, compression uint64) (protobufMessage, error) {
+	data, err := readORCStream(file, offset, length, compression)
+	if err != nil {
+		return nil, err
+	}
+	return readProtobuf(data)
+}
+
+// readORCStream reads a stream, decompressing the chunks it is split into
+func readORCStream(file io.ReaderAt, offset int64, length int64, compression uint64) ([]byte, error) {
+	data := make([]byte, length)
+	if _, err := file.ReadAt(data, offset); err != nil {
+		return nil, err
+	}
+	if compression == orcNone {
+		return data, nil
+	}
+
+	var decompressed []byte
+	for len(data) > 0 {
+		// Each chunk starts with its length and whether it is compressed,
+		// in 3 little endian bytes
+		if len(data) < 3 {
+			return nil, errors.New("compressed chunk is truncated")
+		}
+		header := int(data[0]) | int(data[1])<<8 | int(data[2])<<16
+		chunkLength := header >> 1
+		if chunkLength > len(data)-3 {
+			return nil, errors.New("compressed chunk is truncated")
+		}
+		chunk := data[3 : 3+chunkLength]
+		data = data[3+chunkLength:]
+
+		if header&1 == 1 {
+			decompressed = append(decompressed, chunk...)
+			continue
+		}
+
+		switch compression {
+		case orcZlib:
+			reader := flate.NewReader(bytes.NewReader(chunk))
+			contents, err := io.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				return nil, err
+			}
+			decompressed = append(decompressed, contents...)
+		case orcSnappy:
+			contents, err := snappy.Decode(nil, chunk)
+			if err != nil {
+				return nil, err
+			}
+			decompressed = append(decompressed, contents...)
+		default:
+			return nil, fmt.Errorf("orc files compressed with %s are not supported", orcCompressionNames[compression])
+		}
+	}
+	return decompressed, nil
+}
+
+// protobufMessage is a decoded protobuf message, which ORC metadata is
+// written as, holding the values of each field by number. Varints are held
+// as uint64 and length delimited values as []byte.
+type protobufMessage map[uint64][]any
+
+func readProtobuf(data []byte) (protobufMessage, error) {
+	message := protobufMessage{}
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		data = data[n:]
+
+		var value any
+		switch key & 0x07 {
+		case 0:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			value = v
+			data = data[n:]
+		case 1:
+			if len(data) < 8 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			value = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case 2:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || length > uint64(len(data)-n) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			value = data[n : n+int(length)]
+			data = data[n+int(length):]
+		case 5:
+			if len(data) < 4 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			value = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		default:
+			return nil, fmt.Errorf("protobuf wire type %d is not supported", key&0x07)
+		}
+		message[key>>3] = append(message[key>>3], value)
+	}
+	return message, nil
+}
+
+func (message protobufMessage) uint(field uint64) uint64 {
+	values := message.uints(field)
+	if len(values) == 0 {
+		return 0
+	}
+	return values[len(values)-1]
+}
+
+// uints returns the values of a repeated integer field, which are packed
+// into length delimited values or not
+func (message protobufMessage) uints(field uint64) []uint64 {
+	var values []uint64
+	for _, value := range message[field] {
+		switch value := value.(type) {
+		case uint64:
+			values = append(values, value)
+		case []byte:
+			for len(value) > 0 {
+				v, n := binary.Uvarint(value)
+				if n <= 0 {
+					break
+				}
+				values = append(values, v)
+				value = value[n:]
+			}
+		}
+	}
+	return values
+}
+
+func (message protobufMessage) string(field uint64) string {
+	values := message.strings(field)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+func (message protobufMessage) strings(field uint64) []string {
+	var values []string
+	for _, value := range message[field] {
+		if value, ok := value.([]byte); ok {
+			values = append(values, string(value))
+		}
+	}
+	return values
+}
+
+func (message protobufMessage) messages(field uint64) []protobufMessage {
+	var messages []protobufMessage
+	for _, value := range message[field] {
+		if value, ok := value.([]byte); ok {
+			if decoded, err := readProtobuf(value); err == nil {
+				messages = append(messages, decoded)
+			}
+		}
+	}
+	return messages
+}
@@ -0,0 +1,50 @@
+package versions
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The encoded integers are the examples of the ORC specification
+var _ = Describe("Decoding ORC integers", func() {
+	DescribeTable("run length encoding v2",
+		func(data []byte, expected []uint64) {
+			values, err := decodeORCUints(data, len(expected), true)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(values).Should(Equal(expected))
+		},
+		Entry("short repeat", []byte{0x0a, 0x27, 0x10}, []uint64{10000, 10000, 10000, 10000, 10000}),
+		Entry("direct", []byte{0x5e, 0x03, 0x5c, 0xa1, 0xab, 0x1e, 0xde, 0xad, 0xbe, 0xef}, []uint64{23713, 43806, 57005, 48879}),
+		Entry("patched base",
+			[]byte{0x8e, 0x13, 0x2b, 0x21, 0x07, 0xd0, 0x1e, 0x00, 0x14, 0x70, 0x28, 0x32, 0x3c, 0x46, 0x50, 0x5a, 0x64, 0x6e, 0x78, 0x82, 0x8c, 0x96, 0xa0, 0xaa, 0xb4, 0xbe, 0xfc, 0xe8},
+			[]uint64{2030, 2000, 2020, 1000000, 2040, 2050, 2060, 2070, 2080, 2090, 2100, 2110, 2120, 2130, 2140, 2150, 2160, 2170, 2180, 2190},
+		),
+		Entry("delta", []byte{0xc6, 0x09, 0x02, 0x02, 0x22, 0x42, 0x42, 0x46}, []uint64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29}),
+	)
+
+	DescribeTable("run length encoding v1",
+		func(data []byte, expected []uint64) {
+			values, err := decodeORCUints(data, len(expected), false)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(values).Should(Equal(expected))
+		},
+		Entry("run", []byte{0x61, 0x00, 0x07}, repeatedUints(100, 7)),
+		Entry("literals", []byte{0xfb, 0x02, 0x03, 0x06, 0x07, 0x0b}, []uint64{2, 3, 6, 7, 11}),
+	)
+
+	It("fails on truncated runs", func() {
+		_, err := decodeORCUints([]byte{0x5e, 0x03, 0x5c, 0xa1}, 4, true)
+		Ω(err).Should(HaveOccurred())
+
+		_, err = decodeORCUints([]byte{0xfb, 0x02}, 5, false)
+		Ω(err).Should(HaveOccurred())
+	})
+})
+
+func repeatedUints(count int, value uint64) []uint64 {
+	values := make([]uint64, count)
+	for i := range values {
+		values[i] = value
+	}
+	return values
+}
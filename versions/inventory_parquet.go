@@ -0,0 +1,157 @@
+package versions
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+// parquetMagic starts and ends Parquet files
+const parquetMagic = "PAR1"
+
+// parquetBatchSize is how many rows of each column are read at once, so that
+// large files are not held in memory
+const parquetBatchSize = 10000
+
+// parquetCodecs are the compression codecs which the Parquet reader can
+// decompress. Its LZ4 codec is left out, as it doesn't read the framing
+// other writers use.
+var parquetCodecs = []parquet.CompressionCodec{
+	parquet.CompressionCodec_UNCOMPRESSED,
+	parquet.CompressionCodec_SNAPPY,
+	parquet.CompressionCodec_GZIP,
+	parquet.CompressionCodec_ZSTD,
+}
+
+// readParquetInventoryFile reads the rows of a Parquet file of an inventory
+// report. Its schema is flat, with the columns key, is_latest and
+// is_delete_marker.
+func readParquetInventoryFile(file io.ReaderAt, size int64, add func(inventoryRow)) (err error) {
+	if size < int64(2*len(parquetMagic)) {
+		return errors.New("not a parquet file")
+	}
+	for _, offset := range []int64{0, size - int64(len(parquetMagic))} {
+		magic := make([]byte, len(parquetMagic))
+		if _, err := file.ReadAt(magic, offset); err != nil {
+			return err
+		}
+		if string(magic) != parquetMagic {
+			return errors.New("not a parquet file")
+		}
+	}
+
+	// The reader panics on some malformed files rather than failing
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("parquet file is malformed: %v", recovered)
+		}
+	}()
+
+	parquetReader, err := reader.NewParquetColumnReader(parquetFile{io.NewSectionReader(file, 0, size)}, 1)
+	if err != nil {
+		return fmt.Errorf("error reading parquet metadata: %w", err)
+	}
+	defer parquetReader.ReadStop()
+
+	for _, rowGroup := range parquetReader.Footer.RowGroups {
+		for _, chunk := range rowGroup.Columns {
+			if codec := chunk.MetaData.Codec; !slices.Contains(parquetCodecs, codec) {
+				return fmt.Errorf("parquet files compressed with %s are not supported", codec)
+			}
+		}
+	}
+
+	root := parquetReader.SchemaHandler.GetRootExName()
+	column := func(name string) (string, bool) {
+		path := root + common.PAR_GO_PATH_DELIMITER + name
+		_, ok := parquetReader.SchemaHandler.ExPathToInPath[path]
+		return path, ok
+	}
+	keyPath, ok := column("key")
+	if !ok {
+		return errors.New("parquet file has no key column")
+	}
+	isLatestPath, hasIsLatest := column("is_latest")
+	isDeleteMarkerPath, hasIsDeleteMarker := column("is_delete_marker")
+
+	numRows := parquetReader.GetNumRows()
+	for read := int64(0); read < numRows; read += parquetBatchSize {
+		batchSize := min(parquetBatchSize, numRows-read)
+
+		keys, err := readParquetColumn(parquetReader, keyPath, batchSize)
+		if err != nil {
+			return err
+		}
+		var isLatest, isDeleteMarker []any
+		if hasIsLatest {
+			if isLatest, err = readParquetColumn(parquetReader, isLatestPath, batchSize); err != nil {
+				return err
+			}
+		}
+		if hasIsDeleteMarker {
+			if isDeleteMarker, err = readParquetColumn(parquetReader, isDeleteMarkerPath, batchSize); err != nil {
+				return err
+			}
+		}
+
+		for row, value := range keys {
+			key, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("parquet key column holds %T rather than strings", value)
+			}
+
+			add(inventoryRow{
+				key:            key,
+				isLatest:       !hasIsLatest || isLatest[row] != false,
+				isDeleteMarker: hasIsDeleteMarker && isDeleteMarker[row] == true,
+			})
+		}
+	}
+
+	return nil
+}
+
+// readParquetColumn reads the next n values of the column at path, which are
+// nil where they are null
+func readParquetColumn(parquetReader *reader.ParquetReader, path string, n int64) ([]any, error) {
+	values, _, _, err := parquetReader.ReadColumnByPath(path, n)
+	if err != nil {
+		return nil, err
+	}
+
+	// The reader returns fewer values rather than an error when a page
+	// cannot be read
+	if int64(len(values)) != n {
+		return nil, fmt.Errorf("parquet column %s has fewer values than rows", common.StrToPath(path)[1])
+	}
+	return values, nil
+}
+
+// parquetFile gives the Parquet reader a file of an inventory report, which
+// it opens again for each column it reads
+type parquetFile struct {
+	*io.SectionReader
+}
+
+func (file parquetFile) Open(string) (source.ParquetFile, error) {
+	outer, offset, size := file.Outer()
+	return parquetFile{io.NewSectionReader(outer, offset, size)}, nil
+}
+
+func (file parquetFile) Create(string) (source.ParquetFile, error) {
+	return nil, errors.New("parquet file is read only")
+}
+
+func (file parquetFile) Write([]byte) (int, error) {
+	return 0, errors.New("parquet file is read only")
+}
+
+func (file parquetFile) Close() error {
+	return nil
+}
@@ -0,0 +1,381 @@
+package versions_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	s3resource "github.com/concourse/s3-resource"
+	"github.com/concourse/s3-resource/fakes"
+	"github.com/concourse/s3-resource/versions"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetMatchingPathsFromInventory", func() {
+	var (
+		s3client *fakes.FakeS3Client
+		source   s3resource.Source
+		reports  s3resource.BucketListChunk
+		files    map[string][]byte
+	)
+
+	gzipped := func(rows ...string) []byte {
+		var buffer bytes.Buffer
+		writer := gzip.NewWriter(&buffer)
+		_, err := writer.Write([]byte(strings.Join(rows, "\n") + "\n"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(writer.Close()).Should(Succeed())
+		return buffer.Bytes()
+	}
+
+	manifest := func(format string, keys ...string) []byte {
+		entries := make([]string, len(keys))
+		for i, key := range keys {
+			entries[i] = fmt.Sprintf(`{"key": %q, "size": 1}`, key)
+		}
+		return []byte(fmt.Sprintf(`{
+			"sourceBucket": "bucket",
+			"destinationBucket": "arn:aws:s3:::inventory-bucket",
+			"version": "2016-11-30",
+			"creationTimestamp": "1704157200000",
+			"fileFormat": %q,
+			"fileSchema": "Bucket, Key, VersionId, IsLatest, IsDeleteMarker, Size",
+			"files": [%s]
+		}`, format, strings.Join(entries, ", ")))
+	}
+
+	BeforeEach(func() {
+		s3client = &fakes.FakeS3Client{}
+		source = s3resource.Source{
+			Bucket: "bucket",
+			Regexp: "files/abc-(.*).tgz",
+			Inventory: s3resource.Inventory{
+				Bucket: "inventory-bucket",
+				Prefix: "inventory/bucket/all",
+			},
+		}
+
+		reports = s3resource.BucketListChunk{
+			CommonPrefixes: []string{
+				"inventory/bucket/all/2024-01-01T01-00Z/",
+				"inventory/bucket/all/2024-01-02T01-00Z/",
+				"inventory/bucket/all/2024-01-03T01-00Z/",
+				"inventory/bucket/all/data/",
+				"inventory/bucket/all/hive/",
+			},
+		}
+		s3client.ChunkedBucketListReturns(reports, nil)
+
+		files = map[string][]byte{
+			"inventory/bucket/all/2024-01-01T01-00Z/manifest.json": manifest("CSV"),
+			"inventory/bucket/all/2024-01-02T01-00Z/manifest.json": manifest("CSV", "inventory/bucket/all/data/1.csv.gz", "inventory/bucket/all/data/2.csv.gz"),
+			"inventory/bucket/all/data/1.csv.gz": gzipped(
+				`"bucket","files/abc-1.tgz","v1","true","false","1"`,
+				`"bucket","files/abc-2.tgz","v3","false","false","1"`,
+				`"bucket","files/abc-2.tgz","v2","false","false","1"`,
+			),
+			"inventory/bucket/all/data/2.csv.gz": gzipped(
+				`"bucket","files/abc-3.tgz","v5","true","true","1"`,
+				`"bucket","files/abc-3.tgz","v4","false","false","1"`,
+				`"bucket","files/abc-4%2Brc.tgz","v6","true","false","1"`,
+				`"bucket","files/nested/abc-5.tgz","v7","true","false","1"`,
+				`"bucket","other/abc-6.tgz","v8","true","false","1"`,
+			),
+		}
+		s3client.OpenFileStub = func(ctx context.Context, bucketName string, remotePath string) (io.ReadCloser, error) {
+			Ω(bucketName).Should(Equal("inventory-bucket"))
+
+			contents, ok := files[remotePath]
+			if !ok {
+				return nil, &s3resource.Error{Kind: s3resource.ErrNotFound, Err: errors.New("NoSuchKey")}
+			}
+			return io.NopCloser(bytes.NewReader(contents)), nil
+		}
+
+		s3client.BucketFilesModifiedSinceReturns([]string{
+			"files/abc-1.tgz",
+			"files/abc-10.tgz",
+			"files/nested/abc-8.tgz",
+			"files/zzz",
+		}, nil)
+	})
+
+	It("reads the latest versions from the latest report, and lists the objects modified since", func() {
+		matchingPaths, err := versions.GetMatchingPathsFromInventory(context.Background(), s3client, source)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(matchingPaths).Should(ConsistOf(
+			"files/abc-1.tgz",
+			"files/abc-10.tgz",
+			"files/abc-4+rc.tgz",
+		))
+
+		_, bucketName, prefix, _ := s3client.ChunkedBucketListArgsForCall(0)
+		Ω(bucketName).Should(Equal("inventory-bucket"))
+		Ω(prefix).Should(Equal("inventory/bucket/all/"))
+
+		Ω(s3client.BucketFilesModifiedSinceCallCount()).Should(Equal(1))
+		_, bucketName, prefix, since := s3client.BucketFilesModifiedSinceArgsForCall(0)
+		Ω(bucketName).Should(Equal("bucket"))
+		Ω(prefix).Should(Equal("files/"))
+		Ω(since).Should(Equal(time.UnixMilli(1704157200000)))
+	})
+
+	It("reads the inventory from the bucket itself by default", func() {
+		source.Inventory.Bucket = ""
+		s3client.OpenFileReturns(nil, &s3resource.Error{Kind: s3resource.ErrNotFound, Err: errors.New("NoSuchKey")})
+
+		_, err := versions.GetMatchingPathsFromInventory(context.Background(), s3client, source)
+		Ω(errors.Is(err, versions.ErrNoInventory)).Should(BeTrue())
+
+		_, bucketName, _, _ := s3client.ChunkedBucketListArgsForCall(0)
+		Ω(bucketName).Should(Equal("bucket"))
+	})
+
+	It("returns no paths when neither the report nor the objects modified since match", func() {
+		source.Regexp = "other/xyz-(.*).tgz"
+
+		matchingPaths, err := versions.GetMatchingPathsFromInventory(context.Background(), s3client, source)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(matchingPaths).Should(BeEmpty())
+		Ω(s3client.ChunkedBucketListCallCount()).Should(Equal(1))
+
+		_, _, prefix, _ := s3client.BucketFilesModifiedSinceArgsForCall(0)
+		Ω(prefix).Should(Equal("other/"))
+	})
+
+	It("only reads the report when the regexp has no prefix to list", func() {
+		source.Regexp = "[a-z]+/abc-(.*).tgz"
+
+		matchingPaths, err := versions.GetMatchingPathsFromInventory(context.Background(), s3client, source)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(matchingPaths).Should(ConsistOf(
+			"files/abc-1.tgz",
+			"files/abc-4+rc.tgz",
+			"other/abc-6.tgz",
+		))
+		Ω(s3client.BucketFilesModifiedSinceCallCount()).Should(Equal(0))
+	})
+
+	It("returns ErrNoInventory when no report has been delivered", func() {
+		s3client.ChunkedBucketListReturns(s3resource.BucketListChunk{
+			CommonPrefixes: []string{"inventory/bucket/all/data/"},
+		}, nil)
+
+		_, err := versions.GetMatchingPathsFromInventory(context.Background(), s3client, source)
+		Ω(errors.Is(err, versions.ErrNoInventory)).Should(BeTrue())
+		Ω(err).Should(MatchError(ContainSubstring("s3://inventory-bucket/inventory/bucket/all/")))
+	})
+
+	It("reads reports in the Parquet format", func() {
+		// The files have the columns of S3 reports, see testdata/generate_parquet.go
+		files["inventory/bucket/all/2024-01-02T01-00Z/manifest.json"] = manifest("Parquet", "inventory/bucket/all/data/1.parquet", "inventory/bucket/all/data/2.parquet")
+		files["inventory/bucket/all/data/1.parquet"] = testdata("inventory-1.snappy.parquet")
+		files["inventory/bucket/all/data/2.parquet"] = testdata("inventory-2.gzip.parquet")
+
+		matchingPaths, err := versions.GetMatchingPathsFromInventory(context.Background(), s3client, source)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(matchingPaths).Should(ConsistOf(
+			"files/abc-1.tgz",
+			"files/abc-3+rc.tgz",
+			"files/abc-5.tgz",
+			"files/abc-6.tgz",
+			"files/abc-10.tgz",
+		))
+	})
+
+	It("fails on Parquet files it cannot read", func() {
+		files["inventory/bucket/all/2024-01-02T01-00Z/manifest.json"] = manifest("Parquet", "inventory/bucket/all/data/1.parquet")
+
+		files["inventory/bucket/all/data/1.parquet"] = testdata("inventory-3.brotli.parquet")
+		_, err := versions.GetMatchingPathsFromInventory(context.Background(), s3client, source)
+		Ω(err).Should(MatchError(ContainSubstring("parquet files compressed with BROTLI are not supported")))
+
+		// The pages of the first row group are overwritten
+		corrupted := testdata("inventory-1.snappy.parquet")
+		for i := 4; i < 1024; i++ {
+			corrupted[i] = 0xff
+		}
+		files["inventory/bucket/all/data/1.parquet"] = corrupted
+		_, err = versions.GetMatchingPathsFromInventory(context.Background(), s3client, source)
+		Ω(err).Should(MatchError(ContainSubstring("parquet column key has fewer values than rows")))
+	})
+
+	It("reads reports in the ORC format", func() {
+		types := map[string]uint64{"bucket": 7, "key": 7, "is_latest": 0, "is_delete_marker": 0}
+		names := []string{"bucket", "key", "is_latest", "is_delete_marker"}
+
+		// The first stripe has a null flag, the second a dictionary of keys
+		stripes := []orcStripe{
+			{
+				numRows:   4,
+				encodings: []uint64{0, 2, 2, 0, 0},
+				streams: []orcStream{
+					{kind: 1, column: 1, data: []byte(strings.Repeat("bucket", 4))},
+					{kind: 2, column: 1, data: []byte{0x01, 0x06}},
+					{kind: 1, column: 2, data: []byte("files/abc-1.tgzfiles/abc-2.tgzfiles/abc-3+rc.tgzfiles/abc-4.tgz")},
+					{kind: 2, column: 2, data: orcDirectV2(15, 15, 18, 15)},
+					{kind: 0, column: 3, data: orcBooleans(true, true, false, true)},
+					{kind: 1, column: 3, data: orcBooleans(true, false, true)},
+					{kind: 1, column: 4, data: orcBooleans(false, false, false, true)},
+				},
+			},
+			{
+				numRows:         3,
+				encodings:       []uint64{0, 2, 1, 0, 0},
+				dictionarySizes: map[int]uint64{2: 2},
+				streams: []orcStream{
+					{kind: 1, column: 1, data: []byte(strings.Repeat("bucket", 3))},
+					{kind: 2, column: 1, data: []byte{0x00, 0x06}},
+					{kind: 1, column: 2, data: orcLiteralsV1(0, 1, 0)},
+					{kind: 2, column: 2, data: orcLiteralsV1(15, 15)},
+					{kind: 3, column: 2, data: []byte("files/abc-5.tgzother/abc-8.tgz")},
+					{kind: 1, column: 3, data: orcBooleans(true, true, false)},
+					{kind: 1, column: 4, data: orcBooleans(false, false, false)},
+				},
+			},
+		}
+
+		for _, compression := range []uint64{0, 1, 2} {
+			files["inventory/bucket/all/2024-01-02T01-00Z/manifest.json"] = manifest("ORC", "inventory/bucket/all/data/1.orc")
+			files["inventory/bucket/all/data/1.orc"] = orcFile(compression, types, names, stripes...)
+
+			matchingPaths, err := versions.GetMatchingPathsFromInventory(context.Background(), s3client, source)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(matchingPaths).Should(ConsistOf(
+				"files/abc-1.tgz",
+				"files/abc-3+rc.tgz",
+				"files/abc-5.tgz",
+				"files/abc-10.tgz",
+			), fmt.Sprintf("compression %d", compression))
+		}
+
+		files["inventory/bucket/all/data/1.orc"] = orcFile(5, types, names, stripes...)
+		_, err := versions.GetMatchingPathsFromInventory(context.Background(), s3client, source)
+		Ω(err).Should(MatchError(ContainSubstring("orc files compressed with ZSTD are not supported")))
+	})
+
+	It("rejects reports in other formats", func() {
+		files["inventory/bucket/all/2024-01-02T01-00Z/manifest.json"] = manifest("JSON", "inventory/bucket/all/data/1.json")
+
+		_, err := versions.GetMatchingPathsFromInventory(context.Background(), s3client, source)
+		Ω(errors.Is(err, s3resource.ErrInvalidConfig)).Should(BeTrue())
+		Ω(err).Should(MatchError(ContainSubstring("reports in JSON format are not supported")))
+	})
+
+	It("rejects inventories of other buckets", func() {
+		source.Bucket = "other-bucket"
+
+		_, err := versions.GetMatchingPathsFromInventory(context.Background(), s3client, source)
+		Ω(errors.Is(err, s3resource.ErrInvalidConfig)).Should(BeTrue())
+	})
+
+	It("fails when a file of the report cannot be read", func() {
+		files["inventory/bucket/all/data/2.csv.gz"] = []byte("not gzipped")
+
+		_, err := versions.GetMatchingPathsFromInventory(context.Background(), s3client, source)
+		Ω(err).Should(MatchError(ContainSubstring("error reading inventory file inventory/bucket/all/data/2.csv.gz")))
+
+		for format, message := range map[string]string{"Parquet": "not a parquet file", "ORC": "not an orc file"} {
+			files["inventory/bucket/all/2024-01-02T01-00Z/manifest.json"] = manifest(format, "inventory/bucket/all/data/1.file")
+			files["inventory/bucket/all/data/1.file"] = []byte("not " + format)
+
+			_, err := versions.GetMatchingPathsFromInventory(context.Background(), s3client, source)
+			Ω(err).Should(MatchError(ContainSubstring(message)))
+		}
+	})
+
+	Describe("GetInventoryFileVersions", func() {
+		var (
+			existing []string
+			lookups  []string
+		)
+
+		paths := func(extractions versions.Extractions) []string {
+			paths := make([]string, len(extractions))
+			for i, extraction := range extractions {
+				paths[i] = extraction.Path
+			}
+			return paths
+		}
+
+		BeforeEach(func() {
+			// The report has abc-1 and abc-4+rc, and abc-10 was put since
+			existing = []string{"files/abc-1.tgz", "files/abc-4+rc.tgz", "files/abc-10.tgz"}
+			lookups = nil
+
+			s3client.BucketFilesModifiedSinceReturns([]string{"files/abc-10.tgz"}, nil)
+			s3client.ChunkedBucketListStub = func(ctx context.Context, bucketName string, prefix string, continuationToken *string) (s3resource.BucketListChunk, error) {
+				if bucketName == "inventory-bucket" {
+					return reports, nil
+				}
+
+				lookups = append(lookups, prefix)
+				chunk := s3resource.BucketListChunk{}
+				for _, path := range existing {
+					if strings.HasPrefix(path, prefix) {
+						chunk.Paths = append(chunk.Paths, path)
+					}
+				}
+				return chunk, nil
+			}
+		})
+
+		It("looks up only the latest version from the report", func() {
+			s3client.BucketFilesModifiedSinceReturns(nil, nil)
+
+			extractions, err := versions.GetInventoryFileVersions(context.Background(), s3client, source, "")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(paths(extractions)).Should(Equal([]string{"files/abc-1.tgz", "files/abc-4+rc.tgz"}))
+			Ω(lookups).Should(Equal([]string{"files/abc-4+rc.tgz"}))
+		})
+
+		It("doesn't look up the objects listed since the report", func() {
+			extractions, err := versions.GetInventoryFileVersions(context.Background(), s3client, source, "")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(paths(extractions)).Should(Equal([]string{"files/abc-1.tgz", "files/abc-4+rc.tgz", "files/abc-10.tgz"}))
+			Ω(lookups).Should(BeEmpty())
+		})
+
+		It("leaves out the latest versions deleted since the report", func() {
+			s3client.BucketFilesModifiedSinceReturns(nil, nil)
+			existing = []string{"files/abc-1.tgz"}
+
+			extractions, err := versions.GetInventoryFileVersions(context.Background(), s3client, source, "")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(paths(extractions)).Should(Equal([]string{"files/abc-1.tgz"}))
+			Ω(lookups).Should(Equal([]string{"files/abc-4+rc.tgz", "files/abc-1.tgz"}))
+		})
+
+		It("looks up every version from the one of the last path on", func() {
+			existing = []string{"files/abc-4+rc.tgz", "files/abc-10.tgz"}
+
+			extractions, err := versions.GetInventoryFileVersions(context.Background(), s3client, source, "files/abc-1.tgz")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(paths(extractions)).Should(Equal([]string{"files/abc-4+rc.tgz", "files/abc-10.tgz"}))
+			Ω(lookups).Should(Equal([]string{"files/abc-4+rc.tgz", "files/abc-1.tgz"}))
+		})
+
+		It("doesn't take an object which starts with the path for it", func() {
+			s3client.BucketFilesModifiedSinceReturns(nil, nil)
+			existing = []string{"files/abc-1.tgz", "files/abc-4+rc.tgz.sig"}
+
+			extractions, err := versions.GetInventoryFileVersions(context.Background(), s3client, source, "")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(paths(extractions)).Should(Equal([]string{"files/abc-1.tgz"}))
+		})
+	})
+})
+
+func testdata(name string) []byte {
+	contents, err := os.ReadFile(filepath.Join("testdata", name))
+	Ω(err).ShouldNot(HaveOccurred())
+	return contents
+}
@@ -0,0 +1,130 @@
+//go:build ignore
+
+// This program writes the Parquet inventory reports of the tests with the
+// columns S3 Inventory writes, run it from the versions directory
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+type versionedRow struct {
+	Bucket           string  `parquet:"name=bucket, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=REQUIRED"`
+	Key              string  `parquet:"name=key, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, repetitiontype=REQUIRED"`
+	VersionID        *string `parquet:"name=version_id, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	IsLatest         *bool   `parquet:"name=is_latest, type=BOOLEAN, repetitiontype=OPTIONAL"`
+	IsDeleteMarker   *bool   `parquet:"name=is_delete_marker, type=BOOLEAN, repetitiontype=OPTIONAL"`
+	Size             *int64  `parquet:"name=size, type=INT64, repetitiontype=OPTIONAL"`
+	LastModifiedDate *int64  `parquet:"name=last_modified_date, type=INT64, convertedtype=TIMESTAMP_MILLIS, repetitiontype=OPTIONAL"`
+	ETag             *string `parquet:"name=e_tag, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+}
+
+// unversionedRow is a row of a report which leaves out the versions
+type unversionedRow struct {
+	Bucket           string  `parquet:"name=bucket, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=REQUIRED"`
+	Key              string  `parquet:"name=key, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=REQUIRED"`
+	Size             *int64  `parquet:"name=size, type=INT64, repetitiontype=OPTIONAL"`
+	LastModifiedDate *int64  `parquet:"name=last_modified_date, type=INT64, convertedtype=TIMESTAMP_MILLIS, repetitiontype=OPTIONAL"`
+	ETag             *string `parquet:"name=e_tag, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+}
+
+type localFile struct {
+	*os.File
+}
+
+func (file localFile) Open(name string) (source.ParquetFile, error) {
+	f, err := os.Open(name)
+	return localFile{f}, err
+}
+
+func (file localFile) Create(name string) (source.ParquetFile, error) {
+	f, err := os.Create(name)
+	return localFile{f}, err
+}
+
+// write writes rows to the file name, starting a row group every
+// rowGroupSize rows
+func write[T any](name string, codec parquet.CompressionCodec, rowGroupSize int, rows []T) {
+	file, err := localFile{}.Create(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	parquetWriter, err := writer.NewParquetWriter(file, new(T), 1)
+	if err != nil {
+		log.Fatal(err)
+	}
+	parquetWriter.CompressionType = codec
+
+	for i, row := range rows {
+		if err := parquetWriter.Write(row); err != nil {
+			log.Fatal(err)
+		}
+		if (i+1)%rowGroupSize == 0 {
+			if err := parquetWriter.Flush(true); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	if err := parquetWriter.WriteStop(); err != nil {
+		log.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func version(key string, versionID string, isLatest *bool, isDeleteMarker *bool) versionedRow {
+	size, modified, etag := int64(1), int64(1704070800000), "d41d8cd98f00b204e9800998ecf8427e"
+	row := versionedRow{
+		Bucket:           "bucket",
+		Key:              key,
+		IsLatest:         isLatest,
+		IsDeleteMarker:   isDeleteMarker,
+		LastModifiedDate: &modified,
+	}
+	if versionID != "" {
+		row.VersionID = &versionID
+	}
+	if isDeleteMarker == nil || !*isDeleteMarker {
+		row.Size = &size
+		row.ETag = &etag
+	}
+	return row
+}
+
+func main() {
+	yes, no := true, false
+
+	// Keys aren't URL encoded, and the flags of objects put before
+	// versioning was enabled may be null
+	write("testdata/inventory-1.snappy.parquet", parquet.CompressionCodec_SNAPPY, 4, []versionedRow{
+		version("files/abc-1.tgz", "v1", &yes, &no),
+		version("files/abc-2.tgz", "v4", &yes, &yes),
+		version("files/abc-2.tgz", "v3", &no, &no),
+		version("files/abc-2.tgz", "v2", &no, &no),
+		version("files/abc-3+rc.tgz", "", nil, nil),
+		version("files/abc-4.tgz", "v6", &no, &no),
+		version("files/abc-5.tgz", "v7", &yes, &no),
+		version("files/nested/abc-8.tgz", "v8", &yes, &no),
+		version("other/abc-9.tgz", "v9", &yes, &no),
+	})
+
+	// More rows than are read at once
+	var rows []unversionedRow
+	for i := range 12000 {
+		rows = append(rows, unversionedRow{Bucket: "bucket", Key: fmt.Sprintf("other/xyz-%d.tgz", i)})
+	}
+	rows = append(rows, unversionedRow{Bucket: "bucket", Key: "files/abc-6.tgz"})
+	write("testdata/inventory-2.gzip.parquet", parquet.CompressionCodec_GZIP, 5000, rows)
+
+	write("testdata/inventory-3.brotli.parquet", parquet.CompressionCodec_BROTLI, 1, []versionedRow{
+		version("files/abc-7.tgz", "v10", &yes, &no),
+	})
+}
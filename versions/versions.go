@@ -1,7 +1,10 @@
 package versions
 
 import (
+	"context"
+	"fmt"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 
@@ -97,6 +100,10 @@ type Extraction struct {
 	VersionNumber string
 }
 
+// specialCharsRE matches the sections of a regex which can match more than
+// a single string
+var specialCharsRE = regexp.MustCompile(`[\\\*\.\[\]\(\)\{\}\?\|\^\$\+]`)
+
 // GetMatchingPathsFromBucket gets all the paths in the S3 bucket `bucketName` which match all the sections of `regex`
 //
 // `regex` is a forward-slash (`/`) delimited list of regular expressions that
@@ -107,14 +114,12 @@ type Extraction struct {
 // collects the full paths that matches `regex` along the way. It takes care of
 // following only the branches (prefix in S3 terms) that matches with the
 // corresponding section of `regex`.
-func GetMatchingPathsFromBucket(client s3resource.S3Client, bucketName string, regex string) ([]string, error) {
+func GetMatchingPathsFromBucket(ctx context.Context, client s3resource.S3Client, bucketName string, regex string) ([]string, error) {
 	type work struct {
 		prefix  string
 		remains []string
 	}
 
-	specialCharsRE := regexp.MustCompile(`[\\\*\.\[\]\(\)\{\}\?\|\^\$\+]`)
-
 	if strings.HasPrefix(regex, "^") {
 		regex = regex[1:]
 	}
@@ -150,7 +155,7 @@ func GetMatchingPathsFromBucket(client s3resource.S3Client, bucketName string, r
 			truncated         bool
 		)
 		for continuationToken, truncated = nil, true; truncated; {
-			s3ListChunk, err := client.ChunkedBucketList(bucketName, prefix, continuationToken)
+			s3ListChunk, err := client.ChunkedBucketList(ctx, bucketName, prefix, continuationToken)
 			if err != nil {
 				return []string{}, err
 			}
@@ -177,16 +182,130 @@ func GetMatchingPathsFromBucket(client s3resource.S3Client, bucketName string, r
 	return matchingPaths, nil
 }
 
-func GetBucketFileVersions(client s3resource.S3Client, source s3resource.Source) Extractions {
-	regex := source.Regexp
+// pathMatcher matches paths the way GetMatchingPathsFromBucket does, each
+// "/" delimited section of the regex against the same section of the path
+type pathMatcher struct {
+	// prefix is made of the leading sections without special chars, which
+	// every matching path starts with
+	prefix   string
+	sections []*regexp.Regexp
+}
+
+func newPathMatcher(regex string) (pathMatcher, error) {
+	regex = strings.TrimPrefix(regex, "^")
+	regex = strings.TrimSuffix(regex, "$")
+
+	matcher := pathMatcher{}
+	literal := true
+	sections := strings.Split(regex, "/")
+	for i, section := range sections {
+		sectionRE, err := regexp.Compile("^" + section + "$")
+		if err != nil {
+			return pathMatcher{}, err
+		}
+		matcher.sections = append(matcher.sections, sectionRE)
+
+		literal = literal && !specialCharsRE.MatchString(section) && i != len(sections)-1
+		if literal {
+			matcher.prefix += section + "/"
+		}
+	}
+
+	return matcher, nil
+}
+
+func (matcher pathMatcher) MatchString(path string) bool {
+	sections := strings.Split(path, "/")
+	if len(sections) != len(matcher.sections) {
+		return false
+	}
+	for i, section := range sections {
+		if !matcher.sections[i].MatchString(section) {
+			return false
+		}
+	}
+	return true
+}
 
-	matchingPaths, err := GetMatchingPathsFromBucket(client, source.Bucket, regex)
+// MatchPaths returns the paths which match `regex` the way
+// GetMatchingPathsFromBucket matches the paths in the bucket
+func MatchPaths(paths []string, regex string) ([]string, error) {
+	matcher, err := newPathMatcher(regex)
 	if err != nil {
-		s3resource.Fatal("listing files", err)
+		return []string{}, err
+	}
+
+	matchingPaths := []string{}
+	for _, path := range paths {
+		if matcher.MatchString(path) {
+			matchingPaths = append(matchingPaths, path)
+		}
 	}
+	return matchingPaths, nil
+}
 
-	var extractions = make(Extractions, 0, len(matchingPaths))
-	for _, path := range matchingPaths {
+func GetBucketFileVersions(ctx context.Context, client s3resource.S3Client, source s3resource.Source) (Extractions, error) {
+	matchingPaths, err := GetMatchingPathsFromBucket(ctx, client, source.Bucket, source.Regexp)
+	if err != nil {
+		return nil, fmt.Errorf("error listing files: %w", err)
+	}
+
+	return ExtractVersions(matchingPaths, source.Regexp), nil
+}
+
+// GetInventoryFileVersions is GetBucketFileVersions reading the paths from
+// the inventory of the bucket, see GetMatchingPathsFromInventory.
+//
+// The objects of the versions from the report which check returns, i.e. the
+// ones from the version of lastPath on, or the latest one if lastPath
+// doesn't match, are looked up, and left out if they were deleted since the
+// report.
+func GetInventoryFileVersions(ctx context.Context, client s3resource.S3Client, source s3resource.Source, lastPath string) (Extractions, error) {
+	reportedPaths, listedPaths, err := readInventoryPaths(ctx, client, source)
+	if err != nil {
+		return nil, fmt.Errorf("error reading inventory: %w", err)
+	}
+
+	matchingPaths := append(reportedPaths, listedPaths...)
+	slices.Sort(matchingPaths)
+	extractions := ExtractVersions(slices.Compact(matchingPaths), source.Regexp)
+
+	listed := make(map[string]bool, len(listedPaths))
+	for _, path := range listedPaths {
+		listed[path] = true
+	}
+
+	lastVersion, matched := Extract(lastPath, source.Regexp)
+	for i := len(extractions) - 1; i >= 0; i-- {
+		extraction := extractions[i]
+		if matched && extraction.Version.Compare(lastVersion.Version) < 0 {
+			break
+		}
+
+		if !listed[extraction.Path] {
+			exists, err := objectExists(ctx, client, source.Bucket, extraction.Path)
+			if err != nil {
+				return nil, fmt.Errorf("error finding %s: %w", extraction.Path, err)
+			}
+			if !exists {
+				extractions = slices.Delete(extractions, i, i+1)
+				continue
+			}
+		}
+
+		if !matched {
+			break
+		}
+	}
+
+	return extractions, nil
+}
+
+// ExtractVersions extracts the versions of the paths which match `regex`,
+// sorted from the oldest to the latest
+func ExtractVersions(paths []string, regex string) Extractions {
+	var extractions = make(Extractions, 0, len(paths))
+	for _, path := range paths {
 		extraction, ok := Extract(path, regex)
 
 		if ok {
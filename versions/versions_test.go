@@ -1,6 +1,7 @@
 package versions_test
 
 import (
+	"context"
 	"errors"
 
 	s3resource "github.com/concourse/s3-resource"
@@ -152,17 +153,17 @@ var _ = Describe("GetMatchingPathsFromBucket", func() {
 	Context("When the regexp has no '/'", func() {
 		Context("when the regexp has no special char", func() {
 			It("uses only the empty string as prefix", func() {
-				versions.GetMatchingPathsFromBucket(s3client, "bucket", "regexp")
+				versions.GetMatchingPathsFromBucket(context.Background(), s3client, "bucket", "regexp")
 				Ω(s3client.ChunkedBucketListCallCount()).Should(Equal(1))
-				_, prefix, _ := s3client.ChunkedBucketListArgsForCall(0)
+				_, _, prefix, _ := s3client.ChunkedBucketListArgsForCall(0)
 				Ω(prefix).Should(Equal(""))
 			})
 		})
 		Context("when the regexp has a special char", func() {
 			It("uses only the empty string as prefix", func() {
-				versions.GetMatchingPathsFromBucket(s3client, "bucket", "reg.xp")
+				versions.GetMatchingPathsFromBucket(context.Background(), s3client, "bucket", "reg.xp")
 				Ω(s3client.ChunkedBucketListCallCount()).Should(Equal(1))
-				_, prefix, _ := s3client.ChunkedBucketListArgsForCall(0)
+				_, _, prefix, _ := s3client.ChunkedBucketListArgsForCall(0)
 				Ω(prefix).Should(Equal(""))
 			})
 		})
@@ -171,10 +172,11 @@ var _ = Describe("GetMatchingPathsFromBucket", func() {
 	Context("When regexp special char appears close to the leaves", func() {
 		It("starts directly with the longest prefix", func() {
 			versions.GetMatchingPathsFromBucket(
+				context.Background(),
 				s3client, "bucket", "regexp/will/appear/only/close/tw?o+/leaves",
 			)
 			Ω(s3client.ChunkedBucketListCallCount()).Should(Equal(1))
-			_, prefix, _ := s3client.ChunkedBucketListArgsForCall(0)
+			_, _, prefix, _ := s3client.ChunkedBucketListArgsForCall(0)
 			Ω(prefix).Should(Equal("regexp/will/appear/only/close/"))
 		})
 
@@ -204,6 +206,7 @@ var _ = Describe("GetMatchingPathsFromBucket", func() {
 			}, nil)
 
 			matchingPaths, err := versions.GetMatchingPathsFromBucket(
+				context.Background(),
 				s3client, "bucket", "regexp/will/appear/only/close/tw?o+/leaves",
 			)
 			Ω(err).ShouldNot(HaveOccurred())
@@ -214,7 +217,7 @@ var _ = Describe("GetMatchingPathsFromBucket", func() {
 				"regexp/will/appear/only/close/too/",
 				"regexp/will/appear/only/close/two/",
 			} {
-				_, prefix, _ := s3client.ChunkedBucketListArgsForCall(idx)
+				_, _, prefix, _ := s3client.ChunkedBucketListArgsForCall(idx)
 				Ω(prefix).Should(Equal(expectedPrefix))
 			}
 			Ω(matchingPaths).Should(ConsistOf("regexp/will/appear/only/close/to/leaves"))
@@ -239,6 +242,7 @@ var _ = Describe("GetMatchingPathsFromBucket", func() {
 			}, nil)
 
 			matchingPaths, err := versions.GetMatchingPathsFromBucket(
+				context.Background(),
 				s3client, "bucket", "prefix/leaf-(.*)",
 			)
 			Ω(err).ShouldNot(HaveOccurred())
@@ -281,6 +285,7 @@ var _ = Describe("GetMatchingPathsFromBucket", func() {
 			}, nil)
 
 			matchingPaths, err := versions.GetMatchingPathsFromBucket(
+				context.Background(),
 				s3client, "bucket", "prefix-\\d+/leaf-(.*)",
 			)
 			Ω(err).ShouldNot(HaveOccurred())
@@ -305,6 +310,7 @@ var _ = Describe("GetMatchingPathsFromBucket", func() {
 			}, nil)
 
 			matchingPaths, err := versions.GetMatchingPathsFromBucket(
+				context.Background(),
 				s3client, "bucket", "sub(.*)ing",
 			)
 			Ω(err).ShouldNot(HaveOccurred())
@@ -323,11 +329,12 @@ var _ = Describe("GetMatchingPathsFromBucket", func() {
 			}, nil)
 
 			matchingPaths, err := versions.GetMatchingPathsFromBucket(
+				context.Background(),
 				s3client, "bucket", "pre/(.*)ing",
 			)
 			Ω(err).ShouldNot(HaveOccurred())
 			Ω(s3client.ChunkedBucketListCallCount()).Should(Equal(1))
-			_, prefix, _ := s3client.ChunkedBucketListArgsForCall(0)
+			_, _, prefix, _ := s3client.ChunkedBucketListArgsForCall(0)
 			Ω(prefix).Should(Equal("pre/"))
 			Ω(matchingPaths).Should(ConsistOf("pre/ssing"))
 		})
@@ -345,6 +352,7 @@ var _ = Describe("GetMatchingPathsFromBucket", func() {
 			}, nil)
 
 			matchingPaths, err := versions.GetMatchingPathsFromBucket(
+				context.Background(),
 				s3client, "bucket", "^sub(.*)ing$",
 			)
 			Ω(err).ShouldNot(HaveOccurred())
@@ -363,11 +371,12 @@ var _ = Describe("GetMatchingPathsFromBucket", func() {
 			}, nil)
 
 			matchingPaths, err := versions.GetMatchingPathsFromBucket(
+				context.Background(),
 				s3client, "bucket", "^pre/(.*)ing$",
 			)
 			Ω(err).ShouldNot(HaveOccurred())
 			Ω(s3client.ChunkedBucketListCallCount()).Should(Equal(1))
-			_, prefix, _ := s3client.ChunkedBucketListArgsForCall(0)
+			_, _, prefix, _ := s3client.ChunkedBucketListArgsForCall(0)
 			Ω(prefix).Should(Equal("pre/"))
 			Ω(matchingPaths).Should(ConsistOf("pre/ssing"))
 		})
@@ -381,7 +390,7 @@ var _ = Describe("GetMatchingPathsFromBucket", func() {
 			)
 		})
 		It("fails", func() {
-			_, err := versions.GetMatchingPathsFromBucket(s3client, "bucket", "dummy")
+			_, err := versions.GetMatchingPathsFromBucket(context.Background(), s3client, "bucket", "dummy")
 			Ω(err).Should(HaveOccurred())
 		})
 	})